@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flash-learn/internal/config"
 	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/metrics"
 	"flash-learn/internal/model"
 	"flash-learn/internal/utils"
 	"fmt"
@@ -10,6 +15,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -18,13 +25,52 @@ const (
 	GetSingleDeckNotFoundErrorMessage string = "Deck not found"
 	InternalServerErrorMessage        string = "Internal server error"
 	DuplicateKeyViolationErrorMessage string = "Duplicate key violation"
+	InvalidParentDeckErrorMessage     string = "Invalid parent deck"
+	InvalidQueryErrorMessage          string = "Invalid query parameter"
+	InvalidPresetIDErrorMessage       string = "Invalid deck preset ID"
+	InvalidTargetDeckErrorMessage     string = "Invalid target deck"
+	InvalidCardIDErrorMessage         string = "Invalid card ID"
+	InvalidRevisionErrorMessage       string = "Invalid card revision"
+	InvalidFlagErrorMessage           string = "Invalid flag"
+	InvalidGradeErrorMessage          string = "Invalid grade"
+	NotFilteredDeckErrorMessage       string = "Not a filtered deck"
+	FilteredDeckErrorMessage          string = "Not allowed in a filtered deck"
+	InvalidStudySessionErrorMessage   string = "Invalid study session"
+	InvalidStudySessionIDErrorMessage string = "Invalid study session ID"
+	StudySessionFinishedErrorMessage  string = "Study session finished"
+	StudySessionEndedErrorMessage     string = "Study session ended"
+	NothingToUndoErrorMessage         string = "Nothing to undo"
+	NoOptimizeResultErrorMessage      string = "Job has no fitted parameters"
+	NoDeckPresetErrorMessage          string = "Deck isn't linked to a preset"
+	InvalidJobIDErrorMessage          string = "Invalid job ID"
+	JobFinishedErrorMessage           string = "Job already finished"
+	JobAppliedErrorMessage            string = "Job already applied"
+	PresetChangedErrorMessage         string = "Deck preset changed since the job started"
+	CORSOriginErrorMessage            string = "Origin not allowed"
+	RequestTooLargeErrorMessage       string = "Request body too large"
+	RequestTimeoutErrorMessage        string = "Request timed out"
+	CardChangedErrorMessage           string = "Card changed since it was read"
+	CardAnsweredAgainErrorMessage     string = "Card answered again since"
+	StudySessionChangedErrorMessage   string = "Study session changed since it was read"
+	NotCurrentCardErrorMessage        string = "Not the current card of the study session"
 )
 
 type APIServer struct {
-	address string
-	deck_db database.DBWrapper
-	card_db database.CardDBWrapperInterface
-	server  *http.Server
+	address     string
+	deck_db     database.DBWrapper
+	card_db     database.CardDBWrapperInterface
+	preset_db   database.PresetDBWrapperInterface
+	flag_db     database.FlagDBWrapperInterface
+	filtered_db database.FilteredDeckDBWrapperInterface
+	session_db  database.StudySessionDBWrapperInterface
+	job_runner  *jobs.Runner
+	settings    config.ServerConfig
+	registry    *metrics.Registry
+	metrics     *apiMetrics
+
+	mu     sync.Mutex
+	server *http.Server
+	closed bool
 }
 
 // NewAPIServer creates a new instance of APIServer.
@@ -32,73 +78,198 @@ type APIServer struct {
 // The address is the server's listening address, and the db is the database wrapper
 // used for database operations.
 func NewAPIServer(address string, deck_db database.DBWrapper, card_db database.CardDBWrapperInterface) *APIServer {
+	settings := config.Default().Server
+	settings.Address = address
+
+	registry := metrics.NewRegistry()
+
 	return &APIServer{
-		address: address,
-		deck_db: deck_db,
-		card_db: card_db,
+		address:  address,
+		deck_db:  deck_db,
+		card_db:  card_db,
+		settings: settings,
+		registry: registry,
+		metrics:  newAPIMetrics(registry),
 	}
 }
 
-// Start initializes the server and starts listening for incoming requests.
+// WithServerConfig sets the address, the CORS policy and the timeouts of
+// the server. Without it, the defaults of the config package
+// are used along with the address given to NewAPIServer.
+//
+// Parameters:
+//   - settings config.ServerConfig : The server settings.
 //
 // Returns:
-//   - error : An error if the server fails to start, nil otherwise.
-func (s *APIServer) Start() error {
-	if s.server != nil {
-		return nil
-	}
+//   - *APIServer : The same server, to allow chaining.
+func (s *APIServer) WithServerConfig(settings config.ServerConfig) *APIServer {
+	s.address = settings.Address
+	s.settings = settings
+	return s
+}
+
+// WithPresetDB sets the database wrapper used for deck preset operations.
+//
+// Parameters:
+//   - preset_db database.PresetDBWrapperInterface : The deck preset database wrapper.
+//
+// Returns:
+//   - *APIServer : The same server, to allow chaining.
+func (s *APIServer) WithPresetDB(preset_db database.PresetDBWrapperInterface) *APIServer {
+	s.preset_db = preset_db
+	return s
+}
+
+// WithFlagDB sets the database wrapper used for flag definition operations.
+//
+// Parameters:
+//   - flag_db database.FlagDBWrapperInterface : The flag definition database wrapper.
+//
+// Returns:
+//   - *APIServer : The same server, to allow chaining.
+func (s *APIServer) WithFlagDB(flag_db database.FlagDBWrapperInterface) *APIServer {
+	s.flag_db = flag_db
+	return s
+}
+
+// WithFilteredDeckDB sets the database wrapper used for filtered deck operations.
+//
+// Parameters:
+//   - filtered_db database.FilteredDeckDBWrapperInterface : The filtered deck database wrapper.
+//
+// Returns:
+//   - *APIServer : The same server, to allow chaining.
+func (s *APIServer) WithFilteredDeckDB(filtered_db database.FilteredDeckDBWrapperInterface) *APIServer {
+	s.filtered_db = filtered_db
+	return s
+}
+
+// WithStudySessionDB sets the database wrapper used for study session operations.
+//
+// Parameters:
+//   - session_db database.StudySessionDBWrapperInterface : The study session database wrapper.
+//
+// Returns:
+//   - *APIServer : The same server, to allow chaining.
+func (s *APIServer) WithStudySessionDB(session_db database.StudySessionDBWrapperInterface) *APIServer {
+	s.session_db = session_db
+	return s
+}
+
+// WithJobRunner sets the runner of the background jobs, such as the one that
+// reschedules cards when the settings of their deck change. Without it, cards
+// keep their due dates and no job can be enqueued.
+//
+// Parameters:
+//   - job_runner *jobs.Runner : The job runner.
+//
+// Returns:
+//   - *APIServer : The same server, to allow chaining.
+func (s *APIServer) WithJobRunner(job_runner *jobs.Runner) *APIServer {
+	s.job_runner = job_runner
+	return s
+}
+
+// WithMetrics sets the registry the metrics of the server are kept in and
+// served from, so that other metrics such as those of the database can be
+// served along with them. Without it, the server keeps its own registry.
+//
+// Parameters:
+//   - registry *metrics.Registry : The metrics registry.
+//
+// Returns:
+//   - *APIServer : The same server, to allow chaining.
+func (s *APIServer) WithMetrics(registry *metrics.Registry) *APIServer {
+	s.registry = registry
+	s.metrics = newAPIMetrics(registry)
+	return s
+}
 
+// handler creates the router and wraps it with the middlewares every request
+// goes through, from the outermost: the request ID and route, CORS, the access
+// log, the metrics, the panic recovery and the route limits. CORS comes before
+// the middlewares that may answer a request themselves, so that a browser
+// can read their errors.
+//
+// Returns:
+//   - http.Handler
+func (s *APIServer) handler() http.Handler {
 	slog.Debug("Creating router")
 	router := http.NewServeMux()
 	addRoutes(router, s)
 
-	slog.Debug("Creating cors handler")
-	corsHandler := corsMiddleware(router)
+	slog.Debug("Creating middlewares")
+	policy := corsPolicy{
+		origins:          s.settings.CORSOrigins,
+		allowCredentials: s.settings.CORSAllowCredentials,
+	}
+	return chain(router,
+		requestInfoMiddleware(router),
+		corsMiddleware(router, policy),
+		accessLogMiddleware,
+		s.metricsMiddleware,
+		recoverMiddleware,
+		s.limitsMiddleware,
+	)
+}
+
+// Start initializes the server and starts listening for incoming requests.
+// It blocks until the server fails or is shut down with Shutdown.
+//
+// Returns:
+//   - error : An error if the server fails to start or stops unexpectedly, nil once it is shut down.
+func (s *APIServer) Start() error {
+	s.mu.Lock()
+	if s.server != nil || s.closed {
+		s.mu.Unlock()
+		return nil
+	}
 
 	s.server = &http.Server{
-		Addr:    s.address,
-		Handler: corsHandler,
+		Addr:              s.address,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: s.settings.ReadHeaderTimeout,
+		ReadTimeout:       s.settings.ReadTimeout,
+		WriteTimeout:      s.settings.WriteTimeout,
+		IdleTimeout:       s.settings.IdleTimeout,
 	}
+	server := s.server
+	s.mu.Unlock()
 
 	slog.Debug("Starting server")
-	return s.server.ListenAndServe()
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-// corsMiddleware adds CORS headers to the HTTP responses.
+// Shutdown stops the server gracefully. It stops accepting connections and
+// waits for the requests in flight to complete, until the context is done,
+// at which point the remaining connections are closed. A server shut down
+// before it is started never starts.
 //
 // Parameters:
-//   - handler http.Handler
+//   - ctx context.Context : The context bounding how long requests are drained.
 //
 // Returns:
-//   - http.Handler
-func corsMiddleware(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers for all responses
-		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		// Handle preflight OPTIONS requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Pass control to the next handler
-		handler.ServeHTTP(w, r)
-	})
-}
+//   - error : The error of the context if requests were cut off, nil otherwise.
+func (s *APIServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	server := s.server
+	s.mu.Unlock()
 
-// Stop stops the server if it is running.
-//
-// Returns:
-//   - error : An error if the server fails to stop, nil otherwise.
-func (s *APIServer) Stop() error {
-	if s.server == nil {
+	if server == nil {
 		return nil
 	}
 
-	return s.server.Close()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Requests still in flight, closing connections", "error", err)
+		server.Close()
+	}
+	return err
 }
 
 // HandleGetSingleDeck handles the HTTP GET request for retrieving a single deck.
@@ -116,19 +287,19 @@ func (s *APIServer) HandleGetSingleDeck(w http.ResponseWriter, r *http.Request)
 	idStr := strings.Split(r.URL.Path, "/")[2]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Invalid deck ID %s", idStr))
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
 		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
 		return
 	}
 
 	// Fetch from database
-	deck, dbErr := s.deck_db.GetSingle(id)
+	deck, dbErr := s.deck_db.GetSingle(r.Context(), id)
 	if dbErr != nil {
 		if dbErr == utils.ErrRecordNotExist {
-			slog.Debug("Deck not found", "error", dbErr)
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
 			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
 		} else {
-			slog.Debug("Error getting single deck", "error", dbErr)
+			slog.DebugContext(r.Context(), "Error getting single deck", "error", dbErr)
 			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		}
 		return
@@ -142,10 +313,12 @@ func (s *APIServer) HandleGetSingleDeck(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	slog.Debug("Sent response", "deck", deck)
+	slog.DebugContext(r.Context(), "Sent response", "deck", deck)
 }
 
 // HandleGetAllDecks handles the HTTP GET request for retrieving all decks.
+// Each deck is returned along with its total card count, the number of new,
+// learning and review cards due today and its last study date.
 //
 // Parameters:
 //   - w http.ResponseWriter : The response writer to send the response.
@@ -156,9 +329,10 @@ func (s *APIServer) HandleGetSingleDeck(w http.ResponseWriter, r *http.Request)
 //   - 200 OK : If the decks are found and the request is successful.
 func (s *APIServer) HandleGetAllDecks(w http.ResponseWriter, r *http.Request) {
 	// Fetch from database
-	deckArray, err := s.deck_db.GetAll()
+	now := time.Now()
+	summaries, err := s.deck_db.GetAllSummaries(r.Context(), utils.StartOfDay(now), utils.StartOfNextDay(now))
 	if err != nil {
-		slog.Debug("Error getting all decks", "error", err)
+		slog.DebugContext(r.Context(), "Error getting all decks", "error", err)
 		if err == utils.ErrDatabaseNotExist {
 			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		} else {
@@ -167,30 +341,15 @@ func (s *APIServer) HandleGetAllDecks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type DeckOutput struct {
-		ID          string `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-	}
-
-	deckOutputArray := make([]DeckOutput, len(deckArray))
-	for i, deck := range deckArray {
-		deckOutputArray[i] = DeckOutput{
-			ID:          strconv.Itoa(deck.ID),
-			Name:        deck.Name,
-			Description: deck.Description,
-		}
-	}
-
 	// Encode and send response
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(deckOutputArray)
+	err = json.NewEncoder(w).Encode(summaries)
 	if err != nil {
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	slog.Debug("Sent response", "deck", deckOutputArray)
+	slog.DebugContext(r.Context(), "Sent response", "deck", summaries)
 }
 
 // HandleGetDeckCount handles the HTTP GET request for retrieving the count of decks.
@@ -204,9 +363,9 @@ func (s *APIServer) HandleGetAllDecks(w http.ResponseWriter, r *http.Request) {
 //   - 200 OK : If the decks are found and the request is successful.
 func (s *APIServer) HandleGetDeckCount(w http.ResponseWriter, r *http.Request) {
 	// Fetch from database
-	count, err := s.deck_db.GetCount()
+	count, err := s.deck_db.GetCount(r.Context())
 	if err != nil {
-		slog.Debug("Error getting deck count", "error", err)
+		slog.DebugContext(r.Context(), "Error getting deck count", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
@@ -215,12 +374,12 @@ func (s *APIServer) HandleGetDeckCount(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(map[string]int{"count": count})
 	if err != nil {
-		slog.Debug("Error encoding deck count", "error", err)
+		slog.DebugContext(r.Context(), "Error encoding deck count", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	slog.Debug("Sent response", "deck count", count)
+	slog.DebugContext(r.Context(), "Sent response", "deck count", count)
 }
 
 // HandleInsertDeck handles the HTTP POST request for inserting a new deck.
@@ -234,6 +393,42 @@ func (s *APIServer) HandleGetDeckCount(w http.ResponseWriter, r *http.Request) {
 //   - 500 Internal Server Error : If there is an error while processing the request.
 //   - 200 OK : If the decks are found and the request is successful.
 func (s *APIServer) HandleInsertDeck(w http.ResponseWriter, r *http.Request) {
+	s.insertDeck(w, r, nil)
+}
+
+// HandleInsertChildDeck handles the HTTP POST request for inserting a new deck
+// as a child of an existing deck.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the parent deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the parent deck ID is invalid, the parent doesn't exist or the request body is invalid.
+//   - 409 Conflict : If the parent already has a child with the same name.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the deck is inserted and the request is successful.
+func (s *APIServer) HandleInsertChildDeck(w http.ResponseWriter, r *http.Request) {
+	// Parse ID from URL
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	parentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	s.insertDeck(w, r, &parentID)
+}
+
+// insertDeck parses a deck from the request body and inserts it under the
+// given parent deck. It is shared by the top-level and child deck handlers.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck in its body.
+//   - parentID *int : The unique ID of the parent deck, nil for a top-level deck.
+func (s *APIServer) insertDeck(w http.ResponseWriter, r *http.Request, parentID *int) {
 	// Parse JSON data from request body
 	type InsertInput struct {
 		Name        string `json:"name"`
@@ -242,7 +437,7 @@ func (s *APIServer) HandleInsertDeck(w http.ResponseWriter, r *http.Request) {
 	var bodyInput InsertInput
 	err := json.NewDecoder(r.Body).Decode(&bodyInput)
 	if err != nil {
-		slog.Debug("Error decoding request body", "error", err)
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
 		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		return
 	}
@@ -251,38 +446,43 @@ func (s *APIServer) HandleInsertDeck(w http.ResponseWriter, r *http.Request) {
 	bodyInput.Name = strings.TrimSpace(bodyInput.Name)
 	bodyInput.Description = strings.TrimSpace(bodyInput.Description)
 	if bodyInput.Name == "" {
-		slog.Debug("Invalid body input", "error", err)
+		slog.DebugContext(r.Context(), "Invalid body input", "error", err)
 		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		return
 	}
 
 	// Insert into database
 	deck := model.NewDeck(bodyInput.Name, bodyInput.Description)
-	deckID, dbErr := s.deck_db.Insert(deck)
+	deck.ParentID = parentID
+	deckID, dbErr := s.deck_db.Insert(r.Context(), deck)
 	if dbErr != nil {
 		if dbErr == utils.ErrMaxLengthExceeded {
-			slog.Debug("Max length exceeded", "error", dbErr)
+			slog.DebugContext(r.Context(), "Max length exceeded", "error", dbErr)
 			http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		} else if dbErr == utils.ErrDuplicateKeyViolation {
-			slog.Debug("Duplicate key violation", "error", dbErr)
+			slog.DebugContext(r.Context(), "Duplicate key violation", "error", dbErr)
 			http.Error(w, DuplicateKeyViolationErrorMessage, http.StatusConflict)
+		} else if dbErr == utils.ErrInvalidParent {
+			slog.DebugContext(r.Context(), "Invalid parent deck", "error", dbErr)
+			http.Error(w, InvalidParentDeckErrorMessage, http.StatusBadRequest)
 		} else {
-			slog.Debug("Error inserting deck", "error", dbErr)
+			slog.DebugContext(r.Context(), "Error inserting deck", "error", dbErr)
 			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		}
 		return
 	}
+	s.metrics.decksCreated.Inc(deckKindNormal)
 
 	// Encode and send response
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(map[string]int{"id": deckID})
 	if err != nil {
-		slog.Debug("Error encoding deck ID", "error", err)
+		slog.DebugContext(r.Context(), "Error encoding deck ID", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
-	slog.Debug("Sent response", "deck ID", deckID)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", deckID)
 }
 
 // HandleModifyDeck handles the HTTP POST request for modifying an existing deck.
@@ -300,7 +500,7 @@ func (s *APIServer) HandleModifyDeck(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.Split(r.URL.Path, "/")[2]
 	deckID, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Invalid deck ID %s", idStr))
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
 		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
 		return
 	}
@@ -313,7 +513,7 @@ func (s *APIServer) HandleModifyDeck(w http.ResponseWriter, r *http.Request) {
 	var bodyInput InsertInput
 	err = json.NewDecoder(r.Body).Decode(&bodyInput)
 	if err != nil {
-		slog.Debug("Error decoding request body", "error", err)
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
 		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		return
 	}
@@ -322,7 +522,7 @@ func (s *APIServer) HandleModifyDeck(w http.ResponseWriter, r *http.Request) {
 	bodyInput.Name = strings.TrimSpace(bodyInput.Name)
 	bodyInput.Description = strings.TrimSpace(bodyInput.Description)
 	if bodyInput.Name == "" {
-		slog.Debug("Invalid body input", "error", err)
+		slog.DebugContext(r.Context(), "Invalid body input", "error", err)
 		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		return
 	}
@@ -330,19 +530,19 @@ func (s *APIServer) HandleModifyDeck(w http.ResponseWriter, r *http.Request) {
 	// Modify row in database
 	deck := model.NewDeck(bodyInput.Name, bodyInput.Description)
 	deck.ID = deckID
-	dbErr := s.deck_db.Modify(deck)
+	dbErr := s.deck_db.Modify(r.Context(), deck)
 	if dbErr != nil {
 		if dbErr == utils.ErrMaxLengthExceeded {
-			slog.Debug("Max length exceeded", "error", dbErr)
+			slog.DebugContext(r.Context(), "Max length exceeded", "error", dbErr)
 			http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		} else if dbErr == utils.ErrDuplicateKeyViolation {
-			slog.Debug("Duplicate key violation", "error", dbErr)
+			slog.DebugContext(r.Context(), "Duplicate key violation", "error", dbErr)
 			http.Error(w, DuplicateKeyViolationErrorMessage, http.StatusConflict)
 		} else if dbErr == utils.ErrRecordNotExist {
-			slog.Debug("Record not exist", "error", dbErr)
+			slog.DebugContext(r.Context(), "Record not exist", "error", dbErr)
 			http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
 		} else {
-			slog.Debug("Error modifying deck", "error", dbErr)
+			slog.DebugContext(r.Context(), "Error modifying deck", "error", dbErr)
 			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		}
 		return
@@ -352,22 +552,31 @@ func (s *APIServer) HandleModifyDeck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(map[string]int{"id": deckID})
 	if err != nil {
-		slog.Debug("Error encoding deck ID", "error", err)
+		slog.DebugContext(r.Context(), "Error encoding deck ID", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	slog.Debug("Sent response", "deck ID", deckID)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", deckID)
 }
 
 // HandleDeleteDeck handles the HTTP GET request for deleting a single deck.
+// By default the deck is moved to the trash, where it can be restored for
+// 30 days. The "mode" query parameter set to "cascade" permanently deletes
+// the deck and its cards, and the "move_to" query parameter moves the cards
+// to the given deck before permanently deleting the deck.
+// Sub-decks are deleted along with the deck, unless the "children" query
+// parameter is set to "reparent", in which case they are moved up to the
+// parent of the deleted deck. The cards borrowed by the filtered decks being
+// deleted are returned to their home decks first.
 //
 // Parameters:
 //   - w http.ResponseWriter : The response writer to send the response.
 //   - r *http.Request : The HTTP request containing the deck ID in the URL path.
 //
 // Errors:
-//   - 400 Bad Request : If the deck ID is invalid or deck is not found.
+//   - 400 Bad Request : If the deck ID, query parameter or target deck is invalid or deck is not found.
+//   - 409 Conflict : If a re-parented child has the same name as one of its new siblings.
 //   - 500 Internal Server Error : If there is an error while processing the request.
 //   - 200 OK : If the deck is found and the request is successful.
 func (s *APIServer) HandleDeleteDeck(w http.ResponseWriter, r *http.Request) {
@@ -375,19 +584,61 @@ func (s *APIServer) HandleDeleteDeck(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.Split(r.URL.Path, "/")[2]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Invalid deck ID %s", idStr))
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
 		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
 		return
 	}
 
-	// Fetch from database
-	dbErr := s.deck_db.Delete(id)
+	var options database.DeckDeleteOptions
+	switch r.URL.Query().Get("children") {
+	case "", "cascade":
+		options.ReparentChildren = false
+	case "reparent":
+		options.ReparentChildren = true
+	default:
+		slog.DebugContext(r.Context(), "Invalid children query parameter", "children", r.URL.Query().Get("children"))
+		http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Query().Get("mode") {
+	case "", "trash":
+		options.Mode = database.DeckDeleteTrash
+	case "cascade":
+		options.Mode = database.DeckDeleteCascade
+	default:
+		slog.DebugContext(r.Context(), "Invalid mode query parameter", "mode", r.URL.Query().Get("mode"))
+		http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	if moveToStr := r.URL.Query().Get("move_to"); moveToStr != "" {
+		moveTo, err := strconv.Atoi(moveToStr)
+		if err != nil || r.URL.Query().Get("mode") != "" {
+			slog.DebugContext(r.Context(), "Invalid move_to query parameter", "move_to", moveToStr)
+			http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+			return
+		}
+		options.Mode = database.DeckDeleteMoveCards
+		options.MoveToDeckID = moveTo
+	}
+
+	dbErr := s.emptyFilteredDecks(r.Context(), id)
+	if dbErr == nil {
+		dbErr = s.deck_db.Delete(r.Context(), id, options)
+	}
 	if dbErr != nil {
 		if dbErr == utils.ErrRecordNotExist {
-			slog.Debug("Record not exist", "error", dbErr)
+			slog.DebugContext(r.Context(), "Record not exist", "error", dbErr)
 			http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		} else if dbErr == utils.ErrDeckNotExist {
+			slog.DebugContext(r.Context(), "Invalid target deck", "error", dbErr)
+			http.Error(w, InvalidTargetDeckErrorMessage, http.StatusBadRequest)
+		} else if dbErr == utils.ErrDuplicateKeyViolation {
+			slog.DebugContext(r.Context(), "Duplicate key violation", "error", dbErr)
+			http.Error(w, DuplicateKeyViolationErrorMessage, http.StatusConflict)
 		} else {
-			slog.Debug("Error deleting deck", "error", dbErr)
+			slog.DebugContext(r.Context(), "Error deleting deck", "error", dbErr)
 			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		}
 		return
@@ -397,12 +648,191 @@ func (s *APIServer) HandleDeleteDeck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	encodingErr := json.NewEncoder(w).Encode(map[string]int{"id": id})
 	if encodingErr != nil {
-		slog.Debug("Error encoding deck ID", "error", encodingErr)
+		slog.DebugContext(r.Context(), "Error encoding deck ID", "error", encodingErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", id)
+}
+
+// HandleGetDeckTrash handles the HTTP GET request for listing the decks in the trash.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the trash is listed and the request is successful.
+func (s *APIServer) HandleGetDeckTrash(w http.ResponseWriter, r *http.Request) {
+	// Fetch from database
+	decks, err := s.deck_db.GetTrash(r.Context())
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error getting deck trash", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	// Encode and send response
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(decks)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck trash", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	slog.Debug("Sent response", "deck ID", id)
+	slog.DebugContext(r.Context(), "Sent response", "trashed decks", len(decks))
+}
+
+// HandleRestoreDeck handles the HTTP POST request for restoring a deck from the trash.
+// Decks can only be restored for database.DeckTrashRetention after being deleted.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID is invalid or the deck is not in the trash.
+//   - 409 Conflict : If another deck with the same name was created in the meantime.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the deck is restored and the request is successful.
+func (s *APIServer) HandleRestoreDeck(w http.ResponseWriter, r *http.Request) {
+	// Parse ID from URL
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	// Restore in database
+	dbErr := s.deck_db.Restore(r.Context(), deckID, time.Now().Add(-database.DeckTrashRetention))
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Record not exist", "error", dbErr)
+			http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		} else if dbErr == utils.ErrDuplicateKeyViolation {
+			slog.DebugContext(r.Context(), "Duplicate key violation", "error", dbErr)
+			http.Error(w, DuplicateKeyViolationErrorMessage, http.StatusConflict)
+		} else {
+			slog.DebugContext(r.Context(), "Error restoring deck", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Encode and send response
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int{"id": deckID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", deckID)
+}
+
+// HandleMoveDeck handles the HTTP POST request for moving a deck under a new parent.
+// A null parent ID moves the deck to the top level.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID or request body is invalid, or the new parent is the deck itself or one of its descendants.
+//   - 409 Conflict : If the new parent already has a child with the same name.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the deck is moved and the request is successful.
+func (s *APIServer) HandleMoveDeck(w http.ResponseWriter, r *http.Request) {
+	// Parse ID from URL
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	// Parse JSON data from request body
+	type MoveInput struct {
+		ParentID *int `json:"parent_id"`
+	}
+	var bodyInput MoveInput
+	err = json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	// Move in database
+	dbErr := s.deck_db.Move(r.Context(), deckID, bodyInput.ParentID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Record not exist", "error", dbErr)
+			http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		} else if dbErr == utils.ErrInvalidParent {
+			slog.DebugContext(r.Context(), "Invalid parent deck", "error", dbErr)
+			http.Error(w, InvalidParentDeckErrorMessage, http.StatusBadRequest)
+		} else if dbErr == utils.ErrDuplicateKeyViolation {
+			slog.DebugContext(r.Context(), "Duplicate key violation", "error", dbErr)
+			http.Error(w, DuplicateKeyViolationErrorMessage, http.StatusConflict)
+		} else {
+			slog.DebugContext(r.Context(), "Error moving deck", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Encode and send response
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int{"id": deckID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", deckID)
+}
+
+// HandleGetDeckTree handles the HTTP GET request for retrieving all decks as a tree.
+// The card counts of every deck include the counts of its sub-decks.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the tree is built and the request is successful.
+func (s *APIServer) HandleGetDeckTree(w http.ResponseWriter, r *http.Request) {
+	// Fetch from database
+	now := time.Now()
+	summaries, err := s.deck_db.GetAllSummaries(r.Context(), utils.StartOfDay(now), utils.StartOfNextDay(now))
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error getting deck summaries", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	tree := model.BuildDeckTree(summaries)
+
+	// Encode and send response
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(tree)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck tree", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "root decks", len(tree))
 }
 
 // HandleGetDeckNameMaxLength handles the HTTP GET request for retrieving the max length of the deck name.
@@ -414,12 +844,12 @@ func (s *APIServer) HandleGetDeckNameMaxLength(w http.ResponseWriter, r *http.Re
 	w.Header().Set("Content-Type", "application/json")
 	err := json.NewEncoder(w).Encode(map[string]int{"maxLength": database.DeckColumnNameMaxLength})
 	if err != nil {
-		slog.Debug("Error encoding deck name max length", "error", err)
+		slog.DebugContext(r.Context(), "Error encoding deck name max length", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	slog.Debug("Sent response", "deck name max length", database.DeckColumnNameMaxLength)
+	slog.DebugContext(r.Context(), "Sent response", "deck name max length", database.DeckColumnNameMaxLength)
 }
 
 // HandleGetDeckDescriptionMaxLength handles the HTTP GET request for retrieving the max length of the deck description.
@@ -431,12 +861,12 @@ func (s *APIServer) HandleGetDeckDescriptionMaxLength(w http.ResponseWriter, r *
 	w.Header().Set("Content-Type", "application/json")
 	err := json.NewEncoder(w).Encode(map[string]int{"maxLength": database.DeckColumnDescriptionMaxLength})
 	if err != nil {
-		slog.Debug("Error encoding deck description max length", "error", err)
+		slog.DebugContext(r.Context(), "Error encoding deck description max length", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	slog.Debug("Sent response", "deck description max length", database.DeckColumnDescriptionMaxLength)
+	slog.DebugContext(r.Context(), "Sent response", "deck description max length", database.DeckColumnDescriptionMaxLength)
 }
 
 // HandleInsertCard handles the HTTP POST request for inserting a new card into a deck.
@@ -446,7 +876,7 @@ func (s *APIServer) HandleGetDeckDescriptionMaxLength(w http.ResponseWriter, r *
 //   - r *http.Request : The HTTP request containing the deck ID in the URL path.
 //
 // Errors:
-//   - 400 Bad Request : If the deck ID is invalid or the request body is invalid.
+//   - 400 Bad Request : If the deck ID is invalid, the deck is a filtered deck or the request body is invalid.
 //   - 500 Internal Server Error : If there is an error while processing the request.
 //   - 200 OK : If the card is found and the request is successful.
 func (s *APIServer) HandleInsertCard(w http.ResponseWriter, r *http.Request) {
@@ -454,43 +884,52 @@ func (s *APIServer) HandleInsertCard(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.Split(r.URL.Path, "/")[2]
 	deckID, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Invalid deck ID %s", idStr))
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
 		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
 		return
 	}
 
-	// Parse content body
-	type Content struct {
-		Fields []string `json:"fields"`
-		Values []string `json:"values"`
+	_, isFiltered, dbErr := s.getFilteredDeck(r.Context(), deckID)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error getting filtered deck", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	} else if isFiltered {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Can't add a card to filtered deck %d", deckID))
+		http.Error(w, FilteredDeckErrorMessage, http.StatusBadRequest)
+		return
 	}
+
+	// Parse content body
 	type InsertInput struct {
-		Content Content `json:"content"`
-		Source  string  `json:"source"`
+		Content model.CardContent `json:"content"`
+		Source  string            `json:"source"`
+		Tags    []string          `json:"tags"`
+		NoteID  *int              `json:"note_id"`
 	}
 	var bodyInput InsertInput
 	err = json.NewDecoder(r.Body).Decode(&bodyInput)
 	if err != nil {
-		slog.Debug("Error decoding request body", "error", err)
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
 		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		return
 	} else if bodyInput.Content.Fields == nil || bodyInput.Content.Values == nil {
-		slog.Debug("Missing mandatory field content")
+		slog.DebugContext(r.Context(), "Missing mandatory field content")
 		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		return
 	} else if len(bodyInput.Content.Fields) != len(bodyInput.Content.Values) {
-		slog.Debug("Fields and values length mismatch")
+		slog.DebugContext(r.Context(), "Fields and values length mismatch")
 		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		return
 	} else if len(bodyInput.Content.Fields) == 0 || len(bodyInput.Content.Values) == 0 {
-		slog.Debug("Fields or values length is 0")
+		slog.DebugContext(r.Context(), "Fields or values length is 0")
 		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 		return
 	}
 
 	for _, value := range bodyInput.Content.Values {
 		if value == "" {
-			slog.Debug("Value is empty")
+			slog.DebugContext(r.Context(), "Value is empty")
 			http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
 			return
 		}
@@ -498,28 +937,31 @@ func (s *APIServer) HandleInsertCard(w http.ResponseWriter, r *http.Request) {
 
 	contentBytes, err := json.Marshal(bodyInput.Content)
 	if err != nil {
-		slog.Debug("Error marshalling content", "error", err)
+		slog.DebugContext(r.Context(), "Error marshalling content", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 
 	card := model.NewCard(deckID, string(contentBytes), bodyInput.Source)
+	card.Tags = model.NormalizeTags(bodyInput.Tags)
+	card.NoteID = bodyInput.NoteID
 
-	cardID, dbErr := s.card_db.Insert(card)
+	cardID, dbErr := s.card_db.Insert(r.Context(), card)
 	if dbErr != nil {
-		slog.Debug(fmt.Sprintf("Error inserting card %s", dbErr))
+		slog.DebugContext(r.Context(), fmt.Sprintf("Error inserting card %s", dbErr))
 		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
 		return
 	}
+	s.metrics.cardsCreated.Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(map[string]int{"id": cardID})
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Error encoding card ID %s", err))
+		slog.DebugContext(r.Context(), fmt.Sprintf("Error encoding card ID %s", err))
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
-	slog.Debug(fmt.Sprintf("Sent response, card ID: %d", cardID))
+	slog.DebugContext(r.Context(), fmt.Sprintf("Sent response, card ID: %d", cardID))
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -538,15 +980,15 @@ func (s *APIServer) HandleGetTotalCards(w http.ResponseWriter, r *http.Request)
 	idStr := strings.Split(r.URL.Path, "/")[2]
 	deckID, err := strconv.Atoi(idStr)
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Invalid deck ID %s", idStr))
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
 		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
 		return
 	}
 
 	// Fetch from database
-	count, dbErr := s.card_db.GetTotalCards(deckID)
+	count, dbErr := s.card_db.GetTotalCards(r.Context(), deckID)
 	if dbErr != nil {
-		slog.Debug("Error getting total cards", "error", dbErr)
+		slog.DebugContext(r.Context(), "Error getting total cards", "error", dbErr)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
@@ -555,9 +997,108 @@ func (s *APIServer) HandleGetTotalCards(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(map[string]int{"total": count})
 	if err != nil {
-		slog.Debug("Error encoding total cards", "error", err)
+		slog.DebugContext(r.Context(), "Error encoding total cards", "error", err)
 		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// HandleGetDueCards handles the HTTP GET request for retrieving the cards to study
+// in a deck. Due cards are pulled from the deck and all of its sub-decks, and
+// the queue is trimmed to the daily limits of the deck's settings. A filtered
+// deck instead gives all of its cards, in its order.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID is invalid or the deck doesn't exist.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the due cards are found and the request is successful.
+func (s *APIServer) HandleGetDueCards(w http.ResponseWriter, r *http.Request) {
+	// Parse ID from URL
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	// Fetch from database
+	deck, dbErr := s.deck_db.GetSingle(r.Context(), deckID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting single deck", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	filtered, isFiltered, dbErr := s.getFilteredDeck(r.Context(), deckID)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error getting filtered deck", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	var cards []model.Card
+	if isFiltered {
+		cards, dbErr = s.getFilteredDueCards(r.Context(), filtered)
+	} else {
+		cards, dbErr = s.getDueCards(r.Context(), deckID, deck.Settings())
+	}
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting due cards", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Encode and send response
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(cards)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding due cards", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), fmt.Sprintf("Sent response, %d due cards", len(cards)))
+}
+
+// getDueCards retrieves the due cards of a deck and its sub-decks, trimmed to
+// what is left of the daily limits of the deck's settings once the cards of
+// the decks studied today are taken out.
+//
+// Returns:
+//   - []model.Card : The cards to study.
+//   - error : utils.ErrRecordNotExist if the deck doesn't exist, or an error if the retrieval fails.
+func (s *APIServer) getDueCards(ctx context.Context, deckID int, settings model.DeckPreset) ([]model.Card, error) {
+	deckIDs, err := s.deck_db.GetDescendantIDs(ctx, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cards, err := s.card_db.GetDueCards(ctx, deckIDs, utils.StartOfNextDay(now))
+	if err != nil {
+		return nil, err
+	}
+
+	studied, err := s.card_db.CountStudied(ctx, deckIDs, utils.StartOfDay(now))
+	if err != nil {
+		return nil, err
+	}
+
+	return settings.LimitStudyQueue(cards, studied), nil
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"flash-learn/internal/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServerShutdown(t *testing.T) {
+	t.Run("Running", func(t *testing.T) {
+		server := NewAPIServer("127.0.0.1:0", database.NewDeckDBWrapperMock(), database.NewCardDBWrapperMock())
+
+		stopped := make(chan error, 1)
+		go func() {
+			stopped <- server.Start()
+		}()
+
+		assert.Eventually(t, func() bool {
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			return server.server != nil
+		}, time.Second, time.Millisecond)
+
+		assert.Nil(t, server.Shutdown(context.Background()))
+		assert.Nil(t, <-stopped, "Expected a shut down server not to report an error")
+	})
+
+	t.Run("Before Start", func(t *testing.T) {
+		server := NewAPIServer("127.0.0.1:0", database.NewDeckDBWrapperMock(), database.NewCardDBWrapperMock())
+
+		assert.Nil(t, server.Shutdown(context.Background()))
+		assert.Nil(t, server.Start(), "Expected a shut down server never to start")
+	})
+
+	t.Run("Invalid Address", func(t *testing.T) {
+		server := NewAPIServer("127.0.0.1:-1", database.NewDeckDBWrapperMock(), database.NewCardDBWrapperMock())
+		assert.NotNil(t, server.Start())
+	})
+}
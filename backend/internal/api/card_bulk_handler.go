@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// HandleSearchCards handles the HTTP GET request for searching cards across decks.
+// The query parameters "deck_id" and "tag" can be repeated, "flag", "state",
+// "suspended", "q", "added_days", "forgotten_days" and "limit" can be given once.
+// All given criteria must match.
+// The flag can be given by value or by the name of its definition.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the search criteria in the query.
+//
+// Errors:
+//   - 400 Bad Request : If a query parameter is invalid or the flag name isn't defined.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the search succeeds.
+func (s *APIServer) HandleSearchCards(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if err := s.resolveFlagName(r.Context(), query); err != nil {
+		writeFlagError(w, r, err)
+		return
+	}
+
+	filter, err := parseCardFilter(query)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Invalid card search query", "error", err)
+		http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	cards, err := s.card_db.Search(r.Context(), filter)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error searching cards", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(cards)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding cards", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "cards", len(cards))
+}
+
+// HandleBulkCards handles the HTTP POST request for applying an operation to many
+// cards at once. The cards are given either as a list of IDs or as a search
+// filter, and the operation is applied in a single transaction.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the cards and the operation in the body.
+//
+// Errors:
+//   - 400 Bad Request : If the request body or operation is invalid, or the target deck doesn't exist.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With a per-card report, even if some cards couldn't be changed.
+func (s *APIServer) HandleBulkCards(w http.ResponseWriter, r *http.Request) {
+	type BulkInput struct {
+		IDs       []int               `json:"ids"`
+		Query     *model.CardFilter   `json:"query"`
+		Operation model.BulkOperation `json:"operation"`
+	}
+	var bodyInput BulkInput
+	err := json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	} else if (bodyInput.IDs == nil) == (bodyInput.Query == nil) {
+		slog.DebugContext(r.Context(), "Exactly one of ids and query must be given")
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	} else if err = bodyInput.Operation.Validate(); err != nil {
+		slog.DebugContext(r.Context(), "Invalid bulk operation", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	cardIDs := bodyInput.IDs
+	if bodyInput.Query != nil {
+		cards, err := s.card_db.Search(r.Context(), *bodyInput.Query)
+		if err != nil {
+			slog.DebugContext(r.Context(), "Error searching cards", "error", err)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+			return
+		}
+
+		cardIDs = make([]int, len(cards))
+		for i, card := range cards {
+			cardIDs[i] = card.ID
+		}
+	}
+
+	changed, dbErr := s.card_db.ApplyBulk(r.Context(), cardIDs, bodyInput.Operation)
+	if dbErr != nil {
+		if dbErr == utils.ErrDeckNotExist {
+			slog.DebugContext(r.Context(), "Invalid target deck", "error", dbErr)
+			http.Error(w, InvalidTargetDeckErrorMessage, http.StatusBadRequest)
+		} else if dbErr == utils.ErrInvalidBulkOperation {
+			slog.DebugContext(r.Context(), "Invalid bulk operation", "error", dbErr)
+			http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error applying bulk operation", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	report := model.NewBulkReport(cardIDs, changed)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding bulk report", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "operation", bodyInput.Operation.Type, "succeeded", report.Succeeded, "failed", report.Failed)
+}
+
+// parseCardFilter builds a card filter from the query parameters of a search.
+//
+// Parameters:
+//   - query url.Values : The query parameters.
+//
+// Returns:
+//   - model.CardFilter : The filter.
+//   - error : An error if a numeric or boolean parameter can't be parsed.
+func parseCardFilter(query url.Values) (model.CardFilter, error) {
+	filter := model.CardFilter{
+		Tags: query["tag"],
+		Text: query.Get("q"),
+	}
+
+	for _, deckIDStr := range query["deck_id"] {
+		deckID, err := strconv.Atoi(deckIDStr)
+		if err != nil {
+			return model.CardFilter{}, err
+		}
+		filter.DeckIDs = append(filter.DeckIDs, deckID)
+	}
+
+	var err error
+	if filter.Flag, err = parseOptionalInt(query, "flag"); err != nil {
+		return model.CardFilter{}, err
+	}
+	if filter.State, err = parseOptionalInt(query, "state"); err != nil {
+		return model.CardFilter{}, err
+	}
+
+	if suspendedStr := query.Get("suspended"); suspendedStr != "" {
+		suspended, err := strconv.ParseBool(suspendedStr)
+		if err != nil {
+			return model.CardFilter{}, err
+		}
+		filter.Suspended = &suspended
+	}
+
+	for name, days := range map[string]*int{"added_days": &filter.AddedDays, "forgotten_days": &filter.ForgottenDays} {
+		if daysStr := query.Get(name); daysStr != "" {
+			value, err := strconv.Atoi(daysStr)
+			if err != nil || value < 0 {
+				return model.CardFilter{}, strconv.ErrRange
+			}
+			*days = value
+		}
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return model.CardFilter{}, strconv.ErrRange
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// parseOptionalInt parses a numeric query parameter that may be omitted.
+//
+// Returns:
+//   - *int : The value, nil if the parameter is missing.
+//   - error : An error if the parameter isn't a number.
+func parseOptionalInt(query url.Values, name string) (*int, error) {
+	valueStr := query.Get(name)
+	if valueStr == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APICardBulkServerTestSuite struct {
+	suite.Suite
+	card_db *database.CardDBWrapperMock
+	server  *APIServer
+}
+
+func (suite *APICardBulkServerTestSuite) SetupTest() {
+	suite.card_db = database.NewCardDBWrapperMock()
+	flag_db := database.NewFlagDBWrapperMock()
+	flag_db.CreateTable()
+	flag_db.Set(context.Background(), model.FlagDefinition{Flag: 1, Name: "Red", Color: "#e53935"})
+	suite.server = NewAPIServer("localhost:8080", database.NewDeckDBWrapperMock(), suite.card_db).
+		WithFlagDB(flag_db)
+
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+	suite.card_db.InsertDeck(1)
+
+	verb := model.NewCard(0, `{"fields":["Front"],"values":["laufen"]}`, "")
+	verb.Tags = []string{"german", "verb"}
+	suite.card_db.Insert(context.Background(), verb)
+	noun := model.NewCard(0, `{"fields":["Front"],"values":["Hund"]}`, "")
+	noun.Tags = []string{"german", "noun"}
+	noun.Flag = 1
+	suite.card_db.Insert(context.Background(), noun)
+}
+
+func TestAPICardBulkServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APICardBulkServerTestSuite))
+}
+
+func (suite *APICardBulkServerTestSuite) TestSearchCardsHandler() {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "Undefined flag name", query: "?flag=blue", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid suspended", query: "?suspended=maybe", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid limit", query: "?limit=-1", expectedStatus: http.StatusBadRequest},
+		{name: "All cards", query: "", expectedStatus: http.StatusOK, expectedCount: 2},
+		{name: "By tags", query: "?tag=german&tag=verb", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "By flag", query: "?flag=1", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "By flag name", query: "?flag=red", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "By text", query: "?q=hund", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "By deck", query: "?deck_id=1", expectedStatus: http.StatusOK, expectedCount: 0},
+		{name: "With limit", query: "?limit=1", expectedStatus: http.StatusOK, expectedCount: 1},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/card"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleSearchCards(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var cards []model.Card
+				err := json.NewDecoder(rr.Body).Decode(&cards)
+				assert.Nil(t, err, "Expected response body to be a JSON array")
+				assert.Equal(t, tc.expectedCount, len(cards))
+			}
+		})
+	}
+}
+
+func (suite *APICardBulkServerTestSuite) TestBulkCardsHandlerWithBadRequests() {
+	testCases := []struct {
+		name         string
+		body         string
+		expectedBody string
+	}{
+		{
+			name:         "Neither ids nor query",
+			body:         `{"operation":{"type":"suspend"}}`,
+			expectedBody: InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:         "Both ids and query",
+			body:         `{"ids":[0],"query":{},"operation":{"type":"suspend"}}`,
+			expectedBody: InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:         "Unknown operation",
+			body:         `{"ids":[0],"operation":{"type":"shred"}}`,
+			expectedBody: InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:         "Flag out of range",
+			body:         `{"ids":[0],"operation":{"type":"set_flag","flag":10}}`,
+			expectedBody: InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:         "Move to unknown deck",
+			body:         `{"ids":[0],"operation":{"type":"move","deck_id":7}}`,
+			expectedBody: InvalidTargetDeckErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/card/bulk", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			suite.server.HandleBulkCards(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func (suite *APICardBulkServerTestSuite) TestBulkCardsHandler() {
+	// By IDs, with a missing card reported as failed
+	body := `{"ids":[0,1,9],"operation":{"type":"add_tags","tags":["exam"]}}`
+	req := httptest.NewRequest(http.MethodPost, "/card/bulk", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	suite.server.HandleBulkCards(rr, req)
+
+	var report model.BulkReport
+	err := json.NewDecoder(rr.Body).Decode(&report)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), 2, report.Succeeded)
+	assert.Equal(suite.T(), 1, report.Failed)
+	assert.Equal(suite.T(), 9, report.Results[2].ID)
+	assert.False(suite.T(), report.Results[2].Success)
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), []string{"german", "verb", "exam"}, card.Tags)
+
+	// By query
+	body = `{"query":{"tags":["noun"]},"operation":{"type":"suspend"}}`
+	req = httptest.NewRequest(http.MethodPost, "/card/bulk", strings.NewReader(body))
+	rr = httptest.NewRecorder()
+	suite.server.HandleBulkCards(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	card, _ = suite.card_db.GetSingle(context.Background(), 1)
+	assert.True(suite.T(), card.Suspended, "Expected the noun card to be suspended")
+	card, _ = suite.card_db.GetSingle(context.Background(), 0)
+	assert.False(suite.T(), card.Suspended, "Expected the verb card not to be suspended")
+
+	// Move
+	body = `{"ids":[0],"operation":{"type":"move","deck_id":1}}`
+	req = httptest.NewRequest(http.MethodPost, "/card/bulk", strings.NewReader(body))
+	rr = httptest.NewRecorder()
+	suite.server.HandleBulkCards(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	total, _ := suite.card_db.GetTotalCards(context.Background(), 1)
+	assert.Equal(suite.T(), 1, total, "Expected the card to be moved")
+}
+
+func (suite *APICardBulkServerTestSuite) TestCardsOfTrashedDeckHidden() {
+	suite.card_db.TrashDeck(0, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/card?flag=1", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleSearchCards(rr, req)
+
+	var cards []model.Card
+	assert.Nil(suite.T(), json.NewDecoder(rr.Body).Decode(&cards))
+	assert.Empty(suite.T(), cards, "Expected the cards of a trashed deck not to be searched")
+
+	req = httptest.NewRequest(http.MethodGet, "/card/1", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleGetSingleCard(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+
+	body := `{"query":{},"operation":{"type":"suspend"}}`
+	req = httptest.NewRequest(http.MethodPost, "/card/bulk", strings.NewReader(body))
+	rr = httptest.NewRecorder()
+	suite.server.HandleBulkCards(rr, req)
+
+	var report model.BulkReport
+	assert.Nil(suite.T(), json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(suite.T(), 0, report.Succeeded, "Expected the cards of a trashed deck not to be changed")
+
+	suite.card_db.TrashDeck(0, false)
+	cards, _ = suite.card_db.Search(context.Background(), model.CardFilter{})
+	assert.Len(suite.T(), cards, 2, "Expected the cards to come back with their deck")
+}
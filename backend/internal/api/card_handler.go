@@ -0,0 +1,410 @@
+package api
+
+import (
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandleDeleteCard handles the HTTP DELETE request for moving a single card to the trash.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is moved to the trash and the request is successful.
+func (s *APIServer) HandleDeleteCard(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	dbErr := s.card_db.Delete(r.Context(), cardID)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]int{"id": cardID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card ID", cardID)
+}
+
+// HandleGetSingleCard handles the HTTP GET request for retrieving a single card.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is found and the request is successful.
+func (s *APIServer) HandleGetSingleCard(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	card, dbErr := s.card_db.GetSingle(r.Context(), cardID)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(card)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card ID", cardID)
+}
+
+// HandleModifyCard handles the HTTP POST request for modifying the content,
+// source and tags of a card. The previous version is kept as a revision.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID or request body is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is modified and the request is successful.
+func (s *APIServer) HandleModifyCard(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	type ModifyInput struct {
+		Content model.CardContent `json:"content"`
+		Source  string            `json:"source"`
+		Tags    []string          `json:"tags"`
+	}
+	var bodyInput ModifyInput
+	err := json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	} else if !bodyInput.Content.Valid() {
+		slog.DebugContext(r.Context(), "Invalid card content")
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	contentBytes, err := json.Marshal(bodyInput.Content)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error marshalling content", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	card := model.Card{
+		ID:      cardID,
+		Content: string(contentBytes),
+		Source:  bodyInput.Source,
+		Tags:    bodyInput.Tags,
+	}
+
+	dbErr := s.card_db.Modify(r.Context(), card)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int{"id": cardID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card ID", cardID)
+}
+
+// HandleGetCardRevisions handles the HTTP GET request for listing the revisions
+// of a card, newest first. Every revision comes with the field-level changes
+// of the edit that replaced it.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the revisions are found and the request is successful.
+func (s *APIServer) HandleGetCardRevisions(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	card, dbErr := s.card_db.GetSingle(r.Context(), cardID)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	revisions, dbErr := s.card_db.GetRevisions(r.Context(), cardID)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	diffs := model.BuildRevisionDiffs(revisions, card)
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(diffs)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card revisions", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card revisions", len(diffs))
+}
+
+// HandleRevertCard handles the HTTP POST request for reverting a card to one
+// of its revisions. The version being replaced is saved as a new revision,
+// so a revert can itself be reverted.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID and revision in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID or revision is invalid or not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is reverted and the request is successful.
+func (s *APIServer) HandleRevertCard(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	revStr := strings.Split(r.URL.Path, "/")[4]
+	rev, err := strconv.Atoi(revStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid card revision %s", revStr))
+		http.Error(w, InvalidRevisionErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	revision, dbErr := s.card_db.GetRevision(r.Context(), cardID, rev)
+	if dbErr == utils.ErrRecordNotExist {
+		slog.DebugContext(r.Context(), "Card revision not found", "error", dbErr)
+		http.Error(w, InvalidRevisionErrorMessage, http.StatusBadRequest)
+		return
+	} else if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	card := model.Card{
+		ID:      cardID,
+		Content: revision.Content,
+		Source:  revision.Source,
+		Tags:    revision.Tags,
+	}
+
+	dbErr = s.card_db.Modify(r.Context(), card)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int{"id": cardID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card ID", cardID, "revision", rev)
+}
+
+// HandleSuspendCard handles the HTTP POST request for suspending a card,
+// removing it from the study queue until it is unsuspended.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is suspended and the request is successful.
+func (s *APIServer) HandleSuspendCard(w http.ResponseWriter, r *http.Request) {
+	s.setCardSuspended(w, r, true)
+}
+
+// HandleUnsuspendCard handles the HTTP POST request for bringing a suspended card
+// back into the study queue.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is unsuspended and the request is successful.
+func (s *APIServer) HandleUnsuspendCard(w http.ResponseWriter, r *http.Request) {
+	s.setCardSuspended(w, r, false)
+}
+
+func (s *APIServer) setCardSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	dbErr := s.card_db.SetSuspended(r.Context(), cardID, suspended)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]int{"id": cardID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card ID", cardID, "suspended", suspended)
+}
+
+// HandleBuryCard handles the HTTP POST request for burying a card until the next day.
+// The siblings of the card, the other cards generated from the same note, are
+// buried along with it unless the "siblings" query parameter is set to false.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID or query parameter is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the IDs of the buried cards, if the request is successful.
+func (s *APIServer) HandleBuryCard(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	siblings := true
+	if siblingsStr := r.URL.Query().Get("siblings"); siblingsStr != "" {
+		var err error
+		siblings, err = strconv.ParseBool(siblingsStr)
+		if err != nil {
+			slog.DebugContext(r.Context(), "Invalid siblings query parameter", "siblings", siblingsStr)
+			http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+			return
+		}
+	}
+
+	buried, dbErr := s.card_db.Bury(r.Context(), cardID, utils.StartOfNextDay(time.Now()), siblings)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string][]int{"buried": buried})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding buried card IDs", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "buried cards", len(buried))
+}
+
+// HandleUnburyCard handles the HTTP POST request for bringing a buried card
+// back into the study queue. Its siblings stay buried.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is unburied and the request is successful.
+func (s *APIServer) HandleUnburyCard(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	dbErr := s.card_db.Unbury(r.Context(), cardID)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]int{"id": cardID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card ID", cardID)
+}
+
+// parseCardID parses the card ID from the URL path and writes a
+// 400 Bad Request response if it isn't a number.
+//
+// Returns:
+//   - int : The card ID.
+//   - bool : False if the response has already been written.
+func parseCardID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	cardID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid card ID %s", idStr))
+		http.Error(w, InvalidCardIDErrorMessage, http.StatusBadRequest)
+		return 0, false
+	}
+
+	return cardID, true
+}
+
+// writeCardError writes the response matching an error returned by the card database.
+func writeCardError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case utils.ErrRecordNotExist:
+		slog.DebugContext(r.Context(), "Card not found", "error", err)
+		http.Error(w, InvalidCardIDErrorMessage, http.StatusBadRequest)
+	default:
+		slog.DebugContext(r.Context(), "Error accessing cards", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+	}
+}
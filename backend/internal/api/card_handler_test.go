@@ -1,14 +1,18 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"flash-learn/internal/database"
 	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -207,25 +211,27 @@ func (suite *APICardServerTestSuite) TestInsertCardHandlerWithValid() {
 		assert.Equal(suite.T(), tc.expectedBody, rr.Body.String(), "Expected response body to be '%s', got '%s'", tc.expectedBody, rr.Body.String())
 
 		deckID, _ := strconv.Atoi(tc.deckID)
-		count, _ := suite.card_db.GetTotalCards(deckID)
+		count, _ := suite.card_db.GetTotalCards(context.Background(), deckID)
 		assert.Equal(suite.T(), tc.expectedCount, count, "Expected card count to be %d, got %d", tc.expectedCount, count)
 	}
 
-	count, _ := suite.card_db.GetTotalCards(2)
+	count, _ := suite.card_db.GetTotalCards(context.Background(), 2)
 	assert.Equal(suite.T(), 0, count, "Expected card count to be %d, got %d", 0, count)
+
+	assert.Equal(suite.T(), 4.0, suite.server.metrics.cardsCreated.Value())
 }
 
 func (suite *APICardServerTestSuite) TestGetTotalCardsHandler() {
 	suite.card_db.CreateTable()
 	suite.card_db.InsertDeck(0)
-	suite.card_db.Insert(model.NewCard(0, "Test content #1", "Test source 1"))
-	suite.card_db.Insert(model.NewCard(0, "Test content #2", "Test source 2"))
-	suite.card_db.Insert(model.NewCard(0, "Test content #3", "Test source 3"))
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content #1", "Test source 1"))
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content #2", "Test source 2"))
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content #3", "Test source 3"))
 	suite.card_db.InsertDeck(1)
-	suite.card_db.Insert(model.NewCard(1, "Test content #4", "Test source 4"))
-	suite.card_db.Insert(model.NewCard(1, "Test content #5", "Test source 5"))
+	suite.card_db.Insert(context.Background(), model.NewCard(1, "Test content #4", "Test source 4"))
+	suite.card_db.Insert(context.Background(), model.NewCard(1, "Test content #5", "Test source 5"))
 	suite.card_db.InsertDeck(2)
-	suite.card_db.Insert(model.NewCard(2, "Test content #7", "Test source 7"))
+	suite.card_db.Insert(context.Background(), model.NewCard(2, "Test content #7", "Test source 7"))
 	suite.card_db.InsertDeck(3)
 
 	testCases := []struct {
@@ -285,3 +291,324 @@ func (suite *APICardServerTestSuite) TestGetTotalCardsHandler() {
 		assert.Equal(suite.T(), tc.expectedBody, rr.Body.String(), "Expected response body to be '%s', got '%s'", tc.expectedBody, rr.Body.String())
 	}
 }
+
+func (suite *APICardServerTestSuite) TestGetDueCardsHandler() {
+	suite.deck_db.CreateTable()
+	suite.card_db.CreateTable()
+
+	parentID := 0
+	suite.deck_db.Insert(context.Background(), model.NewDeck("Parent", "Parent deck"))
+	child := model.NewDeck("Child", "Child deck")
+	child.ParentID = &parentID
+	suite.deck_db.Insert(context.Background(), child)
+
+	suite.card_db.InsertDeck(0)
+	suite.card_db.InsertDeck(1)
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content #1", "Test source 1"))
+	suite.card_db.Insert(context.Background(), model.NewCard(1, "Test content #2", "Test source 2"))
+
+	reviewed := model.NewCard(1, "Test content #3", "Test source 3")
+	reviewed.State = model.CardStateReview
+	reviewed.NextReviewTime = reviewed.NextReviewTime.AddDate(0, 0, 7)
+	suite.card_db.Insert(context.Background(), reviewed)
+
+	testCases := []struct {
+		name           string
+		deckID         string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{
+			name:           "Bad Request (Deck ID not number)",
+			deckID:         "a",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bad Request (Deck doesn't exist)",
+			deckID:         "5",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Valid request (Parent includes child cards)",
+			deckID:         "0",
+			expectedStatus: http.StatusOK,
+			expectedCount:  2,
+		},
+		{
+			name:           "Valid request (Child only)",
+			deckID:         "1",
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/deck/"+tc.deckID+"/card/due", nil)
+		rr := httptest.NewRecorder()
+
+		suite.server.HandleGetDueCards(rr, req)
+
+		assert.Equal(suite.T(), tc.expectedStatus, rr.Code, "%s: expected status code to be %d, got %d", tc.name, tc.expectedStatus, rr.Code)
+
+		if tc.expectedStatus == http.StatusOK {
+			cards := []model.Card{}
+			_ = json.NewDecoder(rr.Body).Decode(&cards)
+			assert.Equal(suite.T(), tc.expectedCount, len(cards), "%s: expected %d due cards, got %d", tc.name, tc.expectedCount, len(cards))
+		}
+	}
+}
+
+func (suite *APICardServerTestSuite) TestGetDueCardsAfterStudyingToday() {
+	suite.deck_db.CreateTable()
+	suite.card_db.CreateTable()
+
+	preset := model.DefaultDeckPreset()
+	preset.NewCardsPerDay = 3
+	suite.deck_db.Insert(context.Background(), model.Deck{Name: "Deck", Preset: &preset})
+	suite.card_db.InsertDeck(0)
+
+	for i := 0; i < 4; i++ {
+		suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content", "Test source"))
+	}
+	studied, _ := suite.card_db.GetSingle(context.Background(), 0)
+	suite.card_db.RecordReview(context.Background(), model.NewCardAnswer(studied, studied), model.Review{
+		CardID: studied.ID, DeckID: 0, Grade: model.GradeGood, Kind: model.ReviewKindLearn,
+		State: model.CardStateNew, ReviewTime: time.Now(),
+	})
+	suite.card_db.RecordReview(context.Background(), model.NewCardAnswer(studied, studied), model.Review{
+		CardID: studied.ID, DeckID: 0, Grade: model.GradeGood, Kind: model.ReviewKindLearn,
+		State: model.CardStateNew, ReviewTime: utils.StartOfDay(time.Now()).Add(-time.Minute),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/deck/0/card/due", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleGetDueCards(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	cards := []model.Card{}
+	_ = json.NewDecoder(rr.Body).Decode(&cards)
+	assert.Equal(suite.T(), 2, len(cards), "Expected the new card studied today to be taken from the daily limit")
+}
+
+func (suite *APICardServerTestSuite) TestModifyCardHandler() {
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+	suite.card_db.Insert(context.Background(), model.NewCard(0, `{"fields":["Front"],"values":["Hund"]}`, "GenAI"))
+
+	testCases := []struct {
+		name           string
+		cardID         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Card ID not number)",
+			cardID:         "a",
+			body:           `{"content":{"fields":["Front"],"values":["Dog"]}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidCardIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Empty value)",
+			cardID:         "0",
+			body:           `{"content":{"fields":["Front"],"values":[""]}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Card doesn't exist)",
+			cardID:         "5",
+			body:           `{"content":{"fields":["Front"],"values":["Dog"]}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidCardIDErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request",
+			cardID:         "0",
+			body:           `{"content":{"fields":["Front"],"values":["Dog"]},"source":"Manual","tags":["animals"]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"id\":0}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/card/"+tc.cardID, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			suite.server.HandleModifyCard(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), "Manual", card.Source)
+	assert.Equal(suite.T(), []string{"animals"}, card.Tags)
+}
+
+func (suite *APICardServerTestSuite) TestCardRevisionsHandlers() {
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+	suite.card_db.Insert(context.Background(), model.NewCard(0, `{"fields":["Front"],"values":["Hund"]}`, "GenAI"))
+
+	req := httptest.NewRequest(http.MethodPost, "/card/0", strings.NewReader(`{"content":{"fields":["Front"],"values":["Dog"]},"source":"GenAI"}`))
+	rr := httptest.NewRecorder()
+	suite.server.HandleModifyCard(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/card/0/revisions", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleGetCardRevisions(rr, req)
+
+	var revisions []model.CardRevisionDiff
+	err := json.NewDecoder(rr.Body).Decode(&revisions)
+	assert.Nil(suite.T(), err, "Expected response body to be a JSON array")
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), 1, len(revisions))
+	assert.Equal(suite.T(), 1, revisions[0].Revision)
+	assert.Equal(suite.T(), 1, len(revisions[0].Changes))
+	assert.Equal(suite.T(), "content.Front", revisions[0].Changes[0].Field)
+	assert.Equal(suite.T(), "Hund", *revisions[0].Changes[0].Old)
+	assert.Equal(suite.T(), "Dog", *revisions[0].Changes[0].New)
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Revision not number)",
+			path:           "/card/0/revisions/a/revert",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidRevisionErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Revision doesn't exist)",
+			path:           "/card/0/revisions/3/revert",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidRevisionErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request",
+			path:           "/card/0/revisions/1/revert",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"id\":0}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleRevertCard(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), `{"fields":["Front"],"values":["Hund"]}`, card.Content, "Expected card to be reverted")
+	revs, _ := suite.card_db.GetRevisions(context.Background(), 0)
+	assert.Equal(suite.T(), 2, len(revs), "Expected the revert to save a revision")
+}
+
+func (suite *APICardServerTestSuite) TestSuspendCardHandlers() {
+	suite.deck_db.CreateTable()
+	suite.card_db.CreateTable()
+	suite.deck_db.Insert(context.Background(), model.NewDeck("Deck", "Deck"))
+	suite.card_db.InsertDeck(0)
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content #1", ""))
+
+	req := httptest.NewRequest(http.MethodPost, "/card/a/suspend", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleSuspendCard(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+	assert.Equal(suite.T(), InvalidCardIDErrorMessage+"\n", rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/card/0/suspend", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleSuspendCard(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	cards, _ := suite.card_db.GetDueCards(context.Background(), []int{0}, time.Now())
+	assert.Equal(suite.T(), 0, len(cards), "Expected suspended card to leave the queue")
+
+	req = httptest.NewRequest(http.MethodPost, "/card/0/unsuspend", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleUnsuspendCard(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	cards, _ = suite.card_db.GetDueCards(context.Background(), []int{0}, time.Now())
+	assert.Equal(suite.T(), 1, len(cards), "Expected unsuspended card to be back in the queue")
+}
+
+func (suite *APICardServerTestSuite) TestBuryCardHandlers() {
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+	noteID := 7
+	for i := 0; i < 3; i++ {
+		card := model.NewCard(0, "Test content", "")
+		if i < 2 {
+			card.NoteID = &noteID
+		}
+		suite.card_db.Insert(context.Background(), card)
+	}
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Invalid siblings)",
+			path:           "/card/0/bury?siblings=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidQueryErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Card doesn't exist)",
+			path:           "/card/9/bury",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidCardIDErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request (Without siblings)",
+			path:           "/card/1/bury?siblings=false",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"buried\":[1]}\n",
+		},
+		{
+			name:           "Valid request (With siblings)",
+			path:           "/card/0/bury",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"buried\":[0,1]}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleBuryCard(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	cards, _ := suite.card_db.GetDueCards(context.Background(), []int{0}, time.Now())
+	assert.Equal(suite.T(), 1, len(cards), "Expected buried cards to leave the queue")
+
+	req := httptest.NewRequest(http.MethodPost, "/card/0/unbury", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleUnburyCard(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	cards, _ = suite.card_db.GetDueCards(context.Background(), []int{0}, time.Now())
+	assert.Equal(suite.T(), 2, len(cards), "Expected unburied card to be back while its sibling stays buried")
+}
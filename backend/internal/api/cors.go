@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// corsAllowedHeaders are the request headers the browser may send cross-origin.
+	corsAllowedHeaders = "Content-Type, Authorization, " + RequestIDHeader
+	// corsExposedHeaders are the response headers the browser lets scripts read.
+	corsExposedHeaders = RequestIDHeader
+	// corsMaxAge is how long, in seconds, the browser may cache a preflight response.
+	corsMaxAge = "600"
+	// corsAnyOrigin is the origin pattern matching every origin.
+	corsAnyOrigin = "*"
+)
+
+// corsMethods are the methods checked against the router to answer a preflight
+// request. HEAD is left out since it is allowed wherever GET is.
+var corsMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// corsPolicy decides which origins may call the API from a browser.
+//
+// An origin pattern is either an exact origin such as "https://app.example.com"
+// or "chrome-extension://abcdef", a wildcard origin such as
+// "https://*.example.com", which matches the subdomains of example.com at any
+// depth but not example.com itself, or "*", which matches every origin and
+// can't be combined with credentials.
+type corsPolicy struct {
+	origins          []string
+	allowCredentials bool
+}
+
+// allows reports whether an origin matches one of the patterns of the policy.
+//
+// Parameters:
+//   - origin string : The Origin header of the request.
+//
+// Returns:
+//   - bool : Whether the origin may call the API.
+func (policy corsPolicy) allows(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	origin = strings.ToLower(origin)
+
+	for _, pattern := range policy.origins {
+		pattern = strings.ToLower(pattern)
+		if pattern == corsAnyOrigin || pattern == origin {
+			return true
+		}
+
+		scheme, host, found := strings.Cut(pattern, "://*.")
+		if !found {
+			continue
+		}
+		prefix := scheme + "://"
+		rest, hasScheme := strings.CutPrefix(origin, prefix)
+		subdomain, matches := strings.CutSuffix(rest, "."+host)
+		if hasScheme && matches && subdomain != "" && !strings.ContainsAny(subdomain, "/:@") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// corsMiddleware adds CORS headers to the responses of the requests coming
+// from an allowed origin, and answers their preflight requests. The headers
+// are set before the request is passed on, so that the responses written by
+// the middlewares after it, such as a 413 or a recovered panic, carry them too.
+//
+// A preflight request is answered with the methods the router has for its
+// path, with a 404 if the router has none and with a 403 if the origin isn't
+// allowed. Other OPTIONS requests are passed to the router, which answers
+// them with a 405 or a 404. Every response carries Vary: Origin since its CORS
+// headers depend on the origin, which keeps shared caches from mixing them up.
+//
+// Parameters:
+//   - router *http.ServeMux : The router, used to find the methods of a path.
+//   - policy corsPolicy : The origins allowed and whether credentials are.
+//
+// Returns:
+//   - middleware
+func corsMiddleware(router *http.ServeMux, policy corsPolicy) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			allowed := policy.allows(origin)
+
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+				if policy.allowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			preflight := r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+			if !preflight {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+			methods := routeMethods(router, r)
+			if len(methods) == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			if !allowed {
+				http.Error(w, CORSOriginErrorMessage, http.StatusForbidden)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// routeMethods returns the methods the router has a route for at the path of
+// a request, by asking the router which route it would pick for each method.
+//
+// Parameters:
+//   - router *http.ServeMux : The router.
+//   - r *http.Request : The request whose path is looked up.
+//
+// Returns:
+//   - []string : The methods with a route, empty if the path is unknown.
+func routeMethods(router *http.ServeMux, r *http.Request) []string {
+	methods := []string{}
+	for _, method := range corsMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := router.Handler(probe); pattern != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
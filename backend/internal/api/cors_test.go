@@ -0,0 +1,146 @@
+package api
+
+import (
+	"flash-learn/internal/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSPolicyAllows(t *testing.T) {
+	policy := corsPolicy{origins: []string{
+		"http://localhost:5173",
+		"https://*.example.com",
+		"chrome-extension://abcdefghijklmnop",
+	}}
+
+	testCases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"http://localhost:5173", true},
+		{"HTTP://LOCALHOST:5173", true},
+		{"http://localhost:3000", false},
+		{"https://app.example.com", true},
+		{"https://staging.app.example.com", true},
+		{"https://example.com", false},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://evilexample.com", false},
+		{"https://app.example.com.evil.com", false},
+		{"chrome-extension://abcdefghijklmnop", true},
+		{"chrome-extension://other", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.allowed, policy.allows(tc.origin), tc.origin)
+	}
+
+	assert.True(t, corsPolicy{origins: []string{"*"}}.allows("https://anywhere.org"))
+	assert.False(t, corsPolicy{origins: []string{"*"}}.allows(""))
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	server := NewAPIServer("localhost:8080", database.NewDeckDBWrapperMock(), database.NewCardDBWrapperMock())
+	router := http.NewServeMux()
+	addRoutes(router, server)
+	handler := corsMiddleware(router, corsPolicy{origins: []string{"https://*.example.com"}, allowCredentials: true})(router)
+
+	testCases := []struct {
+		name            string
+		method          string
+		path            string
+		origin          string
+		requestMethod   string
+		expectedStatus  int
+		expectedOrigin  string
+		expectedMethods string
+	}{
+		{
+			name:            "Preflight",
+			method:          http.MethodOptions,
+			path:            "/deck/1",
+			origin:          "https://app.example.com",
+			requestMethod:   http.MethodDelete,
+			expectedStatus:  http.StatusNoContent,
+			expectedOrigin:  "https://app.example.com",
+			expectedMethods: "GET, POST, DELETE",
+		},
+		{
+			name:            "Preflight (Single method)",
+			method:          http.MethodOptions,
+			path:            "/jobs/1/cancel",
+			origin:          "https://app.example.com",
+			requestMethod:   http.MethodPost,
+			expectedStatus:  http.StatusNoContent,
+			expectedOrigin:  "https://app.example.com",
+			expectedMethods: "POST",
+		},
+		{
+			name:           "Preflight (Unknown route)",
+			method:         http.MethodOptions,
+			path:           "/unknown",
+			origin:         "https://app.example.com",
+			requestMethod:  http.MethodGet,
+			expectedStatus: http.StatusNotFound,
+			expectedOrigin: "https://app.example.com",
+		},
+		{
+			name:           "Preflight (Origin not allowed)",
+			method:         http.MethodOptions,
+			path:           "/deck/1",
+			origin:         "https://example.org",
+			requestMethod:  http.MethodGet,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Options without preflight",
+			method:         http.MethodOptions,
+			path:           "/deck/1",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "Request (Origin not allowed)",
+			method:         http.MethodGet,
+			path:           "/deck/nameMaxLength",
+			origin:         "https://example.org",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Request",
+			method:         http.MethodGet,
+			path:           "/deck/nameMaxLength",
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://app.example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tc.requestMethod)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedOrigin, rr.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tc.expectedMethods, rr.Header().Get("Access-Control-Allow-Methods"))
+			assert.Contains(t, rr.Header().Values("Vary"), "Origin")
+
+			if tc.expectedOrigin != "" {
+				assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+			} else {
+				assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+			}
+		})
+	}
+}
@@ -107,8 +107,8 @@ func (suite *APIDeckServerTestSuite) TestGetSingleDeckHandlerWithValid() {
 	decks = append(decks, model.NewDeck("Deck #1", "This is a first deck"))
 	decks = append(decks, model.NewDeck("Deck #2", "This is a second deck"))
 
-	suite.db.Insert(decks[0])
-	suite.db.Insert(decks[1])
+	suite.db.Insert(context.Background(), decks[0])
+	suite.db.Insert(context.Background(), decks[1])
 
 	testCases := []struct {
 		name           string
@@ -195,8 +195,8 @@ func (suite *APIDeckServerTestSuite) TestGetAllDecksHandlerNonEmpty() {
 	decks = append(decks, model.NewDeck("Deck #1", "This is a first deck"))
 	decks = append(decks, model.NewDeck("Deck #2", "This is a second deck"))
 
-	suite.db.Insert(decks[0])
-	suite.db.Insert(decks[1])
+	suite.db.Insert(context.Background(), decks[0])
+	suite.db.Insert(context.Background(), decks[1])
 
 	expectedStatus := http.StatusOK
 
@@ -217,6 +217,38 @@ func (suite *APIDeckServerTestSuite) TestGetAllDecksHandlerNonEmpty() {
 	assert.True(suite.T(), expectedLength == actualLength, "Expected lengths to be %d, got %d", expectedLength, actualLength)
 }
 
+func (suite *APIDeckServerTestSuite) TestGetAllDecksHandlerSummary() {
+	suite.db.CreateTable()
+
+	suite.db.Insert(context.Background(), model.NewDeck("B Deck", "This is a second deck"))
+	suite.db.Insert(context.Background(), model.NewDeck("A Deck", "This is a first deck"))
+
+	// Create a new request
+	req := httptest.NewRequest(http.MethodGet, "/deck", nil)
+
+	// Create a ResponseRecorder to record the response
+	rr := httptest.NewRecorder()
+
+	suite.server.HandleGetAllDecks(rr, req)
+
+	decksReturned := []map[string]any{}
+	err := json.NewDecoder(rr.Body).Decode(&decksReturned)
+
+	assert.Nil(suite.T(), err, "Expected response body to be a JSON array")
+	assert.Equal(suite.T(), http.StatusOK, rr.Code, "Expected status code to be %d, got %d", http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), 2, len(decksReturned), "Expected 2 decks, got %d", len(decksReturned))
+
+	expectedIDs := []float64{1, 0}
+	for i, deck := range decksReturned {
+		assert.Equal(suite.T(), expectedIDs[i], deck["id"], "Expected decks to be sorted by name with numeric IDs")
+
+		for _, key := range []string{"last_study_date", "total_cards", "new_count", "learning_count", "review_count"} {
+			_, ok := deck[key]
+			assert.True(suite.T(), ok, "Expected key %s in deck summary", key)
+		}
+	}
+}
+
 func (suite *APIDeckServerTestSuite) TestGetDeckCountHandlerWithError() {
 	expectedStatus := http.StatusInternalServerError
 	expectedBody := InternalServerErrorMessage + "\n"
@@ -265,8 +297,8 @@ func (suite *APIDeckServerTestSuite) TestGetDeckCountHandlerNonEmpty() {
 	decks = append(decks, model.NewDeck("Deck #1", "This is a first deck"))
 	decks = append(decks, model.NewDeck("Deck #2", "This is a second deck"))
 
-	suite.db.Insert(decks[0])
-	suite.db.Insert(decks[1])
+	suite.db.Insert(context.Background(), decks[0])
+	suite.db.Insert(context.Background(), decks[1])
 
 	expectedStatus := http.StatusOK
 
@@ -480,8 +512,8 @@ func (suite *APIDeckServerTestSuite) TestModifyDeckHandlerWithNotEmpty() {
 	decks = append(decks, model.NewDeck("Deck #1", "This is a first deck"))
 	decks = append(decks, model.NewDeck("Deck #2", "This is a second deck"))
 
-	suite.db.Insert(decks[0])
-	suite.db.Insert(decks[1])
+	suite.db.Insert(context.Background(), decks[0])
+	suite.db.Insert(context.Background(), decks[1])
 
 	testCases := []struct {
 		name           string
@@ -523,7 +555,7 @@ func (suite *APIDeckServerTestSuite) TestModifyDeckHandlerWithNotEmpty() {
 
 		if i > 0 {
 			deckID, _ := strconv.Atoi(tc.deckID)
-			deck, _ := suite.server.deck_db.GetSingle(deckID)
+			deck, _ := suite.server.deck_db.GetSingle(context.Background(), deckID)
 			assert.Equal(
 				suite.T(),
 				"Modified Name",
@@ -610,8 +642,8 @@ func (suite *APIDeckServerTestSuite) TestDeleteDeckHandlerWithValid() {
 	decks = append(decks, model.NewDeck("Deck #1", "This is a first deck"))
 	decks = append(decks, model.NewDeck("Deck #2", "This is a second deck"))
 
-	suite.db.Insert(decks[0])
-	suite.db.Insert(decks[1])
+	suite.db.Insert(context.Background(), decks[0])
+	suite.db.Insert(context.Background(), decks[1])
 
 	testCases := []struct {
 		name           string
@@ -659,7 +691,7 @@ func (suite *APIDeckServerTestSuite) TestDeleteDeckHandlerWithValid() {
 		assert.Equal(suite.T(), expectedBody, rr.Body.String(), "Expected response body to be '%s', got '%s'", expectedBody, rr.Body.String())
 
 		if i > 1 {
-			count, _ := suite.server.deck_db.GetCount()
+			count, _ := suite.server.deck_db.GetCount(context.Background())
 			assert.True(suite.T(), count == 1, "Expected length of database to be %d but got %d", 1, count)
 		}
 	}
@@ -696,3 +728,333 @@ func (suite *APIDeckServerTestSuite) TestGetDeckDescriptionMaxLength() {
 	assert.Equal(suite.T(), expectedStatus, rr.Code, "Expected status code to be %d, got %d", expectedStatus, rr.Code)
 	assert.Equal(suite.T(), expectedBody, rr.Body.String(), "Expected response body to be '%s', got '%s'", expectedBody, rr.Body.String())
 }
+
+func (suite *APIDeckServerTestSuite) TestInsertChildDeckHandler() {
+	suite.db.CreateTable()
+	suite.db.Insert(context.Background(), model.NewDeck("Parent", "Parent deck"))
+
+	testCases := []struct {
+		name           string
+		deckID         string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Deck ID not number)",
+			deckID:         "a",
+			requestBody:    `{"name": "Child"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidDeckIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (No name in request body)",
+			deckID:         "0",
+			requestBody:    `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request",
+			deckID:         "0",
+			requestBody:    `{"name": "Child", "description": "Child deck"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":1}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "/deck/"+tc.deckID+"/child", nil)
+		req.Body = io.NopCloser(strings.NewReader(tc.requestBody))
+		rr := httptest.NewRecorder()
+
+		suite.server.HandleInsertChildDeck(rr, req)
+
+		assert.Equal(suite.T(), tc.expectedStatus, rr.Code, "%s: expected status code to be %d, got %d", tc.name, tc.expectedStatus, rr.Code)
+		assert.Equal(suite.T(), tc.expectedBody, rr.Body.String(), "%s: expected response body to be '%s', got '%s'", tc.name, tc.expectedBody, rr.Body.String())
+	}
+
+	child, _ := suite.db.GetSingle(context.Background(), 1)
+	assert.NotNil(suite.T(), child.ParentID, "Expected child deck to have a parent")
+	assert.Equal(suite.T(), 0, *child.ParentID, "Expected child deck to be under deck 0")
+}
+
+func (suite *APIDeckServerTestSuite) TestMoveDeckHandler() {
+	suite.db.CreateTable()
+	parentID := 0
+	suite.db.Insert(context.Background(), model.NewDeck("Root", "Root deck"))
+	child := model.NewDeck("Child", "Child deck")
+	child.ParentID = &parentID
+	suite.db.Insert(context.Background(), child)
+	suite.db.Insert(context.Background(), model.NewDeck("Other", "Other deck"))
+	namesake := model.NewDeck("Other", "Other deck under the root")
+	namesake.ParentID = &parentID
+	suite.db.Insert(context.Background(), namesake)
+
+	testCases := []struct {
+		name           string
+		deckID         string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Deck ID not number)",
+			deckID:         "a",
+			requestBody:    `{"parent_id": 0}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidDeckIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (corrupted request body)",
+			deckID:         "2",
+			requestBody:    `{"parent_id": `,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Deck doesn't exist)",
+			deckID:         "5",
+			requestBody:    `{"parent_id": 0}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidDeckIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Move under own descendant)",
+			deckID:         "0",
+			requestBody:    `{"parent_id": 1}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidParentDeckErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Move under itself)",
+			deckID:         "2",
+			requestBody:    `{"parent_id": 2}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidParentDeckErrorMessage + "\n",
+		},
+		{
+			name:           "Conflict (Move next to a sibling with the same name)",
+			deckID:         "3",
+			requestBody:    `{"parent_id": null}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   DuplicateKeyViolationErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request (Move under child)",
+			deckID:         "2",
+			requestBody:    `{"parent_id": 1}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":2}` + "\n",
+		},
+		{
+			name:           "Valid request (Move to top level)",
+			deckID:         "1",
+			requestBody:    `{"parent_id": null}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":1}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "/deck/"+tc.deckID+"/move", nil)
+		req.Body = io.NopCloser(strings.NewReader(tc.requestBody))
+		rr := httptest.NewRecorder()
+
+		suite.server.HandleMoveDeck(rr, req)
+
+		assert.Equal(suite.T(), tc.expectedStatus, rr.Code, "%s: expected status code to be %d, got %d", tc.name, tc.expectedStatus, rr.Code)
+		assert.Equal(suite.T(), tc.expectedBody, rr.Body.String(), "%s: expected response body to be '%s', got '%s'", tc.name, tc.expectedBody, rr.Body.String())
+	}
+
+	moved, _ := suite.db.GetSingle(context.Background(), 1)
+	assert.Nil(suite.T(), moved.ParentID, "Expected deck 1 to be a top-level deck")
+	moved, _ = suite.db.GetSingle(context.Background(), 2)
+	assert.Equal(suite.T(), 1, *moved.ParentID, "Expected deck 2 to be under deck 1")
+	moved, _ = suite.db.GetSingle(context.Background(), 3)
+	assert.Equal(suite.T(), 0, *moved.ParentID, "Expected deck 3 to stay under deck 0")
+}
+
+func (suite *APIDeckServerTestSuite) TestGetDeckTreeHandler() {
+	suite.db.CreateTable()
+	parentID := 0
+	suite.db.Insert(context.Background(), model.NewDeck("Root", "Root deck"))
+	child := model.NewDeck("Child", "Child deck")
+	child.ParentID = &parentID
+	suite.db.Insert(context.Background(), child)
+
+	req := httptest.NewRequest(http.MethodGet, "/deck/tree", nil)
+	rr := httptest.NewRecorder()
+
+	suite.server.HandleGetDeckTree(rr, req)
+
+	tree := []model.DeckNode{}
+	err := json.NewDecoder(rr.Body).Decode(&tree)
+
+	assert.Nil(suite.T(), err, "Expected response body to be a JSON array")
+	assert.Equal(suite.T(), http.StatusOK, rr.Code, "Expected status code to be %d, got %d", http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), 1, len(tree), "Expected a single root deck")
+	assert.Equal(suite.T(), "Root", tree[0].Name)
+	assert.Equal(suite.T(), 1, len(tree[0].Children), "Expected root deck to have a child")
+	assert.Equal(suite.T(), "Child", tree[0].Children[0].Name)
+}
+
+func (suite *APIDeckServerTestSuite) TestDeleteDeckHandlerWithChildren() {
+	suite.db.CreateTable()
+	rootID, childID := 0, 1
+	suite.db.Insert(context.Background(), model.NewDeck("Root", "Root deck"))
+	child := model.NewDeck("Child", "Child deck")
+	child.ParentID = &rootID
+	suite.db.Insert(context.Background(), child)
+	grandChild := model.NewDeck("Grand child", "Grand child deck")
+	grandChild.ParentID = &childID
+	suite.db.Insert(context.Background(), grandChild)
+
+	// Invalid children mode
+	req := httptest.NewRequest(http.MethodDelete, "/deck/1?children=orphan", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleDeleteDeck(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+	assert.Equal(suite.T(), InvalidQueryErrorMessage+"\n", rr.Body.String())
+
+	// Re-parent the grand child to the root
+	req = httptest.NewRequest(http.MethodDelete, "/deck/1?children=reparent", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleDeleteDeck(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	count, _ := suite.db.GetCount(context.Background())
+	assert.Equal(suite.T(), 2, count, "Expected only the child deck to be deleted")
+	deck, _ := suite.db.GetSingle(context.Background(), 2)
+	assert.Equal(suite.T(), rootID, *deck.ParentID, "Expected grand child to move under the root")
+
+	// Cascade to the remaining child
+	req = httptest.NewRequest(http.MethodDelete, "/deck/0", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleDeleteDeck(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	count, _ = suite.db.GetCount(context.Background())
+	assert.Equal(suite.T(), 0, count, "Expected the root and its children to be deleted")
+}
+
+func (suite *APIDeckServerTestSuite) TestDeleteDeckHandlerModes() {
+	suite.db.CreateTable()
+	suite.db.Insert(context.Background(), model.NewDeck("First", "First deck"))
+	suite.db.Insert(context.Background(), model.NewDeck("Second", "Second deck"))
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Invalid mode",
+			query:          "?mode=shred",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidQueryErrorMessage + "\n",
+		},
+		{
+			name:           "Invalid move_to",
+			query:          "?move_to=abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidQueryErrorMessage + "\n",
+		},
+		{
+			name:           "move_to combined with mode",
+			query:          "?mode=cascade&move_to=1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidQueryErrorMessage + "\n",
+		},
+		{
+			name:           "move_to unknown deck",
+			query:          "?move_to=42",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidTargetDeckErrorMessage + "\n",
+		},
+		{
+			name:           "move_to deck being deleted",
+			query:          "?move_to=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidTargetDeckErrorMessage + "\n",
+		},
+		{
+			name:           "move_to other deck",
+			query:          "?move_to=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"id\":0}\n",
+		},
+	}
+
+	for _, test := range testCases {
+		suite.T().Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/deck/0"+test.query, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleDeleteDeck(rr, req)
+
+			assert.Equal(t, test.expectedStatus, rr.Code)
+			assert.Equal(t, test.expectedBody, rr.Body.String())
+		})
+	}
+
+	trash, _ := suite.db.GetTrash(context.Background())
+	assert.Equal(suite.T(), 0, len(trash), "Expected a permanently deleted deck not to be in the trash")
+}
+
+func (suite *APIDeckServerTestSuite) TestDeckTrashAndRestoreHandlers() {
+	suite.db.CreateTable()
+	rootID := 0
+	suite.db.Insert(context.Background(), model.NewDeck("Root", "Root deck"))
+	child := model.NewDeck("Child", "Child deck")
+	child.ParentID = &rootID
+	suite.db.Insert(context.Background(), child)
+
+	// Trash the root along with its child
+	req := httptest.NewRequest(http.MethodDelete, "/deck/0", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleDeleteDeck(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	count, _ := suite.db.GetCount(context.Background())
+	assert.Equal(suite.T(), 0, count, "Expected trashed decks to be hidden")
+
+	req = httptest.NewRequest(http.MethodGet, "/deck/trash", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleGetDeckTrash(rr, req)
+
+	var trash []model.Deck
+	err := json.NewDecoder(rr.Body).Decode(&trash)
+	assert.Nil(suite.T(), err, "Expected response body to be a JSON array")
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), 2, len(trash))
+	for _, deck := range trash {
+		assert.NotNil(suite.T(), deck.DeletedAt, "Expected trashed deck to have a deletion time")
+	}
+
+	// Restoring the child alone detaches it from its trashed parent
+	req = httptest.NewRequest(http.MethodPost, "/deck/1/restore", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleRestoreDeck(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	deck, err := suite.db.GetSingle(context.Background(), 1)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), deck.ParentID, "Expected restored child to become a top-level deck")
+	assert.Nil(suite.T(), deck.DeletedAt)
+
+	// Restoring twice fails
+	req = httptest.NewRequest(http.MethodPost, "/deck/1/restore", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleRestoreDeck(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+	assert.Equal(suite.T(), InvalidDeckIDErrorMessage+"\n", rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/deck/0/restore", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleRestoreDeck(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	count, _ = suite.db.GetCount(context.Background())
+	assert.Equal(suite.T(), 2, count, "Expected both decks to be restored")
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilteredDeckDefaultLimit is the number of cards a filtered deck borrows when no limit is given.
+const FilteredDeckDefaultLimit = 100
+
+// HandleInsertFilteredDeck handles the HTTP POST request for creating a filtered deck,
+// a temporary deck for a custom study session. The body holds the name of the deck,
+// optionally its description and parent, and the search it is built from, for example
+// {"name": "Forgotten", "query": {"forgotten_days": 7}, "order": "lapses", "limit": 50}.
+// The order is one of "due", "random", "added" and "lapses", by default "due", the
+// limit defaults to 100, and "reschedule" set to false lets the cards be previewed
+// without changing their scheduling. The matching cards are borrowed from their
+// home decks right away.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the filtered deck in its body.
+//
+// Errors:
+//   - 400 Bad Request : If the request body or the search is invalid, or the parent doesn't exist.
+//   - 409 Conflict : If the parent already has a child with the same name.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 201 Created : With the ID of the deck and the IDs of the borrowed cards.
+func (s *APIServer) HandleInsertFilteredDeck(w http.ResponseWriter, r *http.Request) {
+	type InsertInput struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		ParentID    *int   `json:"parent_id"`
+		model.FilteredDeck
+	}
+	bodyInput := InsertInput{FilteredDeck: defaultFilteredDeck()}
+	err := json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	bodyInput.Name = strings.TrimSpace(bodyInput.Name)
+	bodyInput.Description = strings.TrimSpace(bodyInput.Description)
+	if bodyInput.Name == "" {
+		slog.DebugContext(r.Context(), "Missing filtered deck name")
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	} else if err = bodyInput.FilteredDeck.Validate(); err != nil {
+		slog.DebugContext(r.Context(), "Invalid filtered deck", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	deck := model.NewDeck(bodyInput.Name, bodyInput.Description)
+	deck.ParentID = bodyInput.ParentID
+	deckID, dbErr := s.deck_db.Insert(r.Context(), deck)
+	if dbErr != nil {
+		if dbErr == utils.ErrMaxLengthExceeded {
+			slog.DebugContext(r.Context(), "Max length exceeded", "error", dbErr)
+			http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		} else if dbErr == utils.ErrDuplicateKeyViolation {
+			slog.DebugContext(r.Context(), "Duplicate key violation", "error", dbErr)
+			http.Error(w, DuplicateKeyViolationErrorMessage, http.StatusConflict)
+		} else if dbErr == utils.ErrInvalidParent {
+			slog.DebugContext(r.Context(), "Invalid parent deck", "error", dbErr)
+			http.Error(w, InvalidParentDeckErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error inserting deck", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	filtered := bodyInput.FilteredDeck
+	filtered.DeckID = deckID
+	borrowed, dbErr := s.buildFilteredDeck(r.Context(), filtered)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error building filtered deck", "error", dbErr)
+		if err = s.deck_db.Delete(r.Context(), deckID, database.DeckDeleteOptions{Mode: database.DeckDeleteCascade}); err != nil {
+			slog.ErrorContext(r.Context(), "Error deleting unbuilt filtered deck", "deck ID", deckID, "error", err)
+		}
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	s.metrics.decksCreated.Inc(deckKindFiltered)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(filteredDeckOutput{ID: deckID, Cards: borrowed})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding filtered deck", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", deckID, "borrowed cards", len(borrowed))
+}
+
+// HandleGetFilteredDeck handles the HTTP GET request for retrieving the search a
+// filtered deck is built from.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID is invalid or the deck isn't a filtered deck.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the filtered deck is found and the request is successful.
+func (s *APIServer) HandleGetFilteredDeck(w http.ResponseWriter, r *http.Request) {
+	filtered, ok := s.parseFilteredDeck(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(filtered)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding filtered deck", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", filtered.DeckID)
+}
+
+// HandleModifyFilteredDeck handles the HTTP POST request for changing the search
+// a filtered deck is built from. The body holds the search as for
+// HandleInsertFilteredDeck, and the deck is rebuilt with it.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID, the request body or the search is invalid, or the deck isn't a filtered deck.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the IDs of the borrowed cards.
+func (s *APIServer) HandleModifyFilteredDeck(w http.ResponseWriter, r *http.Request) {
+	current, ok := s.parseFilteredDeck(w, r)
+	if !ok {
+		return
+	}
+
+	filtered := defaultFilteredDeck()
+	err := json.NewDecoder(r.Body).Decode(&filtered)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+	filtered.DeckID = current.DeckID
+
+	if err = filtered.Validate(); err != nil {
+		slog.DebugContext(r.Context(), "Invalid filtered deck", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	s.writeBuiltFilteredDeck(w, r, filtered)
+}
+
+// HandleRebuildFilteredDeck handles the HTTP POST request for rebuilding a filtered
+// deck. Its cards are returned to their home decks and the cards matching its
+// search are borrowed again.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID is invalid or the deck isn't a filtered deck.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the IDs of the borrowed cards.
+func (s *APIServer) HandleRebuildFilteredDeck(w http.ResponseWriter, r *http.Request) {
+	filtered, ok := s.parseFilteredDeck(w, r)
+	if !ok {
+		return
+	}
+
+	s.writeBuiltFilteredDeck(w, r, filtered)
+}
+
+// HandleEmptyFilteredDeck handles the HTTP POST request for emptying a filtered deck,
+// which returns its cards to their home decks. The deck keeps its search and can
+// be rebuilt later.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID is invalid or the deck isn't a filtered deck.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the number of cards returned to their home decks.
+func (s *APIServer) HandleEmptyFilteredDeck(w http.ResponseWriter, r *http.Request) {
+	filtered, ok := s.parseFilteredDeck(w, r)
+	if !ok {
+		return
+	}
+
+	returned, dbErr := s.card_db.EmptyFilteredDecks(r.Context(), []int{filtered.DeckID})
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error emptying filtered deck", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]int{"id": filtered.DeckID, "returned": returned})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding filtered deck", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", filtered.DeckID, "returned cards", returned)
+}
+
+// filteredDeckOutput is the response of the handlers that build a filtered deck.
+type filteredDeckOutput struct {
+	ID    int   `json:"id"`
+	Cards []int `json:"cards"`
+}
+
+// defaultFilteredDeck returns the search options used when a request leaves them out.
+func defaultFilteredDeck() model.FilteredDeck {
+	return model.FilteredDeck{
+		Order:      model.FilteredOrderDue,
+		Limit:      FilteredDeckDefaultLimit,
+		Reschedule: true,
+	}
+}
+
+// buildFilteredDeck saves the search of a filtered deck and borrows the cards matching it.
+//
+// Returns:
+//   - []int : The IDs of the borrowed cards, in the order they were picked.
+//   - error : An error if the search can't be saved or the cards can't be borrowed.
+func (s *APIServer) buildFilteredDeck(ctx context.Context, filtered model.FilteredDeck) ([]int, error) {
+	if err := s.filtered_db.Set(ctx, filtered); err != nil {
+		return nil, err
+	}
+
+	return s.card_db.FillFilteredDeck(ctx, filtered)
+}
+
+// writeBuiltFilteredDeck builds a filtered deck and writes the borrowed cards to the response.
+func (s *APIServer) writeBuiltFilteredDeck(w http.ResponseWriter, r *http.Request, filtered model.FilteredDeck) {
+	borrowed, dbErr := s.buildFilteredDeck(r.Context(), filtered)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error building filtered deck", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(filteredDeckOutput{ID: filtered.DeckID, Cards: borrowed})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding filtered deck", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", filtered.DeckID, "borrowed cards", len(borrowed))
+}
+
+// parseFilteredDeck parses the deck ID from the URL path and retrieves its
+// search, writing a 400 Bad Request response if the ID isn't a number or the
+// deck isn't a filtered deck.
+//
+// Returns:
+//   - model.FilteredDeck : The search of the filtered deck.
+//   - bool : False if the response has already been written.
+func (s *APIServer) parseFilteredDeck(w http.ResponseWriter, r *http.Request) (model.FilteredDeck, bool) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return model.FilteredDeck{}, false
+	}
+
+	filtered, isFiltered, dbErr := s.getFilteredDeck(r.Context(), deckID)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error getting filtered deck", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return model.FilteredDeck{}, false
+	} else if !isFiltered {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Deck %d isn't a filtered deck", deckID))
+		http.Error(w, NotFilteredDeckErrorMessage, http.StatusBadRequest)
+		return model.FilteredDeck{}, false
+	}
+
+	return filtered, true
+}
+
+// getFilteredDeck retrieves the search of a deck if it is a filtered deck.
+// Without a filtered deck database, no deck is a filtered deck.
+//
+// Returns:
+//   - model.FilteredDeck : The search of the filtered deck.
+//   - bool : True if the deck is a filtered deck.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (s *APIServer) getFilteredDeck(ctx context.Context, deckID int) (model.FilteredDeck, bool, error) {
+	if s.filtered_db == nil {
+		return model.FilteredDeck{}, false, nil
+	}
+
+	filtered, err := s.filtered_db.Get(ctx, deckID)
+	if err == utils.ErrRecordNotExist {
+		return model.FilteredDeck{}, false, nil
+	} else if err != nil {
+		return model.FilteredDeck{}, false, err
+	}
+
+	return filtered, true, nil
+}
+
+// getFilteredDueCards retrieves the cards to study in a filtered deck, which are
+// all of its cards that aren't suspended or buried, in the order of the deck.
+// The daily limits don't apply, since the deck only holds what the search picked.
+//
+// Returns:
+//   - []model.Card : The cards to study.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (s *APIServer) getFilteredDueCards(ctx context.Context, filtered model.FilteredDeck) ([]model.Card, error) {
+	borrowed, err := s.card_db.Search(ctx, model.CardFilter{DeckIDs: []int{filtered.DeckID}})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cards := []model.Card{}
+	for _, card := range borrowed {
+		if card.InQueue(now) {
+			cards = append(cards, card)
+		}
+	}
+
+	// Seeded by the deck so that the random order doesn't change between requests.
+	filtered.Sort(cards, rand.New(rand.NewPCG(uint64(filtered.DeckID), 0)))
+	return cards, nil
+}
+
+// emptyFilteredDecks returns the cards borrowed by a deck and its sub-decks to
+// their home decks. Without a filtered deck database, no deck borrows cards.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the deck doesn't exist, or an error if the update fails.
+func (s *APIServer) emptyFilteredDecks(ctx context.Context, deckID int) error {
+	if s.filtered_db == nil {
+		return nil
+	}
+
+	deckIDs, err := s.deck_db.GetDescendantIDs(ctx, deckID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.card_db.EmptyFilteredDecks(ctx, deckIDs)
+	return err
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIFilteredDeckServerTestSuite struct {
+	suite.Suite
+	deck_db     *database.DeckDBWrapperMock
+	card_db     *database.CardDBWrapperMock
+	filtered_db *database.FilteredDeckDBWrapperMock
+	server      *APIServer
+}
+
+func (suite *APIFilteredDeckServerTestSuite) SetupTest() {
+	suite.deck_db = database.NewDeckDBWrapperMock()
+	suite.card_db = database.NewCardDBWrapperMock()
+	suite.filtered_db = database.NewFilteredDeckDBWrapperMock()
+	suite.server = NewAPIServer("localhost:8080", suite.deck_db, suite.card_db).WithFilteredDeckDB(suite.filtered_db)
+
+	suite.deck_db.CreateTable()
+	suite.card_db.CreateTable()
+	suite.filtered_db.CreateTable()
+	suite.deck_db.Insert(context.Background(), model.Deck{Name: "Home deck"})
+	for deckID := 0; deckID < 2; deckID++ {
+		suite.card_db.InsertDeck(deckID)
+	}
+	for _, tags := range [][]string{{"verbs"}, {"verbs"}, {}} {
+		card := model.NewCard(0, "Test content", "")
+		card.Tags = tags
+		suite.card_db.Insert(context.Background(), card)
+	}
+}
+
+func (suite *APIFilteredDeckServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPIFilteredDeckServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APIFilteredDeckServerTestSuite))
+}
+
+// insertFilteredDeck creates the filtered deck used by the tests, which previews
+// the cards tagged "verbs" and gets the deck ID 1.
+func (suite *APIFilteredDeckServerTestSuite) insertFilteredDeck() filteredDeckOutput {
+	body := `{"name":"Verbs","query":{"tags":["verbs"]},"reschedule":false}`
+	req := httptest.NewRequest(http.MethodPost, "/deck/filtered", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	suite.server.HandleInsertFilteredDeck(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var output filteredDeckOutput
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &output))
+	return output
+}
+
+func (suite *APIFilteredDeckServerTestSuite) TestInsertFilteredDeckHandler() {
+	testCases := []struct {
+		name           string
+		requestBody    string
+		expectedStatus int
+		expectedCards  []int
+	}{
+		{name: "Bad Request (Empty body)", requestBody: "", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Missing name)", requestBody: `{"query":{}}`, expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Unknown order)", requestBody: `{"name":"Custom","order":"oldest"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Limit too high)", requestBody: `{"name":"Custom","limit":10000}`, expectedStatus: http.StatusBadRequest},
+		{name: "Valid request (Tag)", requestBody: `{"name":"Verbs","query":{"tags":["verbs"]}}`, expectedStatus: http.StatusOK, expectedCards: []int{0, 1}},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/deck/filtered", strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			suite.server.HandleInsertFilteredDeck(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var output filteredDeckOutput
+				assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &output))
+				assert.Equal(t, tc.expectedCards, output.Cards)
+
+				filtered, err := suite.filtered_db.Get(context.Background(), output.ID)
+				assert.Nil(t, err)
+				assert.Equal(t, model.FilteredOrderDue, filtered.Order)
+				assert.Equal(t, FilteredDeckDefaultLimit, filtered.Limit)
+				assert.True(t, filtered.Reschedule)
+
+				for _, cardID := range tc.expectedCards {
+					card, _ := suite.card_db.GetSingle(context.Background(), cardID)
+					assert.Equal(t, output.ID, card.DeckID)
+					assert.Equal(t, 0, *card.OriginalDeckID)
+				}
+			}
+		})
+	}
+}
+
+func (suite *APIFilteredDeckServerTestSuite) TestFilteredDeckStudy() {
+	output := suite.insertFilteredDeck()
+	suite.Equal(1, output.ID)
+	suite.Equal([]int{0, 1}, output.Cards)
+
+	getDueCards := func(path string) []model.Card {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		suite.server.HandleGetDueCards(rr, req)
+		suite.Equal(http.StatusOK, rr.Code)
+
+		var cards []model.Card
+		suite.Nil(json.Unmarshal(rr.Body.Bytes(), &cards))
+		return cards
+	}
+	suite.Equal(2, len(getDueCards("/deck/1/card/due")))
+	suite.Equal(1, len(getDueCards("/deck/0/card/due")), "Expected borrowed cards to leave the home deck queue")
+
+	review := func(cardID string, grade string) model.Card {
+		req := httptest.NewRequest(http.MethodPost, "/card/"+cardID+"/review", strings.NewReader(`{"grade":`+grade+`}`))
+		rr := httptest.NewRecorder()
+		suite.server.HandleReviewCard(rr, req)
+		suite.Equal(http.StatusOK, rr.Code)
+
+		var output struct {
+			Card model.Card `json:"card"`
+		}
+		suite.Nil(json.Unmarshal(rr.Body.Bytes(), &output))
+		return output.Card
+	}
+
+	failed := review("0", "1")
+	suite.Equal(1, failed.DeckID, "Expected a failed preview to stay in the filtered deck")
+	suite.Equal(model.CardStateNew, failed.State)
+
+	passed := review("1", "3")
+	suite.Equal(0, passed.DeckID, "Expected a passed preview to go back to its home deck")
+	suite.Nil(passed.OriginalDeckID)
+	suite.Equal(model.CardStateNew, passed.State)
+
+	for _, entry := range suite.card_db.GetReviewLog() {
+		suite.Equal(model.ReviewKindPreview, entry.Kind)
+		suite.Equal(0, entry.DeckID)
+	}
+	suite.Equal(1, len(getDueCards("/deck/1/card/due")))
+}
+
+func (suite *APIFilteredDeckServerTestSuite) TestFilteredDeckHandlers() {
+	suite.insertFilteredDeck()
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		requestBody    string
+		handler        func(w http.ResponseWriter, r *http.Request)
+		expectedStatus int
+	}{
+		{name: "Get (Deck ID not number)", method: http.MethodGet, path: "/deck/a/filter", handler: suite.server.HandleGetFilteredDeck, expectedStatus: http.StatusBadRequest},
+		{name: "Get (Not filtered)", method: http.MethodGet, path: "/deck/0/filter", handler: suite.server.HandleGetFilteredDeck, expectedStatus: http.StatusBadRequest},
+		{name: "Get", method: http.MethodGet, path: "/deck/1/filter", handler: suite.server.HandleGetFilteredDeck, expectedStatus: http.StatusOK},
+		{name: "Modify (Not filtered)", method: http.MethodPost, path: "/deck/0/filter", requestBody: `{"limit":1}`, handler: suite.server.HandleModifyFilteredDeck, expectedStatus: http.StatusBadRequest},
+		{name: "Modify (Invalid limit)", method: http.MethodPost, path: "/deck/1/filter", requestBody: `{"limit":0}`, handler: suite.server.HandleModifyFilteredDeck, expectedStatus: http.StatusBadRequest},
+		{name: "Modify", method: http.MethodPost, path: "/deck/1/filter", requestBody: `{"limit":1,"order":"added"}`, handler: suite.server.HandleModifyFilteredDeck, expectedStatus: http.StatusOK},
+		{name: "Rebuild (Not filtered)", method: http.MethodPost, path: "/deck/0/rebuild", handler: suite.server.HandleRebuildFilteredDeck, expectedStatus: http.StatusBadRequest},
+		{name: "Rebuild", method: http.MethodPost, path: "/deck/1/rebuild", handler: suite.server.HandleRebuildFilteredDeck, expectedStatus: http.StatusOK},
+		{name: "Empty (Not filtered)", method: http.MethodPost, path: "/deck/0/empty", handler: suite.server.HandleEmptyFilteredDeck, expectedStatus: http.StatusBadRequest},
+		{name: "Empty", method: http.MethodPost, path: "/deck/1/empty", handler: suite.server.HandleEmptyFilteredDeck, expectedStatus: http.StatusOK},
+		{name: "Insert card", method: http.MethodPost, path: "/deck/1/card", requestBody: `{"content":{"fields":["Front"],"values":["Value"]}}`, handler: suite.server.HandleInsertCard, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			tc.handler(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+
+	total, _ := suite.card_db.GetTotalCards(context.Background(), 0)
+	suite.Equal(3, total, "Expected the emptied deck to return its cards")
+	filtered, _ := suite.filtered_db.Get(context.Background(), 1)
+	suite.Equal(1, filtered.Limit)
+	suite.Equal(model.FilteredOrderAdded, filtered.Order)
+}
+
+func (suite *APIFilteredDeckServerTestSuite) TestDeleteFilteredDeck() {
+	suite.insertFilteredDeck()
+
+	req := httptest.NewRequest(http.MethodDelete, "/deck/1", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleDeleteDeck(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	total, _ := suite.card_db.GetTotalCards(context.Background(), 0)
+	suite.Equal(3, total, "Expected the deleted filtered deck to return its cards")
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+	suite.Nil(card.OriginalDeckID)
+}
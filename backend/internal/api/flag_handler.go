@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HandleGetAllFlags handles the HTTP GET request for retrieving all flag definitions.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the flag definitions are found and the request is successful.
+func (s *APIServer) HandleGetAllFlags(w http.ResponseWriter, r *http.Request) {
+	definitions, err := s.flag_db.GetAll(r.Context())
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error getting all flag definitions", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(definitions)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding flag definitions", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "flag definitions", len(definitions))
+}
+
+// HandleSetFlagDefinition handles the HTTP POST request for naming a flag value
+// and giving it a color. An existing definition is replaced.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the flag value in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the flag value or the request body is invalid.
+//   - 409 Conflict : If another flag has the same name.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the flag is defined and the request is successful.
+func (s *APIServer) HandleSetFlagDefinition(w http.ResponseWriter, r *http.Request) {
+	flag, ok := parseFlag(w, r)
+	if !ok {
+		return
+	}
+
+	var definition model.FlagDefinition
+	err := json.NewDecoder(r.Body).Decode(&definition)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+	definition.Flag = flag
+
+	dbErr := s.flag_db.Set(r.Context(), definition)
+	if dbErr != nil {
+		if dbErr == utils.ErrInvalidFlag {
+			slog.DebugContext(r.Context(), "Invalid flag definition", "error", dbErr)
+			http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		} else if dbErr == utils.ErrDuplicateKeyViolation {
+			slog.DebugContext(r.Context(), "Duplicate key violation", "error", dbErr)
+			http.Error(w, DuplicateKeyViolationErrorMessage, http.StatusConflict)
+		} else {
+			slog.DebugContext(r.Context(), "Error setting flag definition", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int{"flag": flag})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding flag", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "flag", flag)
+}
+
+// HandleDeleteFlagDefinition handles the HTTP DELETE request for removing the
+// definition of a flag value. Cards marked with the flag keep it.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the flag value in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the flag value is invalid or not defined.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the definition is removed and the request is successful.
+func (s *APIServer) HandleDeleteFlagDefinition(w http.ResponseWriter, r *http.Request) {
+	flag, ok := parseFlag(w, r)
+	if !ok {
+		return
+	}
+
+	dbErr := s.flag_db.Delete(r.Context(), flag)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Flag not defined", "error", dbErr)
+			http.Error(w, InvalidFlagErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error deleting flag definition", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]int{"flag": flag})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding flag", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "flag", flag)
+}
+
+// HandleSetCardFlag handles the HTTP POST request for marking a card with a flag.
+// The flag is given in the body either by value or by the name of its definition,
+// for example {"flag": 1} or {"flag": "red"}. To flag many cards at once, use
+// the set_flag operation of the bulk endpoint.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID, the request body or the flag is invalid, or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is flagged and the request is successful.
+func (s *APIServer) HandleSetCardFlag(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	type FlagInput struct {
+		Flag json.RawMessage `json:"flag"`
+	}
+	var bodyInput FlagInput
+	err := json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil || bodyInput.Flag == nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	var flag int
+	var name string
+	if json.Unmarshal(bodyInput.Flag, &flag) != nil {
+		if json.Unmarshal(bodyInput.Flag, &name) != nil {
+			slog.DebugContext(r.Context(), "Flag is neither a number nor a name")
+			http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+			return
+		}
+
+		definition, dbErr := s.flag_db.GetByName(r.Context(), name)
+		if dbErr != nil {
+			writeFlagError(w, r, dbErr)
+			return
+		}
+		flag = definition.Flag
+	}
+
+	s.setCardFlag(w, r, cardID, flag)
+}
+
+// HandleClearCardFlag handles the HTTP DELETE request for removing the flag of a card.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID is invalid or the card is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the flag is removed and the request is successful.
+func (s *APIServer) HandleClearCardFlag(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	s.setCardFlag(w, r, cardID, model.CardMinFlag)
+}
+
+func (s *APIServer) setCardFlag(w http.ResponseWriter, r *http.Request, cardID int, flag int) {
+	dbErr := s.card_db.SetFlag(r.Context(), cardID, flag)
+	if dbErr != nil {
+		if dbErr == utils.ErrInvalidFlag {
+			writeFlagError(w, r, dbErr)
+		} else {
+			writeCardError(w, r, dbErr)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]int{"id": cardID, "flag": flag})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card flag", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card ID", cardID, "flag", flag)
+}
+
+// resolveFlagName replaces a flag name in the "flag" query parameter with
+// the flag value it is defined for, so searches can use names such as "red".
+//
+// Parameters:
+//   - ctx context.Context : The context of the request.
+//   - query url.Values : The query parameters, modified in place.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if no flag has this name, nil otherwise.
+func (s *APIServer) resolveFlagName(ctx context.Context, query url.Values) error {
+	name := query.Get("flag")
+	if name == "" {
+		return nil
+	} else if _, err := strconv.Atoi(name); err == nil {
+		return nil
+	}
+
+	definition, err := s.flag_db.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	query.Set("flag", strconv.Itoa(definition.Flag))
+	return nil
+}
+
+// parseFlag parses the flag value from the URL path and writes a
+// 400 Bad Request response if it isn't a number.
+//
+// Returns:
+//   - int : The flag value.
+//   - bool : False if the response has already been written.
+func parseFlag(w http.ResponseWriter, r *http.Request) (int, bool) {
+	flagStr := strings.Split(r.URL.Path, "/")[2]
+	flag, err := strconv.Atoi(flagStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid flag %s", flagStr))
+		http.Error(w, InvalidFlagErrorMessage, http.StatusBadRequest)
+		return 0, false
+	}
+
+	return flag, true
+}
+
+// writeFlagError writes the response matching an error returned while resolving a flag.
+func writeFlagError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case utils.ErrRecordNotExist, utils.ErrInvalidFlag:
+		slog.DebugContext(r.Context(), "Invalid flag", "error", err)
+		http.Error(w, InvalidFlagErrorMessage, http.StatusBadRequest)
+	default:
+		slog.DebugContext(r.Context(), "Error accessing flags", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+	}
+}
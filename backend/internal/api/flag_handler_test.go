@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIFlagServerTestSuite struct {
+	suite.Suite
+	card_db *database.CardDBWrapperMock
+	flag_db *database.FlagDBWrapperMock
+	server  *APIServer
+}
+
+func (suite *APIFlagServerTestSuite) SetupTest() {
+	suite.card_db = database.NewCardDBWrapperMock()
+	suite.flag_db = database.NewFlagDBWrapperMock()
+	suite.server = NewAPIServer("localhost:8080", database.NewDeckDBWrapperMock(), suite.card_db).
+		WithFlagDB(suite.flag_db)
+}
+
+func (suite *APIFlagServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPIFlagServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APIFlagServerTestSuite))
+}
+
+func (suite *APIFlagServerTestSuite) TestFlagDefinitionHandlers() {
+	suite.flag_db.CreateTable()
+	suite.flag_db.Set(context.Background(), model.FlagDefinition{Flag: 2, Name: "Orange", Color: "#fb8c00"})
+
+	testCases := []struct {
+		name           string
+		path           string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Flag not number)",
+			path:           "/flag/red",
+			requestBody:    "{\"name\":\"Red\",\"color\":\"#e53935\"}",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidFlagErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Flag out of range)",
+			path:           "/flag/10",
+			requestBody:    "{\"name\":\"Red\",\"color\":\"#e53935\"}",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Invalid color)",
+			path:           "/flag/1",
+			requestBody:    "{\"name\":\"Red\",\"color\":\"red\"}",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Conflict (Name already used)",
+			path:           "/flag/1",
+			requestBody:    "{\"name\":\"orange\",\"color\":\"#e53935\"}",
+			expectedStatus: http.StatusConflict,
+			expectedBody:   DuplicateKeyViolationErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request",
+			path:           "/flag/1",
+			requestBody:    "{\"name\":\"Red\",\"color\":\"#e53935\"}",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"flag\":1}\n",
+		},
+		{
+			name:           "Valid request (Rename)",
+			path:           "/flag/2",
+			requestBody:    "{\"name\":\"Amber\",\"color\":\"#ffb300\"}",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"flag\":2}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			suite.server.HandleSetFlagDefinition(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/flag", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleGetAllFlags(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(),
+		"[{\"flag\":1,\"name\":\"Red\",\"color\":\"#e53935\"},{\"flag\":2,\"name\":\"Amber\",\"color\":\"#ffb300\"}]\n",
+		rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodDelete, "/flag/2", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleDeleteFlagDefinition(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/flag/2", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleDeleteFlagDefinition(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, "Expected deleting an undefined flag to fail")
+}
+
+func (suite *APIFlagServerTestSuite) TestCardFlagHandlers() {
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content", ""))
+	suite.flag_db.CreateTable()
+	suite.flag_db.Set(context.Background(), model.FlagDefinition{Flag: 1, Name: "Red", Color: "#e53935"})
+
+	testCases := []struct {
+		name           string
+		path           string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Card doesn't exist)",
+			path:           "/card/9/flag",
+			requestBody:    "{\"flag\":1}",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidCardIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Missing flag)",
+			path:           "/card/0/flag",
+			requestBody:    "{}",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Flag out of range)",
+			path:           "/card/0/flag",
+			requestBody:    "{\"flag\":10}",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidFlagErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Undefined flag name)",
+			path:           "/card/0/flag",
+			requestBody:    "{\"flag\":\"blue\"}",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidFlagErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request (By value)",
+			path:           "/card/0/flag",
+			requestBody:    "{\"flag\":3}",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"flag\":3,\"id\":0}\n",
+		},
+		{
+			name:           "Valid request (By name)",
+			path:           "/card/0/flag",
+			requestBody:    "{\"flag\":\"red\"}",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"flag\":1,\"id\":0}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			suite.server.HandleSetCardFlag(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), 1, card.Flag)
+
+	req := httptest.NewRequest(http.MethodDelete, "/card/0/flag", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleClearCardFlag(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	card, _ = suite.card_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), model.CardMinFlag, card.Flag, "Expected flag to be cleared")
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HandleGetJob handles the HTTP GET request for retrieving a background job,
+// whose status, progress and total tell how far it got.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the job ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the job ID is invalid or the job is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the job is found and the request is successful.
+func (s *APIServer) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	jobID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid job ID %s", idStr))
+		http.Error(w, InvalidJobIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	if s.job_runner == nil {
+		slog.ErrorContext(r.Context(), "No job runner configured")
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	job, dbErr := s.job_runner.Get(r.Context(), jobID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Job not found", "error", dbErr)
+			http.Error(w, InvalidJobIDErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting job", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(job)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding job", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "job", job.ID)
+}
+
+// HandleCancelJob handles the HTTP POST request for cancelling a background job.
+// A queued job is cancelled right away, while a running job is flagged and stops
+// after its current step, so its status must be followed with HandleGetJob.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the job ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the job ID is invalid, the job is not found or it already finished.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the cancellation is requested and the request is successful.
+func (s *APIServer) HandleCancelJob(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	jobID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid job ID %s", idStr))
+		http.Error(w, InvalidJobIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	if s.job_runner == nil {
+		slog.ErrorContext(r.Context(), "No job runner configured")
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	job, dbErr := s.job_runner.Cancel(r.Context(), jobID)
+	if dbErr != nil {
+		switch dbErr {
+		case utils.ErrRecordNotExist:
+			slog.DebugContext(r.Context(), "Job not found", "error", dbErr)
+			http.Error(w, InvalidJobIDErrorMessage, http.StatusBadRequest)
+		case utils.ErrJobFinished:
+			slog.DebugContext(r.Context(), "Job already finished", "error", dbErr)
+			http.Error(w, JobFinishedErrorMessage, http.StatusBadRequest)
+		default:
+			slog.DebugContext(r.Context(), "Error cancelling job", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(job)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding job", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "job", job.ID)
+}
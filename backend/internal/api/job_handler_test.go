@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIJobServerTestSuite struct {
+	suite.Suite
+	job_runner *jobs.Runner
+	server     *APIServer
+}
+
+func (suite *APIJobServerTestSuite) SetupTest() {
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	suite.job_runner = jobs.NewRunner(job_db, 1)
+	suite.job_runner.Register("test", func(ctx context.Context, run *jobs.Run) error {
+		return run.Progress(context.Background(), 2, 2, nil)
+	})
+
+	deck_db := database.NewDeckDBWrapperMock()
+	card_db := database.NewCardDBWrapperMock()
+	suite.server = NewAPIServer("localhost:8080", deck_db, card_db).WithJobRunner(suite.job_runner)
+
+	suite.job_runner.Enqueue(context.Background(), "test", struct{}{})
+	suite.job_runner.Enqueue(context.Background(), "test", struct{}{})
+	suite.job_runner.RunOnce(context.Background())
+}
+
+func (suite *APIJobServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPIJobServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APIJobServerTestSuite))
+}
+
+func (suite *APIJobServerTestSuite) TestGetJobHandler() {
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Invalid job ID)",
+			path:           "/jobs/abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidJobIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Job not found)",
+			path:           "/jobs/5",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidJobIDErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleGetJob(rr, req)
+
+			assert.Equal(suite.T(), tc.expectedStatus, rr.Code)
+			assert.Equal(suite.T(), tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/0", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleGetJob(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var job model.Job
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &job))
+	suite.Equal(model.JobStatusSucceeded, job.Status)
+	suite.Equal(2, job.Progress)
+	suite.Equal(2, job.Total)
+	suite.NotContains(rr.Body.String(), "lease", "Expected the lease to stay internal")
+}
+
+func (suite *APIJobServerTestSuite) TestCancelJobHandler() {
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Invalid job ID)",
+			path:           "/jobs/abc/cancel",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidJobIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Job not found)",
+			path:           "/jobs/5/cancel",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidJobIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Job finished)",
+			path:           "/jobs/0/cancel",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   JobFinishedErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleCancelJob(rr, req)
+
+			assert.Equal(suite.T(), tc.expectedStatus, rr.Code)
+			assert.Equal(suite.T(), tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/1/cancel", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleCancelJob(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var job model.Job
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &job))
+	suite.Equal(model.JobStatusCancelled, job.Status)
+	suite.True(job.CancelRequested)
+	suite.False(suite.job_runner.RunOnce(context.Background()), "Expected the cancelled job not to run")
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"flash-learn/internal/metrics"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Kinds of decks counted by the created decks metric.
+const (
+	deckKindNormal   = "normal"
+	deckKindFiltered = "filtered"
+)
+
+// apiMetrics are the metrics kept by the API server: the requests it answers
+// and what they did to the collection.
+type apiMetrics struct {
+	requests        *metrics.CounterVec
+	requestDuration *metrics.HistogramVec
+	reviews         *metrics.CounterVec
+	cardsCreated    *metrics.CounterVec
+	decksCreated    *metrics.CounterVec
+	sessionsStarted *metrics.CounterVec
+}
+
+// newAPIMetrics registers the metrics of the API server.
+//
+// Parameters:
+//   - registry *metrics.Registry : The registry the metrics are added to.
+//
+// Returns:
+//   - *apiMetrics
+func newAPIMetrics(registry *metrics.Registry) *apiMetrics {
+	return &apiMetrics{
+		requests: registry.Counter("flashlearn_http_requests_total",
+			"Number of HTTP requests answered, by route pattern and status.", "method", "route", "status"),
+		requestDuration: registry.Histogram("flashlearn_http_request_duration_seconds",
+			"Time taken to answer HTTP requests, by route pattern.", metrics.DefaultDurationBuckets, "method", "route"),
+		reviews: registry.Counter("flashlearn_reviews_total",
+			"Number of card reviews submitted, by study mode and grade.", "mode", "grade"),
+		cardsCreated: registry.Counter("flashlearn_cards_created_total",
+			"Number of cards created."),
+		decksCreated: registry.Counter("flashlearn_decks_created_total",
+			"Number of decks created, by kind.", "kind"),
+		sessionsStarted: registry.Counter("flashlearn_study_sessions_started_total",
+			"Number of study sessions started, by study mode.", "mode"),
+	}
+}
+
+// observeRequest records an answered request.
+//
+// Parameters:
+//   - method string : The method of the request.
+//   - route string : The pattern of the route of the request, or unmatchedRoute.
+//   - status int : The status of the response.
+//   - duration time.Duration : How long the request took.
+func (m *apiMetrics) observeRequest(method string, route string, status int, duration time.Duration) {
+	m.requests.Inc(method, route, strconv.Itoa(status))
+	m.requestDuration.Observe(duration.Seconds(), method, route)
+}
+
+// HandleGetMetrics handles the HTTP GET request for the metrics of the
+// server, in the Prometheus text format.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 200 OK : With the metrics, leaving out those that couldn't be collected.
+func (s *APIServer) HandleGetMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	if err := s.registry.WriteText(w); err != nil {
+		slog.DebugContext(r.Context(), "Error writing metrics", "error", err)
+		return
+	}
+	slog.DebugContext(r.Context(), "Sent metrics")
+}
+
+// metricsMiddleware counts the requests and their durations by route pattern,
+// so that the number of series doesn't grow with the IDs in the paths.
+func (s *APIServer) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newResponseRecorder(w)
+		next.ServeHTTP(recorder, r)
+
+		s.metrics.observeRequest(r.Method, requestInfoFrom(r.Context()).route(), recorder.statusCode(), time.Since(start))
+	})
+}
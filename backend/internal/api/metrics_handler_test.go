@@ -0,0 +1,49 @@
+package api
+
+import (
+	"flash-learn/internal/database"
+	"flash-learn/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMetricsHandler(t *testing.T) {
+	captureLogs(t)
+	deck_db := database.NewDeckDBWrapperMock()
+	deck_db.CreateTable()
+	registry := metrics.NewRegistry()
+	registry.GaugeFunc("test_gauge", "A gauge registered outside the server.", nil, func() ([]metrics.Sample, error) {
+		return []metrics.Sample{{Value: 7}}, nil
+	})
+	server := NewAPIServer("localhost:8080", deck_db, database.NewCardDBWrapperMock()).WithMetrics(registry)
+	handler := server.handler()
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/deck", strings.NewReader(`{"name": "Deck", "description": ""}`)),
+		httptest.NewRequest(http.MethodGet, "/deck/abc", nil),
+		httptest.NewRequest(http.MethodGet, "/deck/def", nil),
+		httptest.NewRequest(http.MethodGet, "/nowhere", nil),
+	}
+	for _, req := range requests {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, metrics.ContentType, rr.Header().Get("Content-Type"))
+	body := rr.Body.String()
+	assert.Contains(t, body, "# TYPE flashlearn_http_requests_total counter\n")
+	assert.Contains(t, body, `flashlearn_http_requests_total{method="POST",route="POST /deck",status="200"} 1`+"\n")
+	assert.Contains(t, body, `flashlearn_http_requests_total{method="GET",route="GET /deck/{id}",status="400"} 2`+"\n",
+		"Expected the requests to be grouped by route pattern")
+	assert.Contains(t, body, `flashlearn_http_requests_total{method="GET",route="unmatched",status="404"} 1`+"\n")
+	assert.Contains(t, body, `flashlearn_http_request_duration_seconds_count{method="GET",route="GET /deck/{id}"} 2`+"\n")
+	assert.Contains(t, body, `flashlearn_decks_created_total{kind="normal"} 1`+"\n")
+	assert.Contains(t, body, "test_gauge 7\n", "Expected the metrics of the shared registry to be served")
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flash-learn/internal/config"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	// RequestIDHeader is the header carrying the ID of a request, taken from
+	// the request when the client sends a valid one and sent in the response.
+	RequestIDHeader = "X-Request-ID"
+	// requestIDMaxLength is the maximum length of a request ID sent by a client.
+	requestIDMaxLength = 128
+	// unmatchedRoute is the route logged for the requests no route matches.
+	unmatchedRoute = "unmatched"
+	// routeTimeoutGrace is added to the timeout of a route when extending the
+	// write deadline of its connection, so that the timeout response can be sent.
+	routeTimeoutGrace = 5 * time.Second
+)
+
+// middleware wraps a handler with a behaviour shared by every request.
+type middleware func(http.Handler) http.Handler
+
+// chain wraps a handler with middlewares, the first one being the outermost.
+//
+// Parameters:
+//   - handler http.Handler : The handler called last.
+//   - middlewares ...middleware : The middlewares, in the order they see the request.
+//
+// Returns:
+//   - http.Handler
+func chain(handler http.Handler, middlewares ...middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// requestInfo is what the middlewares know about a request, shared through
+// its context.
+type requestInfo struct {
+	id      string
+	pattern string
+}
+
+type requestInfoKey struct{}
+
+// requestInfoFrom returns the information stored in a context by
+// requestInfoMiddleware, empty if there is none.
+func requestInfoFrom(ctx context.Context) requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(requestInfo)
+	return info
+}
+
+// route returns the pattern of the route matching the request, or
+// unmatchedRoute if none does, so that logs don't grow with every path.
+func (info requestInfo) route() string {
+	if info.pattern == "" {
+		return unmatchedRoute
+	}
+	return info.pattern
+}
+
+// requestInfoMiddleware gives every request an ID and finds the pattern of
+// the route matching it. The ID is the one sent by the client in
+// RequestIDHeader if it is valid, a random one otherwise, and it is sent back
+// in the response and added to every line logged with the context of the
+// request, which are the lines of the middlewares, the handlers and the
+// database wrappers they call.
+//
+// Parameters:
+//   - router *http.ServeMux : The router, used to find the route of a request.
+//
+// Returns:
+//   - middleware
+func requestInfoMiddleware(router *http.ServeMux) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			_, pattern := router.Handler(r)
+			ctx := context.WithValue(r.Context(), requestInfoKey{}, requestInfo{id: id, pattern: pattern})
+			ctx = utils.WithLogAttrs(ctx, slog.String("request_id", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// accessLogMiddleware logs a line for every request once it is answered, with
+// its method, route, status, latency and the size of its response body.
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newResponseRecorder(w)
+		next.ServeHTTP(recorder, r)
+
+		slog.InfoContext(r.Context(), "Request",
+			"method", r.Method,
+			"route", requestInfoFrom(r.Context()).route(),
+			"path", r.URL.Path,
+			"status", recorder.statusCode(),
+			"latency", time.Since(start),
+			"bytes", recorder.bytes,
+		)
+	})
+}
+
+// recoverMiddleware turns a panic in a handler into a 500 response, or into
+// a closed connection if the response was already started. A panic with
+// http.ErrAbortHandler is let through since it is meant to abort the response.
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := newResponseRecorder(w)
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			slog.ErrorContext(r.Context(), "Panic while handling request", "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+			if recorder.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// routeLimits are the limits enforced on the requests of a route, zero
+// meaning no limit.
+type routeLimits struct {
+	timeout      time.Duration
+	maxBodyBytes int64
+}
+
+// limitsFor returns the limits of a route: the limits of the server,
+// overridden by the built-in limits of the route, overridden by its settings.
+//
+// Parameters:
+//   - pattern string : The pattern of the route, empty for unmatched requests.
+//
+// Returns:
+//   - routeLimits
+func (s *APIServer) limitsFor(pattern string) routeLimits {
+	limits := routeLimits{timeout: s.settings.RequestTimeout, maxBodyBytes: s.settings.MaxBodyBytes}
+	for _, override := range []config.RouteConfig{defaultRouteLimits[pattern], s.settings.Routes[pattern]} {
+		if override.Timeout > 0 {
+			limits.timeout = override.Timeout
+		}
+		if override.MaxBodyBytes > 0 {
+			limits.maxBodyBytes = override.MaxBodyBytes
+		}
+	}
+	return limits
+}
+
+// limitsMiddleware enforces the timeout and the maximum body size of the
+// route of a request. A body declared larger than the maximum is answered
+// with a 413, and one turning out larger fails to be read. A request taking
+// longer than its timeout is answered with a 503 and its context is cancelled,
+// which the handler may not notice before it finishes.
+func (s *APIServer) limitsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limits := s.limitsFor(requestInfoFrom(r.Context()).pattern)
+
+		if limits.maxBodyBytes > 0 {
+			if r.ContentLength > limits.maxBodyBytes {
+				slog.DebugContext(r.Context(), "Request body too large", "length", r.ContentLength, "limit", limits.maxBodyBytes)
+				http.Error(w, RequestTooLargeErrorMessage, http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limits.maxBodyBytes)
+		}
+
+		if limits.timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.settings.WriteTimeout > 0 && limits.timeout >= s.settings.WriteTimeout {
+			// The connection would be cut before the route times out
+			deadline := time.Now().Add(limits.timeout + routeTimeoutGrace)
+			if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+				slog.DebugContext(r.Context(), "Error extending write deadline", "error", err)
+			}
+		}
+		http.TimeoutHandler(next, limits.timeout, RequestTimeoutErrorMessage+"\n").ServeHTTP(w, r)
+	})
+}
+
+// responseRecorder is a http.ResponseWriter recording the status and the
+// size of the response it writes.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// statusCode returns the status of the response, 200 if nothing was written.
+func (rec *responseRecorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// validRequestID reports whether a request ID sent by a client can be used
+// as is: it must be short and made of letters, digits and "-_.:", so that it
+// can't forge log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > requestIDMaxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID of 32 hexadecimal characters.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
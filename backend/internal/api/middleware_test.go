@@ -0,0 +1,310 @@
+package api
+
+import (
+	"bytes"
+	"flash-learn/internal/config"
+	"flash-learn/internal/database"
+	"flash-learn/internal/utils"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureLogs makes the default logger write to the returned buffer until
+// the test ends.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	logs := &bytes.Buffer{}
+	previous := slog.Default()
+	slog.SetDefault(utils.NewLogger(logs, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return logs
+}
+
+// newMiddlewareTestServer returns a server and a handler with every
+// middleware around a router with test routes.
+func newMiddlewareTestServer() (*APIServer, http.Handler) {
+	server := NewAPIServer("localhost:8080", database.NewDeckDBWrapperMock(), database.NewCardDBWrapperMock())
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /hello/{name}", func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "Saying hello")
+		w.Write([]byte("hello " + r.PathValue("name")))
+	})
+	router.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	router.HandleFunc("POST /echo", func(w http.ResponseWriter, r *http.Request) {
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(r.Body); err != nil {
+			http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+			return
+		}
+		w.Write(body.Bytes())
+	})
+	router.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	handler := chain(router,
+		requestInfoMiddleware(router),
+		corsMiddleware(router, corsPolicy{origins: []string{"https://app.example.com"}}),
+		accessLogMiddleware,
+		recoverMiddleware,
+		server.limitsMiddleware,
+	)
+	return server, handler
+}
+
+func TestChain(t *testing.T) {
+	order := []string{}
+	named := func(name string) middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), named("first"), named("second"))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, []string{"first", "second", "handler"}, order)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	logs := captureLogs(t)
+	_, handler := newMiddlewareTestServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/hello/world", nil)
+	req.Header.Set(RequestIDHeader, "client-id.42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "client-id.42", rr.Header().Get(RequestIDHeader))
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Contains(t, line, "request_id=client-id.42", "Expected every line logged with the request's context to carry the request ID")
+	}
+
+	generated := regexp.MustCompile("^[0-9a-f]{32}$")
+	for _, id := range []string{"", "has spaces", "new\nline", strings.Repeat("a", requestIDMaxLength+1)} {
+		req = httptest.NewRequest(http.MethodGet, "/hello/world", nil)
+		req.Header.Set(RequestIDHeader, id)
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Regexp(t, generated, rr.Header().Get(RequestIDHeader), "Expected %q to be replaced", id)
+	}
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	logs := captureLogs(t)
+	_, handler := newMiddlewareTestServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/hello/world", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := strings.Split(strings.TrimSpace(logs.String()), "\n")[1]
+	assert.Contains(t, line, "msg=Request")
+	assert.Contains(t, line, "method=GET")
+	assert.Contains(t, line, `route="GET /hello/{name}"`)
+	assert.Contains(t, line, "path=/hello/world")
+	assert.Contains(t, line, "status=200")
+	assert.Contains(t, line, "latency=")
+	assert.Contains(t, line, "bytes=11")
+
+	logs.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere/42", nil))
+	assert.Contains(t, logs.String(), "route="+unmatchedRoute)
+	assert.Contains(t, logs.String(), "status=404")
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	logs := captureLogs(t)
+	_, handler := newMiddlewareTestServer()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, InternalServerErrorMessage+"\n", rr.Body.String())
+	assert.Contains(t, logs.String(), "panic=boom")
+	assert.Contains(t, logs.String(), "status=500", "Expected the access log to see the recovered status")
+
+	aborting := recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		aborting.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestLimitsMiddleware(t *testing.T) {
+	captureLogs(t)
+	server, handler := newMiddlewareTestServer()
+	server.settings.MaxBodyBytes = 8
+	server.settings.RequestTimeout = 10 * time.Millisecond
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		chunked        bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Body Within Limit",
+			method:         http.MethodPost,
+			path:           "/echo",
+			body:           "12345678",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "12345678",
+		},
+		{
+			name:           "Declared Body Too Large",
+			method:         http.MethodPost,
+			path:           "/echo",
+			body:           "123456789",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   RequestTooLargeErrorMessage + "\n",
+		},
+		{
+			name:           "Streamed Body Too Large",
+			method:         http.MethodPost,
+			path:           "/echo",
+			body:           "123456789",
+			chunked:        true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Timeout",
+			method:         http.MethodGet,
+			path:           "/slow",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   RequestTimeoutErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	server.settings.Routes = map[string]config.RouteConfig{"POST /echo": {MaxBodyBytes: 16}}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("123456789")))
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected the route setting to raise the limit")
+}
+
+func TestLimitsFor(t *testing.T) {
+	server := NewAPIServer("localhost:8080", database.NewDeckDBWrapperMock(), database.NewCardDBWrapperMock())
+	server.settings.RequestTimeout = 30 * time.Second
+	server.settings.MaxBodyBytes = 1 << 20
+	server.settings.Routes = map[string]config.RouteConfig{
+		"POST /card/bulk":  {Timeout: 5 * time.Minute},
+		"GET /deck/{id}":   {MaxBodyBytes: 1},
+		"GET /not/a/route": {},
+	}
+
+	assert.Equal(t, routeLimits{timeout: 30 * time.Second, maxBodyBytes: 1 << 20}, server.limitsFor(""))
+	assert.Equal(t, routeLimits{timeout: 30 * time.Second, maxBodyBytes: 1 << 20}, server.limitsFor("POST /deck/{id}/optimize"),
+		"Expected a route without limits to get the server's")
+	assert.Equal(t, routeLimits{timeout: 5 * time.Minute, maxBodyBytes: 8 << 20}, server.limitsFor("POST /card/bulk"),
+		"Expected the setting to override only the limit it sets")
+	assert.Equal(t, routeLimits{timeout: 30 * time.Second, maxBodyBytes: 1}, server.limitsFor("GET /deck/{id}"))
+}
+
+func TestHandlerMiddlewares(t *testing.T) {
+	captureLogs(t)
+	deck_db := database.NewDeckDBWrapperMock()
+	deck_db.CreateTable()
+	server := NewAPIServer("localhost:8080", deck_db, database.NewCardDBWrapperMock())
+
+	req := httptest.NewRequest(http.MethodGet, "/deck/count", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	rr := httptest.NewRecorder()
+	server.handler().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get(RequestIDHeader))
+	assert.Equal(t, RequestIDHeader, rr.Header().Get("Access-Control-Expose-Headers"))
+
+	server.settings.Routes = map[string]config.RouteConfig{"POST /deck": {MaxBodyBytes: 1}}
+	req = httptest.NewRequest(http.MethodPost, "/deck", strings.NewReader(`{"name": "Deck"}`))
+	req.Header.Set("Origin", "http://localhost:5173")
+	rr = httptest.NewRecorder()
+	server.handler().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, "http://localhost:5173", rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSOnMiddlewareResponses(t *testing.T) {
+	captureLogs(t)
+	server, handler := newMiddlewareTestServer()
+	server.settings.MaxBodyBytes = 8
+	server.settings.RequestTimeout = 10 * time.Millisecond
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "Body Too Large",
+			method:         http.MethodPost,
+			path:           "/echo",
+			body:           "123456789",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "Panic",
+			method:         http.MethodGet,
+			path:           "/panic",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "Timeout",
+			method:         http.MethodGet,
+			path:           "/slow",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Origin", "https://app.example.com")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"),
+				"Expected the browser to be allowed to read the error")
+		})
+	}
+}
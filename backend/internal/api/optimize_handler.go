@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HandleOptimizeDeck handles the HTTP POST request for fitting the parameters
+// of a scheduler to the review log of a deck and its sub-decks. The body may
+// name the scheduler to fit, the one of the deck's settings by default, for
+// example {"scheduler": "fsrs"}. Fitting goes through the whole log, so it is
+// done in the background: the response holds the job, whose result is the
+// fitted parameters along with the log-loss of the log before and after
+// fitting once it succeeds. The parameters are applied with HandleApplyOptimizeJob.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID or request body is invalid, or the deck is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the job is enqueued and the request is successful.
+func (s *APIServer) HandleOptimizeDeck(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	type OptimizeInput struct {
+		Scheduler string `json:"scheduler"`
+	}
+	var bodyInput OptimizeInput
+	err = json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	deck, ok := s.getOptimizedDeck(w, r, deckID)
+	if !ok {
+		return
+	}
+
+	scheduler := deck.Settings().Scheduler
+	if bodyInput.Scheduler != "" {
+		scheduler = bodyInput.Scheduler
+	}
+	if scheduler != model.SchedulerSM2 && scheduler != model.SchedulerFSRS {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid scheduler %s", scheduler))
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	if s.job_runner == nil {
+		slog.ErrorContext(r.Context(), "No job runner configured")
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	job, err := s.job_runner.Enqueue(r.Context(), model.JobKindOptimize, model.OptimizePayload{DeckID: deckID, Scheduler: scheduler})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error enqueuing optimize job", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(job)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding optimize job", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "job", job.ID)
+}
+
+// HandleApplyOptimizeJob handles the HTTP POST request for applying the
+// parameters fitted by an optimize job to the preset of its deck. The cards
+// of the decks of the preset are then rescheduled by the job RescheduleJobID
+// of the response, if any. A job is applied only once, and only while the
+// deck is linked to the preset it was fitted from and the scheduling
+// settings of the preset are unchanged since the job started.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the job ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the job ID is invalid, the job is not found or isn't an optimize job
+//     that succeeded, or its deck is not found or isn't linked to a preset.
+//   - 409 Conflict : If the job was already applied, or the preset changed since the job started.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the applied result, if the request is successful.
+func (s *APIServer) HandleApplyOptimizeJob(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	jobID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid job ID %s", idStr))
+		http.Error(w, InvalidJobIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	if s.job_runner == nil {
+		slog.ErrorContext(r.Context(), "No job runner configured")
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	job, dbErr := s.job_runner.Get(r.Context(), jobID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Job not found", "error", dbErr)
+			http.Error(w, InvalidJobIDErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting job", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var payload model.OptimizePayload
+	var result model.OptimizeResult
+	if job.Kind != model.JobKindOptimize || job.Status != model.JobStatusSucceeded ||
+		json.Unmarshal(job.Payload, &payload) != nil || json.Unmarshal(job.Result, &result) != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Job %d has no fitted parameters", jobID), "kind", job.Kind, "status", job.Status)
+		http.Error(w, NoOptimizeResultErrorMessage, http.StatusBadRequest)
+		return
+	}
+	if job.AppliedAt != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Job %d already applied", jobID))
+		http.Error(w, JobAppliedErrorMessage, http.StatusConflict)
+		return
+	}
+
+	deck, ok := s.getOptimizedDeck(w, r, payload.DeckID)
+	if !ok {
+		return
+	}
+	if deck.Preset == nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Deck %d isn't linked to a preset", deck.ID))
+		http.Error(w, NoDeckPresetErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	if result.Preset == nil || result.Preset.ID != deck.Preset.ID {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Deck %d was linked to another preset since job %d started", deck.ID, jobID))
+		http.Error(w, PresetChangedErrorMessage, http.StatusConflict)
+		return
+	}
+
+	// The preset is only modified if it is unchanged, so when the job is
+	// applied twice at once, the second request finds the preset changed by
+	// the first one
+	applied := result.Apply(*deck.Preset)
+	dbErr = s.preset_db.ModifyIfUnchanged(r.Context(), *result.Preset, applied)
+	if dbErr == utils.ErrPresetChanged {
+		slog.DebugContext(r.Context(), "Deck preset changed", "error", dbErr)
+		http.Error(w, PresetChangedErrorMessage, http.StatusConflict)
+		return
+	} else if dbErr != nil {
+		writePresetError(w, r, dbErr)
+		return
+	}
+
+	dbErr = s.job_runner.MarkApplied(r.Context(), jobID)
+	if dbErr == utils.ErrJobApplied {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Job %d already applied", jobID))
+		http.Error(w, JobAppliedErrorMessage, http.StatusConflict)
+		return
+	} else if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error marking job as applied", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	result.Applied = true
+	result.RescheduleJobID = s.reschedulePreset(r.Context(), *deck.Preset, applied)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding optimize result", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "optimize result", result)
+}
+
+// getOptimizedDeck retrieves the deck whose scheduler is optimized, writing
+// a 400 Bad Request response if it doesn't exist.
+//
+// Returns:
+//   - model.Deck : The deck.
+//   - bool : False if the response has already been written.
+func (s *APIServer) getOptimizedDeck(w http.ResponseWriter, r *http.Request, deckID int) (model.Deck, bool) {
+	deck, dbErr := s.deck_db.GetSingle(r.Context(), deckID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting single deck", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return model.Deck{}, false
+	}
+
+	return deck, true
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/maintenance"
+	"flash-learn/internal/model"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIOptimizeServerTestSuite struct {
+	suite.Suite
+	deck_db    *database.DeckDBWrapperMock
+	card_db    *database.CardDBWrapperMock
+	preset_db  *database.PresetDBWrapperMock
+	job_runner *jobs.Runner
+	server     *APIServer
+}
+
+func (suite *APIOptimizeServerTestSuite) SetupTest() {
+	suite.deck_db = database.NewDeckDBWrapperMock()
+	suite.card_db = database.NewCardDBWrapperMock()
+	suite.preset_db = database.NewPresetDBWrapperMock()
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	suite.job_runner = jobs.NewRunner(job_db, 1)
+	suite.job_runner.Register(model.JobKindOptimize, maintenance.NewOptimizer(suite.deck_db, suite.card_db).Handle)
+	suite.job_runner.Register(model.JobKindReschedule, maintenance.NewRescheduler(suite.deck_db, suite.card_db, maintenance.RescheduleBatchSize).Handle)
+	suite.server = NewAPIServer("localhost:8080", suite.deck_db, suite.card_db).
+		WithPresetDB(suite.preset_db).
+		WithJobRunner(suite.job_runner)
+
+	suite.preset_db.CreateTable()
+	preset := model.DefaultDeckPreset()
+	preset.Name = "Exam"
+	presetID, _ := suite.preset_db.Insert(context.Background(), preset)
+	preset.ID = presetID
+
+	suite.deck_db.CreateTable()
+	suite.deck_db.Insert(context.Background(), model.Deck{Name: "Linked deck", PresetID: &presetID, Preset: &preset})
+	suite.deck_db.Insert(context.Background(), model.Deck{Name: "Default deck"})
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+	suite.card_db.InsertDeck(1)
+}
+
+func (suite *APIOptimizeServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPIOptimizeServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APIOptimizeServerTestSuite))
+}
+
+// recordReviews logs a learning answer and then reviews every ten days,
+// all remembered, for a number of cards of the deck.
+func (suite *APIOptimizeServerTestSuite) recordReviews(deckID int, cards int) {
+	start := time.Now().AddDate(0, 0, -100)
+	for i := 0; i < cards; i++ {
+		card := model.NewCard(deckID, "Test content", "")
+		card.ID, _ = suite.card_db.Insert(context.Background(), card)
+
+		reviewTime := start
+		suite.card_db.RecordReview(context.Background(), model.NewCardAnswer(card, card), model.Review{
+			CardID: card.ID, Grade: model.GradeGood, Kind: model.ReviewKindLearn,
+			State: model.CardStateNew, Interval: 10, ReviewTime: reviewTime,
+		})
+		for j := 0; j < 3; j++ {
+			reviewTime = reviewTime.AddDate(0, 0, 10)
+			suite.card_db.RecordReview(context.Background(), model.NewCardAnswer(card, card), model.Review{
+				CardID: card.ID, Grade: model.GradeGood, Kind: model.ReviewKindReview,
+				State: model.CardStateReview, Interval: 10, LastInterval: 10, ReviewTime: reviewTime,
+			})
+		}
+	}
+}
+
+// optimize enqueues an optimize job of the deck and runs it.
+func (suite *APIOptimizeServerTestSuite) optimize(deckID int, requestBody string) model.Job {
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/deck/%d/optimize", deckID), strings.NewReader(requestBody))
+	rr := httptest.NewRecorder()
+	suite.server.HandleOptimizeDeck(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var job model.Job
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &job))
+	suite.Equal(model.JobStatusQueued, job.Status)
+	suite.Equal(model.JobKindOptimize, job.Kind)
+
+	suite.True(suite.job_runner.RunOnce(context.Background()))
+	job, err := suite.job_runner.Get(context.Background(), job.ID)
+	suite.Nil(err)
+	return job
+}
+
+func (suite *APIOptimizeServerTestSuite) TestOptimizeDeckHandler() {
+	testCases := []struct {
+		name           string
+		path           string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Invalid deck ID)",
+			path:           "/deck/abc/optimize",
+			requestBody:    `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidDeckIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Empty request body)",
+			path:           "/deck/0/optimize",
+			requestBody:    "",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Deck not found)",
+			path:           "/deck/99/optimize",
+			requestBody:    `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   GetSingleDeckNotFoundErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Unknown scheduler)",
+			path:           "/deck/0/optimize",
+			requestBody:    `{"scheduler": "leitner"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			suite.server.HandleOptimizeDeck(rr, req)
+
+			assert.Equal(suite.T(), tc.expectedStatus, rr.Code)
+			assert.Equal(suite.T(), tc.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func (suite *APIOptimizeServerTestSuite) TestOptimizeDeckInBackground() {
+	suite.recordReviews(0, 8)
+
+	job := suite.optimize(0, `{"scheduler": "fsrs"}`)
+	suite.Equal(model.JobStatusSucceeded, job.Status)
+	suite.JSONEq(`{"deck_id":0,"scheduler":"fsrs"}`, string(job.Payload))
+
+	var result model.OptimizeResult
+	suite.Nil(json.Unmarshal(job.Result, &result))
+	suite.Equal(model.SchedulerFSRS, result.Scheduler)
+	suite.Equal(24, result.Reviews)
+	suite.Equal(model.FSRSWeightCount, len(result.FSRSWeights))
+	suite.Less(result.LogLossAfter, result.LogLossBefore)
+	suite.False(result.Applied)
+
+	preset, _ := suite.preset_db.GetSingle(context.Background(), 0)
+	suite.Empty(preset.FSRSWeights, "Expected the preset to be left untouched until the result is applied")
+}
+
+func (suite *APIOptimizeServerTestSuite) TestOptimizeDeckWithoutEnoughReviews() {
+	job := suite.optimize(1, `{}`)
+	suite.Equal(model.JobStatusFailed, job.Status, "Expected the job to fail without being retried")
+	suite.Equal(1, job.Attempts)
+	suite.NotEmpty(job.LastError)
+	suite.Empty(job.Result)
+}
+
+func (suite *APIOptimizeServerTestSuite) TestApplyOptimizeJobHandler() {
+	suite.recordReviews(1, 8)
+	suite.Equal(model.JobStatusSucceeded, suite.optimize(1, `{}`).Status)
+	suite.job_runner.Enqueue(context.Background(), model.JobKindOptimize, model.OptimizePayload{DeckID: 0})
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Invalid job ID)",
+			path:           "/jobs/abc/apply",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidJobIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Job not found)",
+			path:           "/jobs/99/apply",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidJobIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Deck without preset)",
+			path:           "/jobs/0/apply",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   NoDeckPresetErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Job not run yet)",
+			path:           "/jobs/1/apply",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   NoOptimizeResultErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleApplyOptimizeJob(rr, req)
+
+			assert.Equal(suite.T(), tc.expectedStatus, rr.Code)
+			assert.Equal(suite.T(), tc.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func (suite *APIOptimizeServerTestSuite) TestApplyOptimizeJob() {
+	suite.recordReviews(0, 8)
+	job := suite.optimize(0, `{}`)
+	suite.Equal(model.JobStatusSucceeded, job.Status)
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/jobs/%d/apply", job.ID), nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleApplyOptimizeJob(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var result model.OptimizeResult
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &result))
+	suite.Equal(model.SchedulerSM2, result.Scheduler)
+	suite.Greater(result.EaseModifier, 1.0)
+	suite.True(result.Applied)
+
+	preset, _ := suite.preset_db.GetSingle(context.Background(), 0)
+	suite.Equal(result.EaseModifier, preset.EaseModifier)
+	suite.Equal("Exam", preset.Name)
+	suite.NotNil(result.RescheduleJobID, "Expected the cards of the preset to be rescheduled")
+
+	rr = httptest.NewRecorder()
+	suite.server.HandleApplyOptimizeJob(rr, req)
+	suite.Equal(http.StatusConflict, rr.Code, "Expected a job to be applied only once")
+	suite.Equal(JobAppliedErrorMessage+"\n", rr.Body.String())
+	job, _ = suite.job_runner.Get(context.Background(), job.ID)
+	suite.NotNil(job.AppliedAt)
+}
+
+func (suite *APIOptimizeServerTestSuite) TestApplyOptimizeJobToChangedPreset() {
+	suite.recordReviews(0, 8)
+	job := suite.optimize(0, `{}`)
+	suite.Equal(model.JobStatusSucceeded, job.Status)
+
+	changed, _ := suite.preset_db.GetSingle(context.Background(), 0)
+	changed.DesiredRetention = 0.8
+	suite.Nil(suite.preset_db.Modify(context.Background(), changed))
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/jobs/%d/apply", job.ID), nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleApplyOptimizeJob(rr, req)
+	suite.Equal(http.StatusConflict, rr.Code)
+	suite.Equal(PresetChangedErrorMessage+"\n", rr.Body.String())
+
+	preset, _ := suite.preset_db.GetSingle(context.Background(), 0)
+	suite.Equal(changed, preset, "Expected the changed preset to be left untouched")
+	job, _ = suite.job_runner.Get(context.Background(), job.ID)
+	suite.Nil(job.AppliedAt)
+}
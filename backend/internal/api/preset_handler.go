@@ -0,0 +1,368 @@
+package api
+
+import (
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HandleGetAllPresets handles the HTTP GET request for retrieving all deck presets.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the presets are found and the request is successful.
+func (s *APIServer) HandleGetAllPresets(w http.ResponseWriter, r *http.Request) {
+	presets, err := s.preset_db.GetAll(r.Context())
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error getting all deck presets", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(presets)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck presets", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck presets", len(presets))
+}
+
+// HandleGetSinglePreset handles the HTTP GET request for retrieving a single deck preset.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the preset ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the preset ID is invalid or the preset is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the preset is found and the request is successful.
+func (s *APIServer) HandleGetSinglePreset(w http.ResponseWriter, r *http.Request) {
+	presetID, ok := parsePresetID(w, r)
+	if !ok {
+		return
+	}
+
+	preset, err := s.preset_db.GetSingle(r.Context(), presetID)
+	if err != nil {
+		writePresetError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(preset)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck preset", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck preset", preset)
+}
+
+// HandleInsertPreset handles the HTTP POST request for inserting a new deck preset.
+// Settings missing from the request body are taken from the default preset.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the preset in its body.
+//
+// Errors:
+//   - 400 Bad Request : If the request body is invalid or a setting is out of range.
+//   - 409 Conflict : If a preset with the same name exists.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the preset is inserted and the request is successful.
+func (s *APIServer) HandleInsertPreset(w http.ResponseWriter, r *http.Request) {
+	preset, ok := parsePresetBody(w, r)
+	if !ok {
+		return
+	}
+
+	presetID, err := s.preset_db.Insert(r.Context(), preset)
+	if err != nil {
+		writePresetError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int{"id": presetID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck preset ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	slog.DebugContext(r.Context(), "Sent response", "deck preset ID", presetID)
+}
+
+// HandleModifyPreset handles the HTTP POST request for modifying an existing deck preset.
+// Settings missing from the request body are reset to their default values. If the
+// scheduling settings changed, the cards of the linked decks are rescheduled in the
+// background and the response holds the ID of the reschedule job.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the preset ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the preset ID or request body is invalid, or the preset is not found.
+//   - 409 Conflict : If a preset with the same name exists.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the preset is modified and the request is successful.
+func (s *APIServer) HandleModifyPreset(w http.ResponseWriter, r *http.Request) {
+	presetID, ok := parsePresetID(w, r)
+	if !ok {
+		return
+	}
+
+	preset, ok := parsePresetBody(w, r)
+	if !ok {
+		return
+	}
+	preset.ID = presetID
+
+	before, err := s.preset_db.GetSingle(r.Context(), presetID)
+	if err != nil {
+		writePresetError(w, r, err)
+		return
+	}
+
+	err = s.preset_db.Modify(r.Context(), preset)
+	if err != nil {
+		writePresetError(w, r, err)
+		return
+	}
+
+	output := rescheduleOutput{ID: presetID, RescheduleJobID: s.reschedulePreset(r.Context(), before, preset)}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(output)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck preset ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck preset ID", presetID)
+}
+
+// HandleDeletePreset handles the HTTP DELETE request for deleting a deck preset.
+// Decks linked to the preset fall back to the default settings.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the preset ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the preset ID is invalid or the preset is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the preset is deleted and the request is successful.
+func (s *APIServer) HandleDeletePreset(w http.ResponseWriter, r *http.Request) {
+	presetID, ok := parsePresetID(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.preset_db.Delete(r.Context(), presetID)
+	if err != nil {
+		writePresetError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int{"id": presetID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck preset ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck preset ID", presetID)
+}
+
+// HandleSetDeckPreset handles the HTTP POST request for linking a deck to a preset.
+// A null preset ID unlinks the deck so it uses the default settings. If the
+// scheduling settings of the deck changed, its cards are rescheduled in the
+// background and the response holds the ID of the reschedule job.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID or request body is invalid, or the deck or preset is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the preset is linked and the request is successful.
+func (s *APIServer) HandleSetDeckPreset(w http.ResponseWriter, r *http.Request) {
+	// Parse ID from URL
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	type SetPresetInput struct {
+		PresetID *int `json:"preset_id"`
+	}
+	var bodyInput SetPresetInput
+	err = json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	before, dbErr := s.deck_db.GetSingle(r.Context(), deckID)
+	if dbErr == nil {
+		dbErr = s.deck_db.SetPreset(r.Context(), deckID, bodyInput.PresetID)
+	}
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Record not exist", "error", dbErr)
+			http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		} else {
+			writePresetError(w, r, dbErr)
+		}
+		return
+	}
+
+	output := rescheduleOutput{ID: deckID}
+	if after, dbErr := s.deck_db.GetSingle(r.Context(), deckID); dbErr != nil {
+		slog.ErrorContext(r.Context(), "Error getting deck settings", "error", dbErr)
+	} else if !before.Settings().SameScheduling(after.Settings()) {
+		output.RescheduleJobID = s.rescheduleDecks(r.Context(), []int{deckID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(output)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck ID", deckID)
+}
+
+// HandleGetDeckSettings handles the HTTP GET request for retrieving the effective
+// study settings of a deck.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID is invalid or deck is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the deck is found and the request is successful.
+func (s *APIServer) HandleGetDeckSettings(w http.ResponseWriter, r *http.Request) {
+	// Parse ID from URL
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	deck, dbErr := s.deck_db.GetSingle(r.Context(), deckID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting single deck", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	settings := deck.Settings()
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(settings)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deck settings", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deck settings", settings)
+}
+
+// parsePresetID parses the preset ID from the URL path and writes a
+// 400 Bad Request response if it isn't a number.
+//
+// Returns:
+//   - int : The preset ID.
+//   - bool : False if the response has already been written.
+func parsePresetID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	presetID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck preset ID %s", idStr))
+		http.Error(w, InvalidPresetIDErrorMessage, http.StatusBadRequest)
+		return 0, false
+	}
+
+	return presetID, true
+}
+
+// parsePresetBody decodes a preset from the request body on top of the default
+// settings and validates it, writing a 400 Bad Request response on failure.
+//
+// Returns:
+//   - model.DeckPreset : The decoded preset.
+//   - bool : False if the response has already been written.
+func parsePresetBody(w http.ResponseWriter, r *http.Request) (model.DeckPreset, bool) {
+	preset := model.DefaultDeckPreset()
+	preset.Name = ""
+
+	err := json.NewDecoder(r.Body).Decode(&preset)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return model.DeckPreset{}, false
+	}
+
+	preset.Name = strings.TrimSpace(preset.Name)
+	if err = preset.Validate(); err != nil {
+		slog.DebugContext(r.Context(), "Invalid deck preset", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return model.DeckPreset{}, false
+	}
+
+	return preset, true
+}
+
+// writePresetError writes the response matching an error returned by the preset database.
+func writePresetError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case utils.ErrPresetNotExist:
+		slog.DebugContext(r.Context(), "Deck preset not found", "error", err)
+		http.Error(w, InvalidPresetIDErrorMessage, http.StatusBadRequest)
+	case utils.ErrMaxLengthExceeded:
+		slog.DebugContext(r.Context(), "Max length exceeded", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+	case utils.ErrDuplicateKeyViolation:
+		slog.DebugContext(r.Context(), "Duplicate key violation", "error", err)
+		http.Error(w, DuplicateKeyViolationErrorMessage, http.StatusConflict)
+	default:
+		slog.DebugContext(r.Context(), "Error accessing deck presets", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIPresetServerTestSuite struct {
+	suite.Suite
+	address   string
+	deck_db   *database.DeckDBWrapperMock
+	preset_db *database.PresetDBWrapperMock
+	server    *APIServer
+}
+
+func (suite *APIPresetServerTestSuite) SetupTest() {
+	suite.address = "localhost:8080"
+	suite.deck_db = database.NewDeckDBWrapperMock()
+	suite.preset_db = database.NewPresetDBWrapperMock()
+	suite.server = NewAPIServer(suite.address, suite.deck_db, nil).WithPresetDB(suite.preset_db)
+}
+
+func (suite *APIPresetServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPIPresetServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APIPresetServerTestSuite))
+}
+
+func (suite *APIPresetServerTestSuite) TestWithPresetDB() {
+	assert.NotNil(suite.T(), suite.server.preset_db, "Expected preset db to be initialized")
+}
+
+func (suite *APIPresetServerTestSuite) TestInsertPresetHandler() {
+	suite.preset_db.CreateTable()
+
+	testCases := []struct {
+		name           string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Empty request body)",
+			requestBody:    "",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (No name)",
+			requestBody:    `{"new_cards_per_day": 10}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Unknown scheduler)",
+			requestBody:    `{"name": "Exam", "scheduler": "leitner"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Decreasing learning steps)",
+			requestBody:    `{"name": "Exam", "learning_steps": [10, 1]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidBodyErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request",
+			requestBody:    `{"name": "Exam", "new_cards_per_day": 50, "scheduler": "fsrs"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":0}` + "\n",
+		},
+		{
+			name:           "Conflict (Duplicate name)",
+			requestBody:    `{"name": "Exam"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   DuplicateKeyViolationErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "/preset", nil)
+		req.Body = io.NopCloser(strings.NewReader(tc.requestBody))
+		rr := httptest.NewRecorder()
+
+		suite.server.HandleInsertPreset(rr, req)
+
+		assert.Equal(suite.T(), tc.expectedStatus, rr.Code, "%s: expected status code to be %d, got %d", tc.name, tc.expectedStatus, rr.Code)
+		assert.Equal(suite.T(), tc.expectedBody, rr.Body.String(), "%s: expected response body to be '%s', got '%s'", tc.name, tc.expectedBody, rr.Body.String())
+	}
+
+	preset, _ := suite.preset_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), 50, preset.NewCardsPerDay, "Expected new cards per day from request")
+	assert.Equal(suite.T(), model.DefaultDeckPreset().MaxReviewsPerDay, preset.MaxReviewsPerDay, "Expected missing settings to use defaults")
+}
+
+func (suite *APIPresetServerTestSuite) TestGetPresetHandlers() {
+	suite.preset_db.CreateTable()
+	preset := model.DefaultDeckPreset()
+	preset.Name = "Exam"
+	suite.preset_db.Insert(context.Background(), preset)
+
+	// Single preset
+	req := httptest.NewRequest(http.MethodGet, "/preset/0", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleGetSinglePreset(rr, req)
+
+	returned := model.DeckPreset{}
+	_ = json.NewDecoder(rr.Body).Decode(&returned)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), "Exam", returned.Name)
+
+	// Missing preset
+	req = httptest.NewRequest(http.MethodGet, "/preset/4", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleGetSinglePreset(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+	assert.Equal(suite.T(), InvalidPresetIDErrorMessage+"\n", rr.Body.String())
+
+	// Invalid preset ID
+	req = httptest.NewRequest(http.MethodGet, "/preset/a", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleGetSinglePreset(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+	assert.Equal(suite.T(), InvalidPresetIDErrorMessage+"\n", rr.Body.String())
+
+	// All presets
+	req = httptest.NewRequest(http.MethodGet, "/preset", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleGetAllPresets(rr, req)
+
+	presets := []model.DeckPreset{}
+	_ = json.NewDecoder(rr.Body).Decode(&presets)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), 1, len(presets))
+}
+
+func (suite *APIPresetServerTestSuite) TestModifyAndDeletePresetHandlers() {
+	suite.preset_db.CreateTable()
+	preset := model.DefaultDeckPreset()
+	preset.Name = "Exam"
+	suite.preset_db.Insert(context.Background(), preset)
+
+	req := httptest.NewRequest(http.MethodPost, "/preset/0", nil)
+	req.Body = io.NopCloser(strings.NewReader(`{"name": "Cram", "max_reviews_per_day": 9999}`))
+	rr := httptest.NewRecorder()
+	suite.server.HandleModifyPreset(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	modified, _ := suite.preset_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), "Cram", modified.Name)
+	assert.Equal(suite.T(), 9999, modified.MaxReviewsPerDay)
+
+	req = httptest.NewRequest(http.MethodPost, "/preset/3", nil)
+	req.Body = io.NopCloser(strings.NewReader(`{"name": "Cram"}`))
+	rr = httptest.NewRecorder()
+	suite.server.HandleModifyPreset(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/preset/0", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleDeletePreset(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), `{"id":0}`+"\n", rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodDelete, "/preset/0", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleDeletePreset(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *APIPresetServerTestSuite) TestDeckPresetHandlers() {
+	suite.deck_db.CreateTable()
+	suite.preset_db.CreateTable()
+	suite.deck_db.Insert(context.Background(), model.NewDeck("Deck", "Deck description"))
+
+	req := httptest.NewRequest(http.MethodPost, "/deck/0/preset", nil)
+	req.Body = io.NopCloser(strings.NewReader(`{"preset_id": 3}`))
+	rr := httptest.NewRecorder()
+	suite.server.HandleSetDeckPreset(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	deck, _ := suite.deck_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), 3, *deck.PresetID, "Expected deck to be linked to preset 3")
+
+	req = httptest.NewRequest(http.MethodPost, "/deck/1/preset", nil)
+	req.Body = io.NopCloser(strings.NewReader(`{"preset_id": null}`))
+	rr = httptest.NewRecorder()
+	suite.server.HandleSetDeckPreset(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+	assert.Equal(suite.T(), InvalidDeckIDErrorMessage+"\n", rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/deck/0/settings", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleGetDeckSettings(rr, req)
+
+	settings := model.DeckPreset{}
+	_ = json.NewDecoder(rr.Body).Decode(&settings)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), model.DefaultDeckPreset(), settings, "Expected default settings for a deck without a loaded preset")
+}
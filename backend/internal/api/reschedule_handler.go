@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HandleRescheduleDeck handles the HTTP POST request for rescheduling the cards
+// of a deck and its sub-decks with their current settings. The cards are
+// rescheduled in the background, and the response holds the job whose progress
+// can be followed with HandleGetJob.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID is invalid or the deck is not found.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the job is enqueued and the request is successful.
+func (s *APIServer) HandleRescheduleDeck(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	deckIDs, dbErr := s.deck_db.GetDescendantIDs(r.Context(), deckID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting deck descendants", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if s.job_runner == nil {
+		slog.ErrorContext(r.Context(), "No job runner configured")
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	job, err := s.job_runner.Enqueue(r.Context(), model.JobKindReschedule, model.ReschedulePayload{DeckIDs: deckIDs})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error enqueuing reschedule job", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(job)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding reschedule job", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "job", job.ID)
+}
+
+// rescheduleDecks enqueues a job rescheduling the cards of the given decks after
+// their settings changed. Nothing is done if there are no decks or no job runner,
+// and a failure to enqueue is only logged since the settings are already saved.
+//
+// Returns:
+//   - *int : The ID of the enqueued job, nil if none was enqueued.
+func (s *APIServer) rescheduleDecks(ctx context.Context, deckIDs []int) *int {
+	if s.job_runner == nil || len(deckIDs) == 0 {
+		return nil
+	}
+
+	job, err := s.job_runner.Enqueue(ctx, model.JobKindReschedule, model.ReschedulePayload{DeckIDs: deckIDs})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error enqueuing reschedule job", "error", err)
+		return nil
+	}
+	return &job.ID
+}
+
+// presetDeckIDs returns the IDs of the decks linked to a preset.
+func (s *APIServer) presetDeckIDs(ctx context.Context, presetID int) ([]int, error) {
+	decks, err := s.deck_db.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deckIDs := []int{}
+	for _, deck := range decks {
+		if deck.PresetID != nil && *deck.PresetID == presetID {
+			deckIDs = append(deckIDs, deck.ID)
+		}
+	}
+	return deckIDs, nil
+}
+
+// rescheduleOutput is the response of a request that changed the settings of
+// some decks, with the job rescheduling their cards if the change requires it.
+type rescheduleOutput struct {
+	ID              int  `json:"id"`
+	RescheduleJobID *int `json:"reschedule_job_id,omitempty"`
+}
+
+// reschedulePreset enqueues a job rescheduling the cards of the decks linked
+// to a preset if its scheduling settings changed.
+//
+// Returns:
+//   - *int : The ID of the enqueued job, nil if none was enqueued.
+func (s *APIServer) reschedulePreset(ctx context.Context, before model.DeckPreset, after model.DeckPreset) *int {
+	if s.job_runner == nil || before.SameScheduling(after) {
+		return nil
+	}
+
+	deckIDs, err := s.presetDeckIDs(ctx, after.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting decks of preset", "error", err)
+		return nil
+	}
+	return s.rescheduleDecks(ctx, deckIDs)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/maintenance"
+	"flash-learn/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIRescheduleServerTestSuite struct {
+	suite.Suite
+	deck_db    *database.DeckDBWrapperMock
+	card_db    *database.CardDBWrapperMock
+	preset_db  *database.PresetDBWrapperMock
+	job_runner *jobs.Runner
+	server     *APIServer
+}
+
+func (suite *APIRescheduleServerTestSuite) SetupTest() {
+	suite.deck_db = database.NewDeckDBWrapperMock()
+	suite.card_db = database.NewCardDBWrapperMock()
+	suite.preset_db = database.NewPresetDBWrapperMock()
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	suite.job_runner = jobs.NewRunner(job_db, 1)
+	suite.job_runner.Register(model.JobKindReschedule, maintenance.NewRescheduler(suite.deck_db, suite.card_db, maintenance.RescheduleBatchSize).Handle)
+	suite.server = NewAPIServer("localhost:8080", suite.deck_db, suite.card_db).
+		WithPresetDB(suite.preset_db).
+		WithJobRunner(suite.job_runner)
+
+	suite.preset_db.CreateTable()
+	preset := model.DefaultDeckPreset()
+	preset.Name = "Exam"
+	presetID, _ := suite.preset_db.Insert(context.Background(), preset)
+	preset.ID = presetID
+
+	suite.deck_db.CreateTable()
+	suite.deck_db.Insert(context.Background(), model.Deck{Name: "Linked deck", PresetID: &presetID, Preset: &preset})
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+
+	// The IDs of the mock start at 0, which a job treats as already processed
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Placeholder", ""))
+
+	card := model.NewCard(0, "Test content", "")
+	card.ID, _ = suite.card_db.Insert(context.Background(), card)
+	card.State = model.CardStateReview
+	card.Interval = 100
+	start := time.Now().AddDate(0, 0, -10)
+	suite.card_db.RecordReview(context.Background(), model.NewCardAnswer(card, card), model.Review{CardID: card.ID, Grade: model.GradeGood, Kind: model.ReviewKindLearn, State: model.CardStateNew, ReviewTime: start})
+	suite.card_db.RecordReview(context.Background(), model.NewCardAnswer(card, card), model.Review{CardID: card.ID, Grade: model.GradeGood, Kind: model.ReviewKindLearn, State: model.CardStateLearning, ReviewTime: start.Add(10 * time.Minute)})
+}
+
+func (suite *APIRescheduleServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPIRescheduleServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APIRescheduleServerTestSuite))
+}
+
+func (suite *APIRescheduleServerTestSuite) TestRescheduleDeckHandler() {
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Invalid deck ID)",
+			path:           "/deck/abc/reschedule",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidDeckIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Deck not found)",
+			path:           "/deck/99/reschedule",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   GetSingleDeckNotFoundErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleRescheduleDeck(rr, req)
+
+			assert.Equal(suite.T(), tc.expectedStatus, rr.Code)
+			assert.Equal(suite.T(), tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/deck/0/reschedule", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleRescheduleDeck(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var job model.Job
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &job))
+	suite.Equal(model.JobStatusQueued, job.Status)
+	suite.Equal(model.JobKindReschedule, job.Kind)
+	suite.JSONEq(`{"deck_ids":[0]}`, string(job.Payload))
+
+	suite.True(suite.job_runner.RunOnce(context.Background()))
+
+	req = httptest.NewRequest(http.MethodGet, "/jobs/0", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleGetJob(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &job))
+	suite.Equal(model.JobStatusSucceeded, job.Status)
+	suite.Equal(1, job.Total)
+	suite.Equal(1, job.Progress)
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 1)
+	suite.Equal(1, card.Interval, "Expected the card to be replayed with the graduating interval")
+}
+
+func (suite *APIRescheduleServerTestSuite) TestModifyPresetReschedules() {
+	testCases := []struct {
+		name         string
+		requestBody  string
+		expectedBody string
+	}{
+		{
+			name:         "Same scheduling",
+			requestBody:  `{"name": "Exam", "new_cards_per_day": 5}`,
+			expectedBody: `{"id":0}` + "\n",
+		},
+		{
+			name:         "Other scheduler",
+			requestBody:  `{"name": "Exam", "scheduler": "fsrs"}`,
+			expectedBody: `{"id":0,"reschedule_job_id":0}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			req := httptest.NewRequest(http.MethodPost, "/preset/0", strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			suite.server.HandleModifyPreset(rr, req)
+
+			assert.Equal(suite.T(), http.StatusOK, rr.Code)
+			assert.Equal(suite.T(), tc.expectedBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/scheduler"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandleReviewCard handles the HTTP POST request for answering a card while studying.
+// The body holds the grade, from 1 (again) to 4 (easy), and optionally how long
+// the answer took in milliseconds, for example {"grade": 3, "duration_ms": 4200}.
+// The card is rescheduled by the scheduler of its deck and the answer is added
+// to the review log. The interval of a card put in review is fuzzed by a few
+// days, towards the days its deck has the fewest cards due. A card that lapses
+// too often becomes a leech: it is tagged "leech" and the leech action of its
+// deck is applied.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID, the request body or the grade is invalid, or the card is not found.
+//   - 409 Conflict : If the card was reviewed by another request meanwhile.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the answer is recorded and the request is successful.
+func (s *APIServer) HandleReviewCard(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	type ReviewInput struct {
+		Grade    int `json:"grade"`
+		Duration int `json:"duration_ms"`
+	}
+	var bodyInput ReviewInput
+	err := json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	card, dbErr := s.card_db.GetSingle(r.Context(), cardID)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	reviewID, result, err := s.answerCard(r.Context(), card, bodyInput.Grade, bodyInput.Duration, model.StudyModeNormal, time.Now())
+	if err != nil {
+		writeAnswerError(w, r, err)
+		return
+	}
+
+	type ReviewOutput struct {
+		ReviewID int        `json:"review_id"`
+		Card     model.Card `json:"card"`
+		Leech    bool       `json:"leech"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(ReviewOutput{ReviewID: reviewID, Card: result.Card, Leech: result.Leech})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding review", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "review ID", reviewID)
+}
+
+// answerCard answers a card with a grade and records the answer in the review log.
+// A card borrowed by a filtered deck is scheduled with the settings of its home
+// deck, or only previewed if the filtered deck doesn't reschedule its cards, and
+// goes back to its home deck once it is done with. In cram mode the card keeps
+// its scheduling state and its deck.
+//
+// Parameters:
+//   - ctx context.Context : The context of the request, used for logging.
+//   - card model.Card : The card being answered.
+//   - grade int : The grade of the answer.
+//   - duration int : How long the answer took, in milliseconds.
+//   - mode string : The study mode the card is answered in.
+//   - now time.Time : The time of the answer.
+//
+// Returns:
+//   - int : The ID of the review log entry.
+//   - scheduler.Result : The answered card and its review log entry.
+//   - error : utils.ErrInvalidGrade if the grade or the duration is invalid,
+//     utils.ErrRecordNotExist if the card doesn't exist anymore,
+//     utils.ErrCardChanged if it was reviewed meanwhile, nil on success.
+func (s *APIServer) answerCard(ctx context.Context, card model.Card, grade int, duration int, mode string, now time.Time) (int, scheduler.Result, error) {
+	answerCtx, err := s.getAnswerContext(ctx, card)
+	if err != nil {
+		return -1, scheduler.Result{}, err
+	}
+
+	result, err := answerCtx.schedule(card, grade, duration, mode, now, answerCtx.fuzzer(card))
+	if err != nil {
+		return -1, scheduler.Result{}, err
+	}
+
+	reviewID, err := s.card_db.RecordReview(ctx, model.NewCardAnswer(card, result.Card), result.Review)
+	if err != nil {
+		return -1, scheduler.Result{}, err
+	}
+	s.metrics.reviews.Inc(mode, strconv.Itoa(grade))
+
+	if result.Leech {
+		slog.InfoContext(ctx, "Card became a leech", "card ID", card.ID, "lapses", result.Card.Lapses)
+	}
+
+	return reviewID, result, nil
+}
+
+// getGradeIntervals computes how long a card would wait until its next review
+// for every grade it can be answered with, without answering it. The intervals
+// aren't fuzzed, so the due date given by an answer may be a few days off.
+//
+// Parameters:
+//   - ctx context.Context : The context of the request, used for logging.
+//   - card model.Card : The card about to be answered.
+//   - mode string : The study mode the card is answered in.
+//   - now time.Time : The time of the answer.
+//
+// Returns:
+//   - []model.GradeInterval : The interval given by every grade, from Again to Easy.
+//   - error : An error if the settings of the card can't be retrieved, nil otherwise.
+func (s *APIServer) getGradeIntervals(ctx context.Context, card model.Card, mode string, now time.Time) ([]model.GradeInterval, error) {
+	answerCtx, err := s.getAnswerContext(ctx, card)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := []model.GradeInterval{}
+	for grade := model.GradeAgain; grade <= model.GradeEasy; grade++ {
+		result, err := answerCtx.schedule(card, grade, 0, mode, now, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		wait := max(0, result.Card.NextReviewTime.Sub(now))
+		intervals = append(intervals, model.GradeInterval{Grade: grade, Seconds: int64(wait / time.Second)})
+	}
+
+	return intervals, nil
+}
+
+// answerContext holds what answering a card depends on besides the card itself.
+type answerContext struct {
+	homeDeckID int
+	settings   model.DeckPreset
+	filtered   model.FilteredDeck
+	isFiltered bool
+	dueCounts  scheduler.DueCounter
+	// reviews is the number of answers in the review log of the card.
+	reviews int
+}
+
+// getAnswerContext retrieves the settings of the home deck of a card, the
+// filtered deck the card is borrowed by, if any, and how many times the card
+// was answered.
+//
+// Returns:
+//   - answerContext : What answering the card depends on.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (s *APIServer) getAnswerContext(ctx context.Context, card model.Card) (answerContext, error) {
+	answerCtx := answerContext{homeDeckID: card.DeckID}
+	if card.OriginalDeckID != nil {
+		answerCtx.homeDeckID = *card.OriginalDeckID
+	}
+	answerCtx.dueCounts = func(from time.Time, days int) ([]int, error) {
+		return s.card_db.CountDueByDay(ctx, []int{answerCtx.homeDeckID}, from, days)
+	}
+
+	deck, err := s.deck_db.GetSingle(ctx, answerCtx.homeDeckID)
+	if err != nil {
+		slog.DebugContext(ctx, "Error getting deck of card", "error", err)
+		return answerContext{}, err
+	}
+	answerCtx.settings = deck.Settings()
+
+	answerCtx.filtered, answerCtx.isFiltered, err = s.getFilteredDeck(ctx, card.DeckID)
+	if err != nil {
+		slog.DebugContext(ctx, "Error getting filtered deck", "error", err)
+		return answerContext{}, err
+	}
+
+	reviews, err := s.card_db.GetCardReviews(ctx, []int{card.ID})
+	if err != nil {
+		slog.DebugContext(ctx, "Error getting reviews of card", "error", err)
+		return answerContext{}, err
+	}
+	answerCtx.reviews = len(reviews)
+
+	return answerCtx, nil
+}
+
+// fuzzer returns the fuzzer spreading the due dates of a card over its home deck,
+// seeded by the card and its number of answers, so that an answer replayed to
+// the card in the same state is fuzzed the same way.
+func (c answerContext) fuzzer(card model.Card) *scheduler.Fuzzer {
+	rng := rand.New(rand.NewPCG(uint64(card.ID), uint64(c.reviews)))
+	return scheduler.NewFuzzer(rng, c.dueCounts)
+}
+
+// schedule answers a card with a grade without recording the answer.
+//
+// Returns:
+//   - scheduler.Result : The answered card and its review log entry.
+//   - error : utils.ErrInvalidGrade if the grade or the duration is invalid,
+//     or an error if the fuzzer can't retrieve the due counts, nil otherwise.
+func (c answerContext) schedule(card model.Card, grade int, duration int, mode string, now time.Time, fuzzer *scheduler.Fuzzer) (scheduler.Result, error) {
+	var result scheduler.Result
+	var err error
+	switch {
+	case mode == model.StudyModeCram:
+		result, err = scheduler.Cram(card, grade, duration, now)
+	case c.isFiltered && !c.filtered.Reschedule:
+		result, err = scheduler.Preview(card, grade, duration, now)
+	default:
+		result, err = scheduler.Answer(card, grade, duration, c.settings, now, fuzzer)
+	}
+	if err != nil {
+		return scheduler.Result{}, err
+	}
+
+	result.Review.DeckID = c.homeDeckID
+	if c.isFiltered && mode != model.StudyModeCram {
+		result.Card = c.filtered.Answered(result.Card, grade)
+	}
+
+	return result, nil
+}
+
+// writeAnswerError writes the HTTP error matching an error returned by answerCard.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the error.
+//   - r *http.Request : The request being answered.
+//   - err error : The error returned by answerCard.
+func writeAnswerError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == utils.ErrInvalidGrade {
+		slog.DebugContext(r.Context(), "Invalid answer", "error", err)
+		http.Error(w, InvalidGradeErrorMessage, http.StatusBadRequest)
+		return
+	}
+	if err == utils.ErrCardChanged {
+		slog.DebugContext(r.Context(), "Card changed since it was read", "error", err)
+		http.Error(w, CardChangedErrorMessage, http.StatusConflict)
+		return
+	}
+
+	writeCardError(w, r, err)
+}
+
+// HandleGetLeeches handles the HTTP GET request for retrieving the leeches of a deck
+// and its sub-decks, the cards tagged "leech", with the most lapses first.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID is invalid or the deck doesn't exist.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the leeches are found and the request is successful.
+func (s *APIServer) HandleGetLeeches(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	deckIDs, dbErr := s.deck_db.GetDescendantIDs(r.Context(), deckID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting deck descendants", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	cards, dbErr := s.card_db.Search(r.Context(), model.CardFilter{DeckIDs: deckIDs, Tags: []string{model.LeechTag}})
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error searching leeches", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	sort.SliceStable(cards, func(i, j int) bool {
+		return cards[i].Lapses > cards[j].Lapses
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(cards)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding leeches", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), fmt.Sprintf("Sent response, %d leeches", len(cards)))
+}
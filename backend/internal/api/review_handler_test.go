@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIReviewServerTestSuite struct {
+	suite.Suite
+	deck_db *database.DeckDBWrapperMock
+	card_db *database.CardDBWrapperMock
+	server  *APIServer
+}
+
+func (suite *APIReviewServerTestSuite) SetupTest() {
+	suite.deck_db = database.NewDeckDBWrapperMock()
+	suite.card_db = database.NewCardDBWrapperMock()
+	suite.server = NewAPIServer("localhost:8080", suite.deck_db, suite.card_db)
+
+	preset := model.DefaultDeckPreset()
+	preset.LeechThreshold = 2
+	preset.LeechAction = model.LeechActionSuspend
+	preset.RelearningSteps = []int{}
+
+	suite.deck_db.CreateTable()
+	suite.deck_db.Insert(context.Background(), model.Deck{Name: "Test deck", Preset: &preset})
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+	for i := 0; i < 2; i++ {
+		suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content", ""))
+	}
+}
+
+func (suite *APIReviewServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPIReviewServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APIReviewServerTestSuite))
+}
+
+func (suite *APIReviewServerTestSuite) TestReviewCardHandler() {
+	testCases := []struct {
+		name           string
+		path           string
+		requestBody    string
+		expectedStatus int
+		expectedState  int
+	}{
+		{
+			name:           "Bad Request (Card doesn't exist)",
+			path:           "/card/9/review",
+			requestBody:    "{\"grade\":3}",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bad Request (Empty body)",
+			path:           "/card/0/review",
+			requestBody:    "",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bad Request (Invalid grade)",
+			path:           "/card/0/review",
+			requestBody:    "{\"grade\":5}",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bad Request (Negative duration)",
+			path:           "/card/0/review",
+			requestBody:    "{\"grade\":3,\"duration_ms\":-1}",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Valid request (First step)",
+			path:           "/card/0/review",
+			requestBody:    "{\"grade\":3,\"duration_ms\":4200}",
+			expectedStatus: http.StatusOK,
+			expectedState:  model.CardStateLearning,
+		},
+		{
+			name:           "Valid request (Graduation)",
+			path:           "/card/0/review",
+			requestBody:    "{\"grade\":3,\"duration_ms\":3100}",
+			expectedStatus: http.StatusOK,
+			expectedState:  model.CardStateReview,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			suite.server.HandleReviewCard(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var response struct {
+					Card  model.Card `json:"card"`
+					Leech bool       `json:"leech"`
+				}
+				assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &response))
+				assert.Equal(t, tc.expectedState, response.Card.State)
+				assert.False(t, response.Leech)
+			}
+		})
+	}
+
+	reviews := suite.card_db.GetReviewLog()
+	assert.Equal(suite.T(), 2, len(reviews), "Expected only valid answers to be logged")
+	assert.Equal(suite.T(), model.ReviewKindLearn, reviews[0].Kind)
+	assert.Equal(suite.T(), 4200, reviews[0].Duration)
+	assert.Equal(suite.T(), 2.0, suite.server.metrics.reviews.Value(model.StudyModeNormal, "3"), "Expected only valid answers to be counted")
+}
+
+func (suite *APIReviewServerTestSuite) TestLeechHandling() {
+	answer := func(cardID string, grade string) bool {
+		req := httptest.NewRequest(http.MethodPost, "/card/"+cardID+"/review", strings.NewReader("{\"grade\":"+grade+"}"))
+		rr := httptest.NewRecorder()
+		suite.server.HandleReviewCard(rr, req)
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+		var response struct {
+			Leech bool `json:"leech"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return response.Leech
+	}
+
+	answer("0", "4")
+	assert.False(suite.T(), answer("0", "1"), "Expected first lapse not to make a leech")
+	assert.True(suite.T(), answer("0", "1"), "Expected lapse at the threshold to make a leech")
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+	assert.Equal(suite.T(), 2, card.Lapses)
+	assert.Equal(suite.T(), []string{model.LeechTag}, card.Tags)
+	assert.True(suite.T(), card.Suspended, "Expected leech to be suspended")
+
+	testCases := []struct {
+		name           string
+		deckID         string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "Bad Request (Deck ID not number)", deckID: "a", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Deck doesn't exist)", deckID: "9", expectedStatus: http.StatusBadRequest},
+		{name: "Valid request", deckID: "0", expectedStatus: http.StatusOK, expectedCount: 1},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/deck/"+tc.deckID+"/leeches", nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleGetLeeches(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var cards []model.Card
+				assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &cards))
+				assert.Equal(t, tc.expectedCount, len(cards))
+			}
+		})
+	}
+}
+
+func (suite *APIReviewServerTestSuite) TestReviewStaleCard() {
+	stale, _ := suite.card_db.GetSingle(context.Background(), 1)
+	edited := stale
+	edited.Tags = []string{"verb"}
+	suite.card_db.Modify(context.Background(), edited)
+	suite.card_db.SetFlag(context.Background(), 1, 3)
+
+	_, _, err := suite.server.answerCard(context.Background(), stale, model.GradeGood, 0, model.StudyModeNormal, time.Now())
+	assert.Nil(suite.T(), err, "Expected an answer to a card edited since it was read to be saved")
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 1)
+	assert.Equal(suite.T(), model.CardStateLearning, card.State)
+	assert.Equal(suite.T(), []string{"verb"}, card.Tags, "Expected the edits made since the card was read to be kept")
+	assert.Equal(suite.T(), 3, card.Flag)
+
+	_, _, err = suite.server.answerCard(context.Background(), stale, model.GradeGood, 0, model.StudyModeNormal, time.Now())
+	assert.Equal(suite.T(), utils.ErrCardChanged, err, "Expected an answer to a card reviewed since it was read to be refused")
+	assert.Equal(suite.T(), 1, len(suite.card_db.GetReviewLog()))
+
+	rr := httptest.NewRecorder()
+	writeAnswerError(rr, httptest.NewRequest(http.MethodPost, "/card/1/review", nil), err)
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *APIReviewServerTestSuite) TestReviewLoadBalancing() {
+	now := time.Now()
+	card := model.NewCard(0, "Test content", "")
+	card.State = model.CardStateReview
+	card.Interval = 10
+	card.Ease = model.CardDefaultEase
+	cardID, _ := suite.card_db.Insert(context.Background(), card)
+
+	// The card would be due in 25 days, fuzzed to between 22 and 28 days,
+	// and every day but the 26th already has cards due.
+	for day := 22; day <= 28; day++ {
+		if day == 26 {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			due := model.NewCard(0, "Test content", "")
+			due.State = model.CardStateReview
+			due.NextReviewTime = now.AddDate(0, 0, day)
+			suite.card_db.Insert(context.Background(), due)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/card/"+strconv.Itoa(cardID)+"/review", strings.NewReader("{\"grade\":3}"))
+	rr := httptest.NewRecorder()
+	suite.server.HandleReviewCard(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var response struct {
+		Card model.Card `json:"card"`
+	}
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &response))
+	suite.Equal(26, response.Card.Interval, "Expected the card to be due on the least loaded day")
+	suite.Equal(26, suite.card_db.GetReviewLog()[0].Interval)
+}
+
+func (suite *APIReviewServerTestSuite) TestReviewFuzzIsReplayable() {
+	card := model.NewCard(0, "Test content", "")
+	card.State = model.CardStateReview
+	card.Interval = 100
+	card.Ease = model.CardDefaultEase
+	card.ID, _ = suite.card_db.Insert(context.Background(), card)
+
+	answerCtx, err := suite.server.getAnswerContext(context.Background(), card)
+	suite.Nil(err)
+	now := time.Now()
+	first, err := answerCtx.schedule(card, model.GradeGood, 0, model.StudyModeNormal, now, answerCtx.fuzzer(card))
+	suite.Nil(err)
+	replayed, err := answerCtx.schedule(card, model.GradeGood, 0, model.StudyModeNormal, now.Add(time.Second), answerCtx.fuzzer(card))
+	suite.Nil(err)
+	suite.Equal(first.Card.Interval, replayed.Card.Interval, "Expected an answer replayed to the same card state to get the same interval")
+}
@@ -1,10 +1,29 @@
 package api
 
-import "net/http"
+import (
+	"flash-learn/internal/config"
+	"net/http"
+	"time"
+)
+
+// defaultRouteLimits are the limits of the routes which need more than the
+// limits of the server, before the settings of the routes are applied.
+var defaultRouteLimits = map[string]config.RouteConfig{
+	// A bulk request carries many cards at once
+	"POST /card/bulk": {Timeout: time.Minute, MaxBodyBytes: 8 << 20},
+}
 
 func addRoutes(router *http.ServeMux, s *APIServer) {
 	addDeckRoutes(router, s)
 	addCardRoutes(router, s)
+	addPresetRoutes(router, s)
+	addTrashRoutes(router, s)
+	addFlagRoutes(router, s)
+	addStatsRoutes(router, s)
+	addFilteredDeckRoutes(router, s)
+	addSessionRoutes(router, s)
+	addJobRoutes(router, s)
+	addMetricsRoutes(router, s)
 }
 
 // addDeckRoutes adds the routes for the deck API.
@@ -16,10 +35,23 @@ func addDeckRoutes(router *http.ServeMux, s *APIServer) {
 	router.HandleFunc("GET /deck/{id}", s.HandleGetSingleDeck)
 	router.HandleFunc("GET /deck", s.HandleGetAllDecks)
 	router.HandleFunc("GET /deck/count", s.HandleGetDeckCount)
+	router.HandleFunc("GET /deck/tree", s.HandleGetDeckTree)
+	router.HandleFunc("GET /deck/trash", s.HandleGetDeckTrash)
 	router.HandleFunc("GET /deck/nameMaxLength", s.HandleGetDeckNameMaxLength)
 	router.HandleFunc("GET /deck/descriptionMaxLength", s.HandleGetDeckDescriptionMaxLength)
 	router.HandleFunc("POST /deck", s.HandleInsertDeck)
 	router.HandleFunc("POST /deck/{id}", s.HandleModifyDeck)
+	router.HandleFunc("POST /deck/{id}/child", s.HandleInsertChildDeck)
+	router.HandleFunc("POST /deck/{id}/move", s.HandleMoveDeck)
+	router.HandleFunc("POST /deck/{id}/restore", s.HandleRestoreDeck)
+	router.HandleFunc("GET /deck/{id}/settings", s.HandleGetDeckSettings)
+	router.HandleFunc("GET /deck/{id}/leeches", s.HandleGetLeeches)
+	router.HandleFunc("GET /deck/{id}/stats", s.HandleGetDeckStats)
+	router.HandleFunc("GET /deck/{id}/forecast", s.HandleGetDeckForecast)
+	router.HandleFunc("GET /deck/{id}/heatmap", s.HandleGetDeckHeatmap)
+	router.HandleFunc("POST /deck/{id}/preset", s.HandleSetDeckPreset)
+	router.HandleFunc("POST /deck/{id}/optimize", s.HandleOptimizeDeck)
+	router.HandleFunc("POST /deck/{id}/reschedule", s.HandleRescheduleDeck)
 	router.HandleFunc("DELETE /deck/{id}", s.HandleDeleteDeck)
 }
 
@@ -31,4 +63,110 @@ func addDeckRoutes(router *http.ServeMux, s *APIServer) {
 func addCardRoutes(router *http.ServeMux, s *APIServer) {
 	router.HandleFunc("POST /deck/{id}/card", s.HandleInsertCard)
 	router.HandleFunc("GET /deck/{id}/card/total", s.HandleGetTotalCards)
+	router.HandleFunc("GET /deck/{id}/card/due", s.HandleGetDueCards)
+	router.HandleFunc("GET /card", s.HandleSearchCards)
+	router.HandleFunc("POST /card/bulk", s.HandleBulkCards)
+	router.HandleFunc("GET /card/{id}", s.HandleGetSingleCard)
+	router.HandleFunc("POST /card/{id}", s.HandleModifyCard)
+	router.HandleFunc("DELETE /card/{id}", s.HandleDeleteCard)
+	router.HandleFunc("POST /card/{id}/suspend", s.HandleSuspendCard)
+	router.HandleFunc("POST /card/{id}/unsuspend", s.HandleUnsuspendCard)
+	router.HandleFunc("POST /card/{id}/bury", s.HandleBuryCard)
+	router.HandleFunc("POST /card/{id}/unbury", s.HandleUnburyCard)
+	router.HandleFunc("POST /card/{id}/review", s.HandleReviewCard)
+	router.HandleFunc("POST /card/{id}/flag", s.HandleSetCardFlag)
+	router.HandleFunc("DELETE /card/{id}/flag", s.HandleClearCardFlag)
+	router.HandleFunc("GET /card/{id}/revisions", s.HandleGetCardRevisions)
+	router.HandleFunc("POST /card/{id}/revisions/{rev}/revert", s.HandleRevertCard)
+}
+
+// addPresetRoutes adds the routes for the deck preset API.
+//
+// Parameters:
+//   - router *http.ServeMux
+//   - s *APIServer
+func addPresetRoutes(router *http.ServeMux, s *APIServer) {
+	router.HandleFunc("GET /preset", s.HandleGetAllPresets)
+	router.HandleFunc("GET /preset/{id}", s.HandleGetSinglePreset)
+	router.HandleFunc("POST /preset", s.HandleInsertPreset)
+	router.HandleFunc("POST /preset/{id}", s.HandleModifyPreset)
+	router.HandleFunc("DELETE /preset/{id}", s.HandleDeletePreset)
+}
+
+// addTrashRoutes adds the routes for the card trash API.
+//
+// Parameters:
+//   - router *http.ServeMux
+//   - s *APIServer
+func addTrashRoutes(router *http.ServeMux, s *APIServer) {
+	router.HandleFunc("GET /trash", s.HandleGetTrash)
+	router.HandleFunc("POST /trash/{id}/restore", s.HandleRestoreCard)
+	router.HandleFunc("DELETE /trash", s.HandleEmptyTrash)
+}
+
+// addFlagRoutes adds the routes for the flag definition API.
+//
+// Parameters:
+//   - router *http.ServeMux
+//   - s *APIServer
+func addFlagRoutes(router *http.ServeMux, s *APIServer) {
+	router.HandleFunc("GET /flag", s.HandleGetAllFlags)
+	router.HandleFunc("POST /flag/{flag}", s.HandleSetFlagDefinition)
+	router.HandleFunc("DELETE /flag/{flag}", s.HandleDeleteFlagDefinition)
+}
+
+// addFilteredDeckRoutes adds the routes for the filtered deck API.
+//
+// Parameters:
+//   - router *http.ServeMux
+//   - s *APIServer
+func addFilteredDeckRoutes(router *http.ServeMux, s *APIServer) {
+	router.HandleFunc("POST /deck/filtered", s.HandleInsertFilteredDeck)
+	router.HandleFunc("GET /deck/{id}/filter", s.HandleGetFilteredDeck)
+	router.HandleFunc("POST /deck/{id}/filter", s.HandleModifyFilteredDeck)
+	router.HandleFunc("POST /deck/{id}/rebuild", s.HandleRebuildFilteredDeck)
+	router.HandleFunc("POST /deck/{id}/empty", s.HandleEmptyFilteredDeck)
+}
+
+// addStatsRoutes adds the routes for the study statistics API.
+//
+// Parameters:
+//   - router *http.ServeMux
+//   - s *APIServer
+func addStatsRoutes(router *http.ServeMux, s *APIServer) {
+	router.HandleFunc("GET /stats", s.HandleGetStats)
+	router.HandleFunc("GET /stats/heatmap", s.HandleGetHeatmap)
+}
+
+// addSessionRoutes adds the routes for the study session API.
+//
+// Parameters:
+//   - router *http.ServeMux
+//   - s *APIServer
+func addSessionRoutes(router *http.ServeMux, s *APIServer) {
+	router.HandleFunc("POST /session", s.HandleStartSession)
+	router.HandleFunc("GET /session/{id}/next", s.HandleGetSessionNext)
+	router.HandleFunc("POST /session/{id}/answer", s.HandleAnswerSession)
+	router.HandleFunc("POST /session/{id}/undo", s.HandleUndoSession)
+	router.HandleFunc("POST /session/{id}/end", s.HandleEndSession)
+}
+
+// addJobRoutes adds the routes for the background job API.
+//
+// Parameters:
+//   - router *http.ServeMux
+//   - s *APIServer
+func addJobRoutes(router *http.ServeMux, s *APIServer) {
+	router.HandleFunc("GET /jobs/{id}", s.HandleGetJob)
+	router.HandleFunc("POST /jobs/{id}/cancel", s.HandleCancelJob)
+	router.HandleFunc("POST /jobs/{id}/apply", s.HandleApplyOptimizeJob)
+}
+
+// addMetricsRoutes adds the route of the Prometheus metrics.
+//
+// Parameters:
+//   - router *http.ServeMux
+//   - s *APIServer
+func addMetricsRoutes(router *http.ServeMux, s *APIServer) {
+	router.HandleFunc("GET /metrics", s.HandleGetMetrics)
 }
@@ -0,0 +1,518 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandleStartSession handles the HTTP POST request for starting a study session.
+// The body holds either the ID of the deck to study or a search, and optionally
+// the mode of the session and how its queue is run, for example
+// {"deck_id": 3, "mode": "cram", "shuffle": true, "repeat_failed": true}.
+// A normal session studies the cards due in the deck and its sub-decks, within
+// the daily limits of the deck, and reschedules them. A cram session runs through
+// every card that isn't suspended or buried, logs the answers as "cram" reviews
+// and leaves the scheduling of the cards untouched. "seed" makes the shuffle
+// reproducible.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the session in its body.
+//
+// Errors:
+//   - 400 Bad Request : If the request body, the mode or the search is invalid, or the deck doesn't exist.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 201 Created : With the started session.
+func (s *APIServer) HandleStartSession(w http.ResponseWriter, r *http.Request) {
+	type StartInput struct {
+		DeckID       *int              `json:"deck_id"`
+		Query        *model.CardFilter `json:"query"`
+		Mode         string            `json:"mode"`
+		Shuffle      bool              `json:"shuffle"`
+		RepeatFailed bool              `json:"repeat_failed"`
+		Seed         *uint64           `json:"seed"`
+	}
+	bodyInput := StartInput{Mode: model.StudyModeNormal}
+	err := json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	session := model.StudySession{
+		DeckID:       bodyInput.DeckID,
+		Mode:         bodyInput.Mode,
+		Shuffle:      bodyInput.Shuffle,
+		RepeatFailed: bodyInput.RepeatFailed,
+		StartTime:    time.Now(),
+	}
+	if (bodyInput.DeckID == nil) == (bodyInput.Query == nil) || session.Validate() != nil {
+		slog.DebugContext(r.Context(), "Invalid study session", "mode", bodyInput.Mode)
+		http.Error(w, InvalidStudySessionErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	cards, dbErr := s.getSessionCards(r.Context(), session, bodyInput.Query)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting session cards", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	seed := uint64(session.StartTime.UnixNano())
+	if bodyInput.Seed != nil {
+		seed = *bodyInput.Seed
+	}
+	session = session.Start(cards, rand.New(rand.NewPCG(seed, 0)))
+
+	session.ID, dbErr = s.session_db.Insert(r.Context(), session)
+	if dbErr != nil {
+		if dbErr == utils.ErrDeckNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error inserting study session", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+	s.metrics.sessionsStarted.Inc(session.Mode)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(session)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding study session", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	slog.DebugContext(r.Context(), "Sent response", "session ID", session.ID, "cards", len(session.Queue))
+}
+
+// HandleGetSessionNext handles the HTTP GET request for retrieving the card to
+// study next in a study session, along with how long the card would wait until
+// its next review for every grade it can be answered with. Cards deleted,
+// suspended or buried since the session started are skipped. The card is null
+// once the session is finished or ended.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the session ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the session ID is invalid or the session doesn't exist.
+//   - 409 Conflict : If the session was saved by another request meanwhile.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the session, its current card and the intervals of the grades.
+func (s *APIServer) HandleGetSessionNext(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.parseSession(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	var card *model.Card
+	if !session.Ended() {
+		var dbErr error
+		session, card, dbErr = s.nextSessionCard(r.Context(), session, now)
+		if dbErr != nil {
+			writeSessionError(w, r, "Error getting next session card", dbErr)
+			return
+		}
+	}
+
+	s.writeSessionCard(w, r, session, card, now)
+}
+
+// HandleAnswerSession handles the HTTP POST request for answering the current
+// card of a study session. The body holds the ID of the card, which must be the
+// current card of the session, the grade, from 1 (again) to 4 (easy), and
+// optionally how long the answer took in milliseconds, for example
+// {"card_id": 12, "grade": 3, "duration_ms": 4200}. The answer is recorded in the mode of the session, and a failed card
+// goes back to the end of the queue if the session repeats failed cards.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the session ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the session ID, the request body or the grade is invalid,
+//     the session doesn't exist, or it is finished or ended.
+//   - 409 Conflict : If the card isn't the current card of the session anymore, or the card
+//     or the session was saved by another request meanwhile.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the answered card and the session.
+func (s *APIServer) HandleAnswerSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.parseSession(w, r)
+	if !ok {
+		return
+	}
+
+	type AnswerInput struct {
+		CardID   *int `json:"card_id"`
+		Grade    int  `json:"grade"`
+		Duration int  `json:"duration_ms"`
+	}
+	var bodyInput AnswerInput
+	err := json.NewDecoder(r.Body).Decode(&bodyInput)
+	if err != nil || bodyInput.CardID == nil {
+		slog.DebugContext(r.Context(), "Error decoding request body", "error", err)
+		http.Error(w, InvalidBodyErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	if session.Ended() {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Study session %d has ended", session.ID))
+		http.Error(w, StudySessionEndedErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	session, card, dbErr := s.nextSessionCard(r.Context(), session, now)
+	if dbErr != nil {
+		writeSessionError(w, r, "Error getting next session card", dbErr)
+		return
+	} else if card == nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Study session %d is finished", session.ID))
+		http.Error(w, StudySessionFinishedErrorMessage, http.StatusBadRequest)
+		return
+	} else if card.ID != *bodyInput.CardID {
+		slog.DebugContext(r.Context(), "Answered card isn't the current card", "session ID", session.ID,
+			"card ID", *bodyInput.CardID, "current card ID", card.ID)
+		http.Error(w, NotCurrentCardErrorMessage, http.StatusConflict)
+		return
+	}
+
+	reviewID, result, dbErr := s.answerCard(r.Context(), *card, bodyInput.Grade, bodyInput.Duration, session.Mode, now)
+	if dbErr != nil {
+		writeAnswerError(w, r, dbErr)
+		return
+	}
+
+	session = session.Answer(model.StudySessionAnswer{ReviewID: reviewID, Grade: bodyInput.Grade, Duration: bodyInput.Duration, Card: *card})
+	if session.Version, dbErr = s.session_db.Update(r.Context(), session); dbErr != nil {
+		// The session doesn't hold the answer, so it can't be undone from the session
+		if undoErr := s.card_db.UndoReview(r.Context(), *card, reviewID); undoErr != nil {
+			slog.WarnContext(r.Context(), "Error taking back answer", "card ID", card.ID, "review ID", reviewID, "error", undoErr)
+		}
+		writeSessionError(w, r, "Error updating study session", dbErr)
+		return
+	}
+
+	type AnswerOutput struct {
+		ReviewID int                `json:"review_id"`
+		Card     model.Card         `json:"card"`
+		Leech    bool               `json:"leech"`
+		Session  model.StudySession `json:"session"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(AnswerOutput{ReviewID: reviewID, Card: result.Card, Leech: result.Leech, Session: session})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding answer", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "session ID", session.ID, "review ID", reviewID)
+}
+
+// HandleUndoSession handles the HTTP POST request for undoing the last answer of
+// a study session. The card gets back the scheduling state it had before the
+// answer, the answer is removed from the review log and the card becomes the
+// current card of the session again. Answers can be undone one after another
+// back to the start of the session, unless the card was answered again outside
+// of the session since.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the session ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the session ID is invalid, the session doesn't exist or has ended,
+//     there is no answer to undo, or the card of the answer doesn't exist anymore.
+//   - 409 Conflict : If the card was answered again since, or the session was saved
+//     by another request meanwhile.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the session, its current card and the intervals of the grades.
+func (s *APIServer) HandleUndoSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.parseSession(w, r)
+	if !ok {
+		return
+	}
+
+	if session.Ended() {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Study session %d has ended", session.ID))
+		http.Error(w, StudySessionEndedErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	undone, answer, ok := session.Undo()
+	if !ok {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Nothing to undo in study session %d", session.ID))
+		http.Error(w, NothingToUndoErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	// The session is saved first, so that a concurrent answer or undo fails
+	// before the card is touched
+	var dbErr error
+	if undone.Version, dbErr = s.session_db.Update(r.Context(), undone); dbErr != nil {
+		writeSessionError(w, r, "Error updating study session", dbErr)
+		return
+	}
+
+	if dbErr = s.card_db.UndoReview(r.Context(), answer.Card, answer.ReviewID); dbErr != nil {
+		session.Version = undone.Version
+		if _, restoreErr := s.session_db.Update(r.Context(), session); restoreErr != nil {
+			slog.WarnContext(r.Context(), "Error restoring study session", "session ID", session.ID, "error", restoreErr)
+		}
+
+		if dbErr == utils.ErrCardChanged {
+			slog.DebugContext(r.Context(), "Card answered again since", "card ID", answer.Card.ID, "review ID", answer.ReviewID)
+			http.Error(w, CardAnsweredAgainErrorMessage, http.StatusConflict)
+			return
+		}
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Undid answer", "session ID", undone.ID, "review ID", answer.ReviewID)
+	s.writeSessionCard(w, r, undone, &answer.Card, time.Now())
+}
+
+// HandleEndSession handles the HTTP POST request for ending a study session.
+// The deck of the session and the home decks of the answered cards are marked
+// as studied now, once the session is saved as ended, and a summary of the
+// answers is returned. Ending a session that has already ended only returns
+// its summary.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the session ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the session ID is invalid or the session doesn't exist.
+//   - 409 Conflict : If the session was saved by another request meanwhile.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : With the summary of the session.
+func (s *APIServer) HandleEndSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.parseSession(w, r)
+	if !ok {
+		return
+	}
+
+	if !session.Ended() {
+		now := time.Now()
+		session.EndTime = &now
+
+		var dbErr error
+		if session.Version, dbErr = s.session_db.Update(r.Context(), session); dbErr != nil {
+			writeSessionError(w, r, "Error updating study session", dbErr)
+			return
+		}
+
+		if dbErr := s.deck_db.SetLastStudyDate(r.Context(), session.StudiedDeckIDs(), now); dbErr != nil {
+			slog.DebugContext(r.Context(), "Error setting last study date", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	summary := session.Summarize(*session.EndTime)
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(summary)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding study session summary", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "session ID", session.ID, "answered", summary.Answered)
+}
+
+type sessionOutput struct {
+	Session   model.StudySession    `json:"session"`
+	Card      *model.Card           `json:"card"`
+	Intervals []model.GradeInterval `json:"intervals"`
+}
+
+// writeSessionCard writes the current card of a study session to the response,
+// along with the intervals of the grades it can be answered with.
+func (s *APIServer) writeSessionCard(w http.ResponseWriter, r *http.Request, session model.StudySession, card *model.Card, now time.Time) {
+	output := sessionOutput{Session: session, Card: card, Intervals: []model.GradeInterval{}}
+	if card != nil {
+		intervals, dbErr := s.getGradeIntervals(r.Context(), *card, session.Mode, now)
+		if dbErr != nil {
+			slog.DebugContext(r.Context(), "Error computing grade intervals", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+			return
+		}
+		output.Intervals = intervals
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(output)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding study session", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "session ID", session.ID, "remaining cards", len(session.Queue))
+}
+
+// getSessionCards retrieves the cards a study session starts with. A normal
+// session on a deck gets the due cards of the deck, like when studying it,
+// while a normal session on a search gets the matching cards that are due
+// today. A cram session gets every card of the deck and its sub-decks, or
+// matching the search, that isn't suspended or buried.
+//
+// Returns:
+//   - []model.Card : The cards to study, in the order they are studied without shuffling.
+//   - error : utils.ErrRecordNotExist if the deck doesn't exist, or an error if the retrieval fails.
+func (s *APIServer) getSessionCards(ctx context.Context, session model.StudySession, query *model.CardFilter) ([]model.Card, error) {
+	var filter model.CardFilter
+	if query != nil {
+		filter = *query
+	} else {
+		deck, err := s.deck_db.GetSingle(ctx, *session.DeckID)
+		if err != nil {
+			return nil, err
+		}
+
+		if session.Mode == model.StudyModeNormal {
+			filtered, isFiltered, err := s.getFilteredDeck(ctx, deck.ID)
+			if err != nil {
+				return nil, err
+			} else if isFiltered {
+				return s.getFilteredDueCards(ctx, filtered)
+			}
+			return s.getDueCards(ctx, deck.ID, deck.Settings())
+		}
+
+		filter.DeckIDs, err = s.deck_db.GetDescendantIDs(ctx, deck.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matching, err := s.card_db.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cards := []model.Card{}
+	for _, card := range matching {
+		due := !card.NextReviewTime.After(utils.StartOfNextDay(now))
+		if card.InQueue(now) && (due || session.Mode == model.StudyModeCram) {
+			cards = append(cards, card)
+		}
+	}
+
+	return cards, nil
+}
+
+// nextSessionCard retrieves the current card of a study session, first dropping
+// from the queue the cards that can't be studied anymore. The session is saved
+// if cards were dropped.
+//
+// Returns:
+//   - model.StudySession : The session with a card that can be studied current.
+//   - *model.Card : The current card, nil if the session is finished.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (s *APIServer) nextSessionCard(ctx context.Context, session model.StudySession, now time.Time) (model.StudySession, *model.Card, error) {
+	skipped := false
+	for {
+		cardID, ok := session.Current()
+		if !ok {
+			break
+		}
+
+		card, err := s.card_db.GetSingle(ctx, cardID)
+		if err == nil && card.InQueue(now) {
+			if skipped {
+				session.Version, err = s.session_db.Update(ctx, session)
+			}
+			return session, &card, err
+		} else if err != nil && err != utils.ErrRecordNotExist {
+			return session, nil, err
+		}
+
+		slog.DebugContext(ctx, "Skipping session card", "session ID", session.ID, "card ID", cardID)
+		session = session.Skip()
+		skipped = true
+	}
+
+	var err error
+	if skipped {
+		session.Version, err = s.session_db.Update(ctx, session)
+	}
+	return session, nil, err
+}
+
+// writeSessionError writes the HTTP error matching an error returned while
+// saving a study session: 409 Conflict if the session was saved by another
+// request since it was read, 500 Internal Server Error otherwise.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the error.
+//   - r *http.Request : The request being answered.
+//   - message string : The message logged with the error.
+//   - err error : The error.
+func writeSessionError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	slog.DebugContext(r.Context(), message, "error", err)
+	if err == utils.ErrStudySessionChanged {
+		http.Error(w, StudySessionChangedErrorMessage, http.StatusConflict)
+		return
+	}
+	http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+}
+
+// parseSession parses the session ID from the URL path and retrieves the session,
+// writing a 400 Bad Request response if the ID isn't a number or the session
+// doesn't exist.
+//
+// Returns:
+//   - model.StudySession : The session.
+//   - bool : False if the response has already been written.
+func (s *APIServer) parseSession(w http.ResponseWriter, r *http.Request) (model.StudySession, bool) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	sessionID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid session ID %s", idStr))
+		http.Error(w, InvalidStudySessionIDErrorMessage, http.StatusBadRequest)
+		return model.StudySession{}, false
+	}
+
+	session, dbErr := s.session_db.Get(r.Context(), sessionID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Study session not found", "error", dbErr)
+			http.Error(w, InvalidStudySessionIDErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting study session", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return model.StudySession{}, false
+	}
+
+	return session, true
+}
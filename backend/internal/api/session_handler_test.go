@@ -0,0 +1,387 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APISessionServerTestSuite struct {
+	suite.Suite
+	deck_db    *database.DeckDBWrapperMock
+	card_db    *database.CardDBWrapperMock
+	session_db *database.StudySessionDBWrapperMock
+	server     *APIServer
+	later      time.Time
+}
+
+func (suite *APISessionServerTestSuite) SetupTest() {
+	suite.deck_db = database.NewDeckDBWrapperMock()
+	suite.card_db = database.NewCardDBWrapperMock()
+	suite.session_db = database.NewStudySessionDBWrapperMock()
+	suite.server = NewAPIServer("localhost:8080", suite.deck_db, suite.card_db).WithStudySessionDB(suite.session_db)
+
+	suite.deck_db.CreateTable()
+	suite.card_db.CreateTable()
+	suite.session_db.CreateTable()
+	suite.deck_db.Insert(context.Background(), model.Deck{Name: "Test deck"})
+	suite.card_db.InsertDeck(0)
+	for i := 0; i < 2; i++ {
+		suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content", ""))
+	}
+
+	// A card in review that isn't due for a month.
+	suite.later = time.Now().AddDate(0, 1, 0).Truncate(time.Second)
+	card := model.NewCard(0, "Test content", "")
+	card.Tags = []string{"verbs"}
+	card.State = model.CardStateReview
+	card.Interval = 30
+	card.RetentionLevel = 3
+	card.NextReviewTime = suite.later
+	suite.card_db.Insert(context.Background(), card)
+}
+
+func (suite *APISessionServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPISessionServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APISessionServerTestSuite))
+}
+
+// startSession starts a session with the given body and returns it.
+func (suite *APISessionServerTestSuite) startSession(body string) model.StudySession {
+	req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	suite.server.HandleStartSession(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var session model.StudySession
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &session))
+	return session
+}
+
+// answer answers the first card of the queue of the session 0 and returns the response.
+func (suite *APISessionServerTestSuite) answer(grade string) *httptest.ResponseRecorder {
+	cardID := 0
+	if session, err := suite.session_db.Get(context.Background(), 0); err == nil && len(session.Queue) > 0 {
+		cardID = session.Queue[0]
+	}
+	req := httptest.NewRequest(http.MethodPost, "/session/0/answer",
+		strings.NewReader(`{"card_id":`+strconv.Itoa(cardID)+`,"grade":`+grade+`}`))
+	rr := httptest.NewRecorder()
+	suite.server.HandleAnswerSession(rr, req)
+	return rr
+}
+
+func (suite *APISessionServerTestSuite) TestStartSessionHandler() {
+	testCases := []struct {
+		name           string
+		requestBody    string
+		expectedStatus int
+		expectedQueue  []int
+	}{
+		{name: "Bad Request (Empty body)", requestBody: "", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (No deck or query)", requestBody: `{"mode":"cram"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Deck and query)", requestBody: `{"deck_id":0,"query":{}}`, expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Unknown mode)", requestBody: `{"deck_id":0,"mode":"exam"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Deck doesn't exist)", requestBody: `{"deck_id":5}`, expectedStatus: http.StatusBadRequest},
+		{name: "Valid request (Normal deck)", requestBody: `{"deck_id":0}`, expectedStatus: http.StatusOK, expectedQueue: []int{0, 1}},
+		{name: "Valid request (Cram deck)", requestBody: `{"deck_id":0,"mode":"cram"}`, expectedStatus: http.StatusOK, expectedQueue: []int{0, 1, 2}},
+		{name: "Valid request (Normal query)", requestBody: `{"query":{"tags":["verbs"]}}`, expectedStatus: http.StatusOK, expectedQueue: []int{}},
+		{name: "Valid request (Cram query)", requestBody: `{"query":{"tags":["verbs"]},"mode":"cram"}`, expectedStatus: http.StatusOK, expectedQueue: []int{2}},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			suite.server.HandleStartSession(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var session model.StudySession
+				assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &session))
+				assert.Equal(t, tc.expectedQueue, session.Queue)
+			}
+		})
+	}
+}
+
+func (suite *APISessionServerTestSuite) TestStartSessionShuffle() {
+	first := suite.startSession(`{"deck_id":0,"mode":"cram","shuffle":true,"seed":3}`)
+	second := suite.startSession(`{"deck_id":0,"mode":"cram","shuffle":true,"seed":3}`)
+
+	suite.ElementsMatch([]int{0, 1, 2}, first.Queue)
+	suite.Equal(first.Queue, second.Queue, "Expected the same seed to give the same shuffle")
+	suite.NotEqual(first.ID, second.ID)
+}
+
+func (suite *APISessionServerTestSuite) TestCramSession() {
+	suite.startSession(`{"deck_id":0,"mode":"cram","repeat_failed":true}`)
+
+	type AnswerOutput struct {
+		Card    model.Card         `json:"card"`
+		Session model.StudySession `json:"session"`
+	}
+	var output AnswerOutput
+
+	rr := suite.answer("0")
+	suite.Equal(http.StatusBadRequest, rr.Code, "Expected an invalid grade to be rejected")
+
+	for _, cardID := range []int{0, 1, 2} {
+		rr = suite.answer("1")
+		suite.Equal(http.StatusOK, rr.Code)
+		suite.Nil(json.Unmarshal(rr.Body.Bytes(), &output))
+		suite.Equal(cardID, output.Card.ID)
+	}
+	suite.Equal([]int{0, 1, 2}, output.Session.Queue, "Expected failed cards to be repeated")
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 2)
+	suite.Equal(suite.later, card.NextReviewTime)
+	suite.Equal(3, card.RetentionLevel)
+	suite.Equal(model.CardStateReview, card.State)
+	suite.Equal(0, card.Lapses, "Expected a failed cram answer not to be a lapse")
+
+	for i := 0; i < 3; i++ {
+		suite.Equal(http.StatusOK, suite.answer("3").Code)
+	}
+	suite.Equal(http.StatusBadRequest, suite.answer("3").Code, "Expected a finished session to reject answers")
+
+	log := suite.card_db.GetReviewLog()
+	suite.Equal(6, len(log))
+	for _, review := range log {
+		suite.Equal(model.ReviewKindCram, review.Kind)
+	}
+
+	session, _ := suite.session_db.Get(context.Background(), 0)
+	suite.Equal(6, session.Answered)
+	suite.Equal(3, session.Failed)
+}
+
+func (suite *APISessionServerTestSuite) TestNormalSession() {
+	suite.startSession(`{"deck_id":0}`)
+
+	rr := suite.answer("3")
+	suite.Equal(http.StatusOK, rr.Code)
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+	suite.Equal(model.CardStateLearning, card.State, "Expected a normal session to reschedule the card")
+	suite.Equal(model.ReviewKindLearn, suite.card_db.GetReviewLog()[0].Kind)
+}
+
+func (suite *APISessionServerTestSuite) TestGetSessionNextHandler() {
+	suite.startSession(`{"deck_id":0,"mode":"cram"}`)
+	suite.card_db.SetSuspended(context.Background(), 0, true)
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCard   int
+	}{
+		{name: "Bad Request (Session ID not number)", path: "/session/a/next", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Session doesn't exist)", path: "/session/9/next", expectedStatus: http.StatusBadRequest},
+		{name: "Valid request (Suspended card skipped)", path: "/session/0/next", expectedStatus: http.StatusOK, expectedCard: 1},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleGetSessionNext(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var output sessionOutput
+				assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &output))
+				assert.Equal(t, tc.expectedCard, output.Card.ID)
+				assert.Equal(t, []int{1, 2}, output.Session.Queue)
+			}
+		})
+	}
+
+	session, _ := suite.session_db.Get(context.Background(), 0)
+	suite.Equal([]int{1, 2}, session.Queue, "Expected the skipped card to be saved")
+}
+
+func (suite *APISessionServerTestSuite) TestGetSessionNextIntervals() {
+	suite.startSession(`{"deck_id":0}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/session/0/next", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleGetSessionNext(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+
+	var output sessionOutput
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &output))
+	suite.Equal(4, len(output.Intervals))
+	for i, interval := range output.Intervals {
+		suite.Equal(model.GradeAgain+i, interval.Grade)
+		if i > 0 {
+			suite.GreaterOrEqual(interval.Seconds, output.Intervals[i-1].Seconds, "Expected higher grades to wait longer")
+		}
+	}
+	suite.Equal(int64(60), output.Intervals[0].Seconds)
+	suite.Equal(int64(600), output.Intervals[2].Seconds)
+	suite.GreaterOrEqual(output.Intervals[3].Seconds, int64(24*60*60), "Expected Easy to graduate the card")
+	suite.Equal(0, len(suite.card_db.GetReviewLog()), "Expected the intervals not to answer the card")
+}
+
+// A study session database where another request saves the session right
+// after every read.
+type racingSessionDB struct {
+	*database.StudySessionDBWrapperMock
+}
+
+func (session_db racingSessionDB) Get(ctx context.Context, id int) (model.StudySession, error) {
+	session, err := session_db.StudySessionDBWrapperMock.Get(ctx, id)
+	if err == nil {
+		session_db.StudySessionDBWrapperMock.Update(ctx, session)
+	}
+	return session, err
+}
+
+func (suite *APISessionServerTestSuite) TestAnswerSessionConflicts() {
+	suite.startSession(`{"deck_id":0}`)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/session/0/answer", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		suite.server.HandleAnswerSession(rr, req)
+		return rr
+	}
+
+	suite.Equal(http.StatusBadRequest, post(`{"grade":3}`).Code, "Expected the card ID to be required")
+	suite.Equal(http.StatusConflict, post(`{"card_id":1,"grade":3}`).Code, "Expected an answer to another card than the current one to be refused")
+	suite.Equal(0, len(suite.card_db.GetReviewLog()))
+
+	before, _ := suite.card_db.GetSingle(context.Background(), 0)
+	suite.server.WithStudySessionDB(racingSessionDB{suite.session_db})
+	suite.Equal(http.StatusConflict, post(`{"card_id":0,"grade":3}`).Code, "Expected an answer to a session saved meanwhile to be refused")
+	suite.Equal(0, len(suite.card_db.GetReviewLog()), "Expected the refused answer to be taken back")
+	after, _ := suite.card_db.GetSingle(context.Background(), 0)
+	suite.Equal(before, after)
+
+	session, _ := suite.session_db.Get(context.Background(), 0)
+	suite.Equal(0, session.Answered)
+	suite.Equal([]int{0, 1}, session.Queue)
+}
+
+func (suite *APISessionServerTestSuite) TestUndoSessionHandler() {
+	suite.startSession(`{"deck_id":0,"repeat_failed":true}`)
+	before, _ := suite.card_db.GetSingle(context.Background(), 0)
+
+	undo := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/session/0/undo", nil)
+		rr := httptest.NewRecorder()
+		suite.server.HandleUndoSession(rr, req)
+		return rr
+	}
+
+	suite.Equal(http.StatusBadRequest, undo().Code, "Expected nothing to undo")
+	suite.Equal(http.StatusOK, suite.answer("1").Code)
+	suite.Equal(http.StatusOK, suite.answer("3").Code)
+
+	rr := undo()
+	suite.Equal(http.StatusOK, rr.Code)
+	var output sessionOutput
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &output))
+	suite.Equal(1, output.Card.ID)
+	suite.Equal([]int{1, 0}, output.Session.Queue)
+	suite.Equal(4, len(output.Intervals))
+
+	suite.Equal(http.StatusOK, undo().Code)
+	after, _ := suite.card_db.GetSingle(context.Background(), 0)
+	suite.Equal(before, after, "Expected the card to get its scheduling state back")
+	suite.Equal(0, len(suite.card_db.GetReviewLog()))
+
+	session, _ := suite.session_db.Get(context.Background(), 0)
+	suite.Equal([]int{0, 1}, session.Queue)
+	suite.Equal(0, session.Answered)
+	suite.Equal(0, session.Failed)
+	suite.Equal(http.StatusBadRequest, undo().Code, "Expected nothing left to undo")
+}
+
+func (suite *APISessionServerTestSuite) TestUndoSessionAnsweredAgain() {
+	suite.startSession(`{"deck_id":0}`)
+	suite.Equal(http.StatusOK, suite.answer("3").Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/card/0/review", strings.NewReader(`{"grade":3}`))
+	rr := httptest.NewRecorder()
+	suite.server.HandleReviewCard(rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+	answered, _ := suite.card_db.GetSingle(context.Background(), 0)
+
+	req = httptest.NewRequest(http.MethodPost, "/session/0/undo", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleUndoSession(rr, req)
+	suite.Equal(http.StatusConflict, rr.Code, "Expected an answer followed by another one not to be undone")
+
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+	suite.Equal(answered, card)
+	suite.Equal(2, len(suite.card_db.GetReviewLog()))
+	session, _ := suite.session_db.Get(context.Background(), 0)
+	suite.Equal(1, session.Answered)
+}
+
+func (suite *APISessionServerTestSuite) TestEndSessionHandler() {
+	suite.startSession(`{"deck_id":0,"mode":"cram"}`)
+	suite.Equal(http.StatusOK, suite.answer("1").Code)
+	suite.Equal(http.StatusOK, suite.answer("4").Code)
+
+	end := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		rr := httptest.NewRecorder()
+		suite.server.HandleEndSession(rr, req)
+		return rr
+	}
+
+	suite.Equal(http.StatusBadRequest, end("/session/a/end").Code)
+	suite.Equal(http.StatusBadRequest, end("/session/9/end").Code)
+
+	rr := end("/session/0/end")
+	suite.Equal(http.StatusOK, rr.Code)
+	var summary model.StudySessionSummary
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &summary))
+	suite.Equal(2, summary.Answered)
+	suite.Equal(1, summary.Failed)
+	suite.Equal(1, summary.Remaining)
+	suite.Equal([4]int{1, 0, 0, 1}, summary.Grades)
+
+	deck, _ := suite.deck_db.GetSingle(context.Background(), 0)
+	suite.Equal(summary.EndTime.Unix(), deck.LastStudyDate.Unix())
+
+	rr = end("/session/0/end")
+	suite.Equal(http.StatusOK, rr.Code)
+	var again model.StudySessionSummary
+	suite.Nil(json.Unmarshal(rr.Body.Bytes(), &again))
+	suite.Equal(summary.EndTime.Unix(), again.EndTime.Unix(), "Expected ending twice to keep the first end time")
+
+	suite.Equal(http.StatusBadRequest, suite.answer("3").Code, "Expected an ended session to reject answers")
+}
+
+func (suite *APISessionServerTestSuite) TestEndSessionConflict() {
+	suite.startSession(`{"deck_id":0}`)
+	before, _ := suite.deck_db.GetSingle(context.Background(), 0)
+
+	suite.server.WithStudySessionDB(racingSessionDB{suite.session_db})
+	req := httptest.NewRequest(http.MethodPost, "/session/0/end", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleEndSession(rr, req)
+	suite.Equal(http.StatusConflict, rr.Code)
+
+	deck, _ := suite.deck_db.GetSingle(context.Background(), 0)
+	suite.Equal(before.LastStudyDate, deck.LastStudyDate, "Expected a session not saved as ended not to mark its deck as studied")
+	session, _ := suite.session_db.Get(context.Background(), 0)
+	suite.False(session.Ended())
+}
@@ -0,0 +1,397 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"flash-learn/internal/model"
+	"flash-learn/internal/scheduler"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range of days covered by the statistics when the request doesn't give one,
+// and the longest range a request can ask for.
+const (
+	StatsDefaultDays = 30
+	StatsMaxDays     = 3660
+)
+
+// Number of days covered by a forecast when the request doesn't give one,
+// and the most days a request can ask for.
+const (
+	ForecastDefaultDays = 30
+	ForecastMaxDays     = 365
+)
+
+// ForecastHistoryDays is how far back the review history used for
+// the pass rates of a forecast goes.
+const ForecastHistoryDays = 365
+
+var errInvalidStatsRange = errors.New("invalid statistics range")
+
+// HandleGetStats handles the HTTP GET request for retrieving the study statistics
+// of all decks. The query parameters "from" and "to" give the first and last day
+// of the range as YYYY-MM-DD, by default the last 30 days, "tz" gives the IANA
+// timezone the days are taken in, by default UTC, and "rollover" gives the hour
+// at which a new day starts, by default midnight.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 400 Bad Request : If the range, the timezone or the rollover hour is invalid.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the statistics are computed and the request is successful.
+func (s *APIServer) HandleGetStats(w http.ResponseWriter, r *http.Request) {
+	s.writeStudyStats(w, r, nil)
+}
+
+// HandleGetDeckStats handles the HTTP GET request for retrieving the study statistics
+// of a deck and its sub-decks. It accepts the same query parameters as HandleGetStats.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID, the range, the timezone or the rollover hour is invalid, or the deck doesn't exist.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the statistics are computed and the request is successful.
+func (s *APIServer) HandleGetDeckStats(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	deckIDs, dbErr := s.deck_db.GetDescendantIDs(r.Context(), deckID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting deck descendants", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.writeStudyStats(w, r, deckIDs)
+}
+
+// writeStudyStats computes the statistics of the given decks, nil meaning all
+// decks, over the range of the request and writes them to the response.
+func (s *APIServer) writeStudyStats(w http.ResponseWriter, r *http.Request, deckIDs []int) {
+	calendar, from, to, err := parseStatsRange(r.URL.Query(), time.Now())
+	if err != nil {
+		slog.DebugContext(r.Context(), "Invalid statistics range", "error", err)
+		http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	reviews, dbErr := s.card_db.GetReviews(r.Context(), deckIDs, calendar.Start(from), calendar.Start(to.AddDate(0, 0, 1)))
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error getting reviews", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	levels, dbErr := s.card_db.CountByRetentionLevel(r.Context(), deckIDs)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error counting cards by retention level", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	stats := model.ComputeStudyStats(reviews, levels, calendar, from, to)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(stats)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding statistics", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "reviews", stats.Reviews)
+}
+
+// parseStatsRange parses the "from", "to", "tz" and "rollover" query parameters.
+//
+// Parameters:
+//   - query url.Values : The query parameters.
+//   - now time.Time : The current time, which gives the default end of the range.
+//
+// Returns:
+//   - model.StudyCalendar : The calendar of the requested timezone and rollover hour.
+//   - time.Time : The date of the first day of the range, at midnight.
+//   - time.Time : The date of the last day of the range, at midnight.
+//   - error : An error if a parameter is invalid or the range is too long.
+func parseStatsRange(query url.Values, now time.Time) (model.StudyCalendar, time.Time, time.Time, error) {
+	calendar, err := parseStudyCalendar(query)
+	if err != nil {
+		return calendar, time.Time{}, time.Time{}, err
+	}
+	location := calendar.Location
+
+	to := calendar.Day(now)
+	if toStr := query.Get("to"); toStr != "" {
+		if to, err = time.ParseInLocation(model.DateLayout, toStr, location); err != nil {
+			return calendar, time.Time{}, time.Time{}, err
+		}
+	}
+
+	from := to.AddDate(0, 0, 1-StatsDefaultDays)
+	if fromStr := query.Get("from"); fromStr != "" {
+		if from, err = time.ParseInLocation(model.DateLayout, fromStr, location); err != nil {
+			return calendar, time.Time{}, time.Time{}, err
+		}
+	}
+
+	if from.After(to) || from.AddDate(0, 0, StatsMaxDays).Before(to) {
+		return calendar, time.Time{}, time.Time{}, errInvalidStatsRange
+	}
+
+	return calendar, from, to, nil
+}
+
+// HandleGetDeckForecast handles the HTTP GET request for forecasting the number of
+// reviews of a deck and its sub-decks on each of the coming days. Future reviews
+// are simulated with the scheduler of the deck and the pass rates of its review
+// history. Every day is split into learning, young and mature cards so it can be
+// drawn as a stacked bar. The query parameter "days" gives the number of days,
+// by default 30, and "tz" and "rollover" give the timezone and the hour the days
+// start at, as for HandleGetStats.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID, the number of days, the timezone or the rollover hour is invalid, or the deck doesn't exist.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the forecast is computed and the request is successful.
+func (s *APIServer) HandleGetDeckForecast(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	days := ForecastDefaultDays
+	if daysStr := query.Get("days"); daysStr != "" {
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > ForecastMaxDays {
+			slog.DebugContext(r.Context(), fmt.Sprintf("Invalid forecast days %s", daysStr))
+			http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+			return
+		}
+	}
+
+	calendar, err := parseStudyCalendar(query)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Invalid study calendar", "error", err)
+		http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	deck, dbErr := s.deck_db.GetSingle(r.Context(), deckID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting single deck", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	deckIDs, dbErr := s.deck_db.GetDescendantIDs(r.Context(), deckID)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error getting deck descendants", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeDeckForecast(w, r, deckID, deckIDs, deck.Settings(), calendar, days)
+}
+
+// writeDeckForecast simulates the coming reviews of the cards in the given decks
+// and writes the forecast to the response. The simulation is seeded with the deck
+// and the day, so repeated requests on the same day get the same forecast.
+func (s *APIServer) writeDeckForecast(w http.ResponseWriter, r *http.Request, deckID int, deckIDs []int, settings model.DeckPreset, calendar model.StudyCalendar, days int) {
+	notSuspended := false
+	cards, dbErr := s.card_db.Search(r.Context(), model.CardFilter{DeckIDs: deckIDs, Suspended: &notSuspended})
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error getting cards", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	reviews, dbErr := s.card_db.GetReviews(r.Context(), deckIDs, now.AddDate(0, 0, -ForecastHistoryDays), now)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error getting reviews", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(deckID), uint64(calendar.Day(now).Unix())))
+	passRates := model.ComputePassRates(reviews)
+	forecast := model.Forecast{
+		Timezone:  calendar.Location.String(),
+		Series:    model.ForecastSeries,
+		PassRates: passRates,
+		Days:      scheduler.Forecast(cards, settings, passRates, calendar, now, days, rng),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(forecast)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding forecast", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "forecast days", days)
+}
+
+// HandleGetHeatmap handles the HTTP GET request for retrieving the study activity
+// of all decks on every day of a year, with the current and longest streaks.
+// The query parameter "year" gives the year, by default the current one, and
+// "tz" and "rollover" give the timezone and the hour the days start at, as for
+// HandleGetStats.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 400 Bad Request : If the year, the timezone or the rollover hour is invalid.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the heatmap is computed and the request is successful.
+func (s *APIServer) HandleGetHeatmap(w http.ResponseWriter, r *http.Request) {
+	s.writeHeatmap(w, r, nil)
+}
+
+// HandleGetDeckHeatmap handles the HTTP GET request for retrieving the study activity
+// of a deck and its sub-decks on every day of a year. It accepts the same query
+// parameters as HandleGetHeatmap.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the deck ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the deck ID, the year, the timezone or the rollover hour is invalid, or the deck doesn't exist.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the heatmap is computed and the request is successful.
+func (s *APIServer) HandleGetDeckHeatmap(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Split(r.URL.Path, "/")[2]
+	deckID, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.DebugContext(r.Context(), fmt.Sprintf("Invalid deck ID %s", idStr))
+		http.Error(w, InvalidDeckIDErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	deckIDs, dbErr := s.deck_db.GetDescendantIDs(r.Context(), deckID)
+	if dbErr != nil {
+		if dbErr == utils.ErrRecordNotExist {
+			slog.DebugContext(r.Context(), "Deck not found", "error", dbErr)
+			http.Error(w, GetSingleDeckNotFoundErrorMessage, http.StatusBadRequest)
+		} else {
+			slog.DebugContext(r.Context(), "Error getting deck descendants", "error", dbErr)
+			http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.writeHeatmap(w, r, deckIDs)
+}
+
+// writeHeatmap computes the heatmap of the given decks, nil meaning all decks,
+// for the year of the request and writes it to the response.
+func (s *APIServer) writeHeatmap(w http.ResponseWriter, r *http.Request, deckIDs []int) {
+	query := r.URL.Query()
+	calendar, err := parseStudyCalendar(query)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Invalid study calendar", "error", err)
+		http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	year := calendar.Day(now).Year()
+	if yearStr := query.Get("year"); yearStr != "" {
+		year, err = strconv.Atoi(yearStr)
+		if err != nil || year < 1 || year > 9999 {
+			slog.DebugContext(r.Context(), fmt.Sprintf("Invalid year %s", yearStr))
+			http.Error(w, InvalidQueryErrorMessage, http.StatusBadRequest)
+			return
+		}
+	}
+
+	from := calendar.Start(time.Date(year, time.January, 1, 0, 0, 0, 0, calendar.Location))
+	to := calendar.Start(time.Date(year+1, time.January, 1, 0, 0, 0, 0, calendar.Location))
+	reviews, dbErr := s.card_db.GetReviews(r.Context(), deckIDs, from, to)
+	if dbErr != nil {
+		slog.DebugContext(r.Context(), "Error getting reviews", "error", dbErr)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	heatmap := model.ComputeHeatmap(reviews, calendar, year, now)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(heatmap)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding heatmap", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "heatmap year", year)
+}
+
+// parseStudyCalendar parses the "tz" and "rollover" query parameters, which
+// give the IANA timezone of the study days and the hour at which they start.
+//
+// Parameters:
+//   - query url.Values : The query parameters.
+//
+// Returns:
+//   - model.StudyCalendar : The calendar, in UTC with days starting at midnight by default.
+//   - error : An error if a parameter is invalid.
+func parseStudyCalendar(query url.Values) (model.StudyCalendar, error) {
+	location, err := time.LoadLocation(query.Get("tz"))
+	if err != nil {
+		return model.StudyCalendar{}, err
+	}
+	calendar := model.StudyCalendar{Location: location, RolloverHour: model.MinRolloverHour}
+
+	if rolloverStr := query.Get("rollover"); rolloverStr != "" {
+		calendar.RolloverHour, err = strconv.Atoi(rolloverStr)
+		if err != nil {
+			return calendar, err
+		} else if calendar.RolloverHour < model.MinRolloverHour || calendar.RolloverHour > model.MaxRolloverHour {
+			return calendar, errInvalidStatsRange
+		}
+	}
+
+	return calendar, nil
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIStatsServerTestSuite struct {
+	suite.Suite
+	deck_db *database.DeckDBWrapperMock
+	card_db *database.CardDBWrapperMock
+	server  *APIServer
+}
+
+func (suite *APIStatsServerTestSuite) SetupTest() {
+	suite.deck_db = database.NewDeckDBWrapperMock()
+	suite.card_db = database.NewCardDBWrapperMock()
+	suite.server = NewAPIServer("localhost:8080", suite.deck_db, suite.card_db)
+
+	suite.deck_db.CreateTable()
+	suite.card_db.CreateTable()
+	for deckID := 0; deckID < 2; deckID++ {
+		suite.deck_db.Insert(context.Background(), model.Deck{Name: "Test deck"})
+		suite.card_db.InsertDeck(deckID)
+	}
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content", ""))
+	card, _ := suite.card_db.GetSingle(context.Background(), 0)
+
+	for _, day := range []int{1, 2, 2} {
+		review := model.Review{
+			CardID:     0,
+			Grade:      model.GradeGood,
+			Kind:       model.ReviewKindReview,
+			Duration:   1500,
+			ReviewTime: time.Date(2024, 3, day, 12, 0, 0, 0, time.UTC),
+		}
+		suite.card_db.RecordReview(context.Background(), model.NewCardAnswer(card, card), review)
+	}
+}
+
+func (suite *APIStatsServerTestSuite) TearDownTest() {
+	suite.server = nil
+}
+
+func TestAPIStatsServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APIStatsServerTestSuite))
+}
+
+func (suite *APIStatsServerTestSuite) TestStatsHandlers() {
+	testCases := []struct {
+		name            string
+		path            string
+		query           url.Values
+		expectedStatus  int
+		expectedReviews int
+		expectedDays    int
+	}{
+		{
+			name:           "Bad Request (Invalid timezone)",
+			path:           "/stats",
+			query:          url.Values{"tz": {"Mars/Olympus"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bad Request (Invalid date)",
+			path:           "/stats",
+			query:          url.Values{"from": {"03/01/2024"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bad Request (Reversed range)",
+			path:           "/stats",
+			query:          url.Values{"from": {"2024-03-05"}, "to": {"2024-03-01"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bad Request (Range too long)",
+			path:           "/stats",
+			query:          url.Values{"from": {"2000-01-01"}, "to": {"2024-03-01"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bad Request (Deck doesn't exist)",
+			path:           "/deck/9/stats",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:            "Valid request (All decks)",
+			path:            "/stats",
+			query:           url.Values{"from": {"2024-03-01"}, "to": {"2024-03-07"}},
+			expectedStatus:  http.StatusOK,
+			expectedReviews: 3,
+			expectedDays:    7,
+		},
+		{
+			name:            "Valid request (Timezone)",
+			path:            "/stats",
+			query:           url.Values{"from": {"2024-03-02"}, "to": {"2024-03-02"}, "tz": {"Pacific/Kiritimati"}},
+			expectedStatus:  http.StatusOK,
+			expectedReviews: 1,
+			expectedDays:    1,
+		},
+		{
+			name:            "Valid request (Deck)",
+			path:            "/deck/0/stats",
+			query:           url.Values{"from": {"2024-03-01"}, "to": {"2024-03-02"}},
+			expectedStatus:  http.StatusOK,
+			expectedReviews: 3,
+			expectedDays:    2,
+		},
+		{
+			name:            "Valid request (Deck without reviews)",
+			path:            "/deck/1/stats",
+			query:           url.Values{"from": {"2024-03-01"}, "to": {"2024-03-02"}},
+			expectedStatus:  http.StatusOK,
+			expectedReviews: 0,
+			expectedDays:    2,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path+"?"+tc.query.Encode(), nil)
+			rr := httptest.NewRecorder()
+			if tc.path == "/stats" {
+				suite.server.HandleGetStats(rr, req)
+			} else {
+				suite.server.HandleGetDeckStats(rr, req)
+			}
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var stats model.StudyStats
+				assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+				assert.Equal(t, tc.expectedReviews, stats.Reviews)
+				assert.Equal(t, tc.expectedDays, len(stats.Days))
+			}
+		})
+	}
+}
+
+func (suite *APIStatsServerTestSuite) TestDeckForecastHandler() {
+	card := model.NewCard(0, "Test content", "")
+	card.ID, _ = suite.card_db.Insert(context.Background(), card)
+	card.State = model.CardStateReview
+	card.Interval = 30
+	card.NextReviewTime = time.Now().AddDate(0, 0, -1)
+	suite.card_db.RecordReview(context.Background(), model.NewCardAnswer(card, card), model.Review{CardID: card.ID, Grade: model.GradeGood, Kind: model.ReviewKindReview, ReviewTime: time.Now()})
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedDays   int
+		expectedTotal  int
+	}{
+		{name: "Bad Request (Deck ID not number)", path: "/deck/a/forecast", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Deck doesn't exist)", path: "/deck/9/forecast", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Days not number)", path: "/deck/0/forecast?days=a", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Too many days)", path: "/deck/0/forecast?days=366", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Invalid timezone)", path: "/deck/0/forecast?tz=Nowhere", expectedStatus: http.StatusBadRequest},
+		{name: "Valid request (Default days)", path: "/deck/0/forecast", expectedStatus: http.StatusOK, expectedDays: ForecastDefaultDays, expectedTotal: 1},
+		{name: "Valid request (Days)", path: "/deck/0/forecast?days=7&tz=Asia/Tokyo", expectedStatus: http.StatusOK, expectedDays: 7, expectedTotal: 1},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleGetDeckForecast(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var forecast model.Forecast
+				assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &forecast))
+				assert.Equal(t, tc.expectedDays, len(forecast.Days))
+				assert.Equal(t, model.ForecastSeries, forecast.Series)
+				assert.Equal(t, tc.expectedTotal, forecast.Days[0].Total, "Expected the overdue card to be due today")
+				assert.Equal(t, 1.0, forecast.PassRates[model.ReviewKindReview])
+			}
+		})
+	}
+}
+
+func (suite *APIStatsServerTestSuite) TestHeatmapHandlers() {
+	testCases := []struct {
+		name            string
+		path            string
+		expectedStatus  int
+		expectedReviews int
+		expectedDays    int
+	}{
+		{name: "Bad Request (Year not number)", path: "/stats/heatmap?year=a", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Year out of range)", path: "/stats/heatmap?year=0", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Invalid timezone)", path: "/stats/heatmap?tz=Nowhere", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Rollover not number)", path: "/stats/heatmap?rollover=a", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Rollover out of range)", path: "/stats/heatmap?rollover=24", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Deck ID not number)", path: "/deck/a/heatmap", expectedStatus: http.StatusBadRequest},
+		{name: "Bad Request (Deck doesn't exist)", path: "/deck/9/heatmap", expectedStatus: http.StatusBadRequest},
+		{name: "Valid request (All decks)", path: "/stats/heatmap?year=2024", expectedStatus: http.StatusOK, expectedReviews: 3, expectedDays: 366},
+		{name: "Valid request (Other year)", path: "/stats/heatmap?year=2023&rollover=4", expectedStatus: http.StatusOK, expectedReviews: 0, expectedDays: 365},
+		{name: "Valid request (Deck)", path: "/deck/0/heatmap?year=2024&tz=Europe/Paris", expectedStatus: http.StatusOK, expectedReviews: 3, expectedDays: 366},
+		{name: "Valid request (Deck without reviews)", path: "/deck/1/heatmap?year=2024", expectedStatus: http.StatusOK, expectedReviews: 0, expectedDays: 366},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			if tc.path[:6] == "/stats" {
+				suite.server.HandleGetHeatmap(rr, req)
+			} else {
+				suite.server.HandleGetDeckHeatmap(rr, req)
+			}
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var heatmap model.Heatmap
+				assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &heatmap))
+				assert.Equal(t, tc.expectedReviews, heatmap.Reviews)
+				assert.Equal(t, tc.expectedDays, len(heatmap.Days))
+			}
+		})
+	}
+}
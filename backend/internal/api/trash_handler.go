@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// HandleGetTrash handles the HTTP GET request for listing the cards in the trash.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the trash is listed and the request is successful.
+func (s *APIServer) HandleGetTrash(w http.ResponseWriter, r *http.Request) {
+	cards, err := s.card_db.GetTrash(r.Context())
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error getting card trash", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(cards)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card trash", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "trashed cards", len(cards))
+}
+
+// HandleRestoreCard handles the HTTP POST request for restoring a card from the trash.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request containing the card ID in the URL path.
+//
+// Errors:
+//   - 400 Bad Request : If the card ID is invalid or the card is not in the trash.
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the card is restored and the request is successful.
+func (s *APIServer) HandleRestoreCard(w http.ResponseWriter, r *http.Request) {
+	cardID, ok := parseCardID(w, r)
+	if !ok {
+		return
+	}
+
+	dbErr := s.card_db.Restore(r.Context(), cardID)
+	if dbErr != nil {
+		writeCardError(w, r, dbErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]int{"id": cardID})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding card ID", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "card ID", cardID)
+}
+
+// HandleEmptyTrash handles the HTTP DELETE request for permanently deleting every card in the trash.
+//
+// Parameters:
+//   - w http.ResponseWriter : The response writer to send the response.
+//   - r *http.Request : The HTTP request.
+//
+// Errors:
+//   - 500 Internal Server Error : If there is an error while processing the request.
+//   - 200 OK : If the trash is emptied and the request is successful.
+func (s *APIServer) HandleEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	deleted, err := s.card_db.EmptyTrash(r.Context())
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error emptying card trash", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int{"deleted": deleted})
+	if err != nil {
+		slog.DebugContext(r.Context(), "Error encoding deleted count", "error", err)
+		http.Error(w, InternalServerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	slog.DebugContext(r.Context(), "Sent response", "deleted cards", deleted)
+}
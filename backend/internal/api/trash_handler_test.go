@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APITrashServerTestSuite struct {
+	suite.Suite
+	card_db *database.CardDBWrapperMock
+	server  *APIServer
+}
+
+func (suite *APITrashServerTestSuite) SetupTest() {
+	suite.card_db = database.NewCardDBWrapperMock()
+	suite.server = NewAPIServer("localhost:8080", database.NewDeckDBWrapperMock(), suite.card_db)
+
+	suite.card_db.CreateTable()
+	suite.card_db.InsertDeck(0)
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content #1", "Test source 1"))
+	suite.card_db.Insert(context.Background(), model.NewCard(0, "Test content #2", "Test source 2"))
+}
+
+func TestAPITrashServerTestSuite(t *testing.T) {
+	suite.Run(t, new(APITrashServerTestSuite))
+}
+
+func (suite *APITrashServerTestSuite) TestDeleteCardHandler() {
+	testCases := []struct {
+		name           string
+		cardID         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Bad Request (Card ID not number)",
+			cardID:         "a",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidCardIDErrorMessage + "\n",
+		},
+		{
+			name:           "Bad Request (Card doesn't exist)",
+			cardID:         "5",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidCardIDErrorMessage + "\n",
+		},
+		{
+			name:           "Valid request",
+			cardID:         "1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{\"id\":1}\n",
+		},
+		{
+			name:           "Bad Request (Card already in trash)",
+			cardID:         "1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   InvalidCardIDErrorMessage + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/card/"+tc.cardID, nil)
+			rr := httptest.NewRecorder()
+			suite.server.HandleDeleteCard(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	total, _ := suite.card_db.GetTotalCards(context.Background(), 0)
+	assert.Equal(suite.T(), 1, total, "Expected trashed card not to be counted")
+}
+
+func (suite *APITrashServerTestSuite) TestTrashHandlers() {
+	suite.card_db.Delete(context.Background(), 0)
+	suite.card_db.Delete(context.Background(), 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/trash", nil)
+	rr := httptest.NewRecorder()
+	suite.server.HandleGetTrash(rr, req)
+
+	var trash []model.Card
+	err := json.NewDecoder(rr.Body).Decode(&trash)
+	assert.Nil(suite.T(), err, "Expected response body to be a JSON array")
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), 2, len(trash))
+	assert.NotNil(suite.T(), trash[0].DeletedAt, "Expected trashed card to have a deletion time")
+
+	req = httptest.NewRequest(http.MethodPost, "/trash/0/restore", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleRestoreCard(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), "{\"id\":0}\n", rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/trash/0/restore", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleRestoreCard(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, "Expected restoring a card outside the trash to fail")
+
+	req = httptest.NewRequest(http.MethodDelete, "/trash", nil)
+	rr = httptest.NewRecorder()
+	suite.server.HandleEmptyTrash(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), "{\"deleted\":1}\n", rr.Body.String())
+
+	total, _ := suite.card_db.GetTotalCards(context.Background(), 0)
+	assert.Equal(suite.T(), 1, total, "Expected restored card to be kept")
+}
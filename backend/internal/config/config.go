@@ -0,0 +1,427 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables read by Load.
+const EnvPrefix = "FLASHLEARN_"
+
+// legacyEnvFileName is the file the Postgres settings used to be read from.
+// It is still loaded when present, and its POSTGRES_* variables are used to
+// build the DSN if none is configured.
+const legacyEnvFileName = "postgres.env"
+
+// ErrInvalidConfig is returned, wrapped with the offending setting, when the
+// configuration can't be loaded or doesn't validate.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Config holds the settings of the server. They are layered, each layer
+// overriding the one before: the defaults, then a YAML file, then the
+// environment variables, then the command line flags.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+// ServerConfig holds the settings of the HTTP server. A CORS origin is an
+// exact origin, a wildcard origin such as "https://*.example.com" matching
+// its subdomains, or "*" matching every origin, which can't be combined
+// with credentials.
+//
+// RequestTimeout and MaxBodyBytes limit every request, zero meaning no limit,
+// and Routes overrides them for the routes keyed by their pattern, such as
+// "POST /card/bulk".
+type ServerConfig struct {
+	Address              string                 `yaml:"address"`
+	CORSOrigins          []string               `yaml:"cors_origins"`
+	CORSAllowCredentials bool                   `yaml:"cors_allow_credentials"`
+	ReadHeaderTimeout    time.Duration          `yaml:"read_header_timeout"`
+	ReadTimeout          time.Duration          `yaml:"read_timeout"`
+	WriteTimeout         time.Duration          `yaml:"write_timeout"`
+	IdleTimeout          time.Duration          `yaml:"idle_timeout"`
+	ShutdownTimeout      time.Duration          `yaml:"shutdown_timeout"`
+	RequestTimeout       time.Duration          `yaml:"request_timeout"`
+	MaxBodyBytes         int64                  `yaml:"max_body_bytes"`
+	Routes               map[string]RouteConfig `yaml:"routes"`
+}
+
+// RouteConfig holds the limits of a single route. A zero value keeps the
+// limit the route has without it.
+type RouteConfig struct {
+	Timeout      time.Duration `yaml:"timeout"`
+	MaxBodyBytes int64         `yaml:"max_body_bytes"`
+}
+
+// DatabaseConfig holds the settings of the Postgres connection pool.
+// A zero MaxOpenConns means no limit, and zero durations mean connections
+// are reused forever.
+type DatabaseConfig struct {
+	DSN             string        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// LogConfig holds the settings of the logger.
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+// Default returns the configuration used for the settings that aren't set
+// by any other layer, suited for local development.
+//
+// Returns:
+//   - Config : The default configuration.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:           "localhost:8080",
+			CORSOrigins:       []string{"http://localhost:5173"},
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			RequestTimeout:    30 * time.Second,
+			MaxBodyBytes:      1 << 20,
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+}
+
+// A setting that can be set from an environment variable and a flag,
+// both given as a string.
+type setting struct {
+	name  string
+	usage string
+	apply func(config *Config, value string) error
+}
+
+// settings are the settings that can be overridden from the environment and
+// the command line. The variable of a setting is its name in upper case,
+// with underscores instead of dashes and EnvPrefix in front, so that the
+// "database-dsn" flag is FLASHLEARN_DATABASE_DSN.
+var settings = []setting{
+	{"address", "address the server listens on", func(c *Config, v string) error {
+		c.Server.Address = v
+		return nil
+	}},
+	{"cors-origins", "comma-separated origins allowed to call the API", func(c *Config, v string) error {
+		c.Server.CORSOrigins = splitList(v)
+		return nil
+	}},
+	{"cors-allow-credentials", "whether the allowed origins may send cookies and credentials", func(c *Config, v string) error {
+		allow, err := strconv.ParseBool(v)
+		c.Server.CORSAllowCredentials = allow
+		return err
+	}},
+	{"read-header-timeout", "time allowed to read the headers of a request", durationSetting(func(c *Config) *time.Duration { return &c.Server.ReadHeaderTimeout })},
+	{"read-timeout", "time allowed to read a whole request", durationSetting(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"write-timeout", "time allowed to write a response", durationSetting(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"idle-timeout", "time an idle keep-alive connection is kept open", durationSetting(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"shutdown-timeout", "time the requests in flight are given on shutdown", durationSetting(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"request-timeout", "time a request may take before it is answered with a 503, 0 for no limit", durationSetting(func(c *Config) *time.Duration { return &c.Server.RequestTimeout })},
+	{"max-body-bytes", "maximum size of a request body, 0 for no limit", intSetting(func(c *Config) *int64 { return &c.Server.MaxBodyBytes })},
+	{"database-dsn", "Postgres connection string", func(c *Config, v string) error {
+		c.Database.DSN = v
+		return nil
+	}},
+	{"database-max-open-conns", "maximum number of open connections, 0 for no limit", intSetting(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"database-max-idle-conns", "maximum number of idle connections", intSetting(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"database-conn-max-lifetime", "time after which a connection is replaced, 0 for never", durationSetting(func(c *Config) *time.Duration { return &c.Database.ConnMaxLifetime })},
+	{"database-conn-max-idle-time", "time after which an idle connection is closed, 0 for never", durationSetting(func(c *Config) *time.Duration { return &c.Database.ConnMaxIdleTime })},
+	{"log-level", "minimum level of the logs: debug, info, warn or error", func(c *Config, v string) error {
+		c.Log.Level = v
+		return nil
+	}},
+}
+
+// Load builds the configuration from its layers and validates it. The YAML
+// file is the one given by the -config flag, or else by the FLASHLEARN_CONFIG
+// variable, and is optional.
+//
+// Parameters:
+//   - args []string : The command line arguments, without the program name.
+//   - lookupEnv func(string) (string, bool) : Looks up an environment variable, os.LookupEnv outside tests.
+//
+// Returns:
+//   - Config : The validated configuration.
+//   - error : flag.ErrHelp if the usage was asked for, an error wrapping
+//     ErrInvalidConfig if a layer can't be read or the result is invalid,
+//     nil otherwise.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	flags := flag.NewFlagSet("flash-learn", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path of a YAML configuration file")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		name := s.name
+		flags.Func(name, s.usage, func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return Config{}, err
+		}
+		return Config{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	config := Default()
+
+	if *configPath == "" {
+		*configPath, _ = lookupEnv(EnvPrefix + "CONFIG")
+	}
+	if *configPath != "" {
+		if err := config.loadFile(*configPath); err != nil {
+			return Config{}, err
+		}
+	}
+
+	// The flag values hold only the flags given on the command line
+	for _, s := range settings {
+		if value, ok := lookupEnv(envName(s.name)); ok {
+			if err := s.apply(&config, value); err != nil {
+				return Config{}, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, envName(s.name), err)
+			}
+		}
+		if value, ok := flagValues[s.name]; ok {
+			if err := s.apply(&config, value); err != nil {
+				return Config{}, fmt.Errorf("%w: -%s: %v", ErrInvalidConfig, s.name, err)
+			}
+		}
+	}
+
+	if config.Database.DSN == "" {
+		config.Database.DSN = legacyDSN(lookupEnv)
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate checks that every setting has a usable value.
+//
+// Returns:
+//   - error : An error wrapping ErrInvalidConfig for every invalid setting, nil otherwise.
+func (c Config) Validate() error {
+	errs := []error{}
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+		invalid("server address %q: %v", c.Server.Address, err)
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		if origin == "*" {
+			if c.Server.CORSAllowCredentials {
+				invalid("CORS origin \"*\" can't be combined with credentials")
+			}
+			continue
+		}
+
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" || parsed.User != nil {
+			invalid("CORS origin %q must be a scheme and a host", origin)
+			continue
+		}
+		if wildcard := strings.Count(parsed.Host, "*"); wildcard > 1 || (wildcard == 1 && !strings.HasPrefix(parsed.Host, "*.")) {
+			invalid("CORS origin %q may only start its host with \"*.\"", origin)
+		}
+	}
+
+	timeouts := []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"read header timeout", c.Server.ReadHeaderTimeout},
+		{"read timeout", c.Server.ReadTimeout},
+		{"write timeout", c.Server.WriteTimeout},
+		{"idle timeout", c.Server.IdleTimeout},
+		{"request timeout", c.Server.RequestTimeout},
+		{"database connection lifetime", c.Database.ConnMaxLifetime},
+		{"database connection idle time", c.Database.ConnMaxIdleTime},
+	}
+	for _, t := range timeouts {
+		if t.timeout < 0 {
+			invalid("%s %s is negative", t.name, t.timeout)
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		invalid("shutdown timeout %s must be positive", c.Server.ShutdownTimeout)
+	}
+	if c.Server.MaxBodyBytes < 0 {
+		invalid("max body bytes %d is negative", c.Server.MaxBodyBytes)
+	}
+	for _, pattern := range slices.Sorted(maps.Keys(c.Server.Routes)) {
+		route := c.Server.Routes[pattern]
+		method, path, found := strings.Cut(pattern, " ")
+		if !found || method == "" || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			invalid("route %q must be a method and a path, such as \"POST /card/bulk\"", pattern)
+		}
+		if route.Timeout < 0 {
+			invalid("route %q timeout %s is negative", pattern, route.Timeout)
+		}
+		if route.MaxBodyBytes < 0 {
+			invalid("route %q max body bytes %d is negative", pattern, route.MaxBodyBytes)
+		}
+	}
+
+	if c.Database.DSN == "" {
+		invalid("database DSN is missing")
+	} else if strings.Contains(c.Database.DSN, "://") {
+		parsed, err := url.Parse(c.Database.DSN)
+		if err != nil || (parsed.Scheme != "postgres" && parsed.Scheme != "postgresql") {
+			invalid("database DSN must be a postgres:// URL or key=value pairs")
+		}
+	}
+	if c.Database.MaxOpenConns < 0 {
+		invalid("database max open connections %d is negative", c.Database.MaxOpenConns)
+	}
+	if c.Database.MaxIdleConns < 0 {
+		invalid("database max idle connections %d is negative", c.Database.MaxIdleConns)
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		invalid("database max idle connections %d exceed the max open connections %d", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+
+	if _, err := c.Log.SlogLevel(); err != nil {
+		invalid("log level %q: %v", c.Log.Level, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// SlogLevel returns the level of the logger.
+//
+// Returns:
+//   - slog.Level : The minimum level logged.
+//   - error : An error if the level isn't debug, info, warn or error, nil otherwise.
+func (c LogConfig) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.Level))
+	return level, err
+}
+
+// A helper function that overrides the configuration with a YAML file, in
+// which unknown keys are rejected to catch typos.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+	}
+
+	return nil
+}
+
+// A helper function that builds a DSN from the POSTGRES_* variables of the
+// environment or of the legacy env file, so that deployments configured
+// before the config package keep working.
+func legacyDSN(lookupEnv func(string) (string, bool)) string {
+	fileValues, err := godotenv.Read(legacyEnvFileName)
+	if err != nil {
+		fileValues = map[string]string{}
+	}
+	get := func(name string) string {
+		if value, ok := lookupEnv(name); ok {
+			return value
+		}
+		return fileValues[name]
+	}
+
+	host := get("POSTGRES_HOST")
+	if host == "" {
+		return ""
+	}
+
+	slog.Warn("Using the deprecated POSTGRES_* settings, set " + envName("database-dsn") + " instead")
+	if port := get("POSTGRES_PORT"); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(get("POSTGRES_USER"), get("POSTGRES_PASSWORD")),
+		Host:   host,
+		Path:   "/" + get("POSTGRES_DB"),
+	}
+	if sslMode := get("POSTGRES_SSL_MODE"); sslMode != "" {
+		dsn.RawQuery = "sslmode=" + url.QueryEscape(sslMode)
+	}
+	return dsn.String()
+}
+
+// A helper function that returns the environment variable of a setting.
+func envName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// A helper function that splits a comma-separated list, dropping empty entries.
+func splitList(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// A helper function that returns the apply function of a duration setting.
+func durationSetting(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// A helper function that returns the apply function of an integer setting.
+func intSetting[T int | int64](field func(*Config) *T) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = T(n)
+		return nil
+	}
+}
@@ -0,0 +1,268 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Retrieves the cards matching a filter, ordered by ID. Trashed cards are never matched.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - filter model.CardFilter : The criteria the cards must match.
+//
+// Returns:
+//   - []model.Card : The matching cards.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *CardDBWrapper) Search(ctx context.Context, filter model.CardFilter) ([]model.Card, error) {
+	defer observeQuery("card", "Search", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query, args := wrapper.buildSearchQueryString(filter)
+	slog.DebugContext(ctx, fmt.Sprintf("Searching cards: %s", query))
+
+	rows, err := wrapper.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error searching cards: %s", err))
+		return nil, err
+	}
+
+	return scanCards(ctx, rows)
+}
+
+// Helper function that constructs the SQL query string and its arguments
+// to search cards with a filter.
+//
+// Parameters:
+//   - filter model.CardFilter : The criteria the cards must match.
+//
+// Returns:
+//   - string : The SQL query string.
+//   - []any : The query arguments.
+func (wrapper *CardDBWrapper) buildSearchQueryString(filter model.CardFilter) (string, []any) {
+	var sb strings.Builder
+	args := []any{}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	sb.WriteString("SELECT ")
+	sb.WriteString(cardSelectColumns())
+	sb.WriteString(" FROM ")
+	sb.WriteString(cardTableName)
+	sb.WriteString(" WHERE ")
+	sb.WriteString(cardFilterCondition(filter, arg))
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s ASC", cardColumnID))
+
+	if filter.Limit > 0 {
+		sb.WriteString(fmt.Sprintf(" LIMIT %s", arg(filter.Limit)))
+	}
+
+	return sb.String(), args
+}
+
+// Helper function that constructs the SQL condition matching the cards of a
+// filter, except its limit. Trashed cards and the cards of trashed decks are
+// never matched.
+//
+// Parameters:
+//   - filter model.CardFilter : The criteria the cards must match.
+//   - arg func(any) string : Binds a query argument and returns its placeholder.
+//
+// Returns:
+//   - string : The SQL condition.
+func cardFilterCondition(filter model.CardFilter, arg func(any) string) string {
+	var sb strings.Builder
+	sb.WriteString(cardNotTrashedCondition(""))
+
+	if filter.DeckIDs != nil {
+		sb.WriteString(fmt.Sprintf(" AND %s = ANY(%s)", cardColumnDeckID, arg(pq.Array(filter.DeckIDs))))
+	}
+	if tags := model.NormalizeTags(filter.Tags); len(tags) > 0 {
+		sb.WriteString(fmt.Sprintf(" AND %s @> %s", cardColumnTags, arg(pq.Array(tags))))
+	}
+	if filter.Flag != nil {
+		sb.WriteString(fmt.Sprintf(" AND %s = %s", cardColumnFlag, arg(*filter.Flag)))
+	}
+	if filter.State != nil {
+		sb.WriteString(fmt.Sprintf(" AND %s = %s", cardColumnState, arg(*filter.State)))
+	}
+	if filter.Suspended != nil {
+		sb.WriteString(fmt.Sprintf(" AND %s = %s", cardColumnSuspended, arg(*filter.Suspended)))
+	}
+	if filter.Text != "" {
+		sb.WriteString(fmt.Sprintf(" AND %s ILIKE '%%' || %s || '%%' ESCAPE '\\'", cardColumnContent, arg(escapeLike(filter.Text))))
+	}
+	if filter.AddedDays > 0 {
+		sb.WriteString(fmt.Sprintf(" AND %s >= %s", cardColumnCreationTime, arg(time.Now().UTC().AddDate(0, 0, -filter.AddedDays))))
+	}
+	if filter.ForgottenDays > 0 {
+		sb.WriteString(fmt.Sprintf(" AND EXISTS (SELECT 1 FROM %s r WHERE r.%s = %s.%s AND r.%s = '%s' AND r.%s = %d AND r.%s >= %s)",
+			reviewTableName, reviewColumnCardID, cardTableName, cardColumnID, reviewColumnKind, model.ReviewKindReview,
+			reviewColumnGrade, model.GradeAgain, reviewColumnReviewTime, arg(time.Now().UTC().AddDate(0, 0, -filter.ForgottenDays))))
+	}
+
+	return sb.String()
+}
+
+// likeEscaper prefixes the escape character and the wildcards of LIKE with a backslash.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// A helper function that escapes the wildcards of a LIKE pattern, so that a
+// text is matched as it is. The escape character is the backslash, which the
+// pattern must declare with ESCAPE '\'.
+//
+// Parameters:
+//   - text string : The text to match.
+//
+// Returns:
+//   - string : The text with its backslashes, percent signs and underscores escaped.
+func escapeLike(text string) string {
+	return likeEscaper.Replace(text)
+}
+
+// Applies an operation to many cards in a single transaction. Cards that
+// don't exist or are in the trash are skipped.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardIDs []int : The unique IDs of the cards.
+//   - op model.BulkOperation : The operation to apply.
+//
+// Returns:
+//   - []int : The IDs of the cards that were changed.
+//   - error : utils.ErrInvalidBulkOperation if the operation is invalid, utils.ErrDeckNotExist
+//     if the deck to move the cards to doesn't exist, nil on success.
+func (wrapper *CardDBWrapper) ApplyBulk(ctx context.Context, cardIDs []int, op model.BulkOperation) ([]int, error) {
+	defer observeQuery("card", "ApplyBulk", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	if err := op.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error starting transaction: %s", err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if op.Type == model.BulkMove {
+		var exists bool
+		query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1 AND %s IS NULL)",
+			deckTableName, deckColumnID, deckColumnDeletedAt)
+		if err = tx.QueryRowContext(ctx, query, op.DeckID).Scan(&exists); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Error checking target deck: %s", err))
+			return nil, err
+		} else if !exists {
+			return nil, utils.ErrDeckNotExist
+		}
+	}
+
+	set, value := wrapper.buildBulkSetClause(op)
+	query := fmt.Sprintf("UPDATE %s SET %s, %s = %s WHERE %s = ANY($1) AND %s RETURNING %s",
+		cardTableName, set, cardColumnModificationTime, sqlNowUTC, cardColumnID, cardNotTrashedCondition(""), cardColumnID)
+	slog.DebugContext(ctx, fmt.Sprintf("Applying bulk operation: %s", query))
+
+	args := []any{pq.Array(cardIDs)}
+	if value != nil {
+		args = append(args, value)
+	}
+
+	changed, err := queryCardIDs(ctx, tx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error applying bulk operation: %s", err))
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error committing bulk operation: %s", err))
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// Helper function that constructs the SET clause of a bulk operation.
+// The clause may reference the operation parameter as $2.
+//
+// Parameters:
+//   - op model.BulkOperation : A validated operation.
+//
+// Returns:
+//   - string : The SET clause.
+//   - any : The value bound to $2, nil if the clause has no parameter.
+func (wrapper *CardDBWrapper) buildBulkSetClause(op model.BulkOperation) (string, any) {
+	switch op.Type {
+	case model.BulkMove:
+		return fmt.Sprintf("%s = $2", cardColumnDeckID), op.DeckID
+	case model.BulkAddTags:
+		return fmt.Sprintf("%s = ARRAY(SELECT t FROM unnest(%s || $2::TEXT[]) WITH ORDINALITY AS x(t, n) GROUP BY t ORDER BY MIN(n))",
+			cardColumnTags, cardColumnTags), pq.Array(model.NormalizeTags(op.Tags))
+	case model.BulkRemoveTags:
+		return fmt.Sprintf("%s = ARRAY(SELECT t FROM unnest(%s) WITH ORDINALITY AS x(t, n) WHERE t <> ALL($2::TEXT[]) ORDER BY n)",
+			cardColumnTags, cardColumnTags), pq.Array(model.NormalizeTags(op.Tags))
+	case model.BulkSetFlag:
+		return fmt.Sprintf("%s = $2", cardColumnFlag), op.Flag
+	case model.BulkDelete:
+		return fmt.Sprintf("%s = %s", cardColumnDeletedAt, sqlNowUTC), nil
+	case model.BulkSuspend:
+		return fmt.Sprintf("%s = TRUE", cardColumnSuspended), nil
+	case model.BulkResetScheduling:
+		return fmt.Sprintf("%s = %d, %s = %d, %s = %s, %s = 0, %s = 0, %s = %g, %s = 0, %s = 0, %s = NULL",
+			cardColumnState, model.CardStateNew, cardColumnRetentionLevel, cardMinRetentionLevel, cardColumnNextReviewTime, sqlNowUTC,
+			cardColumnStep, cardColumnInterval, cardColumnEase, model.CardDefaultEase, cardColumnStability,
+			cardColumnDifficulty, cardColumnLastReviewTime), nil
+	default:
+		return fmt.Sprintf("%s = $2, %s = CASE WHEN %s = %d THEN %d ELSE %s END",
+			cardColumnNextReviewTime, cardColumnState, cardColumnState, model.CardStateNew, model.CardStateReview, cardColumnState), op.Due.UTC()
+	}
+}
+
+// A helper function that runs a query returning a single column of card IDs.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - tx *sql.Tx : The transaction to run the query in.
+//   - query string : The SQL query string.
+//   - args ...any : The query arguments.
+//
+// Returns:
+//   - []int : The returned IDs, never nil.
+//   - error : An error if the query or a scan fails, nil otherwise.
+func queryCardIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
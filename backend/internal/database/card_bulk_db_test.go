@@ -0,0 +1,101 @@
+package database
+
+import (
+	"flash-learn/internal/model"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// likeMatches reports whether a text matches a LIKE pattern whose escape
+// character is the backslash, as Postgres matches it.
+func likeMatches(pattern string, text string) bool {
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case c == '%':
+			sb.WriteString(".*")
+		case c == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String()).MatchString(text)
+}
+
+func TestEscapeLike(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+		matches  []string
+		misses   []string
+	}{
+		{
+			name:     "Plain text",
+			text:     "plain text",
+			expected: "plain text",
+			matches:  []string{"plain text", "some plain text here"},
+			misses:   []string{"plain_text"},
+		},
+		{
+			name:     "Percent sign",
+			text:     "100% sure",
+			expected: `100\% sure`,
+			matches:  []string{"100% sure"},
+			misses:   []string{"100 percent sure", "1000 sure"},
+		},
+		{
+			name:     "Underscore",
+			text:     "snake_case",
+			expected: `snake\_case`,
+			matches:  []string{"snake_case"},
+			misses:   []string{"snakeXcase", "snake case"},
+		},
+		{
+			name:     "Backslash",
+			text:     `C:\dir`,
+			expected: `C:\\dir`,
+			matches:  []string{`C:\dir`},
+			misses:   []string{"C:dir", `C:\\dir`},
+		},
+		{
+			name:     "Backslash before a wildcard",
+			text:     `\%_`,
+			expected: `\\\%\_`,
+			matches:  []string{`\%_`},
+			misses:   []string{`\`, `\abc_`, `\%x`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			escaped := escapeLike(tc.text)
+			assert.Equal(t, tc.expected, escaped)
+
+			pattern := "%" + escaped + "%"
+			for _, text := range tc.matches {
+				assert.True(t, likeMatches(pattern, text), "Expected %q to match %q", text, tc.text)
+			}
+			for _, text := range tc.misses {
+				assert.False(t, likeMatches(pattern, text), "Expected %q not to match %q", text, tc.text)
+			}
+		})
+	}
+}
+
+func TestSearchQueryEscapesText(t *testing.T) {
+	wrapper := NewCardDBWrapper(nil)
+
+	query, args := wrapper.buildSearchQueryString(model.CardFilter{Text: "50%_off"})
+	assert.True(t, strings.Contains(query, `content ILIKE '%' || $1 || '%' ESCAPE '\'`), query)
+	assert.Equal(t, []any{`50\%\_off`}, args)
+}
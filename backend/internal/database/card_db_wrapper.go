@@ -1,12 +1,16 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"flash-learn/internal/model"
 	"flash-learn/internal/utils"
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 const (
@@ -20,9 +24,25 @@ const (
 	cardColumnRetentionLevel   = "retention_level"
 	cardColumnFlag             = "flag"
 	cardColumnSource           = "source"
+	cardColumnState            = "state"
+	cardColumnDeletedAt        = "deleted_at"
+	cardColumnTags             = "tags"
+	cardColumnSuspended        = "suspended"
+	cardColumnBuriedUntil      = "buried_until"
+	cardColumnNoteID           = "note_id"
+	cardColumnStep             = "step"
+	cardColumnInterval         = "interval_days"
+	cardColumnEase             = "ease"
+	cardColumnStability        = "stability"
+	cardColumnDifficulty       = "difficulty"
+	cardColumnLapses           = "lapses"
+	cardColumnLastReviewTime   = "last_review_time"
+	cardColumnOriginalDeckID   = "original_deck_id"
+	CardTrashRetention         = 30 * 24 * time.Hour
 	cardMinRetentionLevel      = 0
-	cardMinFlag                = 0
-	cardMaxFlag                = 9
+	cardMinFlag                = model.CardMinFlag
+	cardMaxFlag                = model.CardMaxFlag
+	cardNewReviewDelay         = "10 minutes"
 )
 
 // An interface that defines the methods for interacting with the card database.
@@ -30,8 +50,36 @@ const (
 // allowing for easier testing and mocking.
 type CardDBWrapperInterface interface {
 	CreateTable() error
-	Insert(card model.Card) (int, error)
-	GetTotalCards(deckID int) (int, error)
+	Insert(ctx context.Context, card model.Card) (int, error)
+	GetSingle(ctx context.Context, cardID int) (model.Card, error)
+	Modify(ctx context.Context, card model.Card) error
+	GetRevisions(ctx context.Context, cardID int) ([]model.CardRevision, error)
+	GetRevision(ctx context.Context, cardID int, revision int) (model.CardRevision, error)
+	GetTotalCards(ctx context.Context, deckID int) (int, error)
+	GetDueCards(ctx context.Context, deckIDs []int, dueBefore time.Time) ([]model.Card, error)
+	CountDueByDay(ctx context.Context, deckIDs []int, from time.Time, days int) ([]int, error)
+	Delete(ctx context.Context, cardID int) error
+	GetTrash(ctx context.Context) ([]model.Card, error)
+	Restore(ctx context.Context, cardID int) error
+	EmptyTrash(ctx context.Context) (int, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error)
+	Search(ctx context.Context, filter model.CardFilter) ([]model.Card, error)
+	ApplyBulk(ctx context.Context, cardIDs []int, op model.BulkOperation) ([]int, error)
+	SetSuspended(ctx context.Context, cardID int, suspended bool) error
+	Bury(ctx context.Context, cardID int, until time.Time, siblings bool) ([]int, error)
+	Unbury(ctx context.Context, cardID int) error
+	SetFlag(ctx context.Context, cardID int, flag int) error
+	FillFilteredDeck(ctx context.Context, filtered model.FilteredDeck) ([]int, error)
+	EmptyFilteredDecks(ctx context.Context, deckIDs []int) (int, error)
+	RecordReview(ctx context.Context, answer model.CardAnswer, review model.Review) (int, error)
+	UndoReview(ctx context.Context, card model.Card, reviewID int) error
+	GetReviews(ctx context.Context, deckIDs []int, from time.Time, to time.Time) ([]model.Review, error)
+	CountStudied(ctx context.Context, deckIDs []int, since time.Time) (model.StudyCounts, error)
+	CountByRetentionLevel(ctx context.Context, deckIDs []int) ([]model.RetentionLevelCount, error)
+	CountRescheduleCards(ctx context.Context, deckIDs []int) (int, error)
+	GetRescheduleBatch(ctx context.Context, deckIDs []int, afterID int, limit int) ([]model.Card, error)
+	GetCardReviews(ctx context.Context, cardIDs []int) ([]model.Review, error)
+	Reschedule(ctx context.Context, changes []model.CardAnswer) ([]int, error)
 }
 
 // A struct that implements the CardDBWrapperInterface.
@@ -65,6 +113,8 @@ func NewCardDBWrapper(db *sql.DB) *CardDBWrapper {
 //   - error : An error if the table creation fails, nil otherwise.
 
 func (wrapper *CardDBWrapper) CreateTable() error {
+	defer observeQuery("card", "CreateTable", time.Now())
+
 	if wrapper.db == nil {
 		slog.Error("Database connection is nil")
 		return utils.ErrDatabaseNotExist
@@ -77,9 +127,19 @@ func (wrapper *CardDBWrapper) CreateTable() error {
 
 	if err != nil {
 		slog.Error(fmt.Sprintf("Error creating cards table: %s", err))
+		return err
 	}
 
-	return err
+	for _, query := range wrapper.buildMigrationQueryStrings() {
+		slog.Debug("Migrating cards table", "query", query)
+
+		if _, err = wrapper.db.Exec(query); err != nil {
+			slog.Error(fmt.Sprintf("Error migrating cards table: %s", err))
+			return err
+		}
+	}
+
+	return nil
 }
 
 // A helper function that constructs the SQL query string
@@ -94,35 +154,124 @@ func (wrapper *CardDBWrapper) buildCreateTableQueryString() string {
 	sb.WriteString(cardTableName)
 	sb.WriteString(" (")
 	sb.WriteString(fmt.Sprintf("%s SERIAL PRIMARY KEY, ", cardColumnID))
-	sb.WriteString(fmt.Sprintf("%s INT NOT NULL REFERENCES %s(%s), ", cardColumnDeckID, deckTableName, deckColumnID))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL REFERENCES %s(%s) ON DELETE CASCADE, ", cardColumnDeckID, deckTableName, deckColumnID))
 	sb.WriteString(fmt.Sprintf("%s TEXT NOT NULL, ", cardColumnContent))
-	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT NOW(), ", cardColumnCreationTime))
-	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT NOW() CHECK (%s >= %s), ", cardColumnModificationTime, cardColumnModificationTime, cardColumnCreationTime))
-	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT NOW() + INTERVAL '10 minutes' CHECK (%s >= NOW()), ", cardColumnNextReviewTime, cardColumnNextReviewTime))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT %s, ", cardColumnCreationTime, utcDefault("")))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT %s CHECK (%s >= %s), ", cardColumnModificationTime, utcDefault(""), cardColumnModificationTime, cardColumnCreationTime))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT %s, ", cardColumnNextReviewTime, utcDefault(cardNewReviewDelay)))
 	sb.WriteString(fmt.Sprintf("%s INT DEFAULT %d CHECK (%s >= %d), ", cardColumnRetentionLevel, cardMinRetentionLevel, cardColumnRetentionLevel, cardMinRetentionLevel))
 	sb.WriteString(fmt.Sprintf("%s INT DEFAULT 0 CHECK (%s BETWEEN %d AND %d), ", cardColumnFlag, cardColumnFlag, cardMinFlag, cardMaxFlag))
-	sb.WriteString(fmt.Sprintf("%s TEXT ", cardColumnSource))
+	sb.WriteString(fmt.Sprintf("%s TEXT, ", cardColumnSource))
+	sb.WriteString(fmt.Sprintf("%s SMALLINT NOT NULL DEFAULT %d CHECK (%s BETWEEN %d AND %d), ", cardColumnState, model.CardStateNew, cardColumnState, model.CardStateNew, model.CardStateRelearning))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP, ", cardColumnDeletedAt))
+	sb.WriteString(fmt.Sprintf("%s TEXT[] NOT NULL DEFAULT '{}', ", cardColumnTags))
+	sb.WriteString(fmt.Sprintf("%s BOOLEAN NOT NULL DEFAULT FALSE, ", cardColumnSuspended))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP, ", cardColumnBuriedUntil))
+	sb.WriteString(fmt.Sprintf("%s INT, ", cardColumnNoteID))
+	for _, column := range cardSchedulingColumnDefinitions() {
+		sb.WriteString(column)
+		sb.WriteString(", ")
+	}
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP, ", cardColumnLastReviewTime))
+	sb.WriteString(cardOriginalDeckColumnDefinition())
 	sb.WriteString(")")
 
 	query := sb.String()
 	return query
 }
 
+// A helper function that constructs the SQL query strings that bring
+// a cards table created by an older version up to date. Cards used to
+// block the deletion of their deck, now they are deleted along with it.
+// The next review time of a card used to be checked against the current
+// time, which rejected any later update of an overdue card. Cards borrowed
+// by a filtered deck are deleted along with their home deck. The times of
+// cards and revisions used to be stored in the time zone of the session,
+// now they are stored in UTC.
+//
+// Returns:
+//   - []string : The SQL query strings to run in order.
+func (wrapper *CardDBWrapper) buildMigrationQueryStrings() []string {
+	queries := []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s SMALLINT NOT NULL DEFAULT %d CHECK (%s BETWEEN %d AND %d)",
+			cardTableName, cardColumnState, model.CardStateNew, cardColumnState, model.CardStateNew, model.CardStateRelearning),
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s_%s_fkey, ADD CONSTRAINT %s_%s_fkey FOREIGN KEY (%s) REFERENCES %s(%s) ON DELETE CASCADE",
+			cardTableName, cardTableName, cardColumnDeckID, cardTableName, cardColumnDeckID, cardColumnDeckID, deckTableName, deckColumnID),
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s_%s_check", cardTableName, cardTableName, cardColumnNextReviewTime),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TIMESTAMP", cardTableName, cardColumnDeletedAt),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TEXT[] NOT NULL DEFAULT '{}'", cardTableName, cardColumnTags),
+		buildCreateRevisionTableQueryString(),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s BOOLEAN NOT NULL DEFAULT FALSE", cardTableName, cardColumnSuspended),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TIMESTAMP", cardTableName, cardColumnBuriedUntil),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s INT", cardTableName, cardColumnNoteID),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s) WHERE %s IS NOT NULL",
+			cardTableName, cardColumnNoteID, cardTableName, cardColumnNoteID, cardColumnNoteID),
+	}
+
+	for _, column := range cardSchedulingColumnDefinitions() {
+		queries = append(queries, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", cardTableName, column))
+	}
+
+	return append(queries,
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TIMESTAMP", cardTableName, cardColumnLastReviewTime),
+		buildCreateReviewTableQueryString(),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s)", reviewTableName, reviewColumnCardID, reviewTableName, reviewColumnCardID),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s)", reviewTableName, reviewColumnReviewTime, reviewTableName, reviewColumnReviewTime),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", cardTableName, cardOriginalDeckColumnDefinition()),
+		buildConvertToUTCQueryString(cardTableName, cardColumnCreationTime, []string{cardColumnCreationTime, cardColumnModificationTime,
+			cardColumnNextReviewTime, cardColumnDeletedAt, cardColumnBuriedUntil, cardColumnLastReviewTime}),
+		buildConvertToUTCQueryString(revisionTableName, revisionColumnCreationTime, []string{revisionColumnCreationTime}),
+		buildSetUTCDefaultQueryString(cardTableName, cardColumnCreationTime, ""),
+		buildSetUTCDefaultQueryString(cardTableName, cardColumnModificationTime, ""),
+		buildSetUTCDefaultQueryString(cardTableName, cardColumnNextReviewTime, cardNewReviewDelay),
+		buildSetUTCDefaultQueryString(revisionTableName, revisionColumnCreationTime, ""),
+		buildSetUTCDefaultQueryString(reviewTableName, reviewColumnReviewTime, ""),
+	)
+}
+
+// A helper function that returns the definition of the column holding the home
+// deck of a card borrowed by a filtered deck, NULL for cards in their home deck.
+//
+// Returns:
+//   - string : The column definition.
+func cardOriginalDeckColumnDefinition() string {
+	return fmt.Sprintf("%s INT REFERENCES %s(%s) ON DELETE CASCADE", cardColumnOriginalDeckID, deckTableName, deckColumnID)
+}
+
+// A helper function that returns the definitions of the columns holding
+// the scheduling state of a card, shared by the table creation and its migration.
+//
+// Returns:
+//   - []string : The column definitions.
+func cardSchedulingColumnDefinitions() []string {
+	return []string{
+		fmt.Sprintf("%s INT NOT NULL DEFAULT 0 CHECK (%s >= 0)", cardColumnStep, cardColumnStep),
+		fmt.Sprintf("%s INT NOT NULL DEFAULT 0 CHECK (%s >= 0)", cardColumnInterval, cardColumnInterval),
+		fmt.Sprintf("%s REAL NOT NULL DEFAULT %g CHECK (%s > 0)", cardColumnEase, model.CardDefaultEase, cardColumnEase),
+		fmt.Sprintf("%s REAL NOT NULL DEFAULT 0", cardColumnStability),
+		fmt.Sprintf("%s REAL NOT NULL DEFAULT 0", cardColumnDifficulty),
+		fmt.Sprintf("%s INT NOT NULL DEFAULT 0 CHECK (%s >= 0)", cardColumnLapses, cardColumnLapses),
+	}
+}
+
 // Inserts a new card into the database and returns its unique ID.
 //
 // Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
 //   - card model.Card : Details of the card to be inserted as a model.Card object.
 //
 // Returns:
 //   - int : The unique ID of the inserted card.
 //   - error : An error if the insertion fails, nil otherwise.
-func (wrapper *CardDBWrapper) Insert(card model.Card) (int, error) {
+func (wrapper *CardDBWrapper) Insert(ctx context.Context, card model.Card) (int, error) {
+	defer observeQuery("card", "Insert", time.Now())
+
 	query := wrapper.buildInsertQueryString(card)
-	slog.Debug(fmt.Sprintf("Inserting card: %s", query))
+	slog.DebugContext(ctx, fmt.Sprintf("Inserting card: %s", query))
 
-	err := wrapper.db.QueryRow(query, card.DeckID, card.Content, card.Source).Scan(&card.ID)
+	err := wrapper.db.QueryRowContext(ctx, query, card.DeckID, card.Content, card.Source, pq.Array(model.NormalizeTags(card.Tags)), card.NoteID).Scan(&card.ID)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error inserting card: %s", err))
+		slog.ErrorContext(ctx, fmt.Sprintf("Error inserting card: %s", err))
 		return 0, err
 	}
 
@@ -140,22 +289,24 @@ func (wrapper *CardDBWrapper) buildInsertQueryString(card model.Card) string {
 	sb.WriteString("INSERT INTO ")
 	sb.WriteString(cardTableName)
 	sb.WriteString(" (")
-	sb.WriteString(fmt.Sprintf("%s, %s, %s", cardColumnDeckID, cardColumnContent, cardColumnSource))
-	sb.WriteString(") VALUES ($1, $2, $3) RETURNING ")
+	sb.WriteString(fmt.Sprintf("%s, %s, %s, %s, %s", cardColumnDeckID, cardColumnContent, cardColumnSource, cardColumnTags, cardColumnNoteID))
+	sb.WriteString(") VALUES ($1, $2, $3, $4, $5) RETURNING ")
 	sb.WriteString(cardColumnID)
 
 	query := sb.String()
 	return query
 }
 
-func (wrapper *CardDBWrapper) GetTotalCards(deckID int) (int, error) {
+func (wrapper *CardDBWrapper) GetTotalCards(ctx context.Context, deckID int) (int, error) {
+	defer observeQuery("card", "GetTotalCards", time.Now())
+
 	query := wrapper.buildGetTotalCardsQueryString(deckID)
-	slog.Debug(fmt.Sprintf("Getting total cards: %s", query))
+	slog.DebugContext(ctx, fmt.Sprintf("Getting total cards: %s", query))
 
 	var count int
-	err := wrapper.db.QueryRow(query, deckID).Scan(&count)
+	err := wrapper.db.QueryRowContext(ctx, query, deckID).Scan(&count)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error getting total cards: %s", err))
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting total cards: %s", err))
 		return 0, err
 	}
 	return count, nil
@@ -169,8 +320,477 @@ func (wrapper *CardDBWrapper) buildGetTotalCardsQueryString(deckID int) string {
 	sb.WriteString(cardTableName)
 	sb.WriteString(" WHERE ")
 	sb.WriteString(cardColumnDeckID)
-	sb.WriteString(" = $1")
+	sb.WriteString(" = $1 AND ")
+	sb.WriteString(cardNotTrashedCondition(""))
+
+	query := sb.String()
+	return query
+}
+
+// GetDueCards retrieves the cards of the given decks that should be studied.
+// New cards are always included, other cards only once they are due.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the decks to pull cards from.
+//   - dueBefore time.Time : Cards scheduled before this time are due.
+//
+// Returns:
+//   - []model.Card : The due cards, ordered by their next review time.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *CardDBWrapper) GetDueCards(ctx context.Context, deckIDs []int, dueBefore time.Time) ([]model.Card, error) {
+	defer observeQuery("card", "GetDueCards", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildGetDueCardsQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Getting due cards: %s", query))
+
+	rows, err := wrapper.db.QueryContext(ctx, query, pq.Array(deckIDs), dueBefore.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting due cards: %s", err))
+		return nil, err
+	}
+
+	return scanCards(ctx, rows)
+}
+
+func (wrapper *CardDBWrapper) buildGetDueCardsQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(cardSelectColumns())
+	sb.WriteString(" FROM ")
+	sb.WriteString(cardTableName)
+	sb.WriteString(" WHERE ")
+	sb.WriteString(cardColumnDeckID)
+	sb.WriteString(" = ANY($1) AND ")
+	sb.WriteString(cardNotTrashedCondition(""))
+	sb.WriteString(" AND ")
+	sb.WriteString(cardInQueueCondition(""))
+	sb.WriteString(" AND (")
+	sb.WriteString(fmt.Sprintf("%s = %d OR %s < $2", cardColumnState, model.CardStateNew, cardColumnNextReviewTime))
+	sb.WriteString(") ORDER BY ")
+	sb.WriteString(cardColumnNextReviewTime)
+	sb.WriteString(" ASC")
 
 	query := sb.String()
 	return query
 }
+
+// CountDueByDay counts the cards of the given decks that come due on each of a
+// number of days. New cards aren't counted, since they have no due date yet.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the decks.
+//   - from time.Time : The start of the first day.
+//   - days int : The number of days to count.
+//
+// Returns:
+//   - []int : The number of cards due on each day, starting with the first.
+//   - error : An error if the count fails, nil otherwise.
+func (wrapper *CardDBWrapper) CountDueByDay(ctx context.Context, deckIDs []int, from time.Time, days int) ([]int, error) {
+	defer observeQuery("card", "CountDueByDay", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	day := fmt.Sprintf("FLOOR(EXTRACT(EPOCH FROM (%s - $2)) / 86400)::INT", cardColumnNextReviewTime)
+	query := fmt.Sprintf("SELECT %s AS day, COUNT(*) FROM %s WHERE %s = ANY($1) AND %s AND %s <> %d AND %s >= $2 AND %s < $3 GROUP BY day",
+		day, cardTableName, cardColumnDeckID, cardNotTrashedCondition(""), cardColumnState, model.CardStateNew,
+		cardColumnNextReviewTime, cardColumnNextReviewTime)
+	slog.DebugContext(ctx, fmt.Sprintf("Counting due cards by day: %s", query))
+
+	rows, err := wrapper.db.QueryContext(ctx, query, pq.Array(deckIDs), from.UTC(), from.AddDate(0, 0, days).UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error counting due cards by day: %s", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]int, days)
+	for rows.Next() {
+		var day, count int
+		if err = rows.Scan(&day, &count); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Error scanning due count: %s", err))
+			return nil, err
+		}
+		if day >= 0 && day < days {
+			counts[day] += count
+		}
+	}
+
+	return counts, rows.Err()
+}
+
+// Moves a card to the trash. Trashed cards keep their review history and
+// are excluded from every other card query until they are restored.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the card doesn't exist or is already
+//     in the trash, nil on success.
+func (wrapper *CardDBWrapper) Delete(ctx context.Context, cardID int) error {
+	defer observeQuery("card", "Delete", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = $1 AND %s",
+		cardTableName, cardColumnDeletedAt, sqlNowUTC, cardColumnID, cardNotTrashedCondition(""))
+	slog.DebugContext(ctx, fmt.Sprintf("Deleting card: %s", query))
+
+	return wrapper.execSingle(ctx, query, cardID)
+}
+
+// Marks a card with a flag, 0 clearing it.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//   - flag int : The flag value, between cardMinFlag and cardMaxFlag.
+//
+// Returns:
+//   - error : utils.ErrInvalidFlag if the flag is out of range, utils.ErrRecordNotExist
+//     if the card doesn't exist, nil on success.
+func (wrapper *CardDBWrapper) SetFlag(ctx context.Context, cardID int, flag int) error {
+	defer observeQuery("card", "SetFlag", time.Now())
+
+	if flag < cardMinFlag || flag > cardMaxFlag {
+		return utils.ErrInvalidFlag
+	}
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = $1, %s = %s WHERE %s = $2 AND %s",
+		cardTableName, cardColumnFlag, cardColumnModificationTime, sqlNowUTC, cardColumnID, cardNotTrashedCondition(""))
+	slog.DebugContext(ctx, fmt.Sprintf("Setting card flag: %s", query))
+
+	return wrapper.execSingle(ctx, query, flag, cardID)
+}
+
+// Retrieves the cards in the trash, most recently deleted first.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//
+// Returns:
+//   - []model.Card : The trashed cards, with their deletion time set.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *CardDBWrapper) GetTrash(ctx context.Context) ([]model.Card, error) {
+	defer observeQuery("card", "GetTrash", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NOT NULL ORDER BY %s DESC",
+		cardSelectColumns(), cardTableName, cardColumnDeletedAt, cardColumnDeletedAt)
+	slog.DebugContext(ctx, fmt.Sprintf("Getting card trash: %s", query))
+
+	rows, err := wrapper.db.QueryContext(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting card trash: %s", err))
+		return nil, err
+	}
+
+	return scanCards(ctx, rows)
+}
+
+// Restores a card from the trash.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the card isn't in the trash, nil on success.
+func (wrapper *CardDBWrapper) Restore(ctx context.Context, cardID int) error {
+	defer observeQuery("card", "Restore", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = $1 AND %s IS NOT NULL",
+		cardTableName, cardColumnDeletedAt, cardColumnID, cardColumnDeletedAt)
+	slog.DebugContext(ctx, fmt.Sprintf("Restoring card: %s", query))
+
+	return wrapper.execSingle(ctx, query, cardID)
+}
+
+// Permanently deletes every card in the trash.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//
+// Returns:
+//   - int : The number of deleted cards.
+//   - error : An error if the deletion fails, nil otherwise.
+func (wrapper *CardDBWrapper) EmptyTrash(ctx context.Context) (int, error) {
+	defer observeQuery("card", "EmptyTrash", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return 0, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s IS NOT NULL", cardTableName, cardColumnDeletedAt)
+	slog.DebugContext(ctx, fmt.Sprintf("Emptying card trash: %s", query))
+
+	result, err := wrapper.db.ExecContext(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error emptying card trash: %s", err))
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
+
+// Permanently deletes the cards that have been in the trash since before the given time.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deletedBefore time.Time : Cards deleted before this time are purged.
+//
+// Returns:
+//   - int : The number of purged cards.
+//   - error : An error if the purge fails, nil otherwise.
+func (wrapper *CardDBWrapper) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	defer observeQuery("card", "PurgeTrash", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return 0, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s < $1", cardTableName, cardColumnDeletedAt)
+	slog.DebugContext(ctx, fmt.Sprintf("Purging card trash: %s", query))
+
+	result, err := wrapper.db.ExecContext(ctx, query, deletedBefore.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error purging card trash: %s", err))
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), nil
+}
+
+// A helper function that runs a statement expected to change exactly one card.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - query string : The SQL query string.
+//   - args ...any : The query arguments.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if no card was changed, nil on success.
+func (wrapper *CardDBWrapper) execSingle(ctx context.Context, query string, args ...any) error {
+	result, err := wrapper.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error updating card: %s", err))
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrRecordNotExist
+	}
+
+	return nil
+}
+
+// A helper function that returns the SQL condition matching the cards that
+// are neither in the trash nor in a deck in the trash. The cards of a trashed
+// deck keep their own deletion time empty, so that restoring the deck brings
+// them back, and are hidden through their deck instead.
+//
+// Parameters:
+//   - alias string : The alias of the cards table, empty if the table isn't aliased.
+//
+// Returns:
+//   - string : The SQL condition.
+func cardNotTrashedCondition(alias string) string {
+	prefix := alias
+	if prefix != "" {
+		prefix += "."
+	}
+	return fmt.Sprintf("%s%s IS NULL AND %s", prefix, cardColumnDeletedAt, cardInLiveDeckCondition(alias))
+}
+
+// A helper function that returns the SQL condition matching the cards whose
+// deck isn't in the trash, whether or not the cards themselves are.
+//
+// Parameters:
+//   - alias string : The alias of the cards table, empty if the table isn't aliased.
+//
+// Returns:
+//   - string : The SQL condition.
+func cardInLiveDeckCondition(alias string) string {
+	if alias != "" {
+		alias += "."
+	}
+	return fmt.Sprintf("%s%s IN (SELECT %s FROM %s WHERE %s IS NULL)",
+		alias, cardColumnDeckID, deckColumnID, deckTableName, deckColumnDeletedAt)
+}
+
+// A helper function that returns the SQL condition matching the cards that
+// are neither suspended nor buried.
+//
+// Parameters:
+//   - alias string : The alias of the cards table, empty if the table isn't aliased.
+//
+// Returns:
+//   - string : The SQL condition.
+func cardInQueueCondition(alias string) string {
+	if alias != "" {
+		alias += "."
+	}
+	return fmt.Sprintf("NOT %s%s AND (%s%s IS NULL OR %s%s <= %s)",
+		alias, cardColumnSuspended, alias, cardColumnBuriedUntil, alias, cardColumnBuriedUntil, sqlNowUTC)
+}
+
+// A helper function that returns the comma separated list of columns
+// that scanCard expects, in order.
+//
+// Returns:
+//   - string : The columns to select for a full card.
+func cardSelectColumns() string {
+	return strings.Join([]string{
+		cardColumnID,
+		cardColumnDeckID,
+		cardColumnContent,
+		cardColumnCreationTime,
+		cardColumnModificationTime,
+		cardColumnNextReviewTime,
+		cardColumnRetentionLevel,
+		cardColumnFlag,
+		cardColumnSource,
+		cardColumnState,
+		cardColumnDeletedAt,
+		cardColumnTags,
+		cardColumnSuspended,
+		cardColumnBuriedUntil,
+		cardColumnNoteID,
+		cardColumnStep,
+		cardColumnInterval,
+		cardColumnEase,
+		cardColumnStability,
+		cardColumnDifficulty,
+		cardColumnLapses,
+		cardColumnLastReviewTime,
+		cardColumnOriginalDeckID,
+	}, ", ")
+}
+
+// A helper function that scans a single card selected with cardSelectColumns.
+//
+// Parameters:
+//   - scanner interface{ Scan(...any) error } : The row to scan from.
+//
+// Returns:
+//   - model.Card : The scanned card.
+//   - error : An error if the scan fails, nil otherwise.
+func scanCard(scanner interface{ Scan(...any) error }) (model.Card, error) {
+	var card model.Card
+	var source sql.NullString
+	var deletedAt, buriedUntil, lastReviewTime sql.NullTime
+	var noteID, originalDeckID sql.NullInt64
+
+	err := scanner.Scan(
+		&card.ID,
+		&card.DeckID,
+		&card.Content,
+		&card.CreationTime,
+		&card.ModificationTime,
+		&card.NextReviewTime,
+		&card.RetentionLevel,
+		&card.Flag,
+		&source,
+		&card.State,
+		&deletedAt,
+		(*pq.StringArray)(&card.Tags),
+		&card.Suspended,
+		&buriedUntil,
+		&noteID,
+		&card.Step,
+		&card.Interval,
+		&card.Ease,
+		&card.Stability,
+		&card.Difficulty,
+		&card.Lapses,
+		&lastReviewTime,
+		&originalDeckID,
+	)
+	if err != nil {
+		return model.Card{}, err
+	}
+
+	card.Source = source.String
+	if deletedAt.Valid {
+		card.DeletedAt = &deletedAt.Time
+	}
+	if buriedUntil.Valid {
+		card.BuriedUntil = &buriedUntil.Time
+	}
+	if lastReviewTime.Valid {
+		card.LastReviewTime = &lastReviewTime.Time
+	}
+	card.NoteID = nullIntToPointer(noteID)
+	card.OriginalDeckID = nullIntToPointer(originalDeckID)
+	return card, nil
+}
+
+// A helper function that scans and closes all rows of a card query.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - rows *sql.Rows : The rows returned by a query selecting cardSelectColumns.
+//
+// Returns:
+//   - []model.Card : The scanned cards.
+//   - error : An error if any scan fails, nil otherwise.
+func scanCards(ctx context.Context, rows *sql.Rows) ([]model.Card, error) {
+	defer rows.Close()
+
+	cards := []model.Card{}
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Error scanning card: %s", err))
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error iterating cards: %s", err))
+		return nil, err
+	}
+
+	return cards, nil
+}
@@ -1,13 +1,22 @@
 package database
 
 import (
+	"context"
 	"flash-learn/internal/model"
 	"flash-learn/internal/utils"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
 )
 
 type CardDBWrapperMock struct {
-	db    map[int]map[int]model.Card
-	index map[int]int
+	db        map[int]map[int]model.Card
+	index     map[int]int
+	revisions map[int][]model.CardRevision
+	reviews   []model.Review
+	reviewID  int
+	trashed   map[int]bool
 }
 
 func NewCardDBWrapperMock() *CardDBWrapperMock {
@@ -19,17 +28,21 @@ func (wrapper *CardDBWrapperMock) CreateTable() error {
 		wrapper.db = make(map[int]map[int]model.Card)
 	}
 	wrapper.index = make(map[int]int)
+	wrapper.revisions = make(map[int][]model.CardRevision)
+	wrapper.reviews = []model.Review{}
 
 	return nil
 }
 
-func (wrapper *CardDBWrapperMock) Insert(card model.Card) (int, error) {
+func (wrapper *CardDBWrapperMock) Insert(ctx context.Context, card model.Card) (int, error) {
 	_, ok := wrapper.db[card.DeckID]
 
 	if !ok {
 		return -1, utils.ErrDeckNotExist
 	}
 
+	card.ID = wrapper.index[card.DeckID]
+	card.Tags = model.NormalizeTags(card.Tags)
 	wrapper.db[card.DeckID][wrapper.index[card.DeckID]] = card
 	wrapper.index[card.DeckID]++
 
@@ -40,10 +53,683 @@ func (wrapper *CardDBWrapperMock) InsertDeck(deckID int) {
 	wrapper.db[deckID] = make(map[int]model.Card)
 }
 
-func (wrapper *CardDBWrapperMock) GetTotalCards(deckID int) (int, error) {
-	_, ok := wrapper.db[deckID]
-	if !ok {
-		return 0, nil
+// TrashDeck hides the cards of a deck as if the deck was moved to the trash,
+// false bringing them back as if it was restored.
+func (wrapper *CardDBWrapperMock) TrashDeck(deckID int, trashed bool) {
+	if wrapper.trashed == nil {
+		wrapper.trashed = make(map[int]bool)
+	}
+	wrapper.trashed[deckID] = trashed
+}
+
+// hidden reports whether a card is in the trash or in a deck in the trash.
+func (wrapper *CardDBWrapperMock) hidden(card model.Card) bool {
+	return card.DeletedAt != nil || wrapper.trashed[card.DeckID]
+}
+
+func (wrapper *CardDBWrapperMock) GetTotalCards(ctx context.Context, deckID int) (int, error) {
+	count := 0
+	for _, card := range wrapper.db[deckID] {
+		if !wrapper.hidden(card) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (wrapper *CardDBWrapperMock) GetDueCards(ctx context.Context, deckIDs []int, dueBefore time.Time) ([]model.Card, error) {
+	cards := []model.Card{}
+
+	for _, deckID := range deckIDs {
+		for _, card := range wrapper.db[deckID] {
+			if wrapper.hidden(card) || !card.InQueue(time.Now()) {
+				continue
+			}
+			if card.State == model.CardStateNew || card.NextReviewTime.Before(dueBefore) {
+				cards = append(cards, card)
+			}
+		}
+	}
+
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].NextReviewTime.Before(cards[j].NextReviewTime)
+	})
+
+	return cards, nil
+}
+
+// A helper function that finds a card by ID across all decks.
+func (wrapper *CardDBWrapperMock) CountDueByDay(ctx context.Context, deckIDs []int, from time.Time, days int) ([]int, error) {
+	counts := make([]int, days)
+	for _, deckID := range deckIDs {
+		for _, card := range wrapper.db[deckID] {
+			if wrapper.hidden(card) || card.State == model.CardStateNew || card.NextReviewTime.Before(from) {
+				continue
+			}
+
+			day := int(card.NextReviewTime.Sub(from) / (24 * time.Hour))
+			if day < days {
+				counts[day]++
+			}
+		}
+	}
+
+	return counts, nil
+}
+
+func (wrapper *CardDBWrapperMock) find(cardID int) (int, model.Card, bool) {
+	deckIDs := make([]int, 0, len(wrapper.db))
+	for deckID := range wrapper.db {
+		deckIDs = append(deckIDs, deckID)
+	}
+	sort.Ints(deckIDs)
+
+	for _, deckID := range deckIDs {
+		if card, exists := wrapper.db[deckID][cardID]; exists {
+			return deckID, card, true
+		}
+	}
+
+	return 0, model.Card{}, false
+}
+
+func (wrapper *CardDBWrapperMock) Delete(ctx context.Context, cardID int) error {
+	deckID, card, exists := wrapper.find(cardID)
+	if !exists || wrapper.hidden(card) {
+		return utils.ErrRecordNotExist
+	}
+
+	now := time.Now()
+	card.DeletedAt = &now
+	wrapper.db[deckID][cardID] = card
+
+	return nil
+}
+
+func (wrapper *CardDBWrapperMock) GetTrash(ctx context.Context) ([]model.Card, error) {
+	cards := []model.Card{}
+
+	for _, deck := range wrapper.db {
+		for _, card := range deck {
+			if card.DeletedAt != nil {
+				cards = append(cards, card)
+			}
+		}
+	}
+
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].DeletedAt.After(*cards[j].DeletedAt)
+	})
+
+	return cards, nil
+}
+
+func (wrapper *CardDBWrapperMock) Restore(ctx context.Context, cardID int) error {
+	for _, deck := range wrapper.db {
+		if card, exists := deck[cardID]; exists && card.DeletedAt != nil {
+			card.DeletedAt = nil
+			deck[cardID] = card
+			return nil
+		}
+	}
+
+	return utils.ErrRecordNotExist
+}
+
+func (wrapper *CardDBWrapperMock) EmptyTrash(ctx context.Context) (int, error) {
+	deleted := 0
+
+	for _, deck := range wrapper.db {
+		for id, card := range deck {
+			if card.DeletedAt != nil {
+				delete(deck, id)
+				deleted++
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+func (wrapper *CardDBWrapperMock) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	purged := 0
+
+	for _, deck := range wrapper.db {
+		for id, card := range deck {
+			if card.DeletedAt != nil && card.DeletedAt.Before(deletedBefore) {
+				delete(deck, id)
+				purged++
+			}
+		}
+	}
+
+	return purged, nil
+}
+
+func (wrapper *CardDBWrapperMock) GetSingle(ctx context.Context, cardID int) (model.Card, error) {
+	_, card, exists := wrapper.find(cardID)
+	if !exists || wrapper.hidden(card) {
+		return model.Card{}, utils.ErrRecordNotExist
+	}
+
+	return card, nil
+}
+
+func (wrapper *CardDBWrapperMock) Modify(ctx context.Context, card model.Card) error {
+	deckID, current, exists := wrapper.find(card.ID)
+	if !exists || wrapper.hidden(current) {
+		return utils.ErrRecordNotExist
+	}
+
+	revision := model.RevisionOf(current)
+	revision.ID = len(wrapper.revisions[card.ID])
+	revision.Revision = len(wrapper.revisions[card.ID]) + 1
+	revision.CreationTime = time.Now()
+	wrapper.revisions[card.ID] = append(wrapper.revisions[card.ID], revision)
+
+	current.Content = card.Content
+	current.Source = card.Source
+	current.Tags = model.NormalizeTags(card.Tags)
+	current.ModificationTime = time.Now()
+	wrapper.db[deckID][card.ID] = current
+
+	return nil
+}
+
+func (wrapper *CardDBWrapperMock) GetRevisions(ctx context.Context, cardID int) ([]model.CardRevision, error) {
+	revisions := []model.CardRevision{}
+	return append(revisions, wrapper.revisions[cardID]...), nil
+}
+
+func (wrapper *CardDBWrapperMock) GetRevision(ctx context.Context, cardID int, revision int) (model.CardRevision, error) {
+	for _, rev := range wrapper.revisions[cardID] {
+		if rev.Revision == revision {
+			return rev, nil
+		}
+	}
+
+	return model.CardRevision{}, utils.ErrRecordNotExist
+}
+
+func (wrapper *CardDBWrapperMock) Search(ctx context.Context, filter model.CardFilter) ([]model.Card, error) {
+	deckIDs := make([]int, 0, len(wrapper.db))
+	for deckID := range wrapper.db {
+		deckIDs = append(deckIDs, deckID)
+	}
+	sort.Ints(deckIDs)
+
+	cards := []model.Card{}
+	for _, deckID := range deckIDs {
+		if filter.DeckIDs != nil && !containsInt(filter.DeckIDs, deckID) {
+			continue
+		}
+
+		for _, card := range wrapper.db[deckID] {
+			if wrapper.matchesFilter(card, filter) {
+				cards = append(cards, card)
+			}
+		}
+	}
+
+	sort.SliceStable(cards, func(i, j int) bool {
+		if cards[i].DeckID != cards[j].DeckID {
+			return cards[i].DeckID < cards[j].DeckID
+		}
+		return cards[i].ID < cards[j].ID
+	})
+
+	if filter.Limit > 0 && len(cards) > filter.Limit {
+		cards = cards[:filter.Limit]
+	}
+
+	return cards, nil
+}
+
+func (wrapper *CardDBWrapperMock) matchesFilter(card model.Card, filter model.CardFilter) bool {
+	if wrapper.hidden(card) {
+		return false
+	}
+	for _, tag := range model.NormalizeTags(filter.Tags) {
+		if !containsString(card.Tags, tag) {
+			return false
+		}
+	}
+	if filter.Flag != nil && card.Flag != *filter.Flag {
+		return false
+	}
+	if filter.State != nil && card.State != *filter.State {
+		return false
+	}
+	if filter.Suspended != nil && card.Suspended != *filter.Suspended {
+		return false
+	}
+	if filter.Text != "" && !strings.Contains(strings.ToLower(card.Content), strings.ToLower(filter.Text)) {
+		return false
+	}
+	if filter.AddedDays > 0 && card.CreationTime.Before(time.Now().AddDate(0, 0, -filter.AddedDays)) {
+		return false
+	}
+	if filter.ForgottenDays > 0 {
+		since := time.Now().AddDate(0, 0, -filter.ForgottenDays)
+		for _, review := range wrapper.reviews {
+			if review.CardID == card.ID && review.IsLapse() && !review.ReviewTime.Before(since) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (wrapper *CardDBWrapperMock) ApplyBulk(ctx context.Context, cardIDs []int, op model.BulkOperation) ([]int, error) {
+	if err := op.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, exists := wrapper.db[op.DeckID]; op.Type == model.BulkMove && !exists {
+		return nil, utils.ErrDeckNotExist
+	}
+
+	changed := []int{}
+	for _, cardID := range cardIDs {
+		deckID, card, exists := wrapper.find(cardID)
+		if !exists || wrapper.hidden(card) {
+			continue
+		}
+
+		now := time.Now()
+		switch op.Type {
+		case model.BulkMove:
+			card.DeckID = op.DeckID
+		case model.BulkAddTags:
+			card.Tags = model.NormalizeTags(append(card.Tags, op.Tags...))
+		case model.BulkRemoveTags:
+			tags := []string{}
+			for _, tag := range card.Tags {
+				if !containsString(op.Tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			card.Tags = tags
+		case model.BulkSetFlag:
+			card.Flag = op.Flag
+		case model.BulkDelete:
+			card.DeletedAt = &now
+		case model.BulkSuspend:
+			card.Suspended = true
+		case model.BulkResetScheduling:
+			card.State = model.CardStateNew
+			card.RetentionLevel = 0
+			card.NextReviewTime = now
+			card.Step = 0
+			card.Interval = 0
+			card.Ease = model.CardDefaultEase
+			card.Stability = 0
+			card.Difficulty = 0
+			card.LastReviewTime = nil
+		case model.BulkSetDue:
+			card.NextReviewTime = op.Due
+			if card.State == model.CardStateNew {
+				card.State = model.CardStateReview
+			}
+		}
+		card.ModificationTime = now
+
+		delete(wrapper.db[deckID], cardID)
+		wrapper.db[card.DeckID][cardID] = card
+		changed = append(changed, cardID)
+	}
+
+	return changed, nil
+}
+
+func (wrapper *CardDBWrapperMock) FillFilteredDeck(ctx context.Context, filtered model.FilteredDeck) ([]int, error) {
+	if err := filtered.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, exists := wrapper.db[filtered.DeckID]; !exists {
+		return nil, utils.ErrDeckNotExist
+	}
+
+	wrapper.EmptyFilteredDecks(ctx, []int{filtered.DeckID})
+
+	query := filtered.Query
+	query.Limit = 0
+	matching, _ := wrapper.Search(ctx, query)
+
+	now := time.Now()
+	cards := []model.Card{}
+	for _, card := range matching {
+		if card.OriginalDeckID == nil && card.DeckID != filtered.DeckID && card.InQueue(now) {
+			cards = append(cards, card)
+		}
+	}
+	filtered.Sort(cards, rand.New(rand.NewPCG(uint64(filtered.DeckID), 0)))
+	if len(cards) > filtered.Limit {
+		cards = cards[:filtered.Limit]
+	}
+
+	borrowed := []int{}
+	for _, card := range cards {
+		homeDeckID := card.DeckID
+		card.OriginalDeckID = &homeDeckID
+		card.DeckID = filtered.DeckID
+
+		delete(wrapper.db[homeDeckID], card.ID)
+		wrapper.db[card.DeckID][card.ID] = card
+		borrowed = append(borrowed, card.ID)
+	}
+
+	return borrowed, nil
+}
+
+func (wrapper *CardDBWrapperMock) EmptyFilteredDecks(ctx context.Context, deckIDs []int) (int, error) {
+	returned := 0
+	for _, deckID := range deckIDs {
+		for id, card := range wrapper.db[deckID] {
+			if card.OriginalDeckID == nil {
+				continue
+			}
+
+			card.DeckID = *card.OriginalDeckID
+			card.OriginalDeckID = nil
+
+			delete(wrapper.db[deckID], id)
+			wrapper.db[card.DeckID][id] = card
+			returned++
+		}
+	}
+
+	return returned, nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (wrapper *CardDBWrapperMock) SetSuspended(ctx context.Context, cardID int, suspended bool) error {
+	deckID, card, exists := wrapper.find(cardID)
+	if !exists || wrapper.hidden(card) {
+		return utils.ErrRecordNotExist
+	}
+
+	card.Suspended = suspended
+	wrapper.db[deckID][cardID] = card
+
+	return nil
+}
+
+func (wrapper *CardDBWrapperMock) Bury(ctx context.Context, cardID int, until time.Time, siblings bool) ([]int, error) {
+	_, target, exists := wrapper.find(cardID)
+	if !exists || wrapper.hidden(target) {
+		return nil, utils.ErrRecordNotExist
+	}
+
+	buried := []int{}
+	for _, deck := range wrapper.db {
+		for id, card := range deck {
+			isSibling := siblings && target.NoteID != nil && card.NoteID != nil && *card.NoteID == *target.NoteID
+			if !wrapper.hidden(card) && ((id == cardID && card.DeckID == target.DeckID) || isSibling) {
+				card.BuriedUntil = &until
+				deck[id] = card
+				buried = append(buried, id)
+			}
+		}
+	}
+
+	sort.Ints(buried)
+	return buried, nil
+}
+
+func (wrapper *CardDBWrapperMock) Unbury(ctx context.Context, cardID int) error {
+	deckID, card, exists := wrapper.find(cardID)
+	if !exists || wrapper.hidden(card) {
+		return utils.ErrRecordNotExist
+	}
+
+	card.BuriedUntil = nil
+	wrapper.db[deckID][cardID] = card
+
+	return nil
+}
+
+func (wrapper *CardDBWrapperMock) SetFlag(ctx context.Context, cardID int, flag int) error {
+	if flag < cardMinFlag || flag > cardMaxFlag {
+		return utils.ErrInvalidFlag
+	}
+
+	deckID, card, exists := wrapper.find(cardID)
+	if !exists || wrapper.hidden(card) {
+		return utils.ErrRecordNotExist
+	}
+
+	card.Flag = flag
+	wrapper.db[deckID][cardID] = card
+
+	return nil
+}
+
+func (wrapper *CardDBWrapperMock) RecordReview(ctx context.Context, answer model.CardAnswer, review model.Review) (int, error) {
+	deckID, existing, exists := wrapper.find(answer.Card.ID)
+	if !exists || wrapper.hidden(existing) {
+		return -1, utils.ErrRecordNotExist
+	}
+	if !sameTime(existing.LastReviewTime, answer.PreviousReviewTime) {
+		return -1, utils.ErrCardChanged
+	}
+
+	card := withScheduling(existing, answer.Card)
+	card.Tags = model.NormalizeTags(append(append([]string{}, existing.Tags...), answer.AddTags...))
+	card.Suspended = existing.Suspended || answer.Suspend
+	if answer.Flag != nil {
+		card.Flag = *answer.Flag
+	}
+	if answer.ReturnHome && existing.OriginalDeckID != nil {
+		card.DeckID = *existing.OriginalDeckID
+		card.OriginalDeckID = nil
+	}
+
+	delete(wrapper.db[deckID], card.ID)
+	wrapper.db[card.DeckID][card.ID] = card
+
+	review.ID = wrapper.reviewID
+	wrapper.reviews = append(wrapper.reviews, review)
+	wrapper.reviewID++
+	return review.ID, nil
+}
+
+// withScheduling returns the card with the scheduling state of another one.
+func withScheduling(card model.Card, scheduled model.Card) model.Card {
+	card.State = scheduled.State
+	card.Step = scheduled.Step
+	card.Interval = scheduled.Interval
+	card.Ease = scheduled.Ease
+	card.Stability = scheduled.Stability
+	card.Difficulty = scheduled.Difficulty
+	card.Lapses = scheduled.Lapses
+	card.RetentionLevel = scheduled.RetentionLevel
+	card.NextReviewTime = scheduled.NextReviewTime
+	card.LastReviewTime = scheduled.LastReviewTime
+	return card
+}
+
+// sameTime reports whether two optional times are both unset or equal.
+func sameTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func (wrapper *CardDBWrapperMock) UndoReview(ctx context.Context, card model.Card, reviewID int) error {
+	deckID, existing, exists := wrapper.find(card.ID)
+	if !exists || wrapper.hidden(existing) {
+		return utils.ErrRecordNotExist
+	}
+
+	index, newer := -1, false
+	for i, review := range wrapper.reviews {
+		if review.CardID == card.ID && review.ID == reviewID {
+			index = i
+		} else if review.CardID == card.ID && review.ID > reviewID {
+			newer = true
+		}
+	}
+	if index < 0 {
+		return utils.ErrRecordNotExist
+	}
+	if newer {
+		return utils.ErrCardChanged
+	}
+
+	wrapper.reviews = append(wrapper.reviews[:index], wrapper.reviews[index+1:]...)
+	wrapper.db[deckID][card.ID] = withScheduling(existing, card)
+	return nil
+}
+
+// GetReviewLog returns the review log entries recorded so far, for use in tests.
+func (wrapper *CardDBWrapperMock) GetReviewLog() []model.Review {
+	return wrapper.reviews
+}
+
+func (wrapper *CardDBWrapperMock) GetReviews(ctx context.Context, deckIDs []int, from time.Time, to time.Time) ([]model.Review, error) {
+	reviews := []model.Review{}
+	for _, review := range wrapper.reviews {
+		deckID, _, exists := wrapper.find(review.CardID)
+		if !exists || (deckIDs == nil && wrapper.trashed[deckID]) || (deckIDs != nil && !containsInt(deckIDs, deckID)) {
+			continue
+		}
+		if review.ReviewTime.Before(from) || !review.ReviewTime.Before(to) {
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].ReviewTime.Before(reviews[j].ReviewTime)
+	})
+
+	return reviews, nil
+}
+
+func (wrapper *CardDBWrapperMock) CountStudied(ctx context.Context, deckIDs []int, since time.Time) (model.StudyCounts, error) {
+	var counts model.StudyCounts
+	for _, review := range wrapper.reviews {
+		if !containsInt(deckIDs, review.DeckID) || review.ReviewTime.Before(since) {
+			continue
+		}
+		switch {
+		case review.Kind == model.ReviewKindLearn && review.State == model.CardStateNew:
+			counts.NewCards++
+		case review.Kind == model.ReviewKindReview:
+			counts.Reviews++
+		}
+	}
+
+	return counts, nil
+}
+
+func (wrapper *CardDBWrapperMock) CountByRetentionLevel(ctx context.Context, deckIDs []int) ([]model.RetentionLevelCount, error) {
+	counts := make(map[int]int)
+	for deckID, deck := range wrapper.db {
+		if deckIDs != nil && !containsInt(deckIDs, deckID) {
+			continue
+		}
+		for _, card := range deck {
+			if !wrapper.hidden(card) {
+				counts[card.RetentionLevel]++
+			}
+		}
+	}
+
+	levels := []model.RetentionLevelCount{}
+	for level, cards := range counts {
+		levels = append(levels, model.RetentionLevelCount{RetentionLevel: level, Cards: cards})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].RetentionLevel < levels[j].RetentionLevel
+	})
+
+	return levels, nil
+}
+
+// rescheduleCards returns the cards a reschedule job of the given decks goes through, by ascending ID.
+func (wrapper *CardDBWrapperMock) rescheduleCards(deckIDs []int) []model.Card {
+	cards := []model.Card{}
+	for deckID, deck := range wrapper.db {
+		for _, card := range deck {
+			homeDeckID := deckID
+			if card.OriginalDeckID != nil {
+				homeDeckID = *card.OriginalDeckID
+			}
+			if !wrapper.hidden(card) && card.State != model.CardStateNew && containsInt(deckIDs, homeDeckID) {
+				cards = append(cards, card)
+			}
+		}
+	}
+
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].ID < cards[j].ID
+	})
+	return cards
+}
+
+func (wrapper *CardDBWrapperMock) CountRescheduleCards(ctx context.Context, deckIDs []int) (int, error) {
+	return len(wrapper.rescheduleCards(deckIDs)), nil
+}
+
+func (wrapper *CardDBWrapperMock) GetRescheduleBatch(ctx context.Context, deckIDs []int, afterID int, limit int) ([]model.Card, error) {
+	batch := []model.Card{}
+	for _, card := range wrapper.rescheduleCards(deckIDs) {
+		if card.ID > afterID && len(batch) < limit {
+			batch = append(batch, card)
+		}
+	}
+	return batch, nil
+}
+
+func (wrapper *CardDBWrapperMock) GetCardReviews(ctx context.Context, cardIDs []int) ([]model.Review, error) {
+	reviews := []model.Review{}
+	for _, review := range wrapper.reviews {
+		if containsInt(cardIDs, review.CardID) {
+			reviews = append(reviews, review)
+		}
+	}
+
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].ReviewTime.Before(reviews[j].ReviewTime)
+	})
+
+	return reviews, nil
+}
+
+func (wrapper *CardDBWrapperMock) Reschedule(ctx context.Context, changes []model.CardAnswer) ([]int, error) {
+	changed := []int{}
+	for _, change := range changes {
+		deckID, existing, exists := wrapper.find(change.Card.ID)
+		if !exists || wrapper.hidden(existing) {
+			continue
+		}
+		if !sameTime(existing.LastReviewTime, change.PreviousReviewTime) {
+			changed = append(changed, change.Card.ID)
+			continue
+		}
+		wrapper.db[deckID][change.Card.ID] = withScheduling(existing, change.Card)
 	}
-	return len(wrapper.db[deckID]), nil
+	return changed, nil
 }
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Builds a filtered deck, in a single transaction. The cards it already
+// borrowed are returned to their home decks, then up to the limit of cards
+// matching its query are picked in its order and borrowed from their home
+// decks. Cards borrowed by another filtered deck, suspended cards and buried
+// cards are never picked.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - filtered model.FilteredDeck : The filtered deck to build.
+//
+// Returns:
+//   - []int : The IDs of the borrowed cards, in the order they were picked.
+//   - error : utils.ErrInvalidFilteredDeck if the filtered deck is invalid, nil on success.
+func (wrapper *CardDBWrapper) FillFilteredDeck(ctx context.Context, filtered model.FilteredDeck) ([]int, error) {
+	defer observeQuery("card", "FillFilteredDeck", time.Now())
+
+	if err := filtered.Validate(); err != nil {
+		return nil, err
+	}
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error starting transaction: %s", err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := wrapper.buildEmptyFilteredDecksQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Emptying filtered deck: %s", query))
+
+	if _, err = tx.ExecContext(ctx, query, pq.Array([]int{filtered.DeckID})); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error emptying filtered deck: %s", err))
+		return nil, err
+	}
+
+	query, args := wrapper.buildFillFilteredDeckQueryString(filtered)
+	slog.DebugContext(ctx, fmt.Sprintf("Filling filtered deck: %s", query))
+
+	borrowed, err := queryCardIDs(ctx, tx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error filling filtered deck: %s", err))
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error committing filtered deck: %s", err))
+		return nil, err
+	}
+
+	return borrowed, nil
+}
+
+// Helper function that constructs the SQL query string and its arguments to
+// borrow the cards picked by a filtered deck. The picked cards are numbered
+// in a CTE so that the IDs of the borrowed cards can be returned in order.
+//
+// Parameters:
+//   - filtered model.FilteredDeck : A validated filtered deck.
+//
+// Returns:
+//   - string : The SQL query string.
+//   - []any : The query arguments.
+func (wrapper *CardDBWrapper) buildFillFilteredDeckQueryString(filtered model.FilteredDeck) (string, []any) {
+	args := []any{}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	deckID := arg(filtered.DeckID)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("WITH picked AS (SELECT %s, ROW_NUMBER() OVER (ORDER BY %s) AS position FROM %s WHERE ",
+		cardColumnID, filteredOrderClause(filtered.Order), cardTableName))
+	sb.WriteString(cardFilterCondition(filtered.Query, arg))
+	sb.WriteString(fmt.Sprintf(" AND %s IS NULL AND %s <> %s AND %s", cardColumnOriginalDeckID, cardColumnDeckID, deckID, cardInQueueCondition("")))
+	sb.WriteString(fmt.Sprintf(" ORDER BY position LIMIT %s)", arg(filtered.Limit)))
+	sb.WriteString(fmt.Sprintf(", borrowed AS (UPDATE %s c SET %s = c.%s, %s = %s FROM picked WHERE c.%s = picked.%s RETURNING c.%s)",
+		cardTableName, cardColumnOriginalDeckID, cardColumnDeckID, cardColumnDeckID, deckID, cardColumnID, cardColumnID, cardColumnID))
+	sb.WriteString(fmt.Sprintf(" SELECT borrowed.%s FROM borrowed JOIN picked USING (%s) ORDER BY picked.position", cardColumnID, cardColumnID))
+
+	return sb.String(), args
+}
+
+// Helper function that returns the ORDER BY expression of a filtered deck order,
+// matching model.FilteredDeck.Sort.
+//
+// Parameters:
+//   - order string : A valid filtered deck order.
+//
+// Returns:
+//   - string : The ORDER BY expression.
+func filteredOrderClause(order string) string {
+	switch order {
+	case model.FilteredOrderRandom:
+		return "RANDOM()"
+	case model.FilteredOrderAdded:
+		return fmt.Sprintf("%s DESC, %s ASC", cardColumnCreationTime, cardColumnID)
+	case model.FilteredOrderLapses:
+		return fmt.Sprintf("%s DESC, %s ASC", cardColumnLapses, cardColumnID)
+	default:
+		return fmt.Sprintf("%s ASC, %s ASC", cardColumnNextReviewTime, cardColumnID)
+	}
+}
+
+// Returns the cards borrowed by filtered decks to their home decks.
+// Decks that aren't filtered decks have no borrowed cards and are left as is.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the decks to empty.
+//
+// Returns:
+//   - int : The number of cards returned to their home decks.
+//   - error : An error if the update fails, nil otherwise.
+func (wrapper *CardDBWrapper) EmptyFilteredDecks(ctx context.Context, deckIDs []int) (int, error) {
+	defer observeQuery("card", "EmptyFilteredDecks", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return 0, utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildEmptyFilteredDecksQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Emptying filtered decks: %s", query))
+
+	result, err := wrapper.db.ExecContext(ctx, query, pq.Array(deckIDs))
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error emptying filtered decks: %s", err))
+		return 0, err
+	}
+
+	returned, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(returned), nil
+}
+
+// Helper function that constructs the SQL query string to return the cards
+// borrowed by the decks bound to $1 to their home decks, trashed cards included.
+//
+// Returns:
+//   - string : The SQL query string.
+func (wrapper *CardDBWrapper) buildEmptyFilteredDecksQueryString() string {
+	return fmt.Sprintf("UPDATE %s SET %s = %s, %s = NULL WHERE %s = ANY($1) AND %s IS NOT NULL",
+		cardTableName, cardColumnDeckID, cardColumnOriginalDeckID, cardColumnOriginalDeckID, cardColumnDeckID, cardColumnOriginalDeckID)
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// Suspends a card, removing it from the study queue until it is unsuspended,
+// or unsuspends it.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//   - suspended bool : Whether the card should be suspended.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the card doesn't exist, nil on success.
+func (wrapper *CardDBWrapper) SetSuspended(ctx context.Context, cardID int, suspended bool) error {
+	defer observeQuery("card", "SetSuspended", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s = $2 AND %s",
+		cardTableName, cardColumnSuspended, cardColumnID, cardNotTrashedCondition(""))
+	slog.DebugContext(ctx, fmt.Sprintf("Setting card suspension: %s", query))
+
+	return wrapper.execSingle(ctx, query, suspended, cardID)
+}
+
+// Buries a card, hiding it from the study queue until the given time.
+// Its siblings, the other cards generated from the same note, can be
+// buried along with it.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//   - until time.Time : The time the card comes back into the queue.
+//   - siblings bool : Whether the siblings of the card are buried as well.
+//
+// Returns:
+//   - []int : The IDs of the buried cards, in ascending order.
+//   - error : utils.ErrRecordNotExist if the card doesn't exist, nil on success.
+func (wrapper *CardDBWrapper) Bury(ctx context.Context, cardID int, until time.Time, siblings bool) ([]int, error) {
+	defer observeQuery("card", "Bury", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error starting transaction: %s", err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("UPDATE %s SET %s = $2 WHERE %s AND (%s = $1 OR ($3 AND %s = (SELECT %s FROM %s WHERE %s = $1))) RETURNING %s",
+		cardTableName, cardColumnBuriedUntil, cardNotTrashedCondition(""), cardColumnID,
+		cardColumnNoteID, cardColumnNoteID, cardTableName, cardColumnID, cardColumnID)
+	slog.DebugContext(ctx, fmt.Sprintf("Burying card: %s", query))
+
+	buried, err := queryCardIDs(ctx, tx, query, cardID, until.UTC(), siblings)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error burying card: %s", err))
+		return nil, err
+	}
+
+	if len(buried) == 0 {
+		return nil, utils.ErrRecordNotExist
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error committing card burial: %s", err))
+		return nil, err
+	}
+
+	sort.Ints(buried)
+	return buried, nil
+}
+
+// Brings a buried card back into the study queue. Its siblings stay buried.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the card doesn't exist, nil on success.
+func (wrapper *CardDBWrapper) Unbury(ctx context.Context, cardID int) error {
+	defer observeQuery("card", "Unbury", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = $1 AND %s",
+		cardTableName, cardColumnBuriedUntil, cardColumnID, cardNotTrashedCondition(""))
+	slog.DebugContext(ctx, fmt.Sprintf("Unburying card: %s", query))
+
+	return wrapper.execSingle(ctx, query, cardID)
+}
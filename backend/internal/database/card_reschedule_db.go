@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Counts the cards of the given decks that a reschedule job goes through,
+// which are the cards that aren't new or trashed. Cards in filtered decks
+// are counted with their home deck.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the home decks of the cards.
+//
+// Returns:
+//   - int : The number of cards.
+//   - error : An error if the count fails, nil otherwise.
+func (wrapper *CardDBWrapper) CountRescheduleCards(ctx context.Context, deckIDs []int) (int, error) {
+	defer observeQuery("card", "CountRescheduleCards", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return 0, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", cardTableName, rescheduleCardsCondition())
+	slog.DebugContext(ctx, fmt.Sprintf("Counting cards to reschedule: %s", query))
+
+	var count int
+	if err := wrapper.db.QueryRowContext(ctx, query, pq.Array(deckIDs)).Scan(&count); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error counting cards to reschedule: %s", err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Retrieves the next batch of cards of the given decks that a reschedule job
+// goes through, by ascending ID.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the home decks of the cards.
+//   - afterID int : The ID of the last card of the previous batch, 0 for the first batch.
+//   - limit int : The maximum number of cards in the batch.
+//
+// Returns:
+//   - []model.Card : The cards of the batch, empty once every card has been retrieved.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *CardDBWrapper) GetRescheduleBatch(ctx context.Context, deckIDs []int, afterID int, limit int) ([]model.Card, error) {
+	defer observeQuery("card", "GetRescheduleBatch", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s AND %s > $2 ORDER BY %s ASC LIMIT $3",
+		cardSelectColumns(), cardTableName, rescheduleCardsCondition(), cardColumnID, cardColumnID)
+	slog.DebugContext(ctx, fmt.Sprintf("Getting cards to reschedule: %s", query))
+
+	rows, err := wrapper.db.QueryContext(ctx, query, pq.Array(deckIDs), afterID, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting cards to reschedule: %s", err))
+		return nil, err
+	}
+
+	return scanCards(ctx, rows)
+}
+
+// A helper function that returns the condition matching the cards to
+// reschedule, with the IDs of their home decks bound to $1.
+func rescheduleCardsCondition() string {
+	return fmt.Sprintf("%s AND %s <> %d AND COALESCE(%s, %s) = ANY($1)",
+		cardNotTrashedCondition(""), cardColumnState, model.CardStateNew, cardColumnOriginalDeckID, cardColumnDeckID)
+}
+
+// Retrieves the review log entries of the given cards, oldest first.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardIDs []int : The unique IDs of the cards.
+//
+// Returns:
+//   - []model.Review : The review log entries.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *CardDBWrapper) GetCardReviews(ctx context.Context, cardIDs []int) ([]model.Review, error) {
+	defer observeQuery("card", "GetCardReviews", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s r WHERE r.%s = ANY($1) ORDER BY r.%s ASC, r.%s ASC",
+		reviewSelectColumns("r"), reviewTableName, reviewColumnCardID, reviewColumnReviewTime, reviewColumnID)
+	slog.DebugContext(ctx, fmt.Sprintf("Getting card reviews: %s", query))
+
+	rows, err := wrapper.db.QueryContext(ctx, query, pq.Array(cardIDs))
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting card reviews: %s", err))
+		return nil, err
+	}
+
+	return scanReviews(ctx, rows)
+}
+
+// Saves the scheduling state of rescheduled cards in a single transaction.
+// Only the scheduling state is replaced, and only on the cards that weren't
+// reviewed since they were read, so that neither an answer nor an edit made
+// meanwhile is overwritten. Cards that were deleted in the meantime are skipped.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - changes []model.CardAnswer : The rescheduled cards, with their last review times when read.
+//
+// Returns:
+//   - []int : The unique IDs of the cards skipped because they were reviewed since they were read.
+//   - error : An error if the update fails, nil otherwise.
+func (wrapper *CardDBWrapper) Reschedule(ctx context.Context, changes []model.CardAnswer) ([]int, error) {
+	defer observeQuery("card", "Reschedule", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error starting transaction: %s", err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := wrapper.buildUpdateSchedulingQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Rescheduling cards: %s", query))
+
+	changed := []int{}
+	for _, change := range changes {
+		result, err := tx.ExecContext(ctx, query, schedulingArgs(change.Card, change.PreviousReviewTime)...)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Error rescheduling card: %s", err))
+			return nil, err
+		}
+
+		err = checkSchedulingUpdated(ctx, tx, result, change.Card.ID)
+		if err == utils.ErrCardChanged {
+			changed = append(changed, change.Card.ID)
+		} else if err != nil && err != utils.ErrRecordNotExist {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error committing rescheduled cards: %s", err))
+		return nil, err
+	}
+
+	return changed, nil
+}
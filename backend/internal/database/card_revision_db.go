@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	revisionTableName          = "card_revisions"
+	revisionColumnID           = "id"
+	revisionColumnCardID       = "card_id"
+	revisionColumnRevision     = "revision"
+	revisionColumnContent      = "content"
+	revisionColumnSource       = "source"
+	revisionColumnTags         = "tags"
+	revisionColumnCreationTime = "creation_time"
+)
+
+// A helper function that constructs the SQL query string
+// to create the card revisions table.
+//
+// Returns:
+//   - string : The SQL query string to create the card revisions table.
+func buildCreateRevisionTableQueryString() string {
+	var sb strings.Builder
+
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(revisionTableName)
+	sb.WriteString(" (")
+	sb.WriteString(fmt.Sprintf("%s SERIAL PRIMARY KEY, ", revisionColumnID))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL REFERENCES %s(%s) ON DELETE CASCADE, ", revisionColumnCardID, cardTableName, cardColumnID))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s > 0), ", revisionColumnRevision, revisionColumnRevision))
+	sb.WriteString(fmt.Sprintf("%s TEXT NOT NULL, ", revisionColumnContent))
+	sb.WriteString(fmt.Sprintf("%s TEXT, ", revisionColumnSource))
+	sb.WriteString(fmt.Sprintf("%s TEXT[] NOT NULL DEFAULT '{}', ", revisionColumnTags))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT %s, ", revisionColumnCreationTime, utcDefault("")))
+	sb.WriteString(fmt.Sprintf("UNIQUE (%s, %s)", revisionColumnCardID, revisionColumnRevision))
+	sb.WriteString(")")
+
+	query := sb.String()
+	return query
+}
+
+// Retrieves a single card that isn't in the trash.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//
+// Returns:
+//   - model.Card : The card.
+//   - error : utils.ErrRecordNotExist if the card doesn't exist, nil on success.
+func (wrapper *CardDBWrapper) GetSingle(ctx context.Context, cardID int) (model.Card, error) {
+	defer observeQuery("card", "GetSingle", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.Card{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 AND %s",
+		cardSelectColumns(), cardTableName, cardColumnID, cardNotTrashedCondition(""))
+	slog.DebugContext(ctx, fmt.Sprintf("Getting single card: %s", query))
+
+	card, err := scanCard(wrapper.db.QueryRowContext(ctx, query, cardID))
+	if err == sql.ErrNoRows {
+		return model.Card{}, utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting single card: %s", err))
+		return model.Card{}, err
+	}
+
+	return card, nil
+}
+
+// Modifies the content, source and tags of a card. The previous version
+// is saved as a new revision in the same transaction.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - card model.Card : The card with its ID and new content, source and tags.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the card doesn't exist, nil on success.
+func (wrapper *CardDBWrapper) Modify(ctx context.Context, card model.Card) error {
+	defer observeQuery("card", "Modify", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error starting transaction: %s", err))
+		return err
+	}
+	defer tx.Rollback()
+
+	// The lock is taken by its own statement, so that the revision number is
+	// computed by a later statement which sees the revisions committed by the
+	// edits that held the lock before
+	query := wrapper.buildLockCardQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Locking card: %s", query))
+
+	var lockedID int
+	err = tx.QueryRowContext(ctx, query, card.ID).Scan(&lockedID)
+	if err == sql.ErrNoRows {
+		return utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error locking card: %s", err))
+		return err
+	}
+
+	query = wrapper.buildSaveRevisionQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Saving card revision: %s", query))
+
+	_, err = tx.ExecContext(ctx, query, card.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error saving card revision: %s", err))
+		return err
+	}
+
+	query = fmt.Sprintf("UPDATE %s SET %s = $1, %s = $2, %s = $3, %s = %s WHERE %s = $4",
+		cardTableName, cardColumnContent, cardColumnSource, cardColumnTags, cardColumnModificationTime, sqlNowUTC, cardColumnID)
+	slog.DebugContext(ctx, fmt.Sprintf("Modifying card: %s", query))
+
+	_, err = tx.ExecContext(ctx, query, card.Content, card.Source, pq.Array(model.NormalizeTags(card.Tags)), card.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error modifying card: %s", err))
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Helper function that constructs the SQL query string to lock a card that
+// isn't in the trash until the end of the transaction, so that concurrent
+// edits of the card save their revisions one after the other.
+//
+// Returns:
+//   - string : The SQL query string to lock a card.
+func (wrapper *CardDBWrapper) buildLockCardQueryString() string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 AND %s FOR UPDATE",
+		cardColumnID, cardTableName, cardColumnID, cardNotTrashedCondition(""))
+}
+
+// Helper function that constructs the SQL query string to copy the current
+// version of a card into the next revision. The card must be locked with
+// buildLockCardQueryString by an earlier statement of the transaction, since
+// the next revision number is only right if no other edit can commit one
+// after the statement starts.
+//
+// Returns:
+//   - string : The SQL query string to save a revision.
+func (wrapper *CardDBWrapper) buildSaveRevisionQueryString() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("WITH current AS (SELECT %s, %s, %s, %s FROM %s WHERE %s = $1) ",
+		cardColumnID, cardColumnContent, cardColumnSource, cardColumnTags, cardTableName, cardColumnID))
+	sb.WriteString(fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s) ",
+		revisionTableName, revisionColumnCardID, revisionColumnRevision, revisionColumnContent, revisionColumnSource, revisionColumnTags))
+	sb.WriteString(fmt.Sprintf("SELECT %s, (SELECT COALESCE(MAX(%s), 0) + 1 FROM %s WHERE %s = $1), %s, %s, %s FROM current",
+		cardColumnID, revisionColumnRevision, revisionTableName, revisionColumnCardID, cardColumnContent, cardColumnSource, cardColumnTags))
+
+	query := sb.String()
+	return query
+}
+
+// Retrieves all revisions of a card, oldest first.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//
+// Returns:
+//   - []model.CardRevision : The revisions of the card.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *CardDBWrapper) GetRevisions(ctx context.Context, cardID int) ([]model.CardRevision, error) {
+	defer observeQuery("card", "GetRevisions", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 ORDER BY %s ASC",
+		revisionSelectColumns(), revisionTableName, revisionColumnCardID, revisionColumnRevision)
+	slog.DebugContext(ctx, fmt.Sprintf("Getting card revisions: %s", query))
+
+	rows, err := wrapper.db.QueryContext(ctx, query, cardID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting card revisions: %s", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.CardRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Error scanning card revision: %s", err))
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Retrieves a single revision of a card.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - cardID int : The unique ID of the card.
+//   - revision int : The revision number.
+//
+// Returns:
+//   - model.CardRevision : The revision.
+//   - error : utils.ErrRecordNotExist if the revision doesn't exist, nil on success.
+func (wrapper *CardDBWrapper) GetRevision(ctx context.Context, cardID int, revision int) (model.CardRevision, error) {
+	defer observeQuery("card", "GetRevision", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.CardRevision{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 AND %s = $2",
+		revisionSelectColumns(), revisionTableName, revisionColumnCardID, revisionColumnRevision)
+	slog.DebugContext(ctx, fmt.Sprintf("Getting card revision: %s", query))
+
+	rev, err := scanRevision(wrapper.db.QueryRowContext(ctx, query, cardID, revision))
+	if err == sql.ErrNoRows {
+		return model.CardRevision{}, utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting card revision: %s", err))
+		return model.CardRevision{}, err
+	}
+
+	return rev, nil
+}
+
+// A helper function that returns the comma separated list of columns
+// that scanRevision expects, in order.
+func revisionSelectColumns() string {
+	return strings.Join([]string{
+		revisionColumnID,
+		revisionColumnCardID,
+		revisionColumnRevision,
+		revisionColumnContent,
+		revisionColumnSource,
+		revisionColumnTags,
+		revisionColumnCreationTime,
+	}, ", ")
+}
+
+// A helper function that scans a single revision selected with revisionSelectColumns.
+func scanRevision(scanner interface{ Scan(...any) error }) (model.CardRevision, error) {
+	var revision model.CardRevision
+	var source sql.NullString
+
+	err := scanner.Scan(
+		&revision.ID,
+		&revision.CardID,
+		&revision.Revision,
+		&revision.Content,
+		&source,
+		(*pq.StringArray)(&revision.Tags),
+		&revision.CreationTime,
+	)
+	if err != nil {
+		return model.CardRevision{}, err
+	}
+
+	revision.Source = source.String
+	return revision, nil
+}
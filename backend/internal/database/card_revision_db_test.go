@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModifySavesRevision(t *testing.T) {
+	lock := fakeStatement{prefix: "SELECT id FROM cards WHERE id = $1", columns: []string{"id"}, rows: [][]driver.Value{{int64(3)}}}
+	save := fakeStatement{prefix: "WITH current AS (SELECT id, content, source, tags FROM cards WHERE id = $1) INSERT INTO card_revisions"}
+	update := fakeStatement{prefix: "UPDATE cards SET content = $1"}
+
+	testCases := []struct {
+		name        string
+		script      []fakeStatement
+		expectedErr error
+		expectedLog []string
+	}{
+		{
+			name:        "Card edited",
+			script:      []fakeStatement{lock, save, update},
+			expectedLog: []string{"BEGIN", lock.prefix, save.prefix, update.prefix, "COMMIT"},
+		},
+		{
+			name:        "Card not found",
+			script:      []fakeStatement{{prefix: lock.prefix, columns: []string{"id"}}},
+			expectedErr: utils.ErrRecordNotExist,
+			expectedLog: []string{"BEGIN", lock.prefix, "ROLLBACK"},
+		},
+		{
+			name:        "Revision not saved",
+			script:      []fakeStatement{lock, {prefix: save.prefix, err: errors.New("revision failed")}},
+			expectedErr: errors.New("revision failed"),
+			expectedLog: []string{"BEGIN", lock.prefix, save.prefix, "ROLLBACK"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, fake := newFakeDB(tc.script...)
+			card := model.NewCard(0, "New content", "")
+			card.ID = 3
+
+			err := NewCardDBWrapper(db).Modify(context.Background(), card)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedLog, fake.Log(),
+				"Expected the card to be locked by its own statement before its revision is saved, in the transaction of the edit")
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"flash-learn/internal/model"
 	"flash-learn/internal/utils"
@@ -8,6 +9,8 @@ import (
 	"log/slog"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const (
@@ -18,6 +21,11 @@ const (
 	deckColumnCreationDate         = "creation_date"
 	deckColumnModificationDate     = "modification_date"
 	deckColumnLastStudyDate        = "last_study_date"
+	deckColumnParentID             = "parent_id"
+	deckColumnPresetID             = "preset_id"
+	deckColumnDeletedAt            = "deleted_at"
+	deckUniqueNameIndex            = "decks_parent_id_name_active_key"
+	DeckTrashRetention             = 30 * 24 * time.Hour
 	DeckColumnNameMaxLength        = 64
 	DeckColumnDescriptionMaxLength = 255
 )
@@ -27,12 +35,42 @@ const (
 // allowing for easier testing and mocking.
 type DBWrapper interface {
 	CreateTable() error
-	Insert(deck model.Deck) (int, error)
-	GetSingle(deckID int) (model.Deck, error)
-	GetCount() (int, error)
-	GetAll() ([]model.Deck, error)
-	Modify(deck model.Deck) error
-	Delete(id int) error
+	Insert(ctx context.Context, deck model.Deck) (int, error)
+	GetSingle(ctx context.Context, deckID int) (model.Deck, error)
+	GetCount(ctx context.Context) (int, error)
+	GetAll(ctx context.Context) ([]model.Deck, error)
+	GetAllSummaries(ctx context.Context, studiedSince time.Time, dueBefore time.Time) ([]model.DeckSummary, error)
+	Modify(ctx context.Context, deck model.Deck) error
+	Move(ctx context.Context, deckID int, parentID *int) error
+	SetPreset(ctx context.Context, deckID int, presetID *int) error
+	SetLastStudyDate(ctx context.Context, deckIDs []int, date time.Time) error
+	GetDescendantIDs(ctx context.Context, deckID int) ([]int, error)
+	Delete(ctx context.Context, id int, options DeckDeleteOptions) error
+	GetTrash(ctx context.Context) ([]model.Deck, error)
+	Restore(ctx context.Context, id int, deletedAfter time.Time) error
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+// DeckDeleteMode selects what happens to a deleted deck and its cards.
+type DeckDeleteMode int
+
+const (
+	// DeckDeleteTrash soft-deletes the deck so it can be restored from the trash.
+	DeckDeleteTrash DeckDeleteMode = iota
+	// DeckDeleteCascade permanently deletes the deck along with its cards.
+	DeckDeleteCascade
+	// DeckDeleteMoveCards moves the cards to another deck before permanently deleting the deck.
+	DeckDeleteMoveCards
+)
+
+// DeckDeleteOptions controls what happens to a deleted deck, its cards and its sub-decks.
+type DeckDeleteOptions struct {
+	Mode DeckDeleteMode
+	// MoveToDeckID is the deck that receives the cards when Mode is DeckDeleteMoveCards.
+	MoveToDeckID int
+	// ReparentChildren moves the children of the deleted deck up to its parent
+	// instead of deleting them along with it.
+	ReparentChildren bool
 }
 
 type DeckDBWrapper struct {
@@ -55,6 +93,8 @@ func NewDeckDBWrapper(db *sql.DB) *DeckDBWrapper {
 // Returns:
 //   - error : An error if the table creation fails, nil otherwise.
 func (wrapper *DeckDBWrapper) CreateTable() error {
+	defer observeQuery("deck", "CreateTable", time.Now())
+
 	if wrapper.db == nil {
 		slog.Error("Database connection is nil")
 		return utils.ErrDatabaseNotExist
@@ -67,9 +107,19 @@ func (wrapper *DeckDBWrapper) CreateTable() error {
 
 	if err != nil {
 		slog.Error("Error creating decks table", "error", err)
+		return err
 	}
 
-	return err
+	for _, query := range wrapper.buildMigrationQueryStrings() {
+		slog.Debug("Migrating decks table", "query", query)
+
+		if _, err = wrapper.db.Exec(query); err != nil {
+			slog.Error("Error migrating decks table", "error", err)
+			return err
+		}
+	}
+
+	return nil
 }
 
 // A helper function that constructs the SQL query string
@@ -83,51 +133,125 @@ func (wrapper *DeckDBWrapper) buildCreateTableQueryString() string {
 	sb.WriteString(deckTableName)
 	sb.WriteString(" (")
 	sb.WriteString(fmt.Sprintf("%s SERIAL PRIMARY KEY, ", deckColumnID))
-	sb.WriteString(fmt.Sprintf("%s VARCHAR(%d) NOT NULL UNIQUE, ", deckColumnName, DeckColumnNameMaxLength))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(%d) NOT NULL, ", deckColumnName, DeckColumnNameMaxLength))
 	sb.WriteString(fmt.Sprintf("%s VARCHAR(%d) NOT NULL, ", deckColumnDescription, DeckColumnDescriptionMaxLength))
-	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT NOW(), ", deckColumnCreationDate))
-	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT NOW(), ", deckColumnModificationDate))
-	sb.WriteString(fmt.Sprintf("%s TIMESTAMP ", deckColumnLastStudyDate))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT %s, ", deckColumnCreationDate, utcDefault("")))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP DEFAULT %s, ", deckColumnModificationDate, utcDefault("")))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP, ", deckColumnLastStudyDate))
+	sb.WriteString(fmt.Sprintf("%s INT REFERENCES %s(%s) ON DELETE CASCADE, ", deckColumnParentID, deckTableName, deckColumnID))
+	sb.WriteString(fmt.Sprintf("%s INT REFERENCES %s(%s) ON DELETE SET NULL, ", deckColumnPresetID, presetTableName, presetColumnID))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP ", deckColumnDeletedAt))
 	sb.WriteString(")")
 
 	query := sb.String()
 	return query
 }
 
+// A helper function that constructs the SQL query strings that bring
+// a decks table created by an older version up to date. Deck names used to
+// be unique across all decks, now they only need to be unique among the
+// siblings that aren't in the trash. Deck times used to be stored in the
+// time zone of the session, now they are stored in UTC.
+//
+// Returns:
+//   - []string : The SQL query strings to run in order.
+func (wrapper *DeckDBWrapper) buildMigrationQueryStrings() []string {
+	return []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s INT REFERENCES %s(%s) ON DELETE CASCADE",
+			deckTableName, deckColumnParentID, deckTableName, deckColumnID),
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s_%s_key", deckTableName, deckTableName, deckColumnName),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s INT REFERENCES %s(%s) ON DELETE SET NULL",
+			deckTableName, deckColumnPresetID, presetTableName, presetColumnID),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TIMESTAMP", deckTableName, deckColumnDeletedAt),
+		fmt.Sprintf("DROP INDEX IF EXISTS %s_%s_%s_key", deckTableName, deckColumnParentID, deckColumnName),
+		fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (COALESCE(%s, 0), %s) WHERE %s IS NULL",
+			deckUniqueNameIndex, deckTableName, deckColumnParentID, deckColumnName, deckColumnDeletedAt),
+		buildConvertToUTCQueryString(deckTableName, deckColumnCreationDate, []string{deckColumnCreationDate,
+			deckColumnModificationDate, deckColumnLastStudyDate, deckColumnDeletedAt}),
+		buildSetUTCDefaultQueryString(deckTableName, deckColumnCreationDate, ""),
+		buildSetUTCDefaultQueryString(deckTableName, deckColumnModificationDate, ""),
+	}
+}
+
+// A helper function that checks whether an error returned by the database
+// is caused by a deck name that is already used by one of its siblings.
+//
+// Parameters:
+//   - err error : The error returned by the database.
+//
+// Returns:
+//   - bool : True if the error is a duplicate deck name violation.
+func isDuplicateDeckNameError(err error) bool {
+	return err.Error() == fmt.Sprintf("pq: duplicate key value violates unique constraint \"%s\"", deckUniqueNameIndex)
+}
+
+// A helper function that checks whether an error returned by the database
+// is caused by a parent deck that doesn't exist.
+//
+// Parameters:
+//   - err error : The error returned by the database.
+//
+// Returns:
+//   - bool : True if the error is a parent deck foreign key violation.
+func isInvalidParentError(err error) bool {
+	return strings.Contains(err.Error(), fmt.Sprintf("\"%s_%s_fkey\"", deckTableName, deckColumnParentID))
+}
+
+// A helper function that converts a nullable integer scanned from the database.
+//
+// Parameters:
+//   - value sql.NullInt64 : The scanned value.
+//
+// Returns:
+//   - *int : A pointer to the value, nil if the value is NULL.
+func nullIntToPointer(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+
+	i := int(value.Int64)
+	return &i
+}
+
 // Inserts a new deck into the database and returns its unique ID.
 //
 // Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
 //   - deck model.Deck : Details of the deck to be inserted as a model.Deck object.
 //
 // Returns:
 //   - int : The unique ID of the inserted deck.
 //   - error : An error if the insertion fails, nil otherwise.
-func (wrapper *DeckDBWrapper) Insert(deck model.Deck) (int, error) {
+func (wrapper *DeckDBWrapper) Insert(ctx context.Context, deck model.Deck) (int, error) {
+	defer observeQuery("deck", "Insert", time.Now())
+
 	if len(deck.Name) > DeckColumnNameMaxLength || len(deck.Description) > DeckColumnDescriptionMaxLength {
-		slog.Error("Deck name or description exceeds maximum length")
+		slog.ErrorContext(ctx, "Deck name or description exceeds maximum length")
 		return -1, utils.ErrMaxLengthExceeded
 	}
 
 	if wrapper.db == nil {
-		slog.Error("Database connection is nil")
+		slog.ErrorContext(ctx, "Database connection is nil")
 		return -1, utils.ErrDatabaseNotExist
 	}
 
 	query := wrapper.buildInsertQueryString()
-	slog.Debug("Inserting deck", "query", query)
-	err := wrapper.db.QueryRow(query, deck.Name, deck.Description).Scan(&deck.ID)
+	slog.DebugContext(ctx, "Inserting deck", "query", query)
+	err := wrapper.db.QueryRowContext(ctx, query, deck.Name, deck.Description, deck.ParentID).Scan(&deck.ID)
 
 	if err != nil {
-		slog.Error("Error inserting deck", "error", err)
+		slog.ErrorContext(ctx, "Error inserting deck", "error", err)
 
-		if err.Error() == "pq: duplicate key value violates unique constraint \"decks_name_key\"" {
+		if isDuplicateDeckNameError(err) {
 			return -1, utils.ErrDuplicateKeyViolation
+		} else if isInvalidParentError(err) {
+			return -1, utils.ErrInvalidParent
 		}
 
 		return -1, err
 	}
 
-	slog.Debug(fmt.Sprintf("Inserted deck %d", deck.ID))
+	slog.DebugContext(ctx, fmt.Sprintf("Inserted deck %d", deck.ID))
 
 	return deck.ID, nil
 }
@@ -144,7 +268,9 @@ func (wrapper *DeckDBWrapper) buildInsertQueryString() string {
 	sb.WriteString(deckColumnName)
 	sb.WriteString(", ")
 	sb.WriteString(deckColumnDescription)
-	sb.WriteString(") VALUES ($1, $2) RETURNING ")
+	sb.WriteString(", ")
+	sb.WriteString(deckColumnParentID)
+	sb.WriteString(") VALUES ($1, $2, $3) RETURNING ")
 	sb.WriteString(deckColumnID)
 
 	query := sb.String()
@@ -154,36 +280,42 @@ func (wrapper *DeckDBWrapper) buildInsertQueryString() string {
 // Retrieves a single deck from the database based on its unique ID.
 //
 // Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
 //   - deckID int : The unique ID of the deck to be retrieved.
 //
 // Returns:
 //   - model.Deck : The details of the retrieved deck as a model.Deck object.
 //   - error : An error if the retrieval fails, nil otherwise.
-func (wrapper *DeckDBWrapper) GetSingle(deckID int) (model.Deck, error) {
+func (wrapper *DeckDBWrapper) GetSingle(ctx context.Context, deckID int) (model.Deck, error) {
+	defer observeQuery("deck", "GetSingle", time.Now())
+
 	if wrapper.db == nil {
-		slog.Error("Database connection is nil")
+		slog.ErrorContext(ctx, "Database connection is nil")
 		return model.Deck{}, utils.ErrDatabaseNotExist
 	}
 
 	var deck model.Deck
 	var lastStudyDate sql.NullTime
+	var parentID, presetID sql.NullInt64
 
 	query := wrapper.buildGetSingleQueryString()
-	slog.Debug("Getting single deck", "query", query)
+	slog.DebugContext(ctx, "Getting single deck", "query", query)
 
-	err := wrapper.db.QueryRow(query, deckID).Scan(
+	err := wrapper.db.QueryRowContext(ctx, query, deckID).Scan(
 		&deck.ID,
 		&deck.Name,
 		&deck.Description,
 		&deck.CreationDate,
 		&deck.ModificationDate,
-		&lastStudyDate)
-
-	if err != nil {
-		slog.Error("Error getting single deck", "error", err)
-		return model.Deck{}, err
-	} else if err == sql.ErrNoRows {
-		slog.Error(fmt.Sprintf("No deck found with ID %d", deckID))
+		&lastStudyDate,
+		&parentID,
+		&presetID)
+
+	if err == sql.ErrNoRows {
+		slog.ErrorContext(ctx, fmt.Sprintf("No deck found with ID %d", deckID))
+		return model.Deck{}, utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error getting single deck", "error", err)
 		return model.Deck{}, err
 	}
 
@@ -192,7 +324,17 @@ func (wrapper *DeckDBWrapper) GetSingle(deckID int) (model.Deck, error) {
 	} else {
 		deck.LastStudyDate = time.Time{}
 	}
-	slog.Debug("Updated deck last study date", "lastStudyDate", lastStudyDate)
+	slog.DebugContext(ctx, "Updated deck last study date", "lastStudyDate", lastStudyDate)
+	deck.ParentID = nullIntToPointer(parentID)
+	deck.PresetID = nullIntToPointer(presetID)
+
+	if deck.PresetID != nil {
+		preset, err := getPreset(ctx, wrapper.db, *deck.PresetID)
+		if err != nil {
+			return model.Deck{}, err
+		}
+		deck.Preset = &preset
+	}
 
 	return deck, nil
 }
@@ -215,11 +357,17 @@ func (wrapper *DeckDBWrapper) buildGetSingleQueryString() string {
 	sb.WriteString(deckColumnModificationDate)
 	sb.WriteString(", ")
 	sb.WriteString(deckColumnLastStudyDate)
+	sb.WriteString(", ")
+	sb.WriteString(deckColumnParentID)
+	sb.WriteString(", ")
+	sb.WriteString(deckColumnPresetID)
 	sb.WriteString(" FROM ")
 	sb.WriteString(deckTableName)
 	sb.WriteString(" WHERE ")
 	sb.WriteString(deckColumnID)
-	sb.WriteString(" = $1")
+	sb.WriteString(" = $1 AND ")
+	sb.WriteString(deckColumnDeletedAt)
+	sb.WriteString(" IS NULL")
 
 	query := sb.String()
 	return query
@@ -227,75 +375,98 @@ func (wrapper *DeckDBWrapper) buildGetSingleQueryString() string {
 
 // Retrieves all decks from the database.
 //
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//
 // Returns:
 //   - []model.Deck : A slice of model.Deck objects representing all decks.
 //   - error : An error if the retrieval fails, nil otherwise.
-func (wrapper *DeckDBWrapper) GetAll() ([]model.Deck, error) {
+func (wrapper *DeckDBWrapper) GetAll(ctx context.Context) ([]model.Deck, error) {
+	defer observeQuery("deck", "GetAll", time.Now())
+
 	if wrapper.db == nil {
-		slog.Error("Database connection is nil")
+		slog.ErrorContext(ctx, "Database connection is nil")
 		return nil, utils.ErrDatabaseNotExist
 	}
 
 	query := wrapper.buildGetAllQueryString()
-	slog.Debug("Getting all decks", "query", query)
+	slog.DebugContext(ctx, "Getting all decks", "query", query)
 
-	rows, err := wrapper.db.Query(query)
+	rows, err := wrapper.db.QueryContext(ctx, query)
 	if err != nil {
-		slog.Error("Error getting all decks", "error", err)
+		slog.ErrorContext(ctx, "Error getting all decks", "error", err)
 		return nil, err
 	} else if err == sql.ErrNoRows {
-		slog.Error("No decks found")
+		slog.ErrorContext(ctx, "No decks found")
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var decks []model.Deck
+	decks := []model.Deck{}
 
 	for rows.Next() {
 		var deck model.Deck
+		var lastStudyDate sql.NullTime
+		var parentID, presetID sql.NullInt64
 
 		err := rows.Scan(
 			&deck.ID,
 			&deck.Name,
 			&deck.Description,
+			&deck.CreationDate,
+			&deck.ModificationDate,
+			&lastStudyDate,
+			&parentID,
+			&presetID,
 		)
 
 		if err != nil {
 			return nil, err
 		}
 
+		if lastStudyDate.Valid {
+			deck.LastStudyDate = lastStudyDate.Time
+		}
+		deck.ParentID = nullIntToPointer(parentID)
+		deck.PresetID = nullIntToPointer(presetID)
+
 		decks = append(decks, deck)
 	}
 
-	slog.Debug(fmt.Sprintf("Fetched %d decks", len(decks)))
+	slog.DebugContext(ctx, fmt.Sprintf("Fetched %d decks", len(decks)))
 
 	return decks, nil
 }
 
 // Counts the total number of decks in the database.
 //
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//
 // Returns:
 //   - int : The total number of decks.
 //   - error : An error if the count fails, nil otherwise.
-func (wrapper *DeckDBWrapper) GetCount() (int, error) {
+func (wrapper *DeckDBWrapper) GetCount(ctx context.Context) (int, error) {
+	defer observeQuery("deck", "GetCount", time.Now())
+
 	if wrapper.db == nil {
-		slog.Error("Database connection is nil")
+		slog.ErrorContext(ctx, "Database connection is nil")
 		return 0, utils.ErrDatabaseNotExist
 	}
 
 	query := wrapper.buildGetCountQueryString()
-	slog.Debug("Getting deck count", "query", query)
+	slog.DebugContext(ctx, "Getting deck count", "query", query)
 
 	var count int
 
-	err := wrapper.db.QueryRow(query).Scan(&count)
+	err := wrapper.db.QueryRowContext(ctx, query).Scan(&count)
 	if err != nil {
-		slog.Error("Error getting deck count", "error", err)
+		slog.ErrorContext(ctx, "Error getting deck count", "error", err)
 		return -1, err
 	}
 
-	slog.Debug(fmt.Sprintf("Deck count: %d", count))
+	slog.DebugContext(ctx, fmt.Sprintf("Deck count: %d", count))
 
 	return count, nil
 }
@@ -310,6 +481,9 @@ func (wrapper *DeckDBWrapper) buildGetCountQueryString() string {
 	sb.WriteString(deckColumnID)
 	sb.WriteString(") FROM ")
 	sb.WriteString(deckTableName)
+	sb.WriteString(" WHERE ")
+	sb.WriteString(deckColumnDeletedAt)
+	sb.WriteString(" IS NULL")
 
 	query := sb.String()
 	return query
@@ -327,9 +501,21 @@ func (wrapper *DeckDBWrapper) buildGetAllQueryString() string {
 	sb.WriteString(deckColumnName)
 	sb.WriteString(", ")
 	sb.WriteString(deckColumnDescription)
+	sb.WriteString(", ")
+	sb.WriteString(deckColumnCreationDate)
+	sb.WriteString(", ")
+	sb.WriteString(deckColumnModificationDate)
+	sb.WriteString(", ")
+	sb.WriteString(deckColumnLastStudyDate)
+	sb.WriteString(", ")
+	sb.WriteString(deckColumnParentID)
+	sb.WriteString(", ")
+	sb.WriteString(deckColumnPresetID)
 	sb.WriteString(" FROM ")
 	sb.WriteString(deckTableName)
-	sb.WriteString(" ORDER BY ")
+	sb.WriteString(" WHERE ")
+	sb.WriteString(deckColumnDeletedAt)
+	sb.WriteString(" IS NULL ORDER BY ")
 	sb.WriteString(deckColumnName)
 	sb.WriteString(" ASC")
 
@@ -337,21 +523,136 @@ func (wrapper *DeckDBWrapper) buildGetAllQueryString() string {
 	return query
 }
 
+// Retrieves all decks along with their total card count and the number of
+// new, learning and review cards that are due, using a single query.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - studiedSince time.Time : The start of the day, the cards studied since are taken from the daily limits.
+//   - dueBefore time.Time : Learning and review cards scheduled before this time are counted as due.
+//
+// Returns:
+//   - []model.DeckSummary : A slice of model.DeckSummary objects sorted by deck name.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *DeckDBWrapper) GetAllSummaries(ctx context.Context, studiedSince time.Time, dueBefore time.Time) ([]model.DeckSummary, error) {
+	defer observeQuery("deck", "GetAllSummaries", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildGetAllSummariesQueryString()
+	slog.DebugContext(ctx, "Getting all deck summaries", "query", query)
+
+	rows, err := wrapper.db.QueryContext(ctx, query, dueBefore.UTC(), studiedSince.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting all deck summaries", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	summaries := []model.DeckSummary{}
+
+	for rows.Next() {
+		var summary model.DeckSummary
+		var lastStudyDate sql.NullTime
+		var parentID, presetID sql.NullInt64
+
+		err := rows.Scan(
+			&summary.ID,
+			&summary.Name,
+			&summary.Description,
+			&summary.CreationDate,
+			&summary.ModificationDate,
+			&lastStudyDate,
+			&parentID,
+			&presetID,
+			&summary.TotalCards,
+			&summary.NewCount,
+			&summary.LearningCount,
+			&summary.ReviewCount,
+		)
+
+		if err != nil {
+			slog.ErrorContext(ctx, "Error scanning deck summary", "error", err)
+			return nil, err
+		}
+
+		if lastStudyDate.Valid {
+			summary.LastStudyDate = lastStudyDate.Time
+		}
+		summary.ParentID = nullIntToPointer(parentID)
+		summary.PresetID = nullIntToPointer(presetID)
+
+		summaries = append(summaries, summary)
+	}
+
+	if err = rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error iterating deck summaries", "error", err)
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Fetched %d deck summaries", len(summaries)))
+
+	return summaries, nil
+}
+
+// Helper function that constructs the SQL query string to retrieve all decks
+// with their card counts. Cards are left joined so that empty decks are included,
+// and the new and review counts are capped by what is left of the daily limits
+// of the deck's preset once the cards studied today are taken out.
+//
+// Returns:
+//   - string : The SQL query string to retrieve all deck summaries.
+func (wrapper *DeckDBWrapper) buildGetAllSummariesQueryString() string {
+	defaults := model.DefaultDeckPreset()
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(fmt.Sprintf("d.%s, d.%s, d.%s, d.%s, d.%s, d.%s, d.%s, d.%s, ",
+		deckColumnID, deckColumnName, deckColumnDescription,
+		deckColumnCreationDate, deckColumnModificationDate, deckColumnLastStudyDate,
+		deckColumnParentID, deckColumnPresetID))
+	sb.WriteString(fmt.Sprintf("COUNT(c.%s), ", cardColumnID))
+	inQueue := cardInQueueCondition("c")
+	sb.WriteString(fmt.Sprintf("LEAST(COUNT(c.%s) FILTER (WHERE c.%s = %d AND %s), GREATEST(COALESCE(p.%s, %d) - COALESCE(MAX(s.%s), 0), 0)), ",
+		cardColumnID, cardColumnState, model.CardStateNew, inQueue, presetColumnNewCardsPerDay, defaults.NewCardsPerDay, studiedColumnNewCards))
+	sb.WriteString(fmt.Sprintf("COUNT(c.%s) FILTER (WHERE c.%s IN (%d, %d) AND c.%s < $1 AND %s), ",
+		cardColumnID, cardColumnState, model.CardStateLearning, model.CardStateRelearning, cardColumnNextReviewTime, inQueue))
+	sb.WriteString(fmt.Sprintf("LEAST(COUNT(c.%s) FILTER (WHERE c.%s = %d AND c.%s < $1 AND %s), GREATEST(COALESCE(p.%s, %d) - COALESCE(MAX(s.%s), 0), 0))",
+		cardColumnID, cardColumnState, model.CardStateReview, cardColumnNextReviewTime, inQueue, presetColumnMaxReviewsPerDay, defaults.MaxReviewsPerDay, studiedColumnReviews))
+	sb.WriteString(fmt.Sprintf(" FROM %s d LEFT JOIN %s c ON c.%s = d.%s AND c.%s IS NULL",
+		deckTableName, cardTableName, cardColumnDeckID, deckColumnID, cardColumnDeletedAt))
+	sb.WriteString(fmt.Sprintf(" LEFT JOIN %s p ON p.%s = d.%s", presetTableName, presetColumnID, deckColumnPresetID))
+	sb.WriteString(fmt.Sprintf(" LEFT JOIN (%s) s ON s.%s = d.%s", buildStudiedQueryString("$2"), reviewColumnDeckID, deckColumnID))
+	sb.WriteString(fmt.Sprintf(" WHERE d.%s IS NULL", deckColumnDeletedAt))
+	sb.WriteString(fmt.Sprintf(" GROUP BY d.%s, p.%s", deckColumnID, presetColumnID))
+	sb.WriteString(fmt.Sprintf(" ORDER BY d.%s ASC", deckColumnName))
+
+	query := sb.String()
+	return query
+}
+
 // Modifies name and/or description of an existing deck in the database.
 //
 // Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
 //   - deck model.Deck : The deck object containing the new name and/or description.
 //
 // Returns:
 //   - error : An error if the modification fails, nil otherwise.
-func (wrapper *DeckDBWrapper) Modify(deck model.Deck) error {
+func (wrapper *DeckDBWrapper) Modify(ctx context.Context, deck model.Deck) error {
+	defer observeQuery("deck", "Modify", time.Now())
+
 	if len(deck.Name) > DeckColumnNameMaxLength || len(deck.Description) > DeckColumnDescriptionMaxLength {
-		slog.Error("Deck name or description exceeds maximum length")
+		slog.ErrorContext(ctx, "Deck name or description exceeds maximum length")
 		return utils.ErrMaxLengthExceeded
 	}
 
 	if wrapper.db == nil {
-		slog.Error("Database connection is nil")
+		slog.ErrorContext(ctx, "Database connection is nil")
 		return utils.ErrDatabaseNotExist
 	}
 
@@ -359,21 +660,21 @@ func (wrapper *DeckDBWrapper) Modify(deck model.Deck) error {
 	deck.ModificationDate = time.Now()
 
 	query := wrapper.buildModifyQueryString()
-	slog.Debug("Modifying deck", "query", query)
+	slog.DebugContext(ctx, "Modifying deck", "query", query)
 
-	_, err := wrapper.db.Exec(query, deck.Name, deck.Description, deck.ModificationDate, deck.ID)
+	_, err := wrapper.db.ExecContext(ctx, query, deck.Name, deck.Description, deck.ModificationDate.UTC(), deck.ID)
 
 	if err != nil {
-		slog.Error("Error modifying deck", "error", err)
+		slog.ErrorContext(ctx, "Error modifying deck", "error", err)
 
-		if err.Error() == "pq: duplicate key value violates unique constraint \"decks_name_key\"" {
+		if isDuplicateDeckNameError(err) {
 			return utils.ErrDuplicateKeyViolation
 		}
 
 		return err
 	}
 
-	slog.Debug(fmt.Sprintf("Modified deck %d", deck.ID))
+	slog.DebugContext(ctx, fmt.Sprintf("Modified deck %d", deck.ID))
 
 	return nil
 }
@@ -394,39 +695,466 @@ func (wrapper *DeckDBWrapper) buildModifyQueryString() string {
 	sb.WriteString(deckColumnModificationDate)
 	sb.WriteString(" = $3 WHERE ")
 	sb.WriteString(deckColumnID)
-	sb.WriteString(" = $4")
+	sb.WriteString(" = $4 AND ")
+	sb.WriteString(deckColumnDeletedAt)
+	sb.WriteString(" IS NULL")
+
+	query := sb.String()
+	return query
+}
+
+// Moves a deck under a new parent deck. A deck can't be moved under itself
+// or under one of its own descendants. The check and the move run in one
+// transaction that holds a lock taken by every move, so that two concurrent
+// moves can't each pass the check and together make a cycle.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckID int : The unique ID of the deck to be moved.
+//   - parentID *int : The unique ID of the new parent deck, nil to make it a top-level deck.
+//
+// Returns:
+//   - error : An error if the move fails, nil otherwise.
+func (wrapper *DeckDBWrapper) Move(ctx context.Context, deckID int, parentID *int) error {
+	defer observeQuery("deck", "Move", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error starting transaction: %s", err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if parentID != nil {
+		// The lock is taken by its own statement, so that the check is made by
+		// a later statement which sees the moves committed by the transactions
+		// that held the lock before
+		query := wrapper.buildLockMovesQueryString()
+		slog.DebugContext(ctx, "Locking deck moves", "query", query)
+
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			slog.ErrorContext(ctx, "Error locking deck moves", "error", err)
+			return err
+		}
+
+		query = wrapper.buildIsAncestorQueryString()
+		slog.DebugContext(ctx, "Checking deck ancestors", "query", query)
+
+		var isAncestor bool
+		if err = tx.QueryRowContext(ctx, query, *parentID, deckID).Scan(&isAncestor); err != nil {
+			slog.ErrorContext(ctx, "Error checking deck ancestors", "error", err)
+			return err
+		}
+
+		if isAncestor {
+			slog.ErrorContext(ctx, fmt.Sprintf("Can't move deck %d under its descendant %d", deckID, *parentID))
+			return utils.ErrInvalidParent
+		}
+	}
+
+	query := wrapper.buildMoveQueryString()
+	slog.DebugContext(ctx, "Moving deck", "query", query)
+
+	result, err := tx.ExecContext(ctx, query, parentID, time.Now().UTC(), deckID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error moving deck", "error", err)
+
+		if isDuplicateDeckNameError(err) {
+			return utils.ErrDuplicateKeyViolation
+		} else if isInvalidParentError(err) {
+			return utils.ErrInvalidParent
+		}
+
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrRecordNotExist
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing deck move", "error", err)
+		return err
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Moved deck %d", deckID))
+
+	return nil
+}
+
+// Helper function that constructs the SQL query string to take the lock that
+// serializes the moves of decks until the end of the transaction. A move
+// under a deck may change the ancestors of any deck, so the moves share a
+// single advisory lock rather than locking rows.
+//
+// Returns:
+//   - string : The SQL query string to lock the deck moves.
+func (wrapper *DeckDBWrapper) buildLockMovesQueryString() string {
+	return fmt.Sprintf("SELECT pg_advisory_xact_lock(hashtext('%s'))", deckTableName)
+}
+
+// Helper function that constructs the recursive SQL query string to check
+// whether a deck is another deck or one of its ancestors. Decks in the trash are
+// walked through as well, since they keep their parent and may be restored.
+//
+// Returns:
+//   - string : The SQL query string, $1 being the deck whose ancestors are walked and $2 the deck looked for.
+func (wrapper *DeckDBWrapper) buildIsAncestorQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("WITH RECURSIVE ancestors AS (")
+	sb.WriteString(fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s = $1",
+		deckColumnID, deckColumnParentID, deckTableName, deckColumnID))
+	sb.WriteString(" UNION ")
+	sb.WriteString(fmt.Sprintf("SELECT d.%s, d.%s FROM %s d JOIN ancestors a ON d.%s = a.%s",
+		deckColumnID, deckColumnParentID, deckTableName, deckColumnID, deckColumnParentID))
+	sb.WriteString(fmt.Sprintf(") SELECT EXISTS (SELECT 1 FROM ancestors WHERE %s = $2)", deckColumnID))
+
+	query := sb.String()
+	return query
+}
+
+// Helper function that constructs the SQL query string to move a deck.
+//
+// Returns:
+//   - string : The SQL query string to move a deck.
+func (wrapper *DeckDBWrapper) buildMoveQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("UPDATE ")
+	sb.WriteString(deckTableName)
+	sb.WriteString(" SET ")
+	sb.WriteString(deckColumnParentID)
+	sb.WriteString(" = $1, ")
+	sb.WriteString(deckColumnModificationDate)
+	sb.WriteString(" = $2 WHERE ")
+	sb.WriteString(deckColumnID)
+	sb.WriteString(" = $3 AND ")
+	sb.WriteString(deckColumnDeletedAt)
+	sb.WriteString(" IS NULL")
 
 	query := sb.String()
 	return query
 }
 
-// Deletes a deck from the database based on its unique ID.
+// Links a deck to a deck preset, or unlinks it so it uses the default settings.
 //
 // Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckID int : The unique ID of the deck.
+//   - presetID *int : The unique ID of the preset, nil to use the default settings.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the deck doesn't exist,
+//     utils.ErrPresetNotExist if the preset doesn't exist, nil on success.
+func (wrapper *DeckDBWrapper) SetPreset(ctx context.Context, deckID int, presetID *int) error {
+	defer observeQuery("deck", "SetPreset", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = $1, %s = $2 WHERE %s = $3 AND %s IS NULL",
+		deckTableName, deckColumnPresetID, deckColumnModificationDate, deckColumnID, deckColumnDeletedAt)
+	slog.DebugContext(ctx, "Setting deck preset", "query", query)
+
+	result, err := wrapper.db.ExecContext(ctx, query, presetID, time.Now().UTC(), deckID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error setting deck preset", "error", err)
+
+		if strings.Contains(err.Error(), fmt.Sprintf("\"%s_%s_fkey\"", deckTableName, deckColumnPresetID)) {
+			return utils.ErrPresetNotExist
+		}
+
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrRecordNotExist
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Set preset of deck %d", deckID))
+
+	return nil
+}
+
+// Sets the date decks were last studied on. Decks that don't exist are ignored.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the studied decks.
+//   - date time.Time : The time the decks were studied.
+//
+// Returns:
+//   - error : An error if the update fails, nil otherwise.
+func (wrapper *DeckDBWrapper) SetLastStudyDate(ctx context.Context, deckIDs []int, date time.Time) error {
+	defer observeQuery("deck", "SetLastStudyDate", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s = ANY($2) AND %s IS NULL",
+		deckTableName, deckColumnLastStudyDate, deckColumnID, deckColumnDeletedAt)
+	slog.DebugContext(ctx, "Setting deck last study date", "query", query)
+
+	_, err := wrapper.db.ExecContext(ctx, query, date.UTC(), pq.Array(deckIDs))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error setting deck last study date", "error", err)
+		return err
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Set last study date of %d decks", len(deckIDs)))
+
+	return nil
+}
+
+// Retrieves the unique IDs of a deck and all of its descendants.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckID int : The unique ID of the deck at the top of the subtree.
+//
+// Returns:
+//   - []int : The deck ID followed by the IDs of its descendants.
+//   - error : An error if the retrieval fails or the deck doesn't exist, nil otherwise.
+func (wrapper *DeckDBWrapper) GetDescendantIDs(ctx context.Context, deckID int) ([]int, error) {
+	defer observeQuery("deck", "GetDescendantIDs", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildGetDescendantIDsQueryString()
+	slog.DebugContext(ctx, "Getting deck descendants", "query", query)
+
+	rows, err := wrapper.db.QueryContext(ctx, query, deckID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting deck descendants", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, utils.ErrRecordNotExist
+	}
+
+	return ids, nil
+}
+
+// Helper function that constructs the recursive SQL query string to retrieve
+// a deck and all of its descendants.
+//
+// Returns:
+//   - string : The SQL query string to retrieve the subtree of a deck.
+func (wrapper *DeckDBWrapper) buildGetDescendantIDsQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("WITH RECURSIVE subtree AS (")
+	sb.WriteString(fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 AND %s IS NULL",
+		deckColumnID, deckTableName, deckColumnID, deckColumnDeletedAt))
+	sb.WriteString(" UNION ALL ")
+	sb.WriteString(fmt.Sprintf("SELECT d.%s FROM %s d JOIN subtree s ON d.%s = s.%s WHERE d.%s IS NULL",
+		deckColumnID, deckTableName, deckColumnParentID, deckColumnID, deckColumnDeletedAt))
+	sb.WriteString(fmt.Sprintf(") SELECT %s FROM subtree", deckColumnID))
+
+	query := sb.String()
+	return query
+}
+
+// Deletes a deck based on its unique ID. By default the deck is moved to the
+// trash, where it can be restored for DeckTrashRetention. The options can
+// instead permanently delete the deck along with its cards, or move its cards
+// to another deck first. Sub-decks are deleted the same way as the deck unless
+// the options ask for them to be re-parented.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
 //   - id int : The unique ID of the deck to be deleted.
+//   - options DeckDeleteOptions : What to do with the deck, its cards and its sub-decks.
 //
 // Returns:
-//   - error : An error if the deletion fails, nil otherwise.
-func (wrapper *DeckDBWrapper) Delete(id int) error {
+//   - error : utils.ErrRecordNotExist if the deck doesn't exist, utils.ErrDeckNotExist
+//     if the deck to move the cards to is invalid, nil on success.
+func (wrapper *DeckDBWrapper) Delete(ctx context.Context, id int, options DeckDeleteOptions) error {
+	defer observeQuery("deck", "Delete", time.Now())
+
 	if wrapper.db == nil {
-		slog.Error("Database connection is nil")
+		slog.ErrorContext(ctx, "Database connection is nil")
 		return utils.ErrDatabaseNotExist
 	}
 
-	query := wrapper.buildDeleteQueryString()
-	slog.Debug("Deleting deck", "query", query)
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if options.ReparentChildren {
+		query := wrapper.buildReparentChildrenQueryString()
+		slog.DebugContext(ctx, "Re-parenting deck children", "query", query)
+
+		if _, err = tx.ExecContext(ctx, query, id); err != nil {
+			slog.ErrorContext(ctx, "Error re-parenting deck children", "error", err)
+
+			if isDuplicateDeckNameError(err) {
+				return utils.ErrDuplicateKeyViolation
+			}
+
+			return err
+		}
+	}
+
+	var deckIDs []int
+	if err = wrapper.queryIDs(ctx, tx, &deckIDs, wrapper.buildGetDescendantIDsQueryString(), id); err != nil {
+		slog.ErrorContext(ctx, "Error getting deck descendants", "error", err)
+		return err
+	}
+
+	if len(deckIDs) == 0 {
+		return utils.ErrRecordNotExist
+	}
+
+	switch options.Mode {
+	case DeckDeleteTrash:
+		query := fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s = ANY($2)", deckTableName, deckColumnDeletedAt, deckColumnID)
+		slog.DebugContext(ctx, "Moving decks to trash", "query", query)
+		_, err = tx.ExecContext(ctx, query, time.Now().UTC(), pq.Array(deckIDs))
+	case DeckDeleteMoveCards:
+		for _, deckID := range deckIDs {
+			if deckID == options.MoveToDeckID {
+				slog.ErrorContext(ctx, fmt.Sprintf("Can't move cards of deck %d into deck %d being deleted", id, deckID))
+				return utils.ErrDeckNotExist
+			}
+		}
+
+		exists, existsErr := wrapper.deckExists(ctx, tx, options.MoveToDeckID)
+		if existsErr != nil {
+			slog.ErrorContext(ctx, "Error checking target deck", "error", existsErr)
+			return existsErr
+		} else if !exists {
+			return utils.ErrDeckNotExist
+		}
+
+		query := fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s = ANY($2)", cardTableName, cardColumnDeckID, cardColumnDeckID)
+		slog.DebugContext(ctx, "Moving cards of deleted deck", "query", query)
+
+		if _, err = tx.ExecContext(ctx, query, options.MoveToDeckID, pq.Array(deckIDs)); err != nil {
+			break
+		}
+
+		_, err = tx.ExecContext(ctx, wrapper.buildDeleteQueryString(), id)
+	case DeckDeleteCascade:
+		query := wrapper.buildDeleteQueryString()
+		slog.DebugContext(ctx, "Deleting deck", "query", query)
+		_, err = tx.ExecContext(ctx, query, id)
+	}
 
-	_, err := wrapper.db.Exec(query, id)
 	if err != nil {
-		slog.Error("Error deleting deck", "error", err)
+		slog.ErrorContext(ctx, "Error deleting deck", "error", err)
 		return err
 	}
 
-	slog.Debug(fmt.Sprintf("Deleted deck %d", id))
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing deck deletion", "error", err)
+		return err
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Deleted deck %d", id))
 
 	return nil
 }
 
+// A helper function that runs a query returning a single column of IDs.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - tx *sql.Tx : The transaction to run the query in.
+//   - ids *[]int : The slice the IDs are appended to.
+//   - query string : The SQL query string.
+//   - args ...any : The query arguments.
+//
+// Returns:
+//   - error : An error if the query or a scan fails, nil otherwise.
+func (wrapper *DeckDBWrapper) queryIDs(ctx context.Context, tx *sql.Tx, ids *[]int, query string, args ...any) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		*ids = append(*ids, id)
+	}
+
+	return rows.Err()
+}
+
+// A helper function that checks whether a deck exists and isn't in the trash.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - tx *sql.Tx : The transaction to run the query in.
+//   - deckID int : The unique ID of the deck.
+//
+// Returns:
+//   - bool : True if the deck exists.
+//   - error : An error if the query fails, nil otherwise.
+func (wrapper *DeckDBWrapper) deckExists(ctx context.Context, tx *sql.Tx, deckID int) (bool, error) {
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1 AND %s IS NULL)",
+		deckTableName, deckColumnID, deckColumnDeletedAt)
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, query, deckID).Scan(&exists)
+	return exists, err
+}
+
+// Helper function that constructs the SQL query string to move the children
+// of a deck up to the deck's own parent.
+//
+// Returns:
+//   - string : The SQL query string to re-parent the children of a deck.
+func (wrapper *DeckDBWrapper) buildReparentChildrenQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("UPDATE ")
+	sb.WriteString(deckTableName)
+	sb.WriteString(" SET ")
+	sb.WriteString(deckColumnParentID)
+	sb.WriteString(fmt.Sprintf(" = (SELECT %s FROM %s WHERE %s = $1)", deckColumnParentID, deckTableName, deckColumnID))
+	sb.WriteString(" WHERE ")
+	sb.WriteString(deckColumnParentID)
+	sb.WriteString(" = $1 AND ")
+	sb.WriteString(deckColumnDeletedAt)
+	sb.WriteString(" IS NULL")
+
+	query := sb.String()
+	return query
+}
+
 // Helper function that constructs the SQL query string to delete a deck.
 //
 // Returns:
@@ -442,3 +1170,179 @@ func (wrapper *DeckDBWrapper) buildDeleteQueryString() string {
 	query := sb.String()
 	return query
 }
+
+// Retrieves the decks in the trash, most recently deleted first. Sub-decks
+// that were trashed along with their parent are listed as well.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//
+// Returns:
+//   - []model.Deck : The trashed decks, with their deletion time set.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *DeckDBWrapper) GetTrash(ctx context.Context) ([]model.Deck, error) {
+	defer observeQuery("deck", "GetTrash", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s, %s, %s, %s, %s, %s FROM %s WHERE %s IS NOT NULL ORDER BY %s DESC",
+		deckColumnID, deckColumnName, deckColumnDescription, deckColumnCreationDate, deckColumnModificationDate,
+		deckColumnLastStudyDate, deckColumnParentID, deckColumnPresetID, deckColumnDeletedAt,
+		deckTableName, deckColumnDeletedAt, deckColumnDeletedAt)
+	slog.DebugContext(ctx, "Getting deck trash", "query", query)
+
+	rows, err := wrapper.db.QueryContext(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting deck trash", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	decks := []model.Deck{}
+	for rows.Next() {
+		var deck model.Deck
+		var lastStudyDate sql.NullTime
+		var parentID, presetID sql.NullInt64
+		var deletedAt time.Time
+
+		err := rows.Scan(
+			&deck.ID,
+			&deck.Name,
+			&deck.Description,
+			&deck.CreationDate,
+			&deck.ModificationDate,
+			&lastStudyDate,
+			&parentID,
+			&presetID,
+			&deletedAt,
+		)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error scanning trashed deck", "error", err)
+			return nil, err
+		}
+
+		if lastStudyDate.Valid {
+			deck.LastStudyDate = lastStudyDate.Time
+		}
+		deck.ParentID = nullIntToPointer(parentID)
+		deck.PresetID = nullIntToPointer(presetID)
+		deck.DeletedAt = &deletedAt
+
+		decks = append(decks, deck)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return decks, nil
+}
+
+// Restores a deck from the trash along with the sub-decks that were trashed
+// with it. If the parent of the deck is still in the trash, the deck is
+// restored as a top-level deck.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - id int : The unique ID of the deck to be restored.
+//   - deletedAfter time.Time : Decks deleted before this time can no longer be restored.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the deck isn't in the trash or was deleted
+//     too long ago, utils.ErrDuplicateKeyViolation if a sibling took its name, nil on success.
+func (wrapper *DeckDBWrapper) Restore(ctx context.Context, id int, deletedAfter time.Time) error {
+	defer observeQuery("deck", "Restore", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildRestoreQueryString()
+	slog.DebugContext(ctx, "Restoring deck", "query", query)
+
+	result, err := wrapper.db.ExecContext(ctx, query, id, deletedAfter.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error restoring deck", "error", err)
+
+		if isDuplicateDeckNameError(err) {
+			return utils.ErrDuplicateKeyViolation
+		}
+
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrRecordNotExist
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Restored deck %d", id))
+
+	return nil
+}
+
+// Helper function that constructs the SQL query string to restore a deck.
+// The deck and every descendant deleted at the same moment are restored,
+// and the deck is detached from its parent if the parent is still trashed.
+//
+// Returns:
+//   - string : The SQL query string to restore a deck.
+func (wrapper *DeckDBWrapper) buildRestoreQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("WITH RECURSIVE target AS (")
+	sb.WriteString(fmt.Sprintf("SELECT %s, %s, %s FROM %s WHERE %s = $1 AND %s > $2",
+		deckColumnID, deckColumnParentID, deckColumnDeletedAt, deckTableName, deckColumnID, deckColumnDeletedAt))
+	sb.WriteString("), subtree AS (")
+	sb.WriteString(fmt.Sprintf("SELECT %s FROM target", deckColumnID))
+	sb.WriteString(" UNION ALL ")
+	sb.WriteString(fmt.Sprintf("SELECT d.%s FROM %s d JOIN subtree s ON d.%s = s.%s JOIN target t ON d.%s = t.%s",
+		deckColumnID, deckTableName, deckColumnParentID, deckColumnID, deckColumnDeletedAt, deckColumnDeletedAt))
+	sb.WriteString(fmt.Sprintf(") UPDATE %s d SET %s = NULL, ", deckTableName, deckColumnDeletedAt))
+	sb.WriteString(fmt.Sprintf("%s = CASE WHEN d.%s = $1 AND EXISTS (SELECT 1 FROM %s p WHERE p.%s = d.%s AND p.%s IS NOT NULL) THEN NULL ELSE d.%s END",
+		deckColumnParentID, deckColumnID, deckTableName, deckColumnID, deckColumnParentID, deckColumnDeletedAt, deckColumnParentID))
+	sb.WriteString(fmt.Sprintf(" WHERE d.%s IN (SELECT %s FROM subtree)", deckColumnID, deckColumnID))
+
+	query := sb.String()
+	return query
+}
+
+// Permanently deletes the decks that have been in the trash since before the
+// given time. Their cards and sub-decks are deleted along with them.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deletedBefore time.Time : Decks deleted before this time are purged.
+//
+// Returns:
+//   - int : The number of purged decks.
+//   - error : An error if the purge fails, nil otherwise.
+func (wrapper *DeckDBWrapper) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	defer observeQuery("deck", "PurgeTrash", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return 0, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s < $1", deckTableName, deckColumnDeletedAt)
+	slog.DebugContext(ctx, "Purging deck trash", "query", query)
+
+	result, err := wrapper.db.ExecContext(ctx, query, deletedBefore.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error purging deck trash", "error", err)
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Purged %d decks from trash", purged))
+
+	return int(purged), nil
+}
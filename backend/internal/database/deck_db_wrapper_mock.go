@@ -1,13 +1,16 @@
 package database
 
 import (
+	"context"
 	"flash-learn/internal/model"
 	"flash-learn/internal/utils"
+	"sort"
 	"time"
 )
 
 type DeckDBWrapperMock struct {
 	db    map[int]model.Deck
+	trash map[int]model.Deck
 	index int
 }
 
@@ -18,13 +21,14 @@ func NewDeckDBWrapperMock() *DeckDBWrapperMock {
 func (wrapper *DeckDBWrapperMock) CreateTable() error {
 	if wrapper.db == nil {
 		wrapper.db = make(map[int]model.Deck)
+		wrapper.trash = make(map[int]model.Deck)
 		wrapper.index = 0
 	}
 
 	return nil
 }
 
-func (wrapper *DeckDBWrapperMock) Insert(deck model.Deck) (int, error) {
+func (wrapper *DeckDBWrapperMock) Insert(ctx context.Context, deck model.Deck) (int, error) {
 	if len(deck.Name) > DeckColumnNameMaxLength || len(deck.Description) > DeckColumnDescriptionMaxLength {
 		return -1, utils.ErrMaxLengthExceeded
 	}
@@ -38,7 +42,7 @@ func (wrapper *DeckDBWrapperMock) Insert(deck model.Deck) (int, error) {
 	return wrapper.index - 1, nil
 }
 
-func (wrapper *DeckDBWrapperMock) GetSingle(deckID int) (model.Deck, error) {
+func (wrapper *DeckDBWrapperMock) GetSingle(ctx context.Context, deckID int) (model.Deck, error) {
 	if wrapper.db == nil {
 		return model.Deck{}, utils.ErrDatabaseNotExist
 	}
@@ -51,7 +55,7 @@ func (wrapper *DeckDBWrapperMock) GetSingle(deckID int) (model.Deck, error) {
 	return deck, nil
 }
 
-func (wrapper *DeckDBWrapperMock) GetAll() ([]model.Deck, error) {
+func (wrapper *DeckDBWrapperMock) GetAll(ctx context.Context) ([]model.Deck, error) {
 	if wrapper.db == nil {
 		return nil, utils.ErrDatabaseNotExist
 	}
@@ -65,7 +69,26 @@ func (wrapper *DeckDBWrapperMock) GetAll() ([]model.Deck, error) {
 	return decks, nil
 }
 
-func (wrapper *DeckDBWrapperMock) GetCount() (int, error) {
+func (wrapper *DeckDBWrapperMock) GetAllSummaries(ctx context.Context, studiedSince time.Time, dueBefore time.Time) ([]model.DeckSummary, error) {
+	if wrapper.db == nil {
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	summaries := make([]model.DeckSummary, 0, len(wrapper.db))
+
+	for id, deck := range wrapper.db {
+		deck.ID = id
+		summaries = append(summaries, model.DeckSummary{Deck: deck})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return summaries, nil
+}
+
+func (wrapper *DeckDBWrapperMock) GetCount(ctx context.Context) (int, error) {
 	if wrapper.db == nil {
 		return 0, utils.ErrDatabaseNotExist
 	}
@@ -74,7 +97,7 @@ func (wrapper *DeckDBWrapperMock) GetCount() (int, error) {
 	return length, nil
 }
 
-func (wrapper *DeckDBWrapperMock) Modify(deck model.Deck) error {
+func (wrapper *DeckDBWrapperMock) Modify(ctx context.Context, deck model.Deck) error {
 	if len(deck.Name) > DeckColumnNameMaxLength || len(deck.Description) > DeckColumnDescriptionMaxLength {
 		return utils.ErrMaxLengthExceeded
 	}
@@ -98,16 +121,214 @@ func (wrapper *DeckDBWrapperMock) Modify(deck model.Deck) error {
 	return nil
 }
 
-func (wrapper *DeckDBWrapperMock) Delete(id int) error {
+func (wrapper *DeckDBWrapperMock) Move(ctx context.Context, deckID int, parentID *int) error {
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	deck, exists := wrapper.db[deckID]
+	if !exists {
+		return utils.ErrRecordNotExist
+	}
+
+	if parentID != nil {
+		if _, exists := wrapper.db[*parentID]; !exists {
+			return utils.ErrInvalidParent
+		}
+
+		descendantIDs, _ := wrapper.GetDescendantIDs(ctx, deckID)
+		for _, id := range descendantIDs {
+			if id == *parentID {
+				return utils.ErrInvalidParent
+			}
+		}
+	}
+
+	for id, sibling := range wrapper.db {
+		if id != deckID && sibling.Name == deck.Name && sameParent(sibling.ParentID, parentID) {
+			return utils.ErrDuplicateKeyViolation
+		}
+	}
+
+	deck.ParentID = parentID
+	deck.ModificationDate = time.Now()
+	wrapper.db[deckID] = deck
+
+	return nil
+}
+
+// sameParent reports whether two optional parent IDs are both unset or equal.
+func sameParent(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (wrapper *DeckDBWrapperMock) SetPreset(ctx context.Context, deckID int, presetID *int) error {
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	deck, exists := wrapper.db[deckID]
+	if !exists {
+		return utils.ErrRecordNotExist
+	}
+
+	deck.PresetID = presetID
+	wrapper.db[deckID] = deck
+
+	return nil
+}
+
+func (wrapper *DeckDBWrapperMock) SetLastStudyDate(ctx context.Context, deckIDs []int, date time.Time) error {
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	for _, deckID := range deckIDs {
+		if deck, exists := wrapper.db[deckID]; exists {
+			deck.LastStudyDate = date
+			wrapper.db[deckID] = deck
+		}
+	}
+
+	return nil
+}
+
+func (wrapper *DeckDBWrapperMock) GetDescendantIDs(ctx context.Context, deckID int) ([]int, error) {
+	if wrapper.db == nil {
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	if _, exists := wrapper.db[deckID]; !exists {
+		return nil, utils.ErrRecordNotExist
+	}
+
+	ids := []int{deckID}
+	for i := 0; i < len(ids); i++ {
+		for id, deck := range wrapper.db {
+			if deck.ParentID != nil && *deck.ParentID == ids[i] {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func (wrapper *DeckDBWrapperMock) Delete(ctx context.Context, id int, options DeckDeleteOptions) error {
 	if wrapper.db == nil {
 		return utils.ErrDatabaseNotExist
 	}
 
-	if _, exists := wrapper.db[id]; !exists {
+	deck, exists := wrapper.db[id]
+	if !exists {
 		return utils.ErrRecordNotExist
 	}
 
-	delete(wrapper.db, id)
+	if options.ReparentChildren {
+		for childID, child := range wrapper.db {
+			if child.ParentID != nil && *child.ParentID == id {
+				child.ParentID = deck.ParentID
+				wrapper.db[childID] = child
+			}
+		}
+	}
+
+	descendantIDs, _ := wrapper.GetDescendantIDs(ctx, id)
+
+	if options.Mode == DeckDeleteMoveCards {
+		if _, exists := wrapper.db[options.MoveToDeckID]; !exists {
+			return utils.ErrDeckNotExist
+		}
+
+		for _, descendantID := range descendantIDs {
+			if descendantID == options.MoveToDeckID {
+				return utils.ErrDeckNotExist
+			}
+		}
+	}
+
+	deletedAt := time.Now()
+	for _, descendantID := range descendantIDs {
+		if options.Mode == DeckDeleteTrash {
+			trashed := wrapper.db[descendantID]
+			trashed.DeletedAt = &deletedAt
+			wrapper.trash[descendantID] = trashed
+		}
+		delete(wrapper.db, descendantID)
+	}
 
 	return nil
 }
+
+func (wrapper *DeckDBWrapperMock) GetTrash(ctx context.Context) ([]model.Deck, error) {
+	if wrapper.db == nil {
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	decks := make([]model.Deck, 0, len(wrapper.trash))
+
+	for id, deck := range wrapper.trash {
+		deck.ID = id
+		decks = append(decks, deck)
+	}
+
+	sort.Slice(decks, func(i, j int) bool {
+		return decks[i].DeletedAt.After(*decks[j].DeletedAt)
+	})
+
+	return decks, nil
+}
+
+func (wrapper *DeckDBWrapperMock) Restore(ctx context.Context, id int, deletedAfter time.Time) error {
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	deck, exists := wrapper.trash[id]
+	if !exists || !deck.DeletedAt.After(deletedAfter) {
+		return utils.ErrRecordNotExist
+	}
+
+	deletedAt := *deck.DeletedAt
+	if deck.ParentID != nil {
+		if _, exists := wrapper.db[*deck.ParentID]; !exists {
+			deck.ParentID = nil
+		}
+	}
+	wrapper.trash[id] = deck
+
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		restored := wrapper.trash[ids[i]]
+		restored.DeletedAt = nil
+		wrapper.db[ids[i]] = restored
+		delete(wrapper.trash, ids[i])
+
+		for childID, child := range wrapper.trash {
+			if child.ParentID != nil && *child.ParentID == ids[i] && child.DeletedAt.Equal(deletedAt) {
+				ids = append(ids, childID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (wrapper *DeckDBWrapperMock) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	if wrapper.db == nil {
+		return 0, utils.ErrDatabaseNotExist
+	}
+
+	purged := 0
+	for id, deck := range wrapper.trash {
+		if deck.DeletedAt.Before(deletedBefore) {
+			delete(wrapper.trash, id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
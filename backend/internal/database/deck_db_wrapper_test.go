@@ -1,10 +1,16 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"flash-learn/internal/utils"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/attic-labs/testify/mock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,3 +37,105 @@ func TestNewDeckDBWrapper(t *testing.T) {
 		assert.IsType(t, &DeckDBWrapper{}, deckDBWrapper)
 	})
 }
+
+func TestQueryObserver(t *testing.T) {
+	type observation struct {
+		wrapper string
+		method  string
+	}
+	observed := []observation{}
+	SetQueryObserver(func(wrapper string, method string, duration time.Duration) {
+		assert.GreaterOrEqual(t, duration, time.Duration(0))
+		observed = append(observed, observation{wrapper, method})
+	})
+	defer SetQueryObserver(nil)
+
+	deckDBWrapper := NewDeckDBWrapper(nil)
+	_, err := deckDBWrapper.GetSingle(context.Background(), 1)
+	assert.Equal(t, utils.ErrDatabaseNotExist, err)
+	NewJobDBWrapper(nil).CountUnfinished(context.Background())
+
+	assert.Equal(t, []observation{{"deck", "GetSingle"}, {"job", "CountUnfinished"}}, observed,
+		"Expected failed queries to be observed too")
+
+	SetQueryObserver(nil)
+	deckDBWrapper.GetCount(context.Background())
+	assert.Len(t, observed, 2)
+}
+
+// moveStatements returns the statements of a move under a parent deck, up to
+// the update of the deck.
+func moveStatements(isAncestor bool) []fakeStatement {
+	return []fakeStatement{
+		{prefix: "SELECT pg_advisory_xact_lock("},
+		{prefix: "WITH RECURSIVE ancestors", columns: []string{"exists"}, rows: [][]driver.Value{{isAncestor}}},
+	}
+}
+
+func TestMove(t *testing.T) {
+	parentID := 1
+	duplicate := &pq.Error{Message: fmt.Sprintf("duplicate key value violates unique constraint \"%s\"", deckUniqueNameIndex)}
+
+	testCases := []struct {
+		name        string
+		parentID    *int
+		script      []fakeStatement
+		expectedErr error
+		expectedLog []string
+	}{
+		{
+			name:        "Under a deck",
+			parentID:    &parentID,
+			script:      append(moveStatements(false), fakeStatement{prefix: "UPDATE decks SET parent_id", affected: 1}),
+			expectedLog: []string{"BEGIN", "SELECT pg_advisory_xact_lock(", "WITH RECURSIVE ancestors", "UPDATE decks SET parent_id", "COMMIT"},
+		},
+		{
+			name:        "Under its own descendant",
+			parentID:    &parentID,
+			script:      moveStatements(true),
+			expectedErr: utils.ErrInvalidParent,
+			expectedLog: []string{"BEGIN", "SELECT pg_advisory_xact_lock(", "WITH RECURSIVE ancestors", "ROLLBACK"},
+		},
+		{
+			name:        "Next to a sibling with the same name",
+			parentID:    &parentID,
+			script:      append(moveStatements(false), fakeStatement{prefix: "UPDATE decks SET parent_id", err: duplicate}),
+			expectedErr: utils.ErrDuplicateKeyViolation,
+			expectedLog: []string{"BEGIN", "SELECT pg_advisory_xact_lock(", "WITH RECURSIVE ancestors", "UPDATE decks SET parent_id", "ROLLBACK"},
+		},
+		{
+			name:        "Deck not found",
+			parentID:    &parentID,
+			script:      append(moveStatements(false), fakeStatement{prefix: "UPDATE decks SET parent_id", affected: 0}),
+			expectedErr: utils.ErrRecordNotExist,
+			expectedLog: []string{"BEGIN", "SELECT pg_advisory_xact_lock(", "WITH RECURSIVE ancestors", "UPDATE decks SET parent_id", "ROLLBACK"},
+		},
+		{
+			name:        "To the top level",
+			parentID:    nil,
+			script:      []fakeStatement{{prefix: "UPDATE decks SET parent_id", affected: 1}},
+			expectedLog: []string{"BEGIN", "UPDATE decks SET parent_id", "COMMIT"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, fake := newFakeDB(tc.script...)
+			err := NewDeckDBWrapper(db).Move(context.Background(), 2, tc.parentID)
+
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedLog, fake.Log(),
+				"Expected the cycle to be checked under the lock, in the transaction of the move")
+		})
+	}
+}
+
+func TestMoveChecksAncestorsOfParent(t *testing.T) {
+	parentID := 1
+	db, fake := newFakeDB(append(moveStatements(false), fakeStatement{prefix: "UPDATE decks SET parent_id", affected: 1})...)
+
+	assert.Nil(t, NewDeckDBWrapper(db).Move(context.Background(), 2, &parentID))
+	assert.Equal(t, []driver.Value{int64(1), int64(2)}, fake.Args()[1],
+		"Expected the moved deck to be looked for among the ancestors of the new parent")
+	assert.Equal(t, utils.ErrDatabaseNotExist, NewDeckDBWrapper(nil).Move(context.Background(), 2, &parentID))
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeStatement is a statement a fakeDB expects, along with its outcome.
+// Only the start of the statement is compared, so that the tests check what
+// a wrapper does rather than the exact text of its queries.
+type fakeStatement struct {
+	prefix   string
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+// fakeDB is a database that answers the statements of a script in order and
+// records what it was sent, including the transactions. A statement that
+// doesn't match the script fails.
+type fakeDB struct {
+	mu     sync.Mutex
+	script []fakeStatement
+	log    []string
+	args   [][]driver.Value
+}
+
+// newFakeDB returns a connection to a fake database answering the statements
+// of a script.
+func newFakeDB(script ...fakeStatement) (*sql.DB, *fakeDB) {
+	fake := &fakeDB{script: script}
+	return sql.OpenDB(fake), fake
+}
+
+// Log returns the statements sent so far, the queries being cut down to the
+// prefix they matched.
+func (fake *fakeDB) Log() []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]string{}, fake.log...)
+}
+
+// Args returns the arguments of the statements sent so far.
+func (fake *fakeDB) Args() [][]driver.Value {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([][]driver.Value{}, fake.args...)
+}
+
+func (fake *fakeDB) next(query string, args []driver.NamedValue) (fakeStatement, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	if len(fake.script) == 0 {
+		return fakeStatement{}, fmt.Errorf("unexpected statement %q", query)
+	}
+	statement := fake.script[0]
+	if !strings.HasPrefix(query, statement.prefix) {
+		return fakeStatement{}, fmt.Errorf("unexpected statement %q, expected %q", query, statement.prefix)
+	}
+	fake.script = fake.script[1:]
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	fake.log = append(fake.log, statement.prefix)
+	fake.args = append(fake.args, values)
+	return statement, statement.err
+}
+
+func (fake *fakeDB) record(entry string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.log = append(fake.log, entry)
+}
+
+func (fake *fakeDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return fakeConn{fake}, nil
+}
+
+func (fake *fakeDB) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	fake *fakeDB
+}
+
+func (conn fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements aren't supported")
+}
+
+func (conn fakeConn) Close() error {
+	return nil
+}
+
+func (conn fakeConn) Begin() (driver.Tx, error) {
+	return conn.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (conn fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	conn.fake.record("BEGIN")
+	return fakeTx(conn), nil
+}
+
+func (conn fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	statement, err := conn.fake.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(statement.affected), nil
+}
+
+func (conn fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	statement, err := conn.fake.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: statement.columns, rows: statement.rows}, nil
+}
+
+type fakeTx fakeConn
+
+func (tx fakeTx) Commit() error {
+	tx.fake.record("COMMIT")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.fake.record("ROLLBACK")
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (rows *fakeRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *fakeRows) Close() error {
+	return nil
+}
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if len(rows.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.rows[0])
+	rows.rows = rows.rows[1:]
+	return nil
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	filteredDeckTableName        = "filtered_decks"
+	filteredDeckColumnDeckID     = "deck_id"
+	filteredDeckColumnQuery      = "query"
+	filteredDeckColumnOrder      = "card_order"
+	filteredDeckColumnLimit      = "card_limit"
+	filteredDeckColumnReschedule = "reschedule"
+)
+
+// An interface that defines the methods for interacting with the filtered deck database.
+// This interface abstracts the database operations for the searches filtered decks
+// are built from, allowing for easier testing and mocking. The cards themselves are
+// borrowed and returned through CardDBWrapperInterface.
+type FilteredDeckDBWrapperInterface interface {
+	CreateTable() error
+	Get(ctx context.Context, deckID int) (model.FilteredDeck, error)
+	Set(ctx context.Context, filtered model.FilteredDeck) error
+}
+
+// A struct that implements the FilteredDeckDBWrapperInterface.
+//
+// This is the concrete implementation and should be used for actual
+// database operations.
+type FilteredDeckDBWrapper struct {
+	db *sql.DB
+}
+
+// Creates and returns a new instance of FilteredDeckDBWrapper.
+//
+// Parameters:
+//   - db *sql.DB : The database connection.
+//
+// Returns:
+//   - *FilteredDeckDBWrapper
+func NewFilteredDeckDBWrapper(db *sql.DB) *FilteredDeckDBWrapper {
+	return &FilteredDeckDBWrapper{db: db}
+}
+
+// Creates a new table in the database if it doesn't already exist.
+// This table references the decks table, so it must be created after it.
+//
+// Returns:
+//   - error : An error if the table creation fails, nil otherwise.
+func (wrapper *FilteredDeckDBWrapper) CreateTable() error {
+	defer observeQuery("filtered_deck", "CreateTable", time.Now())
+
+	if wrapper.db == nil {
+		slog.Error("Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildCreateTableQueryString()
+	slog.Debug("Creating filtered decks table", "query", query)
+
+	_, err := wrapper.db.Exec(query)
+
+	if err != nil {
+		slog.Error("Error creating filtered decks table", "error", err)
+	}
+
+	return err
+}
+
+// A helper function that constructs the SQL query string
+// to create the filtered decks table.
+//
+// Returns:
+//   - string : The SQL query string to create the filtered decks table.
+func (wrapper *FilteredDeckDBWrapper) buildCreateTableQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(filteredDeckTableName)
+	sb.WriteString(" (")
+	sb.WriteString(fmt.Sprintf("%s INT PRIMARY KEY REFERENCES %s(%s) ON DELETE CASCADE, ", filteredDeckColumnDeckID, deckTableName, deckColumnID))
+	sb.WriteString(fmt.Sprintf("%s JSONB NOT NULL, ", filteredDeckColumnQuery))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(16) NOT NULL CHECK (%s IN ('%s', '%s', '%s', '%s')), ", filteredDeckColumnOrder, filteredDeckColumnOrder,
+		model.FilteredOrderDue, model.FilteredOrderRandom, model.FilteredOrderAdded, model.FilteredOrderLapses))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s BETWEEN 1 AND %d), ", filteredDeckColumnLimit, filteredDeckColumnLimit, model.FilteredDeckMaxLimit))
+	sb.WriteString(fmt.Sprintf("%s BOOLEAN NOT NULL DEFAULT TRUE", filteredDeckColumnReschedule))
+	sb.WriteString(")")
+
+	query := sb.String()
+	return query
+}
+
+// Retrieves the search a filtered deck is built from.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckID int : The unique ID of the deck.
+//
+// Returns:
+//   - model.FilteredDeck : The search of the filtered deck.
+//   - error : utils.ErrRecordNotExist if the deck isn't a filtered deck, nil on success.
+func (wrapper *FilteredDeckDBWrapper) Get(ctx context.Context, deckID int) (model.FilteredDeck, error) {
+	defer observeQuery("filtered_deck", "Get", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.FilteredDeck{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s, %s FROM %s WHERE %s = $1",
+		filteredDeckColumnDeckID, filteredDeckColumnQuery, filteredDeckColumnOrder, filteredDeckColumnLimit,
+		filteredDeckColumnReschedule, filteredDeckTableName, filteredDeckColumnDeckID)
+	slog.DebugContext(ctx, "Getting filtered deck", "query", query)
+
+	var filtered model.FilteredDeck
+	var filter []byte
+	err := wrapper.db.QueryRowContext(ctx, query, deckID).Scan(&filtered.DeckID, &filter, &filtered.Order, &filtered.Limit, &filtered.Reschedule)
+	if err == sql.ErrNoRows {
+		return model.FilteredDeck{}, utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error getting filtered deck", "error", err)
+		return model.FilteredDeck{}, err
+	}
+
+	if err = json.Unmarshal(filter, &filtered.Query); err != nil {
+		slog.ErrorContext(ctx, "Error decoding filtered deck query", "error", err)
+		return model.FilteredDeck{}, err
+	}
+
+	return filtered, nil
+}
+
+// Makes a deck a filtered deck, or replaces the search it is built from.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - filtered model.FilteredDeck : The deck and the search it is built from.
+//
+// Returns:
+//   - error : utils.ErrInvalidFilteredDeck if the search is invalid, utils.ErrDeckNotExist
+//     if the deck doesn't exist, nil on success.
+func (wrapper *FilteredDeckDBWrapper) Set(ctx context.Context, filtered model.FilteredDeck) error {
+	defer observeQuery("filtered_deck", "Set", time.Now())
+
+	if err := filtered.Validate(); err != nil {
+		return err
+	}
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	filter, err := json.Marshal(filtered.Query)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s",
+		filteredDeckTableName, filteredDeckColumnDeckID, filteredDeckColumnQuery, filteredDeckColumnOrder, filteredDeckColumnLimit, filteredDeckColumnReschedule,
+		filteredDeckColumnDeckID, filteredDeckColumnQuery, filteredDeckColumnQuery, filteredDeckColumnOrder, filteredDeckColumnOrder,
+		filteredDeckColumnLimit, filteredDeckColumnLimit, filteredDeckColumnReschedule, filteredDeckColumnReschedule)
+	slog.DebugContext(ctx, "Setting filtered deck", "query", query)
+
+	_, err = wrapper.db.ExecContext(ctx, query, filtered.DeckID, filter, filtered.Order, filtered.Limit, filtered.Reschedule)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error setting filtered deck", "error", err)
+
+		if strings.Contains(err.Error(), fmt.Sprintf("\"%s_%s_fkey\"", filteredDeckTableName, filteredDeckColumnDeckID)) {
+			return utils.ErrDeckNotExist
+		}
+
+		return err
+	}
+
+	return nil
+}
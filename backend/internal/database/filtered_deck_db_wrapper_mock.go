@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+)
+
+type FilteredDeckDBWrapperMock struct {
+	db map[int]model.FilteredDeck
+}
+
+func NewFilteredDeckDBWrapperMock() *FilteredDeckDBWrapperMock {
+	return &FilteredDeckDBWrapperMock{}
+}
+
+func (wrapper *FilteredDeckDBWrapperMock) CreateTable() error {
+	if wrapper.db == nil {
+		wrapper.db = make(map[int]model.FilteredDeck)
+	}
+
+	return nil
+}
+
+func (wrapper *FilteredDeckDBWrapperMock) Get(ctx context.Context, deckID int) (model.FilteredDeck, error) {
+	if wrapper.db == nil {
+		return model.FilteredDeck{}, utils.ErrDatabaseNotExist
+	}
+
+	filtered, exists := wrapper.db[deckID]
+	if !exists {
+		return model.FilteredDeck{}, utils.ErrRecordNotExist
+	}
+
+	return filtered, nil
+}
+
+func (wrapper *FilteredDeckDBWrapperMock) Set(ctx context.Context, filtered model.FilteredDeck) error {
+	if err := filtered.Validate(); err != nil {
+		return err
+	}
+
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	wrapper.db[filtered.DeckID] = filtered
+	return nil
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	flagTableName   = "flag_definitions"
+	flagColumnFlag  = "flag"
+	flagColumnName  = "name"
+	flagColumnColor = "color"
+
+	// Flag names are unique regardless of case, so "Red" and "red"
+	// can't name two different flags.
+	flagUniqueNameIndex = "flag_definitions_name_key"
+)
+
+// An interface that defines the methods for interacting with the flag definition database.
+// This interface abstracts the database operations for flag definitions,
+// allowing for easier testing and mocking.
+type FlagDBWrapperInterface interface {
+	CreateTable() error
+	GetAll(ctx context.Context) ([]model.FlagDefinition, error)
+	GetByName(ctx context.Context, name string) (model.FlagDefinition, error)
+	Set(ctx context.Context, definition model.FlagDefinition) error
+	Delete(ctx context.Context, flag int) error
+}
+
+// A struct that implements the FlagDBWrapperInterface.
+//
+// This is the concrete implementation and should be used for actual
+// database operations.
+type FlagDBWrapper struct {
+	db *sql.DB
+}
+
+// Creates and returns a new instance of FlagDBWrapper.
+//
+// Parameters:
+//   - db *sql.DB : The database connection.
+//
+// Returns:
+//   - *FlagDBWrapper
+func NewFlagDBWrapper(db *sql.DB) *FlagDBWrapper {
+	return &FlagDBWrapper{db: db}
+}
+
+// Creates a new table in the database if it doesn't already exist.
+//
+// Returns:
+//   - error : An error if the table creation fails, nil otherwise.
+func (wrapper *FlagDBWrapper) CreateTable() error {
+	defer observeQuery("flag", "CreateTable", time.Now())
+
+	if wrapper.db == nil {
+		slog.Error("Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildCreateTableQueryString()
+	slog.Debug("Creating flag definitions table", "query", query)
+
+	_, err := wrapper.db.Exec(query)
+
+	if err != nil {
+		slog.Error("Error creating flag definitions table", "error", err)
+	}
+
+	return err
+}
+
+// A helper function that constructs the SQL query string
+// to create the flag definitions table and its name index.
+//
+// Returns:
+//   - string : The SQL query string to create the flag definitions table.
+func (wrapper *FlagDBWrapper) buildCreateTableQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(flagTableName)
+	sb.WriteString(" (")
+	sb.WriteString(fmt.Sprintf("%s INT PRIMARY KEY CHECK (%s BETWEEN %d AND %d), ", flagColumnFlag, flagColumnFlag, cardMinFlag+1, cardMaxFlag))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(%d) NOT NULL, ", flagColumnName, model.FlagNameMaxLength))
+	sb.WriteString(fmt.Sprintf("%s CHAR(7) NOT NULL CHECK (%s ~ '^#[0-9a-fA-F]{6}$')", flagColumnColor, flagColumnColor))
+	sb.WriteString("); ")
+	sb.WriteString(fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (LOWER(%s))", flagUniqueNameIndex, flagTableName, flagColumnName))
+
+	query := sb.String()
+	return query
+}
+
+// Retrieves all flag definitions, ordered by flag value.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//
+// Returns:
+//   - []model.FlagDefinition : The flag definitions.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *FlagDBWrapper) GetAll(ctx context.Context) ([]model.FlagDefinition, error) {
+	defer observeQuery("flag", "GetAll", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s, %s FROM %s ORDER BY %s ASC",
+		flagColumnFlag, flagColumnName, flagColumnColor, flagTableName, flagColumnFlag)
+	slog.DebugContext(ctx, "Getting all flag definitions", "query", query)
+
+	rows, err := wrapper.db.QueryContext(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting all flag definitions", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	definitions := []model.FlagDefinition{}
+	for rows.Next() {
+		var definition model.FlagDefinition
+		if err := rows.Scan(&definition.Flag, &definition.Name, &definition.Color); err != nil {
+			slog.ErrorContext(ctx, "Error scanning flag definition", "error", err)
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return definitions, nil
+}
+
+// Retrieves a flag definition by its name, ignoring case.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - name string : The name of the flag.
+//
+// Returns:
+//   - model.FlagDefinition : The flag definition.
+//   - error : utils.ErrRecordNotExist if no flag has this name, nil on success.
+func (wrapper *FlagDBWrapper) GetByName(ctx context.Context, name string) (model.FlagDefinition, error) {
+	defer observeQuery("flag", "GetByName", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.FlagDefinition{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s, %s FROM %s WHERE LOWER(%s) = LOWER($1)",
+		flagColumnFlag, flagColumnName, flagColumnColor, flagTableName, flagColumnName)
+	slog.DebugContext(ctx, "Getting flag definition by name", "query", query)
+
+	var definition model.FlagDefinition
+	err := wrapper.db.QueryRowContext(ctx, query, strings.TrimSpace(name)).Scan(&definition.Flag, &definition.Name, &definition.Color)
+	if err == sql.ErrNoRows {
+		return model.FlagDefinition{}, utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error getting flag definition", "error", err)
+		return model.FlagDefinition{}, err
+	}
+
+	return definition, nil
+}
+
+// Creates or replaces the definition of a flag value.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - definition model.FlagDefinition : The flag value with its new name and color.
+//
+// Returns:
+//   - error : utils.ErrInvalidFlag if the definition is invalid, utils.ErrDuplicateKeyViolation
+//     if another flag has the same name, nil on success.
+func (wrapper *FlagDBWrapper) Set(ctx context.Context, definition model.FlagDefinition) error {
+	defer observeQuery("flag", "Set", time.Now())
+
+	if err := definition.Validate(); err != nil {
+		return err
+	}
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES ($1, $2, $3) ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s",
+		flagTableName, flagColumnFlag, flagColumnName, flagColumnColor, flagColumnFlag,
+		flagColumnName, flagColumnName, flagColumnColor, flagColumnColor)
+	slog.DebugContext(ctx, "Setting flag definition", "query", query)
+
+	_, err := wrapper.db.ExecContext(ctx, query, definition.Flag, strings.TrimSpace(definition.Name), definition.Color)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error setting flag definition", "error", err)
+
+		if err.Error() == fmt.Sprintf("pq: duplicate key value violates unique constraint \"%s\"", flagUniqueNameIndex) {
+			return utils.ErrDuplicateKeyViolation
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Deletes the definition of a flag value. Cards keep their flag value.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - flag int : The flag value.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the flag isn't defined, nil on success.
+func (wrapper *FlagDBWrapper) Delete(ctx context.Context, flag int) error {
+	defer observeQuery("flag", "Delete", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = $1", flagTableName, flagColumnFlag)
+	slog.DebugContext(ctx, "Deleting flag definition", "query", query)
+
+	result, err := wrapper.db.ExecContext(ctx, query, flag)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting flag definition", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrRecordNotExist
+	}
+
+	return nil
+}
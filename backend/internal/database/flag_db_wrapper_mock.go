@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"sort"
+	"strings"
+)
+
+type FlagDBWrapperMock struct {
+	db map[int]model.FlagDefinition
+}
+
+func NewFlagDBWrapperMock() *FlagDBWrapperMock {
+	return &FlagDBWrapperMock{}
+}
+
+func (wrapper *FlagDBWrapperMock) CreateTable() error {
+	if wrapper.db == nil {
+		wrapper.db = make(map[int]model.FlagDefinition)
+	}
+
+	return nil
+}
+
+func (wrapper *FlagDBWrapperMock) GetAll(ctx context.Context) ([]model.FlagDefinition, error) {
+	if wrapper.db == nil {
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	definitions := make([]model.FlagDefinition, 0, len(wrapper.db))
+	for _, definition := range wrapper.db {
+		definitions = append(definitions, definition)
+	}
+
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Flag < definitions[j].Flag
+	})
+
+	return definitions, nil
+}
+
+func (wrapper *FlagDBWrapperMock) GetByName(ctx context.Context, name string) (model.FlagDefinition, error) {
+	if wrapper.db == nil {
+		return model.FlagDefinition{}, utils.ErrDatabaseNotExist
+	}
+
+	for _, definition := range wrapper.db {
+		if strings.EqualFold(definition.Name, strings.TrimSpace(name)) {
+			return definition, nil
+		}
+	}
+
+	return model.FlagDefinition{}, utils.ErrRecordNotExist
+}
+
+func (wrapper *FlagDBWrapperMock) Set(ctx context.Context, definition model.FlagDefinition) error {
+	if err := definition.Validate(); err != nil {
+		return err
+	}
+
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	definition.Name = strings.TrimSpace(definition.Name)
+	for flag, existing := range wrapper.db {
+		if flag != definition.Flag && strings.EqualFold(existing.Name, definition.Name) {
+			return utils.ErrDuplicateKeyViolation
+		}
+	}
+
+	wrapper.db[definition.Flag] = definition
+	return nil
+}
+
+func (wrapper *FlagDBWrapperMock) Delete(ctx context.Context, flag int) error {
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	if _, exists := wrapper.db[flag]; !exists {
+		return utils.ErrRecordNotExist
+	}
+
+	delete(wrapper.db, flag)
+	return nil
+}
@@ -0,0 +1,672 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	jobTableName             = "jobs"
+	jobColumnID              = "id"
+	jobColumnKind            = "kind"
+	jobColumnPayload         = "payload"
+	jobColumnCheckpoint      = "checkpoint"
+	jobColumnResult          = "result"
+	jobColumnStatus          = "status"
+	jobColumnProgress        = "progress"
+	jobColumnTotal           = "total"
+	jobColumnAttempts        = "attempts"
+	jobColumnMaxAttempts     = "max_attempts"
+	jobColumnLastError       = "last_error"
+	jobColumnCancelRequested = "cancel_requested"
+	jobColumnRunAt           = "run_at"
+	jobColumnLeaseOwner      = "lease_owner"
+	jobColumnLeaseUntil      = "lease_until"
+	jobColumnCreatedAt       = "created_at"
+	jobColumnUpdatedAt       = "updated_at"
+	jobColumnFinishedAt      = "finished_at"
+	jobColumnAppliedAt       = "applied_at"
+)
+
+// An interface that defines the methods for interacting with the job database.
+// This interface abstracts the database operations for the jobs run by the
+// background workers, allowing for easier testing and mocking.
+//
+// A worker leases a job before running it, and only the owner of the lease
+// may save the progress of the job or release it. A lease that isn't extended
+// expires, so the job of a worker that crashed is leased again by another one.
+type JobDBWrapperInterface interface {
+	CreateTable() error
+	Insert(ctx context.Context, job model.Job) (int, error)
+	InsertUnique(ctx context.Context, job model.Job) (model.Job, error)
+	Get(ctx context.Context, id int) (model.Job, error)
+	Lease(ctx context.Context, kinds []string, owner string, now time.Time, leaseUntil time.Time) (model.Job, error)
+	Heartbeat(ctx context.Context, id int, owner string, leaseUntil time.Time) (bool, error)
+	SaveProgress(ctx context.Context, job model.Job) error
+	Release(ctx context.Context, job model.Job, owner string) error
+	Cancel(ctx context.Context, id int, now time.Time) (model.Job, error)
+	CountUnfinished(ctx context.Context) ([]model.JobCount, error)
+	MarkApplied(ctx context.Context, id int, now time.Time) error
+}
+
+// A struct that implements the JobDBWrapperInterface.
+//
+// This is the concrete implementation and should be used for actual
+// database operations.
+type JobDBWrapper struct {
+	db *sql.DB
+}
+
+// Creates and returns a new instance of JobDBWrapper.
+//
+// Parameters:
+//   - db *sql.DB : The database connection.
+//
+// Returns:
+//   - *JobDBWrapper
+func NewJobDBWrapper(db *sql.DB) *JobDBWrapper {
+	return &JobDBWrapper{db: db}
+}
+
+// Creates a new table in the database if it doesn't already exist,
+// along with the index the workers use to find the next job.
+//
+// Returns:
+//   - error : An error if the table creation fails, nil otherwise.
+func (wrapper *JobDBWrapper) CreateTable() error {
+	defer observeQuery("job", "CreateTable", time.Now())
+
+	if wrapper.db == nil {
+		slog.Error("Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildCreateTableQueryString()
+	slog.Debug("Creating jobs table", "query", query)
+
+	if _, err := wrapper.db.Exec(query); err != nil {
+		slog.Error("Error creating jobs table", "error", err)
+		return err
+	}
+
+	// Tables created by older versions lack the result of the jobs and the
+	// time it was applied
+	for _, column := range []string{jobColumnResult + " JSONB", jobColumnAppliedAt + " TIMESTAMP"} {
+		query = fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", jobTableName, column)
+		slog.Debug("Migrating jobs table", "query", query)
+
+		if _, err := wrapper.db.Exec(query); err != nil {
+			slog.Error("Error migrating jobs table", "error", err)
+			return err
+		}
+	}
+
+	query = fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_%s_%s_idx ON %s (%s, %s)",
+		jobTableName, jobColumnStatus, jobColumnRunAt, jobTableName, jobColumnStatus, jobColumnRunAt)
+	slog.Debug("Creating jobs index", "query", query)
+
+	if _, err := wrapper.db.Exec(query); err != nil {
+		slog.Error("Error creating jobs index", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// A helper function that constructs the SQL query string
+// to create the jobs table.
+//
+// Returns:
+//   - string : The SQL query string to create the jobs table.
+func (wrapper *JobDBWrapper) buildCreateTableQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(jobTableName)
+	sb.WriteString(" (")
+	sb.WriteString(fmt.Sprintf("%s SERIAL PRIMARY KEY, ", jobColumnID))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(32) NOT NULL, ", jobColumnKind))
+	sb.WriteString(fmt.Sprintf("%s JSONB NOT NULL, ", jobColumnPayload))
+	sb.WriteString(fmt.Sprintf("%s JSONB, ", jobColumnCheckpoint))
+	sb.WriteString(fmt.Sprintf("%s JSONB, ", jobColumnResult))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(16) NOT NULL CHECK (%s IN ('%s', '%s', '%s', '%s', '%s')), ", jobColumnStatus, jobColumnStatus,
+		model.JobStatusQueued, model.JobStatusRunning, model.JobStatusSucceeded, model.JobStatusFailed, model.JobStatusCancelled))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL DEFAULT 0, ", jobColumnProgress))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL DEFAULT 0, ", jobColumnTotal))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL DEFAULT 0, ", jobColumnAttempts))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s > 0), ", jobColumnMaxAttempts, jobColumnMaxAttempts))
+	sb.WriteString(fmt.Sprintf("%s TEXT NOT NULL DEFAULT '', ", jobColumnLastError))
+	sb.WriteString(fmt.Sprintf("%s BOOLEAN NOT NULL DEFAULT FALSE, ", jobColumnCancelRequested))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP NOT NULL, ", jobColumnRunAt))
+	sb.WriteString(fmt.Sprintf("%s TEXT NOT NULL DEFAULT '', ", jobColumnLeaseOwner))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP, ", jobColumnLeaseUntil))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP NOT NULL, ", jobColumnCreatedAt))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP NOT NULL, ", jobColumnUpdatedAt))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP, ", jobColumnFinishedAt))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP", jobColumnAppliedAt))
+	sb.WriteString(")")
+
+	query := sb.String()
+	return query
+}
+
+// Inserts a new job into the database.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - job model.Job : The job to insert.
+//
+// Returns:
+//   - int : The ID of the inserted job.
+//   - error : An error if the insertion fails, nil otherwise.
+func (wrapper *JobDBWrapper) Insert(ctx context.Context, job model.Job) (int, error) {
+	defer observeQuery("job", "Insert", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING %s",
+		jobTableName, jobColumnKind, jobColumnPayload, jobColumnStatus, jobColumnMaxAttempts,
+		jobColumnRunAt, jobColumnCreatedAt, jobColumnUpdatedAt, jobColumnID)
+	slog.DebugContext(ctx, "Inserting job", "query", query)
+
+	var id int
+	err := wrapper.db.QueryRowContext(ctx, query, job.Kind, string(job.Payload), job.Status, job.MaxAttempts,
+		job.RunAt.UTC(), job.CreatedAt.UTC(), job.UpdatedAt.UTC()).Scan(&id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error inserting job", "error", err)
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// Inserts a new job unless a job of the same kind is queued or running, such
+// as the job of a recurring kind, which must only be pending once. The check
+// and the insertion hold a lock on the kind, so concurrent calls for the same
+// kind insert a single job.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - job model.Job : The job to insert.
+//
+// Returns:
+//   - model.Job : The inserted job, or the pending job of the same kind.
+//   - error : An error if the insertion fails, nil otherwise.
+func (wrapper *JobDBWrapper) InsertUnique(ctx context.Context, job model.Job) (model.Job, error) {
+	defer observeQuery("job", "InsertUnique", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.Job{}, utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return model.Job{}, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("SELECT pg_advisory_xact_lock(hashtext('%s'), hashtext($1))", jobTableName)
+	slog.DebugContext(ctx, "Locking job kind", "query", query)
+
+	if _, err = tx.ExecContext(ctx, query, job.Kind); err != nil {
+		slog.ErrorContext(ctx, "Error locking job kind", "error", err)
+		return model.Job{}, err
+	}
+
+	query = fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 AND %s IN ($2, $3) ORDER BY %s ASC LIMIT 1",
+		jobSelectColumns(), jobTableName, jobColumnKind, jobColumnStatus, jobColumnID)
+	slog.DebugContext(ctx, "Getting pending job", "query", query)
+
+	pending, err := scanJob(tx.QueryRowContext(ctx, query, job.Kind, model.JobStatusQueued, model.JobStatusRunning))
+	if err == nil {
+		return pending, nil
+	} else if err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "Error getting pending job", "error", err)
+		return model.Job{}, err
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING %s",
+		jobTableName, jobColumnKind, jobColumnPayload, jobColumnStatus, jobColumnMaxAttempts,
+		jobColumnRunAt, jobColumnCreatedAt, jobColumnUpdatedAt, jobColumnID)
+	slog.DebugContext(ctx, "Inserting job", "query", query)
+
+	err = tx.QueryRowContext(ctx, query, job.Kind, string(job.Payload), job.Status, job.MaxAttempts,
+		job.RunAt.UTC(), job.CreatedAt.UTC(), job.UpdatedAt.UTC()).Scan(&job.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error inserting job", "error", err)
+		return model.Job{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return model.Job{}, err
+	}
+
+	return job, nil
+}
+
+// Retrieves a job.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - id int : The unique ID of the job.
+//
+// Returns:
+//   - model.Job : The job.
+//   - error : utils.ErrRecordNotExist if the job doesn't exist, nil on success.
+func (wrapper *JobDBWrapper) Get(ctx context.Context, id int) (model.Job, error) {
+	defer observeQuery("job", "Get", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.Job{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1", jobSelectColumns(), jobTableName, jobColumnID)
+	slog.DebugContext(ctx, "Getting job", "query", query)
+
+	job, err := scanJob(wrapper.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return model.Job{}, utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error getting job", "error", err)
+		return model.Job{}, err
+	}
+
+	return job, nil
+}
+
+// Leases the next job of the given kinds, that is the queued job that is due
+// the longest or a running job whose lease expired. The row is locked with
+// SKIP LOCKED, so concurrent workers never lease the same job, and the
+// attempts of the job are counted.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - kinds []string : The kinds of jobs the worker can run.
+//   - owner string : The worker leasing the job.
+//   - now time.Time : The current time.
+//   - leaseUntil time.Time : The time the lease expires unless extended with Heartbeat.
+//
+// Returns:
+//   - model.Job : The leased job, with its status set to running.
+//   - error : utils.ErrNoJob if no job is ready, nil on success.
+func (wrapper *JobDBWrapper) Lease(ctx context.Context, kinds []string, owner string, now time.Time, leaseUntil time.Time) (model.Job, error) {
+	defer observeQuery("job", "Lease", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.Job{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET %s = '%s', %s = %s + 1, %s = $2, %s = $4, %s = $3
+		WHERE %s = (
+			SELECT %s FROM %s
+			WHERE %s = ANY($1) AND ((%s = '%s' AND %s <= $3) OR (%s = '%s' AND %s < $3))
+			ORDER BY %s ASC, %s ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %s`,
+		jobTableName, jobColumnStatus, model.JobStatusRunning, jobColumnAttempts, jobColumnAttempts,
+		jobColumnLeaseOwner, jobColumnLeaseUntil, jobColumnUpdatedAt,
+		jobColumnID,
+		jobColumnID, jobTableName,
+		jobColumnKind, jobColumnStatus, model.JobStatusQueued, jobColumnRunAt, jobColumnStatus, model.JobStatusRunning, jobColumnLeaseUntil,
+		jobColumnRunAt, jobColumnID,
+		jobSelectColumns())
+	slog.DebugContext(ctx, "Leasing job", "query", query)
+
+	job, err := scanJob(wrapper.db.QueryRowContext(ctx, query, pq.Array(kinds), owner, now.UTC(), leaseUntil.UTC()))
+	if err == sql.ErrNoRows {
+		return model.Job{}, utils.ErrNoJob
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error leasing job", "error", err)
+		return model.Job{}, err
+	}
+
+	return job, nil
+}
+
+// Extends the lease of a running job.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - id int : The unique ID of the job.
+//   - owner string : The worker holding the lease.
+//   - leaseUntil time.Time : The new expiry of the lease.
+//
+// Returns:
+//   - bool : Whether the cancellation of the job was requested.
+//   - error : utils.ErrRecordNotExist if the worker no longer holds the lease, nil on success.
+func (wrapper *JobDBWrapper) Heartbeat(ctx context.Context, id int, owner string, leaseUntil time.Time) (bool, error) {
+	defer observeQuery("job", "Heartbeat", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return false, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = $3 WHERE %s = $1 AND %s = $2 AND %s = '%s' RETURNING %s",
+		jobTableName, jobColumnLeaseUntil, jobColumnID, jobColumnLeaseOwner,
+		jobColumnStatus, model.JobStatusRunning, jobColumnCancelRequested)
+	slog.DebugContext(ctx, "Extending job lease", "query", query)
+
+	var cancelRequested bool
+	err := wrapper.db.QueryRowContext(ctx, query, id, owner, leaseUntil.UTC()).Scan(&cancelRequested)
+	if err == sql.ErrNoRows {
+		return false, utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error extending job lease", "error", err)
+		return false, err
+	}
+
+	return cancelRequested, nil
+}
+
+// Saves the progress of a running job and its checkpoint.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - job model.Job : The job, whose LeaseOwner must still hold the lease.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the worker no longer holds the lease, nil on success.
+func (wrapper *JobDBWrapper) SaveProgress(ctx context.Context, job model.Job) error {
+	defer observeQuery("job", "SaveProgress", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = $3, %s = $4, %s = $5, %s = $6 WHERE %s = $1 AND %s = $2 AND %s = '%s'",
+		jobTableName, jobColumnProgress, jobColumnTotal, jobColumnCheckpoint, jobColumnUpdatedAt,
+		jobColumnID, jobColumnLeaseOwner, jobColumnStatus, model.JobStatusRunning)
+	slog.DebugContext(ctx, "Saving job progress", "query", query)
+
+	result, err := wrapper.db.ExecContext(ctx, query, job.ID, job.LeaseOwner, job.Progress, job.Total,
+		nullableJSON(job.Checkpoint), job.UpdatedAt.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error saving job progress", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrRecordNotExist
+	}
+
+	return nil
+}
+
+// Releases the lease of a job, saving the outcome of its run: a final
+// status along with the result of the job, or the queued status along with
+// the time it is retried at.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - job model.Job : The job after its run.
+//   - owner string : The worker holding the lease.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the worker no longer holds the lease, nil on success.
+func (wrapper *JobDBWrapper) Release(ctx context.Context, job model.Job, owner string) error {
+	defer observeQuery("job", "Release", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET %s = $3, %s = $4, %s = $5, %s = $6, %s = $7, %s = $8, %s = $9, %s = $10, %s = $11, %s = $12,
+		%s = '', %s = NULL WHERE %s = $1 AND %s = $2 AND %s = '%s'`,
+		jobTableName, jobColumnStatus, jobColumnProgress, jobColumnTotal, jobColumnCheckpoint, jobColumnAttempts,
+		jobColumnLastError, jobColumnRunAt, jobColumnUpdatedAt, jobColumnFinishedAt, jobColumnResult,
+		jobColumnLeaseOwner, jobColumnLeaseUntil, jobColumnID, jobColumnLeaseOwner, jobColumnStatus, model.JobStatusRunning)
+	slog.DebugContext(ctx, "Releasing job", "query", query)
+
+	var finishedAt *time.Time
+	if job.FinishedAt != nil {
+		utc := job.FinishedAt.UTC()
+		finishedAt = &utc
+	}
+
+	result, err := wrapper.db.ExecContext(ctx, query, job.ID, owner, job.Status, job.Progress, job.Total, nullableJSON(job.Checkpoint),
+		job.Attempts, job.LastError, job.RunAt.UTC(), job.UpdatedAt.UTC(), finishedAt, nullableJSON(job.Result))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error releasing job", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrRecordNotExist
+	}
+
+	return nil
+}
+
+// Requests the cancellation of a job. A queued job is cancelled right away,
+// while a running job is flagged and stopped by its worker.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - id int : The unique ID of the job.
+//   - now time.Time : The current time.
+//
+// Returns:
+//   - model.Job : The job after the request.
+//   - error : utils.ErrRecordNotExist if the job doesn't exist, utils.ErrJobFinished
+//     if it already finished, nil on success.
+func (wrapper *JobDBWrapper) Cancel(ctx context.Context, id int, now time.Time) (model.Job, error) {
+	defer observeQuery("job", "Cancel", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.Job{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET
+		%s = CASE WHEN %s = '%s' THEN '%s' ELSE %s END,
+		%s = CASE WHEN %s = '%s' THEN $2 ELSE %s END,
+		%s = TRUE, %s = $2
+		WHERE %s = $1 AND %s IN ('%s', '%s')
+		RETURNING %s`,
+		jobTableName,
+		jobColumnStatus, jobColumnStatus, model.JobStatusQueued, model.JobStatusCancelled, jobColumnStatus,
+		jobColumnFinishedAt, jobColumnStatus, model.JobStatusQueued, jobColumnFinishedAt,
+		jobColumnCancelRequested, jobColumnUpdatedAt,
+		jobColumnID, jobColumnStatus, model.JobStatusQueued, model.JobStatusRunning,
+		jobSelectColumns())
+	slog.DebugContext(ctx, "Cancelling job", "query", query)
+
+	job, err := scanJob(wrapper.db.QueryRowContext(ctx, query, id, now.UTC()))
+	if err == sql.ErrNoRows {
+		if _, err = wrapper.Get(ctx, id); err != nil {
+			return model.Job{}, err
+		}
+		return model.Job{}, utils.ErrJobFinished
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error cancelling job", "error", err)
+		return model.Job{}, err
+	}
+
+	return job, nil
+}
+
+// Counts the jobs which aren't finished, that is queued or running, by kind
+// and status.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//
+// Returns:
+//   - []model.JobCount : The counts, sorted by kind and status, without the empty ones.
+//   - error : An error if the count fails, nil otherwise.
+func (wrapper *JobDBWrapper) CountUnfinished(ctx context.Context) ([]model.JobCount, error) {
+	defer observeQuery("job", "CountUnfinished", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s, COUNT(*) FROM %s WHERE %s IN ($1, $2) GROUP BY %s, %s ORDER BY %s, %s",
+		jobColumnKind, jobColumnStatus, jobTableName, jobColumnStatus,
+		jobColumnKind, jobColumnStatus, jobColumnKind, jobColumnStatus)
+	slog.DebugContext(ctx, "Counting unfinished jobs", "query", query)
+
+	rows, err := wrapper.db.QueryContext(ctx, query, model.JobStatusQueued, model.JobStatusRunning)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error counting unfinished jobs", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []model.JobCount{}
+	for rows.Next() {
+		var count model.JobCount
+		if err = rows.Scan(&count.Kind, &count.Status, &count.Count); err != nil {
+			slog.ErrorContext(ctx, "Error scanning job count", "error", err)
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	if err = rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error counting unfinished jobs", "error", err)
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// Marks the result of a job that succeeded as applied, so that it is applied
+// only once. Only one of concurrent calls for the same job succeeds.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - id int : The unique ID of the job.
+//   - now time.Time : The current time.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the job doesn't exist, utils.ErrJobApplied
+//     if it was already applied or hasn't succeeded, nil on success.
+func (wrapper *JobDBWrapper) MarkApplied(ctx context.Context, id int, now time.Time) error {
+	defer observeQuery("job", "MarkApplied", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s = $2, %s = $2 WHERE %s = $1 AND %s = '%s' AND %s IS NULL",
+		jobTableName, jobColumnAppliedAt, jobColumnUpdatedAt,
+		jobColumnID, jobColumnStatus, model.JobStatusSucceeded, jobColumnAppliedAt)
+	slog.DebugContext(ctx, "Marking job as applied", "query", query)
+
+	result, err := wrapper.db.ExecContext(ctx, query, id, now.UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error marking job as applied", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		if _, err = wrapper.Get(ctx, id); err != nil {
+			return err
+		}
+		return utils.ErrJobApplied
+	}
+
+	return nil
+}
+
+// A helper function that returns the columns that scanJob expects, in order.
+func jobSelectColumns() string {
+	return strings.Join([]string{
+		jobColumnID,
+		jobColumnKind,
+		jobColumnPayload,
+		jobColumnCheckpoint,
+		jobColumnResult,
+		jobColumnStatus,
+		jobColumnProgress,
+		jobColumnTotal,
+		jobColumnAttempts,
+		jobColumnMaxAttempts,
+		jobColumnLastError,
+		jobColumnCancelRequested,
+		jobColumnRunAt,
+		jobColumnLeaseOwner,
+		jobColumnLeaseUntil,
+		jobColumnCreatedAt,
+		jobColumnUpdatedAt,
+		jobColumnFinishedAt,
+		jobColumnAppliedAt,
+	}, ", ")
+}
+
+// A helper function that scans a single job selected with jobSelectColumns.
+func scanJob(scanner interface{ Scan(...any) error }) (model.Job, error) {
+	var job model.Job
+	var payload, checkpoint, result []byte
+	var leaseUntil, finishedAt, appliedAt sql.NullTime
+
+	err := scanner.Scan(
+		&job.ID,
+		&job.Kind,
+		&payload,
+		&checkpoint,
+		&result,
+		&job.Status,
+		&job.Progress,
+		&job.Total,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.LastError,
+		&job.CancelRequested,
+		&job.RunAt,
+		&job.LeaseOwner,
+		&leaseUntil,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&finishedAt,
+		&appliedAt,
+	)
+	if err != nil {
+		return model.Job{}, err
+	}
+
+	job.Payload = payload
+	job.Checkpoint = checkpoint
+	job.Result = result
+	if leaseUntil.Valid {
+		job.LeaseUntil = &leaseUntil.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	if appliedAt.Valid {
+		job.AppliedAt = &appliedAt.Time
+	}
+	return job, nil
+}
+
+// A helper function that turns an empty JSON document into NULL, and any
+// other into a string, which is how lib/pq sends a JSONB parameter.
+func nullableJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
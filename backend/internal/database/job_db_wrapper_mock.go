@@ -0,0 +1,275 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type JobDBWrapperMock struct {
+	mu     sync.Mutex
+	db     map[int]model.Job
+	nextID int
+}
+
+func NewJobDBWrapperMock() *JobDBWrapperMock {
+	return &JobDBWrapperMock{}
+}
+
+func (wrapper *JobDBWrapperMock) CreateTable() error {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		wrapper.db = make(map[int]model.Job)
+	}
+
+	return nil
+}
+
+func (wrapper *JobDBWrapperMock) Insert(ctx context.Context, job model.Job) (int, error) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	job.ID = wrapper.nextID
+	wrapper.db[job.ID] = cloneJob(job)
+	wrapper.nextID++
+	return job.ID, nil
+}
+
+func (wrapper *JobDBWrapperMock) InsertUnique(ctx context.Context, job model.Job) (model.Job, error) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return model.Job{}, utils.ErrDatabaseNotExist
+	}
+
+	for id := 0; id < wrapper.nextID; id++ {
+		pending, exists := wrapper.db[id]
+		if exists && pending.Kind == job.Kind && !pending.Finished() {
+			return cloneJob(pending), nil
+		}
+	}
+
+	job.ID = wrapper.nextID
+	wrapper.db[job.ID] = cloneJob(job)
+	wrapper.nextID++
+	return job, nil
+}
+
+func (wrapper *JobDBWrapperMock) Get(ctx context.Context, id int) (model.Job, error) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return model.Job{}, utils.ErrDatabaseNotExist
+	}
+
+	job, exists := wrapper.db[id]
+	if !exists {
+		return model.Job{}, utils.ErrRecordNotExist
+	}
+
+	return cloneJob(job), nil
+}
+
+func (wrapper *JobDBWrapperMock) Lease(ctx context.Context, kinds []string, owner string, now time.Time, leaseUntil time.Time) (model.Job, error) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return model.Job{}, utils.ErrDatabaseNotExist
+	}
+
+	leased := -1
+	for id := 0; id < wrapper.nextID; id++ {
+		job, exists := wrapper.db[id]
+		if !exists || !slices.Contains(kinds, job.Kind) {
+			continue
+		}
+
+		queued := job.Status == model.JobStatusQueued && !job.RunAt.After(now)
+		expired := job.Status == model.JobStatusRunning && job.LeaseUntil != nil && job.LeaseUntil.Before(now)
+		if !queued && !expired {
+			continue
+		}
+
+		if leased == -1 || job.RunAt.Before(wrapper.db[leased].RunAt) {
+			leased = id
+		}
+	}
+
+	if leased == -1 {
+		return model.Job{}, utils.ErrNoJob
+	}
+
+	job := wrapper.db[leased]
+	job.Status = model.JobStatusRunning
+	job.Attempts++
+	job.LeaseOwner = owner
+	job.LeaseUntil = &leaseUntil
+	job.UpdatedAt = now
+	wrapper.db[leased] = job
+	return cloneJob(job), nil
+}
+
+func (wrapper *JobDBWrapperMock) Heartbeat(ctx context.Context, id int, owner string, leaseUntil time.Time) (bool, error) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return false, utils.ErrDatabaseNotExist
+	}
+
+	job, exists := wrapper.db[id]
+	if !exists || job.Status != model.JobStatusRunning || job.LeaseOwner != owner {
+		return false, utils.ErrRecordNotExist
+	}
+
+	job.LeaseUntil = &leaseUntil
+	wrapper.db[id] = job
+	return job.CancelRequested, nil
+}
+
+func (wrapper *JobDBWrapperMock) SaveProgress(ctx context.Context, job model.Job) error {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	stored, exists := wrapper.db[job.ID]
+	if !exists || stored.Status != model.JobStatusRunning || stored.LeaseOwner != job.LeaseOwner {
+		return utils.ErrRecordNotExist
+	}
+
+	stored.Progress = job.Progress
+	stored.Total = job.Total
+	stored.Checkpoint = slices.Clone(job.Checkpoint)
+	stored.UpdatedAt = job.UpdatedAt
+	wrapper.db[job.ID] = stored
+	return nil
+}
+
+func (wrapper *JobDBWrapperMock) Release(ctx context.Context, job model.Job, owner string) error {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	stored, exists := wrapper.db[job.ID]
+	if !exists || stored.Status != model.JobStatusRunning || stored.LeaseOwner != owner {
+		return utils.ErrRecordNotExist
+	}
+
+	stored.Status = job.Status
+	stored.Progress = job.Progress
+	stored.Total = job.Total
+	stored.Checkpoint = slices.Clone(job.Checkpoint)
+	stored.Attempts = job.Attempts
+	stored.LastError = job.LastError
+	stored.RunAt = job.RunAt
+	stored.UpdatedAt = job.UpdatedAt
+	stored.FinishedAt = job.FinishedAt
+	stored.Result = slices.Clone(job.Result)
+	stored.LeaseOwner = ""
+	stored.LeaseUntil = nil
+	wrapper.db[job.ID] = stored
+	return nil
+}
+
+func (wrapper *JobDBWrapperMock) Cancel(ctx context.Context, id int, now time.Time) (model.Job, error) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return model.Job{}, utils.ErrDatabaseNotExist
+	}
+
+	job, exists := wrapper.db[id]
+	if !exists {
+		return model.Job{}, utils.ErrRecordNotExist
+	}
+	if job.Finished() {
+		return model.Job{}, utils.ErrJobFinished
+	}
+
+	if job.Status == model.JobStatusQueued {
+		job.Status = model.JobStatusCancelled
+		job.FinishedAt = &now
+	}
+	job.CancelRequested = true
+	job.UpdatedAt = now
+	wrapper.db[id] = job
+	return cloneJob(job), nil
+}
+
+func (wrapper *JobDBWrapperMock) CountUnfinished(ctx context.Context) ([]model.JobCount, error) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	byKey := make(map[model.JobCount]int)
+	for _, job := range wrapper.db {
+		if !job.Finished() {
+			byKey[model.JobCount{Kind: job.Kind, Status: job.Status}]++
+		}
+	}
+
+	counts := []model.JobCount{}
+	for key, count := range byKey {
+		key.Count = count
+		counts = append(counts, key)
+	}
+	slices.SortFunc(counts, func(a, b model.JobCount) int {
+		return cmp.Or(strings.Compare(a.Kind, b.Kind), strings.Compare(a.Status, b.Status))
+	})
+	return counts, nil
+}
+
+func (wrapper *JobDBWrapperMock) MarkApplied(ctx context.Context, id int, now time.Time) error {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	job, exists := wrapper.db[id]
+	if !exists {
+		return utils.ErrRecordNotExist
+	}
+	if job.Status != model.JobStatusSucceeded || job.AppliedAt != nil {
+		return utils.ErrJobApplied
+	}
+
+	job.AppliedAt = &now
+	job.UpdatedAt = now
+	wrapper.db[id] = job
+	return nil
+}
+
+// A helper function that copies the JSON documents of a job, so that the
+// stored job can't be changed through the one handed out.
+func cloneJob(job model.Job) model.Job {
+	job.Payload = slices.Clone(job.Payload)
+	job.Checkpoint = slices.Clone(job.Checkpoint)
+	job.Result = slices.Clone(job.Result)
+	return job
+}
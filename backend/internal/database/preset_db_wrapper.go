@@ -0,0 +1,598 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	presetTableName                = "deck_presets"
+	presetColumnID                 = "id"
+	presetColumnName               = "name"
+	presetColumnNewCardsPerDay     = "new_cards_per_day"
+	presetColumnMaxReviewsPerDay   = "max_reviews_per_day"
+	presetColumnLearningSteps      = "learning_steps"
+	presetColumnRelearningSteps    = "relearning_steps"
+	presetColumnGraduatingInterval = "graduating_interval"
+	presetColumnEasyBonus          = "easy_bonus"
+	presetColumnMaxInterval        = "max_interval"
+	presetColumnScheduler          = "scheduler"
+	presetColumnLeechThreshold     = "leech_threshold"
+	presetColumnLeechAction        = "leech_action"
+	presetColumnLeechFlag          = "leech_flag"
+	presetColumnDesiredRetention   = "desired_retention"
+	presetColumnFSRSWeights        = "fsrs_weights"
+	presetColumnEaseModifier       = "ease_modifier"
+	PresetColumnNameMaxLength      = 64
+)
+
+// An interface that defines the methods for interacting with the deck preset database.
+// This interface abstracts the database operations for deck presets,
+// allowing for easier testing and mocking.
+type PresetDBWrapperInterface interface {
+	CreateTable() error
+	Insert(ctx context.Context, preset model.DeckPreset) (int, error)
+	GetSingle(ctx context.Context, presetID int) (model.DeckPreset, error)
+	GetAll(ctx context.Context) ([]model.DeckPreset, error)
+	Modify(ctx context.Context, preset model.DeckPreset) error
+	ModifyIfUnchanged(ctx context.Context, before model.DeckPreset, preset model.DeckPreset) error
+	Delete(ctx context.Context, id int) error
+}
+
+// A struct that implements the PresetDBWrapperInterface.
+//
+// This is the concrete implementation and should be used for actual
+// database operations.
+type PresetDBWrapper struct {
+	db *sql.DB
+}
+
+// Creates and returns a new instance of PresetDBWrapper.
+//
+// Parameters:
+//   - db *sql.DB : The database connection.
+//
+// Returns:
+//   - *PresetDBWrapper
+func NewPresetDBWrapper(db *sql.DB) *PresetDBWrapper {
+	return &PresetDBWrapper{db: db}
+}
+
+// Creates a new table in the database if it doesn't already exist.
+// The decks table references this table, so it must be created first.
+//
+// Returns:
+//   - error : An error if the table creation fails, nil otherwise.
+func (wrapper *PresetDBWrapper) CreateTable() error {
+	defer observeQuery("preset", "CreateTable", time.Now())
+
+	if wrapper.db == nil {
+		slog.Error("Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildCreateTableQueryString()
+	slog.Debug("Creating deck presets table", "query", query)
+
+	_, err := wrapper.db.Exec(query)
+
+	if err != nil {
+		slog.Error("Error creating deck presets table", "error", err)
+		return err
+	}
+
+	for _, query := range wrapper.buildMigrationQueryStrings() {
+		slog.Debug("Migrating deck presets table", "query", query)
+
+		if _, err = wrapper.db.Exec(query); err != nil {
+			slog.Error("Error migrating deck presets table", "error", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A helper function that constructs the SQL query string
+// to create the deck presets table.
+//
+// Returns:
+//   - string : The SQL query string to create the deck presets table.
+func (wrapper *PresetDBWrapper) buildCreateTableQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(presetTableName)
+	sb.WriteString(" (")
+	sb.WriteString(fmt.Sprintf("%s SERIAL PRIMARY KEY, ", presetColumnID))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(%d) NOT NULL UNIQUE, ", presetColumnName, PresetColumnNameMaxLength))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s >= 0), ", presetColumnNewCardsPerDay, presetColumnNewCardsPerDay))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s >= 0), ", presetColumnMaxReviewsPerDay, presetColumnMaxReviewsPerDay))
+	sb.WriteString(fmt.Sprintf("%s INT[] NOT NULL, ", presetColumnLearningSteps))
+	sb.WriteString(fmt.Sprintf("%s INT[] NOT NULL, ", presetColumnRelearningSteps))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s >= 1), ", presetColumnGraduatingInterval, presetColumnGraduatingInterval))
+	sb.WriteString(fmt.Sprintf("%s REAL NOT NULL CHECK (%s >= 1), ", presetColumnEasyBonus, presetColumnEasyBonus))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s >= %s), ", presetColumnMaxInterval, presetColumnMaxInterval, presetColumnGraduatingInterval))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(16) NOT NULL CHECK (%s IN ('%s', '%s')), ", presetColumnScheduler, presetColumnScheduler, model.SchedulerSM2, model.SchedulerFSRS))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s >= 1), ", presetColumnLeechThreshold, presetColumnLeechThreshold))
+	sb.WriteString(strings.Join(presetReviewColumnDefinitions(), ", "))
+	sb.WriteString(")")
+
+	query := sb.String()
+	return query
+}
+
+// A helper function that constructs the SQL query strings that bring
+// a deck presets table created by an older version up to date.
+//
+// Returns:
+//   - []string : The SQL query strings to run in order.
+func (wrapper *PresetDBWrapper) buildMigrationQueryStrings() []string {
+	queries := []string{}
+	for _, column := range presetReviewColumnDefinitions() {
+		queries = append(queries, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", presetTableName, column))
+	}
+	return queries
+}
+
+// A helper function that returns the definitions of the columns used when cards
+// are answered, shared by the table creation and its migration. Their defaults
+// match the default preset.
+//
+// Returns:
+//   - []string : The column definitions.
+func presetReviewColumnDefinitions() []string {
+	defaults := model.DefaultDeckPreset()
+	return []string{
+		fmt.Sprintf("%s VARCHAR(16) NOT NULL DEFAULT '%s' CHECK (%s IN ('%s', '%s'))",
+			presetColumnLeechAction, defaults.LeechAction, presetColumnLeechAction, model.LeechActionTag, model.LeechActionSuspend),
+		fmt.Sprintf("%s INT NOT NULL DEFAULT %d CHECK (%s BETWEEN %d AND %d)",
+			presetColumnLeechFlag, defaults.LeechFlag, presetColumnLeechFlag, model.CardMinFlag, model.CardMaxFlag),
+		fmt.Sprintf("%s REAL NOT NULL DEFAULT %g CHECK (%s BETWEEN %g AND %g)",
+			presetColumnDesiredRetention, defaults.DesiredRetention, presetColumnDesiredRetention, model.MinDesiredRetention, model.MaxDesiredRetention),
+		fmt.Sprintf("%s DOUBLE PRECISION[] NOT NULL DEFAULT '{}'", presetColumnFSRSWeights),
+		fmt.Sprintf("%s REAL NOT NULL DEFAULT %g CHECK (%s BETWEEN %g AND %g)",
+			presetColumnEaseModifier, defaults.EaseModifier, presetColumnEaseModifier, model.MinEaseModifier, model.MaxEaseModifier),
+	}
+}
+
+// Inserts a new deck preset into the database and returns its unique ID.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - preset model.DeckPreset : Details of the preset to be inserted.
+//
+// Returns:
+//   - int : The unique ID of the inserted preset.
+//   - error : An error if the insertion fails, nil otherwise.
+func (wrapper *PresetDBWrapper) Insert(ctx context.Context, preset model.DeckPreset) (int, error) {
+	defer observeQuery("preset", "Insert", time.Now())
+
+	if len(preset.Name) > PresetColumnNameMaxLength {
+		slog.ErrorContext(ctx, "Deck preset name exceeds maximum length")
+		return -1, utils.ErrMaxLengthExceeded
+	}
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildInsertQueryString()
+	slog.DebugContext(ctx, "Inserting deck preset", "query", query)
+
+	err := wrapper.db.QueryRowContext(ctx, query, presetArguments(preset)...).Scan(&preset.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error inserting deck preset", "error", err)
+
+		if isDuplicatePresetNameError(err) {
+			return -1, utils.ErrDuplicateKeyViolation
+		}
+
+		return -1, err
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Inserted deck preset %d", preset.ID))
+
+	return preset.ID, nil
+}
+
+// A helper function that constructs the SQL query string to insert a new deck preset.
+//
+// Returns:
+//   - string : The SQL query string to insert a new deck preset.
+func (wrapper *PresetDBWrapper) buildInsertQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(presetTableName)
+	sb.WriteString(" (")
+	sb.WriteString(presetWritableColumns())
+	sb.WriteString(") VALUES (")
+	for i := range strings.Split(presetWritableColumns(), ", ") {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("$%d", i+1))
+	}
+	sb.WriteString(") RETURNING ")
+	sb.WriteString(presetColumnID)
+
+	query := sb.String()
+	return query
+}
+
+// Retrieves a single deck preset from the database based on its unique ID.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - presetID int : The unique ID of the preset to be retrieved.
+//
+// Returns:
+//   - model.DeckPreset : The details of the retrieved preset.
+//   - error : utils.ErrPresetNotExist if there is no such preset, nil on success.
+func (wrapper *PresetDBWrapper) GetSingle(ctx context.Context, presetID int) (model.DeckPreset, error) {
+	defer observeQuery("preset", "GetSingle", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.DeckPreset{}, utils.ErrDatabaseNotExist
+	}
+
+	return getPreset(ctx, wrapper.db, presetID)
+}
+
+// Retrieves all deck presets from the database, sorted by name.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//
+// Returns:
+//   - []model.DeckPreset : All deck presets.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *PresetDBWrapper) GetAll(ctx context.Context) ([]model.DeckPreset, error) {
+	defer observeQuery("preset", "GetAll", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s ASC", presetSelectColumns(), presetTableName, presetColumnName)
+	slog.DebugContext(ctx, "Getting all deck presets", "query", query)
+
+	rows, err := wrapper.db.QueryContext(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting all deck presets", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	presets := []model.DeckPreset{}
+	for rows.Next() {
+		preset, err := scanPreset(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error scanning deck preset", "error", err)
+			return nil, err
+		}
+		presets = append(presets, preset)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return presets, nil
+}
+
+// Modifies every setting of an existing deck preset.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - preset model.DeckPreset : The preset with its ID and new settings.
+//
+// Returns:
+//   - error : utils.ErrPresetNotExist if there is no such preset, nil on success.
+func (wrapper *PresetDBWrapper) Modify(ctx context.Context, preset model.DeckPreset) error {
+	defer observeQuery("preset", "Modify", time.Now())
+
+	if len(preset.Name) > PresetColumnNameMaxLength {
+		slog.ErrorContext(ctx, "Deck preset name exceeds maximum length")
+		return utils.ErrMaxLengthExceeded
+	}
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildModifyQueryString()
+	slog.DebugContext(ctx, "Modifying deck preset", "query", query)
+
+	result, err := wrapper.db.ExecContext(ctx, query, append(presetArguments(preset), preset.ID)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error modifying deck preset", "error", err)
+
+		if isDuplicatePresetNameError(err) {
+			return utils.ErrDuplicateKeyViolation
+		}
+
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrPresetNotExist
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Modified deck preset %d", preset.ID))
+
+	return nil
+}
+
+// Modifies every setting of an existing deck preset, only if its scheduling
+// settings are still those of a preset read earlier. The preset is locked
+// while it is compared, so that a concurrent change is either seen by the
+// comparison or made after the modification.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - before model.DeckPreset : The preset as it was read.
+//   - preset model.DeckPreset : The preset with its ID and new settings.
+//
+// Returns:
+//   - error : utils.ErrPresetNotExist if there is no such preset, utils.ErrPresetChanged
+//     if its scheduling settings changed since it was read, nil on success.
+func (wrapper *PresetDBWrapper) ModifyIfUnchanged(ctx context.Context, before model.DeckPreset, preset model.DeckPreset) error {
+	defer observeQuery("preset", "ModifyIfUnchanged", time.Now())
+
+	if len(preset.Name) > PresetColumnNameMaxLength {
+		slog.ErrorContext(ctx, "Deck preset name exceeds maximum length")
+		return utils.ErrMaxLengthExceeded
+	}
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 FOR UPDATE", presetSelectColumns(), presetTableName, presetColumnID)
+	slog.DebugContext(ctx, "Locking deck preset", "query", query)
+
+	current, err := scanPreset(tx.QueryRowContext(ctx, query, preset.ID))
+	if err == sql.ErrNoRows {
+		return utils.ErrPresetNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error locking deck preset", "error", err)
+		return err
+	}
+
+	if !current.SameScheduling(before) {
+		slog.DebugContext(ctx, fmt.Sprintf("Deck preset %d changed since it was read", preset.ID))
+		return utils.ErrPresetChanged
+	}
+
+	query = wrapper.buildModifyQueryString()
+	slog.DebugContext(ctx, "Modifying deck preset", "query", query)
+
+	if _, err = tx.ExecContext(ctx, query, append(presetArguments(preset), preset.ID)...); err != nil {
+		slog.ErrorContext(ctx, "Error modifying deck preset", "error", err)
+
+		if isDuplicatePresetNameError(err) {
+			return utils.ErrDuplicateKeyViolation
+		}
+
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, "Error committing transaction", "error", err)
+		return err
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Modified deck preset %d", preset.ID))
+
+	return nil
+}
+
+// A helper function that constructs the SQL query string to modify a deck preset.
+//
+// Returns:
+//   - string : The SQL query string to modify a deck preset.
+func (wrapper *PresetDBWrapper) buildModifyQueryString() string {
+	columns := strings.Split(presetWritableColumns(), ", ")
+
+	var sb strings.Builder
+	sb.WriteString("UPDATE ")
+	sb.WriteString(presetTableName)
+	sb.WriteString(" SET ")
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("%s = $%d", column, i+1))
+	}
+	sb.WriteString(fmt.Sprintf(" WHERE %s = $%d", presetColumnID, len(columns)+1))
+
+	query := sb.String()
+	return query
+}
+
+// Deletes a deck preset. Decks linked to it fall back to the default settings.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - id int : The unique ID of the preset to be deleted.
+//
+// Returns:
+//   - error : utils.ErrPresetNotExist if there is no such preset, nil on success.
+func (wrapper *PresetDBWrapper) Delete(ctx context.Context, id int) error {
+	defer observeQuery("preset", "Delete", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = $1", presetTableName, presetColumnID)
+	slog.DebugContext(ctx, "Deleting deck preset", "query", query)
+
+	result, err := wrapper.db.ExecContext(ctx, query, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting deck preset", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return utils.ErrPresetNotExist
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Deleted deck preset %d", id))
+
+	return nil
+}
+
+// A helper function that retrieves a single preset. It is shared with the
+// deck wrapper, which loads the preset of a deck along with the deck.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - db *sql.DB : The database connection.
+//   - presetID int : The unique ID of the preset to be retrieved.
+//
+// Returns:
+//   - model.DeckPreset : The details of the retrieved preset.
+//   - error : utils.ErrPresetNotExist if there is no such preset, nil on success.
+func getPreset(ctx context.Context, db *sql.DB, presetID int) (model.DeckPreset, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1", presetSelectColumns(), presetTableName, presetColumnID)
+	slog.DebugContext(ctx, "Getting single deck preset", "query", query)
+
+	preset, err := scanPreset(db.QueryRowContext(ctx, query, presetID))
+	if err == sql.ErrNoRows {
+		slog.ErrorContext(ctx, fmt.Sprintf("No deck preset found with ID %d", presetID))
+		return model.DeckPreset{}, utils.ErrPresetNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error getting single deck preset", "error", err)
+		return model.DeckPreset{}, err
+	}
+
+	return preset, nil
+}
+
+// A helper function that returns the columns of a preset that can be written, in order.
+func presetWritableColumns() string {
+	return strings.Join([]string{
+		presetColumnName,
+		presetColumnNewCardsPerDay,
+		presetColumnMaxReviewsPerDay,
+		presetColumnLearningSteps,
+		presetColumnRelearningSteps,
+		presetColumnGraduatingInterval,
+		presetColumnEasyBonus,
+		presetColumnMaxInterval,
+		presetColumnScheduler,
+		presetColumnLeechThreshold,
+		presetColumnLeechAction,
+		presetColumnLeechFlag,
+		presetColumnDesiredRetention,
+		presetColumnFSRSWeights,
+		presetColumnEaseModifier,
+	}, ", ")
+}
+
+// A helper function that returns the columns that scanPreset expects, in order.
+func presetSelectColumns() string {
+	return presetColumnID + ", " + presetWritableColumns()
+}
+
+// A helper function that returns the query arguments matching presetWritableColumns.
+func presetArguments(preset model.DeckPreset) []any {
+	return []any{
+		preset.Name,
+		preset.NewCardsPerDay,
+		preset.MaxReviewsPerDay,
+		pq.Array(intsToInt64s(preset.LearningSteps)),
+		pq.Array(intsToInt64s(preset.RelearningSteps)),
+		preset.GraduatingInterval,
+		preset.EasyBonus,
+		preset.MaxInterval,
+		preset.Scheduler,
+		preset.LeechThreshold,
+		preset.LeechAction,
+		preset.LeechFlag,
+		preset.DesiredRetention,
+		pq.Array(append([]float64{}, preset.FSRSWeights...)),
+		preset.EaseModifier,
+	}
+}
+
+// A helper function that scans a single preset selected with presetSelectColumns.
+func scanPreset(scanner interface{ Scan(...any) error }) (model.DeckPreset, error) {
+	var preset model.DeckPreset
+	var learningSteps, relearningSteps pq.Int64Array
+	var fsrsWeights pq.Float64Array
+
+	err := scanner.Scan(
+		&preset.ID,
+		&preset.Name,
+		&preset.NewCardsPerDay,
+		&preset.MaxReviewsPerDay,
+		&learningSteps,
+		&relearningSteps,
+		&preset.GraduatingInterval,
+		&preset.EasyBonus,
+		&preset.MaxInterval,
+		&preset.Scheduler,
+		&preset.LeechThreshold,
+		&preset.LeechAction,
+		&preset.LeechFlag,
+		&preset.DesiredRetention,
+		&fsrsWeights,
+		&preset.EaseModifier,
+	)
+	if err != nil {
+		return model.DeckPreset{}, err
+	}
+
+	preset.LearningSteps = int64sToInts(learningSteps)
+	preset.RelearningSteps = int64sToInts(relearningSteps)
+	preset.FSRSWeights = []float64(fsrsWeights)
+	return preset, nil
+}
+
+// A helper function that checks whether an error returned by the database
+// is caused by a preset name that is already taken.
+func isDuplicatePresetNameError(err error) bool {
+	return err.Error() == fmt.Sprintf("pq: duplicate key value violates unique constraint \"%s_%s_key\"", presetTableName, presetColumnName)
+}
+
+// A helper function that converts a slice of ints for use with pq.Array.
+func intsToInt64s(values []int) []int64 {
+	converted := make([]int64, len(values))
+	for i, value := range values {
+		converted[i] = int64(value)
+	}
+	return converted
+}
+
+// A helper function that converts a scanned integer array back to ints.
+func int64sToInts(values []int64) []int {
+	converted := make([]int, len(values))
+	for i, value := range values {
+		converted[i] = int(value)
+	}
+	return converted
+}
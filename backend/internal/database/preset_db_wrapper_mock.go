@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"sort"
+)
+
+type PresetDBWrapperMock struct {
+	db    map[int]model.DeckPreset
+	index int
+}
+
+func NewPresetDBWrapperMock() *PresetDBWrapperMock {
+	return &PresetDBWrapperMock{}
+}
+
+func (wrapper *PresetDBWrapperMock) CreateTable() error {
+	if wrapper.db == nil {
+		wrapper.db = make(map[int]model.DeckPreset)
+		wrapper.index = 0
+	}
+
+	return nil
+}
+
+func (wrapper *PresetDBWrapperMock) Insert(ctx context.Context, preset model.DeckPreset) (int, error) {
+	if len(preset.Name) > PresetColumnNameMaxLength {
+		return -1, utils.ErrMaxLengthExceeded
+	}
+
+	if wrapper.db == nil {
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	for _, existing := range wrapper.db {
+		if existing.Name == preset.Name {
+			return -1, utils.ErrDuplicateKeyViolation
+		}
+	}
+
+	preset.ID = wrapper.index
+	wrapper.db[wrapper.index] = preset
+	wrapper.index++
+	return preset.ID, nil
+}
+
+func (wrapper *PresetDBWrapperMock) GetSingle(ctx context.Context, presetID int) (model.DeckPreset, error) {
+	if wrapper.db == nil {
+		return model.DeckPreset{}, utils.ErrDatabaseNotExist
+	}
+
+	preset, exists := wrapper.db[presetID]
+	if !exists {
+		return model.DeckPreset{}, utils.ErrPresetNotExist
+	}
+
+	return preset, nil
+}
+
+func (wrapper *PresetDBWrapperMock) GetAll(ctx context.Context) ([]model.DeckPreset, error) {
+	if wrapper.db == nil {
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	presets := make([]model.DeckPreset, 0, len(wrapper.db))
+	for _, preset := range wrapper.db {
+		presets = append(presets, preset)
+	}
+
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+
+	return presets, nil
+}
+
+func (wrapper *PresetDBWrapperMock) Modify(ctx context.Context, preset model.DeckPreset) error {
+	if len(preset.Name) > PresetColumnNameMaxLength {
+		return utils.ErrMaxLengthExceeded
+	}
+
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	if _, exists := wrapper.db[preset.ID]; !exists {
+		return utils.ErrPresetNotExist
+	}
+
+	wrapper.db[preset.ID] = preset
+	return nil
+}
+
+func (wrapper *PresetDBWrapperMock) ModifyIfUnchanged(ctx context.Context, before model.DeckPreset, preset model.DeckPreset) error {
+	if len(preset.Name) > PresetColumnNameMaxLength {
+		return utils.ErrMaxLengthExceeded
+	}
+
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	current, exists := wrapper.db[preset.ID]
+	if !exists {
+		return utils.ErrPresetNotExist
+	}
+	if !current.SameScheduling(before) {
+		return utils.ErrPresetChanged
+	}
+
+	wrapper.db[preset.ID] = preset
+	return nil
+}
+
+func (wrapper *PresetDBWrapperMock) Delete(ctx context.Context, id int) error {
+	if wrapper.db == nil {
+		return utils.ErrDatabaseNotExist
+	}
+
+	if _, exists := wrapper.db[id]; !exists {
+		return utils.ErrPresetNotExist
+	}
+
+	delete(wrapper.db, id)
+	return nil
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// QueryObserver is called after every query method of the database wrappers
+// with the wrapper, such as "deck", the method, such as "GetSingle", and how
+// long the method took, whether it failed or not.
+type QueryObserver func(wrapper string, method string, duration time.Duration)
+
+var queryObserver atomic.Pointer[QueryObserver]
+
+// SetQueryObserver sets the function observing the query methods of every
+// database wrapper, such as a metric of their durations. A nil observer
+// stops the observation.
+//
+// Parameters:
+//   - observer QueryObserver : The function called after every query method.
+func SetQueryObserver(observer QueryObserver) {
+	if observer == nil {
+		queryObserver.Store(nil)
+		return
+	}
+	queryObserver.Store(&observer)
+}
+
+// observeQuery passes the duration of a query method to the observer, if
+// any. It is deferred at the start of the method, so that start is taken
+// when the method is called.
+//
+// Parameters:
+//   - wrapper string : The wrapper of the method.
+//   - method string : The name of the method.
+//   - start time.Time : When the method was called.
+func observeQuery(wrapper string, method string, start time.Time) {
+	if observer := queryObserver.Load(); observer != nil {
+		(*observer)(wrapper, method, time.Since(start))
+	}
+}
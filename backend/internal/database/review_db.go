@@ -0,0 +1,634 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	reviewTableName          = "reviews"
+	reviewColumnID           = "id"
+	reviewColumnCardID       = "card_id"
+	reviewColumnDeckID       = "deck_id"
+	reviewColumnGrade        = "grade"
+	reviewColumnKind         = "kind"
+	reviewColumnState        = "state"
+	reviewColumnInterval     = "interval_days"
+	reviewColumnLastInterval = "last_interval_days"
+	reviewColumnEase         = "ease"
+	reviewColumnStability    = "stability"
+	reviewColumnDifficulty   = "difficulty"
+	reviewColumnDuration     = "duration_ms"
+	reviewColumnReviewTime   = "review_time"
+	studiedColumnNewCards    = "studied_new_cards"
+	studiedColumnReviews     = "studied_reviews"
+)
+
+// A helper function that constructs the SQL query string
+// to create the review log table.
+//
+// Returns:
+//   - string : The SQL query string to create the review log table.
+func buildCreateReviewTableQueryString() string {
+	var sb strings.Builder
+
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(reviewTableName)
+	sb.WriteString(" (")
+	sb.WriteString(fmt.Sprintf("%s SERIAL PRIMARY KEY, ", reviewColumnID))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL REFERENCES %s(%s) ON DELETE CASCADE, ", reviewColumnCardID, cardTableName, cardColumnID))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL, ", reviewColumnDeckID))
+	sb.WriteString(fmt.Sprintf("%s SMALLINT NOT NULL CHECK (%s BETWEEN %d AND %d), ", reviewColumnGrade, reviewColumnGrade, model.GradeAgain, model.GradeEasy))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(16) NOT NULL, ", reviewColumnKind))
+	sb.WriteString(fmt.Sprintf("%s SMALLINT NOT NULL, ", reviewColumnState))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL, ", reviewColumnInterval))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL, ", reviewColumnLastInterval))
+	sb.WriteString(fmt.Sprintf("%s REAL NOT NULL, ", reviewColumnEase))
+	sb.WriteString(fmt.Sprintf("%s REAL NOT NULL, ", reviewColumnStability))
+	sb.WriteString(fmt.Sprintf("%s REAL NOT NULL, ", reviewColumnDifficulty))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL CHECK (%s >= 0), ", reviewColumnDuration, reviewColumnDuration))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP NOT NULL DEFAULT %s", reviewColumnReviewTime, utcDefault("")))
+	sb.WriteString(")")
+
+	query := sb.String()
+	return query
+}
+
+// Saves what an answer changed on a card and adds the answer to the review
+// log, in a single transaction. Review times are stored in UTC so that
+// statistics can be split into days in any timezone. Only the scheduling
+// state of the card is replaced: the leech tags, suspension and flag are added
+// to those the card has when saved, and a card answered in a filtered deck
+// goes back to the home deck it has then, so that edits made since the card
+// was read are kept. The answer is refused if the card was reviewed since.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - answer model.CardAnswer : What the answer changed on the card.
+//   - review model.Review : The review log entry recording the answer.
+//
+// Returns:
+//   - int : The unique ID of the review log entry.
+//   - error : utils.ErrRecordNotExist if the card doesn't exist,
+//     utils.ErrCardChanged if it was reviewed since it was read, nil on success.
+func (wrapper *CardDBWrapper) RecordReview(ctx context.Context, answer model.CardAnswer, review model.Review) (int, error) {
+	defer observeQuery("card", "RecordReview", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error starting transaction: %s", err))
+		return -1, err
+	}
+	defer tx.Rollback()
+
+	query := wrapper.buildSaveAnswerQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Saving answer: %s", query))
+
+	card := answer.Card
+	args := append(schedulingArgs(card, answer.PreviousReviewTime),
+		pq.Array(model.NormalizeTags(answer.AddTags)),
+		answer.Suspend,
+		answer.Flag,
+		answer.ReturnHome,
+	)
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error saving answer: %s", err))
+		return -1, err
+	}
+	if err = checkSchedulingUpdated(ctx, tx, result, card.ID); err != nil {
+		return -1, err
+	}
+
+	query = wrapper.buildInsertReviewQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Inserting review: %s", query))
+
+	err = tx.QueryRowContext(ctx, query,
+		review.CardID,
+		review.DeckID,
+		review.Grade,
+		review.Kind,
+		review.State,
+		review.Interval,
+		review.LastInterval,
+		review.Ease,
+		review.Stability,
+		review.Difficulty,
+		review.Duration,
+		review.ReviewTime.UTC(),
+	).Scan(&review.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error inserting review: %s", err))
+		return -1, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error committing review: %s", err))
+		return -1, err
+	}
+
+	return review.ID, nil
+}
+
+// Reverts an answer: the card gets back the scheduling state it had before
+// the answer and the review log entry is removed, in a single transaction.
+// The rest of the card, such as a leech tag or the deck it went back to, is
+// kept. Only the latest answer of a card can be undone.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - card model.Card : The card as it was before the answer.
+//   - reviewID int : The unique ID of the review log entry of the answer.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the card or the review log entry doesn't exist,
+//     utils.ErrCardChanged if the card was answered again since, nil on success.
+func (wrapper *CardDBWrapper) UndoReview(ctx context.Context, card model.Card, reviewID int) error {
+	defer observeQuery("card", "UndoReview", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	tx, err := wrapper.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error starting transaction: %s", err))
+		return err
+	}
+	defer tx.Rollback()
+
+	// The card is locked first, so that an answer saved meanwhile either
+	// waits for the undo or is seen by the next statements
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 AND %s FOR UPDATE",
+		cardColumnLastReviewTime, cardTableName, cardColumnID, cardNotTrashedCondition(""))
+	slog.DebugContext(ctx, fmt.Sprintf("Locking card: %s", query))
+
+	var lastReviewTime *time.Time
+	err = tx.QueryRowContext(ctx, query, card.ID).Scan(&lastReviewTime)
+	if err == sql.ErrNoRows {
+		return utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error locking card: %s", err))
+		return err
+	}
+
+	query = wrapper.buildUndoReviewQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Deleting review: %s", query))
+
+	result, err := tx.ExecContext(ctx, query, reviewID, card.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error deleting review: %s", err))
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		var exists bool
+		query = fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1 AND %s = $2)", reviewTableName, reviewColumnID, reviewColumnCardID)
+		if err = tx.QueryRowContext(ctx, query, reviewID, card.ID).Scan(&exists); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Error checking review: %s", err))
+			return err
+		}
+		if exists {
+			return utils.ErrCardChanged
+		}
+		return utils.ErrRecordNotExist
+	}
+
+	query = wrapper.buildUpdateSchedulingQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Restoring card scheduling: %s", query))
+
+	result, err = tx.ExecContext(ctx, query, schedulingArgs(card, lastReviewTime)...)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error restoring card scheduling: %s", err))
+		return err
+	}
+	if err = checkSchedulingUpdated(ctx, tx, result, card.ID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error committing undone review: %s", err))
+		return err
+	}
+
+	return nil
+}
+
+// A helper function that constructs the SQL query string to remove an entry
+// from the review log, unless the card was answered again after it.
+//
+// Returns:
+//   - string : The SQL query string, with the review ID bound to $1 and the card ID to $2.
+func (wrapper *CardDBWrapper) buildUndoReviewQueryString() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE %s = $1 AND %s = $2 AND NOT EXISTS (SELECT 1 FROM %s WHERE %s = $2 AND %s > $1)",
+		reviewTableName, reviewColumnID, reviewColumnCardID, reviewTableName, reviewColumnCardID, reviewColumnID)
+}
+
+// schedulingColumns are the columns holding the scheduling state of a card,
+// the only ones replaced when the card is answered or rescheduled.
+var schedulingColumns = []string{
+	cardColumnState,
+	cardColumnStep,
+	cardColumnInterval,
+	cardColumnEase,
+	cardColumnStability,
+	cardColumnDifficulty,
+	cardColumnLapses,
+	cardColumnRetentionLevel,
+	cardColumnNextReviewTime,
+	cardColumnLastReviewTime,
+}
+
+// A helper function that returns the arguments of a query built with
+// writeGuardedSchedulingUpdate, in order.
+//
+// Parameters:
+//   - card model.Card : The card with its new scheduling state.
+//   - previousReviewTime *time.Time : The last review time of the card when it was read.
+//
+// Returns:
+//   - []any : The card ID, the previous review time and the scheduling state.
+func schedulingArgs(card model.Card, previousReviewTime *time.Time) []any {
+	return []any{
+		card.ID,
+		utcPointer(previousReviewTime),
+		card.State,
+		card.Step,
+		card.Interval,
+		card.Ease,
+		card.Stability,
+		card.Difficulty,
+		card.Lapses,
+		card.RetentionLevel,
+		card.NextReviewTime.UTC(),
+		utcPointer(card.LastReviewTime),
+	}
+}
+
+// A helper function that writes an UPDATE of the scheduling state of a card,
+// only applied if the card wasn't reviewed since it was read. The card ID is
+// bound to $1, its last review time when read to $2 and the scheduling
+// columns from $3 on; extra assignments are added after them.
+//
+// Parameters:
+//   - sb *strings.Builder : The builder the query is written to.
+//   - extra []string : Additional assignments, such as "flag = $13".
+func writeGuardedSchedulingUpdate(sb *strings.Builder, extra ...string) {
+	sb.WriteString("UPDATE ")
+	sb.WriteString(cardTableName)
+	sb.WriteString(" SET ")
+	for i, column := range schedulingColumns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("%s = $%d", column, i+3))
+	}
+	for _, assignment := range extra {
+		sb.WriteString(", ")
+		sb.WriteString(assignment)
+	}
+	sb.WriteString(fmt.Sprintf(" WHERE %s = $1 AND %s AND %s IS NOT DISTINCT FROM $2",
+		cardColumnID, cardNotTrashedCondition(""), cardColumnLastReviewTime))
+}
+
+// A helper function that constructs the SQL query string to save the
+// scheduling state of a card, if it wasn't reviewed since it was read.
+//
+// Returns:
+//   - string : The SQL query string, with the arguments of schedulingArgs.
+func (wrapper *CardDBWrapper) buildUpdateSchedulingQueryString() string {
+	var sb strings.Builder
+	writeGuardedSchedulingUpdate(&sb)
+
+	query := sb.String()
+	return query
+}
+
+// A helper function that constructs the SQL query string to save an answer.
+// Besides the scheduling state, the tags are added to those of the card, the
+// card is suspended and flagged only if the answer does so, and it goes back
+// to its home deck, all relative to the card as it is when saved.
+//
+// Returns:
+//   - string : The SQL query string, with the arguments of schedulingArgs
+//     followed by the added tags, the suspension, the flag and the return home.
+func (wrapper *CardDBWrapper) buildSaveAnswerQueryString() string {
+	next := len(schedulingColumns) + 3
+
+	var sb strings.Builder
+	writeGuardedSchedulingUpdate(&sb,
+		fmt.Sprintf("%s = %s || ARRAY(SELECT t FROM UNNEST($%d::TEXT[]) t WHERE NOT t = ANY(%s))",
+			cardColumnTags, cardColumnTags, next, cardColumnTags),
+		fmt.Sprintf("%s = %s OR $%d", cardColumnSuspended, cardColumnSuspended, next+1),
+		fmt.Sprintf("%s = COALESCE($%d, %s)", cardColumnFlag, next+2, cardColumnFlag),
+		fmt.Sprintf("%s = CASE WHEN $%d THEN COALESCE(%s, %s) ELSE %s END",
+			cardColumnDeckID, next+3, cardColumnOriginalDeckID, cardColumnDeckID, cardColumnDeckID),
+		fmt.Sprintf("%s = CASE WHEN $%d THEN NULL ELSE %s END",
+			cardColumnOriginalDeckID, next+3, cardColumnOriginalDeckID),
+	)
+
+	query := sb.String()
+	return query
+}
+
+// A helper function that tells why a guarded update of the scheduling state
+// of a card changed nothing.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - tx *sql.Tx : The transaction of the update.
+//   - result sql.Result : The result of the update.
+//   - cardID int : The unique ID of the updated card.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the card doesn't exist, utils.ErrCardChanged
+//     if it was reviewed since it was read, nil if it was updated.
+func checkSchedulingUpdated(ctx context.Context, tx *sql.Tx, result sql.Result, cardID int) error {
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1 AND %s)", cardTableName, cardColumnID, cardNotTrashedCondition(""))
+	if err = tx.QueryRowContext(ctx, query, cardID).Scan(&exists); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error checking card: %s", err))
+		return err
+	}
+	if !exists {
+		return utils.ErrRecordNotExist
+	}
+
+	return utils.ErrCardChanged
+}
+
+// A helper function that constructs the SQL query string
+// to add an entry to the review log.
+//
+// Returns:
+//   - string : The SQL query string.
+func (wrapper *CardDBWrapper) buildInsertReviewQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(reviewTableName)
+	sb.WriteString(" (")
+	sb.WriteString(strings.Join([]string{
+		reviewColumnCardID,
+		reviewColumnDeckID,
+		reviewColumnGrade,
+		reviewColumnKind,
+		reviewColumnState,
+		reviewColumnInterval,
+		reviewColumnLastInterval,
+		reviewColumnEase,
+		reviewColumnStability,
+		reviewColumnDifficulty,
+		reviewColumnDuration,
+		reviewColumnReviewTime,
+	}, ", "))
+	sb.WriteString(") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING ")
+	sb.WriteString(reviewColumnID)
+
+	query := sb.String()
+	return query
+}
+
+// Retrieves the review log entries of the cards in the given decks made
+// between two times, oldest first. The reviews of trashed cards are included,
+// those of the cards of trashed decks only when their decks are given.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the decks the cards are in, nil for all decks.
+//   - from time.Time : The start of the range, included.
+//   - to time.Time : The end of the range, excluded.
+//
+// Returns:
+//   - []model.Review : The review log entries.
+//   - error : An error if the retrieval fails, nil otherwise.
+func (wrapper *CardDBWrapper) GetReviews(ctx context.Context, deckIDs []int, from time.Time, to time.Time) ([]model.Review, error) {
+	defer observeQuery("card", "GetReviews", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildGetReviewsQueryString()
+	slog.DebugContext(ctx, fmt.Sprintf("Getting reviews: %s", query))
+
+	var decks any
+	if deckIDs != nil {
+		decks = pq.Array(deckIDs)
+	}
+
+	rows, err := wrapper.db.QueryContext(ctx, query, from.UTC(), to.UTC(), decks)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error getting reviews: %s", err))
+		return nil, err
+	}
+
+	return scanReviews(ctx, rows)
+}
+
+// A helper function that constructs the SQL query string to retrieve the
+// review log entries of a range, optionally limited to some decks.
+//
+// Returns:
+//   - string : The SQL query string.
+func (wrapper *CardDBWrapper) buildGetReviewsQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(reviewSelectColumns("r"))
+	sb.WriteString(fmt.Sprintf(" FROM %s r JOIN %s c ON c.%s = r.%s", reviewTableName, cardTableName, cardColumnID, reviewColumnCardID))
+	sb.WriteString(fmt.Sprintf(" WHERE r.%s >= $1 AND r.%s < $2", reviewColumnReviewTime, reviewColumnReviewTime))
+	sb.WriteString(fmt.Sprintf(" AND ($3::INT[] IS NULL AND %s OR c.%s = ANY($3))", cardInLiveDeckCondition("c"), cardColumnDeckID))
+	sb.WriteString(fmt.Sprintf(" ORDER BY r.%s ASC, r.%s ASC", reviewColumnReviewTime, reviewColumnID))
+
+	query := sb.String()
+	return query
+}
+
+// Counts the cards of the given decks studied since a time, which are taken
+// from the daily limits of the decks. The reviews are counted in the deck
+// they were made for, the home deck of cards answered in a filtered deck.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the decks.
+//   - since time.Time : The start of the day.
+//
+// Returns:
+//   - model.StudyCounts : The number of new cards and reviews studied since then.
+//   - error : An error if the count fails, nil otherwise.
+func (wrapper *CardDBWrapper) CountStudied(ctx context.Context, deckIDs []int, since time.Time) (model.StudyCounts, error) {
+	defer observeQuery("card", "CountStudied", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.StudyCounts{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT COALESCE(SUM(s.%s), 0), COALESCE(SUM(s.%s), 0) FROM (%s) s WHERE s.%s = ANY($1)",
+		studiedColumnNewCards, studiedColumnReviews, buildStudiedQueryString("$2"), reviewColumnDeckID)
+	slog.DebugContext(ctx, fmt.Sprintf("Counting studied cards: %s", query))
+
+	var counts model.StudyCounts
+	err := wrapper.db.QueryRowContext(ctx, query, pq.Array(deckIDs), since.UTC()).Scan(&counts.NewCards, &counts.Reviews)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error counting studied cards: %s", err))
+		return model.StudyCounts{}, err
+	}
+
+	return counts, nil
+}
+
+// A helper function that constructs the SQL query string counting, for every
+// deck, the new cards answered for the first time and the answers to cards in
+// review since a time. The counts are named studiedColumnNewCards and
+// studiedColumnReviews.
+//
+// Parameters:
+//   - since string : The placeholder of the start time, such as "$2".
+//
+// Returns:
+//   - string : The SQL query string.
+func buildStudiedQueryString(since string) string {
+	return fmt.Sprintf("SELECT %s, COUNT(*) FILTER (WHERE %s = '%s' AND %s = %d) AS %s, COUNT(*) FILTER (WHERE %s = '%s') AS %s FROM %s WHERE %s >= %s GROUP BY %s",
+		reviewColumnDeckID, reviewColumnKind, model.ReviewKindLearn, reviewColumnState, model.CardStateNew, studiedColumnNewCards,
+		reviewColumnKind, model.ReviewKindReview, studiedColumnReviews, reviewTableName, reviewColumnReviewTime, since, reviewColumnDeckID)
+}
+
+// Counts the cards in the given decks at every retention level. Trashed cards
+// and the cards of trashed decks aren't counted.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - deckIDs []int : The unique IDs of the decks, nil for all decks.
+//
+// Returns:
+//   - []model.RetentionLevelCount : The card counts, by ascending retention level.
+//   - error : An error if the count fails, nil otherwise.
+func (wrapper *CardDBWrapper) CountByRetentionLevel(ctx context.Context, deckIDs []int) ([]model.RetentionLevelCount, error) {
+	defer observeQuery("card", "CountByRetentionLevel", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return nil, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s, COUNT(*) FROM %s WHERE %s AND ($1::INT[] IS NULL OR %s = ANY($1)) GROUP BY %s ORDER BY %s ASC",
+		cardColumnRetentionLevel, cardTableName, cardNotTrashedCondition(""), cardColumnDeckID, cardColumnRetentionLevel, cardColumnRetentionLevel)
+	slog.DebugContext(ctx, fmt.Sprintf("Counting cards by retention level: %s", query))
+
+	var decks any
+	if deckIDs != nil {
+		decks = pq.Array(deckIDs)
+	}
+
+	rows, err := wrapper.db.QueryContext(ctx, query, decks)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error counting cards by retention level: %s", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []model.RetentionLevelCount{}
+	for rows.Next() {
+		var count model.RetentionLevelCount
+		if err = rows.Scan(&count.RetentionLevel, &count.Cards); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Error scanning retention level count: %s", err))
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// A helper function that returns the columns of a review log entry that
+// scanReviews expects, in order.
+//
+// Parameters:
+//   - alias string : The alias of the review log table in the query.
+//
+// Returns:
+//   - string : The qualified columns, separated by commas.
+func reviewSelectColumns(alias string) string {
+	columns := []string{
+		reviewColumnID,
+		reviewColumnCardID,
+		reviewColumnDeckID,
+		reviewColumnGrade,
+		reviewColumnKind,
+		reviewColumnState,
+		reviewColumnInterval,
+		reviewColumnLastInterval,
+		reviewColumnEase,
+		reviewColumnStability,
+		reviewColumnDifficulty,
+		reviewColumnDuration,
+		reviewColumnReviewTime,
+	}
+	return alias + "." + strings.Join(columns, ", "+alias+".")
+}
+
+// A helper function that scans and closes all rows of a review log query.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - rows *sql.Rows : The rows returned by a query selecting reviewSelectColumns.
+//
+// Returns:
+//   - []model.Review : The scanned review log entries.
+//   - error : An error if any scan fails, nil otherwise.
+func scanReviews(ctx context.Context, rows *sql.Rows) ([]model.Review, error) {
+	defer rows.Close()
+
+	reviews := []model.Review{}
+	for rows.Next() {
+		var review model.Review
+		err := rows.Scan(
+			&review.ID,
+			&review.CardID,
+			&review.DeckID,
+			&review.Grade,
+			&review.Kind,
+			&review.State,
+			&review.Interval,
+			&review.LastInterval,
+			&review.Ease,
+			&review.Stability,
+			&review.Difficulty,
+			&review.Duration,
+			&review.ReviewTime,
+		)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Error scanning review: %s", err))
+			return nil, err
+		}
+		review.ReviewTime = review.ReviewTime.UTC()
+		reviews = append(reviews, review)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Error iterating reviews: %s", err))
+		return nil, err
+	}
+
+	return reviews, nil
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReview(t *testing.T) {
+	save := fakeStatement{prefix: "UPDATE cards SET state = $3", affected: 1}
+	insert := fakeStatement{prefix: "INSERT INTO reviews", columns: []string{"id"}, rows: [][]driver.Value{{int64(7)}}}
+	checkCard := func(exists bool) fakeStatement {
+		return fakeStatement{prefix: "SELECT EXISTS (SELECT 1 FROM cards", columns: []string{"exists"}, rows: [][]driver.Value{{exists}}}
+	}
+
+	testCases := []struct {
+		name        string
+		script      []fakeStatement
+		expectedID  int
+		expectedErr error
+		expectedLog []string
+	}{
+		{
+			name:        "Answer saved",
+			script:      []fakeStatement{save, insert},
+			expectedID:  7,
+			expectedLog: []string{"BEGIN", save.prefix, insert.prefix, "COMMIT"},
+		},
+		{
+			name:        "Card reviewed since it was read",
+			script:      []fakeStatement{{prefix: save.prefix}, checkCard(true)},
+			expectedID:  -1,
+			expectedErr: utils.ErrCardChanged,
+			expectedLog: []string{"BEGIN", save.prefix, checkCard(true).prefix, "ROLLBACK"},
+		},
+		{
+			name:        "Card deleted",
+			script:      []fakeStatement{{prefix: save.prefix}, checkCard(false)},
+			expectedID:  -1,
+			expectedErr: utils.ErrRecordNotExist,
+			expectedLog: []string{"BEGIN", save.prefix, checkCard(false).prefix, "ROLLBACK"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, fake := newFakeDB(tc.script...)
+			read := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+			card := model.NewCard(0, "Test content", "")
+			card.ID = 3
+			card.LastReviewTime = &read
+
+			answered := card
+			now := read.AddDate(0, 0, 1)
+			answered.LastReviewTime = &now
+			id, err := NewCardDBWrapper(db).RecordReview(context.Background(), model.NewCardAnswer(card, answered), model.Review{CardID: 3, ReviewTime: now})
+
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedID, id)
+			assert.Equal(t, tc.expectedLog, fake.Log(), "Expected the answer to be saved with its review log entry or not at all")
+			assert.Equal(t, read, fake.Args()[0][1], "Expected the answer to be refused if the card was reviewed since it was read")
+		})
+	}
+}
+
+func TestUndoReview(t *testing.T) {
+	lock := fakeStatement{prefix: "SELECT last_review_time FROM cards", columns: []string{"last_review_time"}, rows: [][]driver.Value{{nil}}}
+	remove := fakeStatement{prefix: "DELETE FROM reviews", affected: 1}
+	restore := fakeStatement{prefix: "UPDATE cards SET state = $3", affected: 1}
+	checkReview := func(exists bool) fakeStatement {
+		return fakeStatement{prefix: "SELECT EXISTS (SELECT 1 FROM reviews", columns: []string{"exists"}, rows: [][]driver.Value{{exists}}}
+	}
+
+	testCases := []struct {
+		name        string
+		script      []fakeStatement
+		expectedErr error
+		expectedLog []string
+	}{
+		{
+			name:        "Answer undone",
+			script:      []fakeStatement{lock, remove, restore},
+			expectedLog: []string{"BEGIN", lock.prefix, remove.prefix, restore.prefix, "COMMIT"},
+		},
+		{
+			name:        "Card answered again since",
+			script:      []fakeStatement{lock, {prefix: remove.prefix}, checkReview(true)},
+			expectedErr: utils.ErrCardChanged,
+			expectedLog: []string{"BEGIN", lock.prefix, remove.prefix, checkReview(true).prefix, "ROLLBACK"},
+		},
+		{
+			name:        "Review not found",
+			script:      []fakeStatement{lock, {prefix: remove.prefix}, checkReview(false)},
+			expectedErr: utils.ErrRecordNotExist,
+			expectedLog: []string{"BEGIN", lock.prefix, remove.prefix, checkReview(false).prefix, "ROLLBACK"},
+		},
+		{
+			name:        "Card not found",
+			script:      []fakeStatement{{prefix: lock.prefix, columns: lock.columns}},
+			expectedErr: utils.ErrRecordNotExist,
+			expectedLog: []string{"BEGIN", lock.prefix, "ROLLBACK"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, fake := newFakeDB(tc.script...)
+			card := model.NewCard(0, "Test content", "")
+			card.ID = 3
+
+			err := NewCardDBWrapper(db).UndoReview(context.Background(), card, 7)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedLog, fake.Log(), "Expected the card to be locked before its answer is undone")
+		})
+	}
+}
+
+func TestSaveAnswerQuery(t *testing.T) {
+	wrapper := NewCardDBWrapper(nil)
+
+	query := wrapper.buildSaveAnswerQueryString()
+	assert.True(t, strings.HasSuffix(query, " WHERE id = $1 AND deleted_at IS NULL AND deck_id IN (SELECT id FROM decks WHERE deleted_at IS NULL) AND last_review_time IS NOT DISTINCT FROM $2"),
+		"Expected the answer to be refused if the card was reviewed since it was read")
+	assert.Contains(t, query, "state = $3, ")
+	assert.Contains(t, query, "last_review_time = $12, ")
+	assert.Contains(t, query, "tags = tags || ARRAY(SELECT t FROM UNNEST($13::TEXT[]) t WHERE NOT t = ANY(tags))")
+	assert.Contains(t, query, "suspended = suspended OR $14")
+	assert.Contains(t, query, "flag = COALESCE($15, flag)")
+	assert.Contains(t, query, "deck_id = CASE WHEN $16 THEN COALESCE(original_deck_id, deck_id) ELSE deck_id END")
+	for _, column := range []string{"content", "source", "buried_until"} {
+		assert.NotContains(t, query, column+" =", "Expected only the scheduling state to be replaced")
+	}
+}
+
+func TestStudiedQuery(t *testing.T) {
+	assert.Equal(t, "SELECT deck_id, COUNT(*) FILTER (WHERE kind = 'learn' AND state = 0) AS studied_new_cards, "+
+		"COUNT(*) FILTER (WHERE kind = 'review') AS studied_reviews FROM reviews WHERE review_time >= $2 GROUP BY deck_id",
+		buildStudiedQueryString("$2"))
+}
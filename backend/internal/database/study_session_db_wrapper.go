@@ -0,0 +1,307 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	studySessionTableName          = "study_sessions"
+	studySessionColumnID           = "id"
+	studySessionColumnDeckID       = "deck_id"
+	studySessionColumnMode         = "mode"
+	studySessionColumnShuffle      = "shuffle"
+	studySessionColumnRepeatFailed = "repeat_failed"
+	studySessionColumnQueue        = "queue"
+	studySessionColumnAnswered     = "answered"
+	studySessionColumnFailed       = "failed"
+	studySessionColumnHistory      = "history"
+	studySessionColumnStartTime    = "start_time"
+	studySessionColumnEndTime      = "end_time"
+	studySessionColumnVersion      = "version"
+)
+
+// An interface that defines the methods for interacting with the study session database.
+// This interface abstracts the database operations for the study sessions in progress,
+// allowing for easier testing and mocking. The answers themselves are recorded
+// through CardDBWrapperInterface.
+type StudySessionDBWrapperInterface interface {
+	CreateTable() error
+	Insert(ctx context.Context, session model.StudySession) (int, error)
+	Get(ctx context.Context, id int) (model.StudySession, error)
+	Update(ctx context.Context, session model.StudySession) (int, error)
+}
+
+// A struct that implements the StudySessionDBWrapperInterface.
+//
+// This is the concrete implementation and should be used for actual
+// database operations.
+type StudySessionDBWrapper struct {
+	db *sql.DB
+}
+
+// Creates and returns a new instance of StudySessionDBWrapper.
+//
+// Parameters:
+//   - db *sql.DB : The database connection.
+//
+// Returns:
+//   - *StudySessionDBWrapper
+func NewStudySessionDBWrapper(db *sql.DB) *StudySessionDBWrapper {
+	return &StudySessionDBWrapper{db: db}
+}
+
+// Creates a new table in the database if it doesn't already exist.
+// This table references the decks table, so it must be created after it.
+//
+// Returns:
+//   - error : An error if the table creation fails, nil otherwise.
+func (wrapper *StudySessionDBWrapper) CreateTable() error {
+	defer observeQuery("study_session", "CreateTable", time.Now())
+
+	if wrapper.db == nil {
+		slog.Error("Database connection is nil")
+		return utils.ErrDatabaseNotExist
+	}
+
+	query := wrapper.buildCreateTableQueryString()
+	slog.Debug("Creating study sessions table", "query", query)
+
+	_, err := wrapper.db.Exec(query)
+
+	if err != nil {
+		slog.Error("Error creating study sessions table", "error", err)
+		return err
+	}
+
+	for _, query := range wrapper.buildMigrationQueryStrings() {
+		slog.Debug("Migrating study sessions table", "query", query)
+
+		if _, err = wrapper.db.Exec(query); err != nil {
+			slog.Error("Error migrating study sessions table", "error", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A helper function that constructs the SQL query string
+// to create the study sessions table.
+//
+// Returns:
+//   - string : The SQL query string to create the study sessions table.
+func (wrapper *StudySessionDBWrapper) buildCreateTableQueryString() string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(studySessionTableName)
+	sb.WriteString(" (")
+	sb.WriteString(fmt.Sprintf("%s SERIAL PRIMARY KEY, ", studySessionColumnID))
+	sb.WriteString(fmt.Sprintf("%s INT REFERENCES %s(%s) ON DELETE CASCADE, ", studySessionColumnDeckID, deckTableName, deckColumnID))
+	sb.WriteString(fmt.Sprintf("%s VARCHAR(16) NOT NULL CHECK (%s IN ('%s', '%s')), ", studySessionColumnMode, studySessionColumnMode,
+		model.StudyModeNormal, model.StudyModeCram))
+	sb.WriteString(fmt.Sprintf("%s BOOLEAN NOT NULL DEFAULT FALSE, ", studySessionColumnShuffle))
+	sb.WriteString(fmt.Sprintf("%s BOOLEAN NOT NULL DEFAULT FALSE, ", studySessionColumnRepeatFailed))
+	sb.WriteString(fmt.Sprintf("%s INT[] NOT NULL DEFAULT '{}', ", studySessionColumnQueue))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL DEFAULT 0, ", studySessionColumnAnswered))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL DEFAULT 0, ", studySessionColumnFailed))
+	sb.WriteString(fmt.Sprintf("%s JSONB NOT NULL DEFAULT '[]', ", studySessionColumnHistory))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP NOT NULL, ", studySessionColumnStartTime))
+	sb.WriteString(fmt.Sprintf("%s TIMESTAMP, ", studySessionColumnEndTime))
+	sb.WriteString(fmt.Sprintf("%s INT NOT NULL DEFAULT 0", studySessionColumnVersion))
+	sb.WriteString(")")
+
+	query := sb.String()
+	return query
+}
+
+// A helper function that constructs the SQL query strings adding
+// the columns that tables created by older versions lack.
+//
+// Returns:
+//   - []string : The SQL query strings, in the order they must be run.
+func (wrapper *StudySessionDBWrapper) buildMigrationQueryStrings() []string {
+	return []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s JSONB NOT NULL DEFAULT '[]'", studySessionTableName, studySessionColumnHistory),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TIMESTAMP", studySessionTableName, studySessionColumnEndTime),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s INT NOT NULL DEFAULT 0", studySessionTableName, studySessionColumnVersion),
+	}
+}
+
+// Inserts a new study session into the database.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - session model.StudySession : The session to insert, with its queue filled.
+//
+// Returns:
+//   - int : The ID of the inserted session.
+//   - error : utils.ErrInvalidStudySession if the session is invalid, utils.ErrDeckNotExist
+//     if its deck doesn't exist, nil on success.
+func (wrapper *StudySessionDBWrapper) Insert(ctx context.Context, session model.StudySession) (int, error) {
+	defer observeQuery("study_session", "Insert", time.Now())
+
+	if err := session.Validate(); err != nil {
+		return -1, err
+	}
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING %s",
+		studySessionTableName, studySessionColumnDeckID, studySessionColumnMode, studySessionColumnShuffle, studySessionColumnRepeatFailed,
+		studySessionColumnQueue, studySessionColumnAnswered, studySessionColumnFailed, studySessionColumnStartTime, studySessionColumnID)
+	slog.DebugContext(ctx, "Inserting study session", "query", query)
+
+	var id int
+	err := wrapper.db.QueryRowContext(ctx, query, session.DeckID, session.Mode, session.Shuffle, session.RepeatFailed,
+		pq.Array(session.Queue), session.Answered, session.Failed, session.StartTime.UTC()).Scan(&id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error inserting study session", "error", err)
+
+		if strings.Contains(err.Error(), fmt.Sprintf("\"%s_%s_fkey\"", studySessionTableName, studySessionColumnDeckID)) {
+			return -1, utils.ErrDeckNotExist
+		}
+
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// Retrieves a study session.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - id int : The unique ID of the session.
+//
+// Returns:
+//   - model.StudySession : The session.
+//   - error : utils.ErrRecordNotExist if the session doesn't exist, nil on success.
+func (wrapper *StudySessionDBWrapper) Get(ctx context.Context, id int) (model.StudySession, error) {
+	defer observeQuery("study_session", "Get", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return model.StudySession{}, utils.ErrDatabaseNotExist
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s FROM %s WHERE %s = $1",
+		studySessionColumnID, studySessionColumnDeckID, studySessionColumnMode, studySessionColumnShuffle, studySessionColumnRepeatFailed,
+		studySessionColumnQueue, studySessionColumnAnswered, studySessionColumnFailed, studySessionColumnHistory,
+		studySessionColumnStartTime, studySessionColumnEndTime, studySessionColumnVersion, studySessionTableName, studySessionColumnID)
+	slog.DebugContext(ctx, "Getting study session", "query", query)
+
+	var session model.StudySession
+	var deckID sql.NullInt64
+	var queue pq.Int64Array
+	var history []byte
+	var endTime sql.NullTime
+	err := wrapper.db.QueryRowContext(ctx, query, id).Scan(&session.ID, &deckID, &session.Mode, &session.Shuffle, &session.RepeatFailed,
+		&queue, &session.Answered, &session.Failed, &history, &session.StartTime, &endTime, &session.Version)
+	if err == sql.ErrNoRows {
+		return model.StudySession{}, utils.ErrRecordNotExist
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error getting study session", "error", err)
+		return model.StudySession{}, err
+	}
+
+	if deckID.Valid {
+		id := int(deckID.Int64)
+		session.DeckID = &id
+	}
+	if endTime.Valid {
+		session.EndTime = &endTime.Time
+	}
+	session.Queue = make([]int, len(queue))
+	for i, cardID := range queue {
+		session.Queue[i] = int(cardID)
+	}
+
+	if err = json.Unmarshal(history, &session.History); err != nil {
+		slog.ErrorContext(ctx, "Error decoding study session history", "error", err)
+		return model.StudySession{}, err
+	}
+
+	return session, nil
+}
+
+// Saves the progress of a study session, that is its queue, its counts,
+// its answers and when it ended. The session is only saved if it is still
+// at the version it was read at, so that concurrent requests can't both
+// save their progress over each other.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries, whose values are added to the log lines.
+//   - session model.StudySession : The session to save.
+//
+// Returns:
+//   - int : The new version of the session.
+//   - error : utils.ErrRecordNotExist if the session doesn't exist, utils.ErrStudySessionChanged
+//     if it was saved since it was read, nil on success.
+func (wrapper *StudySessionDBWrapper) Update(ctx context.Context, session model.StudySession) (int, error) {
+	defer observeQuery("study_session", "Update", time.Now())
+
+	if wrapper.db == nil {
+		slog.ErrorContext(ctx, "Database connection is nil")
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	history, err := json.Marshal(session.History)
+	if err != nil {
+		return -1, err
+	}
+
+	var endTime *time.Time
+	if session.EndTime != nil {
+		utc := session.EndTime.UTC()
+		endTime = &utc
+	}
+
+	query := wrapper.buildUpdateQueryString()
+	slog.DebugContext(ctx, "Updating study session", "query", query)
+
+	var version int
+	err = wrapper.db.QueryRowContext(ctx, query, session.ID, pq.Array(session.Queue), session.Answered, session.Failed, history, endTime,
+		session.Version).Scan(&version)
+	if err == sql.ErrNoRows {
+		var exists bool
+		query = fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1)", studySessionTableName, studySessionColumnID)
+		if err = wrapper.db.QueryRowContext(ctx, query, session.ID).Scan(&exists); err != nil {
+			slog.ErrorContext(ctx, "Error checking study session", "error", err)
+			return -1, err
+		}
+		if !exists {
+			return -1, utils.ErrRecordNotExist
+		}
+		return -1, utils.ErrStudySessionChanged
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error updating study session", "error", err)
+		return -1, err
+	}
+
+	return version, nil
+}
+
+// A helper function that constructs the SQL query string to save
+// the progress of a study session still at the version it was read at.
+//
+// Returns:
+//   - string : The SQL query string, returning the new version.
+func (wrapper *StudySessionDBWrapper) buildUpdateQueryString() string {
+	return fmt.Sprintf("UPDATE %s SET %s = $2, %s = $3, %s = $4, %s = $5, %s = $6, %s = %s + 1 WHERE %s = $1 AND %s = $7 RETURNING %s",
+		studySessionTableName, studySessionColumnQueue, studySessionColumnAnswered, studySessionColumnFailed,
+		studySessionColumnHistory, studySessionColumnEndTime, studySessionColumnVersion, studySessionColumnVersion,
+		studySessionColumnID, studySessionColumnVersion, studySessionColumnVersion)
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"slices"
+)
+
+type StudySessionDBWrapperMock struct {
+	db     map[int]model.StudySession
+	nextID int
+}
+
+func NewStudySessionDBWrapperMock() *StudySessionDBWrapperMock {
+	return &StudySessionDBWrapperMock{}
+}
+
+func (wrapper *StudySessionDBWrapperMock) CreateTable() error {
+	if wrapper.db == nil {
+		wrapper.db = make(map[int]model.StudySession)
+	}
+
+	return nil
+}
+
+func (wrapper *StudySessionDBWrapperMock) Insert(ctx context.Context, session model.StudySession) (int, error) {
+	if err := session.Validate(); err != nil {
+		return -1, err
+	}
+
+	if wrapper.db == nil {
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	session.ID = wrapper.nextID
+	session.Queue = slices.Clone(session.Queue)
+	wrapper.db[session.ID] = session
+	wrapper.nextID++
+	return session.ID, nil
+}
+
+func (wrapper *StudySessionDBWrapperMock) Get(ctx context.Context, id int) (model.StudySession, error) {
+	if wrapper.db == nil {
+		return model.StudySession{}, utils.ErrDatabaseNotExist
+	}
+
+	session, exists := wrapper.db[id]
+	if !exists {
+		return model.StudySession{}, utils.ErrRecordNotExist
+	}
+
+	session.Queue = slices.Clone(session.Queue)
+	session.History = slices.Clone(session.History)
+	return session, nil
+}
+
+func (wrapper *StudySessionDBWrapperMock) Update(ctx context.Context, session model.StudySession) (int, error) {
+	if wrapper.db == nil {
+		return -1, utils.ErrDatabaseNotExist
+	}
+
+	stored, exists := wrapper.db[session.ID]
+	if !exists {
+		return -1, utils.ErrRecordNotExist
+	}
+	if stored.Version != session.Version {
+		return -1, utils.ErrStudySessionChanged
+	}
+
+	stored.Queue = slices.Clone(session.Queue)
+	stored.Answered = session.Answered
+	stored.Failed = session.Failed
+	stored.History = slices.Clone(session.History)
+	stored.EndTime = session.EndTime
+	stored.Version++
+	wrapper.db[session.ID] = stored
+	return stored.Version, nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The current time in UTC, the time zone of every timestamp of the database.
+// The TIMESTAMP columns keep no time zone, so NOW() would be stored in the
+// time zone of the session instead.
+const sqlNowUTC = "(NOW() AT TIME ZONE 'UTC')"
+
+// A helper function that returns the query string setting the default of a
+// TIMESTAMP column to the current time in UTC, for tables created before the
+// defaults were in UTC.
+//
+// Parameters:
+//   - table string : The name of the table.
+//   - column string : The name of the column.
+//   - offset string : An interval added to the current time, such as "10 minutes", empty for none.
+//
+// Returns:
+//   - string : The SQL query string.
+func buildSetUTCDefaultQueryString(table string, column string, offset string) string {
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", table, column, utcDefault(offset))
+}
+
+// A helper function that returns the query string converting the times of a
+// table written before they were stored in UTC. Those times were written in
+// the time zone of the database session, which is assumed not to have changed
+// since. The conversion runs while the marker column still has its old
+// default and sets the UTC default of the marker in the same block, so that
+// it runs once. The times of a row are converted by a single UPDATE, so that
+// the checks comparing them keep holding.
+//
+// Parameters:
+//   - table string : The name of the table.
+//   - marker string : A column of the table whose default is the current time, without an interval.
+//   - columns []string : The TIMESTAMP columns to convert.
+//
+// Returns:
+//   - string : The SQL query string.
+func buildConvertToUTCQueryString(table string, marker string, columns []string) string {
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = fmt.Sprintf("%s = %s AT TIME ZONE current_setting('TimeZone') AT TIME ZONE 'UTC'", column, column)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("DO $$ BEGIN IF EXISTS (SELECT 1 FROM information_schema.columns")
+	sb.WriteString(fmt.Sprintf(" WHERE table_schema = current_schema() AND table_name = '%s' AND column_name = '%s'", table, marker))
+	sb.WriteString(" AND column_default NOT LIKE '%''UTC''%')")
+	sb.WriteString(fmt.Sprintf(" THEN UPDATE %s SET %s; %s; END IF; END $$",
+		table, strings.Join(assignments, ", "), buildSetUTCDefaultQueryString(table, marker, "")))
+
+	query := sb.String()
+	return query
+}
+
+// A helper function that returns the default of a TIMESTAMP column holding
+// the current time in UTC, plus an optional interval.
+//
+// Parameters:
+//   - offset string : An interval added to the current time, such as "10 minutes", empty for none.
+//
+// Returns:
+//   - string : The SQL expression.
+func utcDefault(offset string) string {
+	if offset == "" {
+		return sqlNowUTC
+	}
+	return fmt.Sprintf("%s + INTERVAL '%s'", sqlNowUTC, offset)
+}
+
+// A helper function that converts an optional time to UTC before it is bound
+// to a TIMESTAMP column, which drops the offset of the time it is given.
+//
+// Parameters:
+//   - t *time.Time : The time, nil for NULL.
+//
+// Returns:
+//   - *time.Time : The time in UTC, nil if t is nil.
+func utcPointer(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"flash-learn/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUTCDefaultQuery(t *testing.T) {
+	assert.Equal(t, "ALTER TABLE cards ALTER COLUMN next_review_time SET DEFAULT (NOW() AT TIME ZONE 'UTC') + INTERVAL '10 minutes'",
+		buildSetUTCDefaultQueryString(cardTableName, cardColumnNextReviewTime, cardNewReviewDelay))
+	assert.Equal(t, "ALTER TABLE decks ALTER COLUMN creation_date SET DEFAULT (NOW() AT TIME ZONE 'UTC')",
+		buildSetUTCDefaultQueryString(deckTableName, deckColumnCreationDate, ""))
+}
+
+func TestSchedulingArgsInUTC(t *testing.T) {
+	local := time.FixedZone("UTC+2", 2*60*60)
+	previous := time.Date(2024, 3, 1, 8, 0, 0, 0, local)
+	reviewed := time.Date(2024, 3, 2, 1, 30, 0, 0, local)
+
+	card := model.Card{ID: 1, NextReviewTime: reviewed.AddDate(0, 0, 3), LastReviewTime: &reviewed}
+	args := schedulingArgs(card, &previous)
+
+	assert.Equal(t, time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC), *args[1].(*time.Time))
+	assert.Equal(t, time.Date(2024, 3, 4, 23, 30, 0, 0, time.UTC), args[10])
+	assert.Equal(t, time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC), *args[11].(*time.Time))
+	assert.Equal(t, local, card.LastReviewTime.Location(), "Expected the card not to be changed")
+
+	args = schedulingArgs(model.Card{ID: 1}, nil)
+	assert.Nil(t, args[1].(*time.Time))
+	assert.Nil(t, args[11].(*time.Time))
+}
+
+func TestConvertToUTCQuery(t *testing.T) {
+	query := buildConvertToUTCQueryString(deckTableName, deckColumnCreationDate, []string{deckColumnCreationDate, deckColumnDeletedAt})
+
+	assert.Equal(t, "DO $$ BEGIN IF EXISTS (SELECT 1 FROM information_schema.columns"+
+		" WHERE table_schema = current_schema() AND table_name = 'decks' AND column_name = 'creation_date'"+
+		" AND column_default NOT LIKE '%''UTC''%')"+
+		" THEN UPDATE decks SET creation_date = creation_date AT TIME ZONE current_setting('TimeZone') AT TIME ZONE 'UTC',"+
+		" deleted_at = deleted_at AT TIME ZONE current_setting('TimeZone') AT TIME ZONE 'UTC';"+
+		" ALTER TABLE decks ALTER COLUMN creation_date SET DEFAULT (NOW() AT TIME ZONE 'UTC'); END IF; END $$", query,
+		"Expected the times to be converted once, along with the default of the marker")
+}
@@ -0,0 +1,608 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWorkers is the number of jobs a runner runs at once.
+	DefaultWorkers = 2
+	// DefaultLease is how long a job stays leased to a worker without a heartbeat.
+	DefaultLease = time.Minute
+	// DefaultPollInterval is how often an idle worker looks for due jobs,
+	// such as retries, when no job is enqueued by its own runner.
+	DefaultPollInterval = 5 * time.Second
+	// DefaultRetryDelay is the delay before the first retry of a failed job,
+	// which doubles on every further attempt.
+	DefaultRetryDelay = 5 * time.Second
+	// DefaultMaxRetryDelay caps the delay between two attempts of a job.
+	DefaultMaxRetryDelay = 10 * time.Minute
+)
+
+// errLeaseLost is the cause of a run stopped because its lease expired
+// and another worker may have leased the job since.
+var errLeaseLost = errors.New("job lease lost")
+
+// permanentError is an error of a handler that retrying the job can't fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Marks an error returned by a handler as permanent, so that the job fails
+// right away instead of being retried, such as for an invalid payload.
+//
+// Parameters:
+//   - err error : The error of the handler.
+//
+// Returns:
+//   - error : The error, failing the job for good.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Handler runs a job of a given kind. It should stop and return the error of
+// the context once the context is cancelled, which happens when the job is
+// cancelled or the runner stops. A job whose handler returns an error is
+// retried with a backoff until it runs out of attempts, unless the error is
+// marked with Permanent, so a handler should save checkpoints with
+// Run.Progress to resume where it left off.
+type Handler func(ctx context.Context, run *Run) error
+
+// A pool of workers that run the jobs saved in the job database. Jobs
+// survive restarts: a job left running by a crash is leased again once
+// its lease expires, and a job interrupted by a shutdown is queued again.
+// Several runners, in one process or in several, can share the same database.
+type Runner struct {
+	job_db        database.JobDBWrapperInterface
+	handlers      map[string]Handler
+	recurring     map[string]time.Duration
+	workers       int
+	owner         string
+	lease         time.Duration
+	pollInterval  time.Duration
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+	wake          chan struct{}
+
+	mu      sync.Mutex
+	running map[int]context.CancelCauseFunc
+}
+
+// Creates and returns a new instance of Runner.
+//
+// Parameters:
+//   - job_db database.JobDBWrapperInterface : The database the jobs are saved in.
+//   - workers int : The number of jobs run at once.
+//
+// Returns:
+//   - *Runner
+func NewRunner(job_db database.JobDBWrapperInterface, workers int) *Runner {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return &Runner{
+		job_db:        job_db,
+		handlers:      make(map[string]Handler),
+		recurring:     make(map[string]time.Duration),
+		workers:       max(workers, 1),
+		owner:         fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		lease:         DefaultLease,
+		pollInterval:  DefaultPollInterval,
+		retryDelay:    DefaultRetryDelay,
+		maxRetryDelay: DefaultMaxRetryDelay,
+		wake:          make(chan struct{}, 1),
+		running:       make(map[int]context.CancelCauseFunc),
+	}
+}
+
+// WithLease sets how long a job stays leased without a heartbeat.
+// Heartbeats are sent three times per lease.
+//
+// Parameters:
+//   - lease time.Duration : The duration of a lease.
+//
+// Returns:
+//   - *Runner : The same runner, to allow chaining.
+func (r *Runner) WithLease(lease time.Duration) *Runner {
+	r.lease = lease
+	return r
+}
+
+// WithPollInterval sets how often an idle worker looks for due jobs.
+//
+// Parameters:
+//   - interval time.Duration : The poll interval.
+//
+// Returns:
+//   - *Runner : The same runner, to allow chaining.
+func (r *Runner) WithPollInterval(interval time.Duration) *Runner {
+	r.pollInterval = interval
+	return r
+}
+
+// WithRetryDelay sets the backoff of failed jobs.
+//
+// Parameters:
+//   - delay time.Duration : The delay before the first retry, doubled on every further attempt.
+//   - maxDelay time.Duration : The longest delay between two attempts.
+//
+// Returns:
+//   - *Runner : The same runner, to allow chaining.
+func (r *Runner) WithRetryDelay(delay time.Duration, maxDelay time.Duration) *Runner {
+	r.retryDelay = delay
+	r.maxRetryDelay = maxDelay
+	return r
+}
+
+// Registers the handler of a kind of jobs. Only the kinds with a handler
+// are leased by the runner, and handlers must be registered before Run.
+//
+// Parameters:
+//   - kind string : The kind of jobs.
+//   - handler Handler : The handler running them.
+func (r *Runner) Register(kind string, handler Handler) {
+	r.handlers[kind] = handler
+}
+
+// Registers the handler of a recurring kind of jobs, of which a single job is
+// pending at any time. The job is enqueued when the runner starts unless one
+// is already pending, and once it finishes, whether it succeeded or failed,
+// it is queued again to run after the interval. A cancelled job isn't run
+// again until a runner starts.
+//
+// Parameters:
+//   - kind string : The kind of jobs.
+//   - handler Handler : The handler running them.
+//   - interval time.Duration : The time between the end of a run and the start of the next one.
+func (r *Runner) RegisterRecurring(kind string, handler Handler, interval time.Duration) {
+	r.handlers[kind] = handler
+	r.recurring[kind] = interval
+}
+
+// Saves a job, which is run as soon as a worker is free.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries.
+//   - kind string : The kind of the job.
+//   - payload any : The input of the job, encoded as JSON.
+//
+// Returns:
+//   - model.Job : The queued job.
+//   - error : An error if the job can't be encoded or saved, nil otherwise.
+func (r *Runner) Enqueue(ctx context.Context, kind string, payload any) (model.Job, error) {
+	job, err := model.NewJob(kind, payload, time.Now())
+	if err != nil {
+		return model.Job{}, err
+	}
+
+	id, err := r.job_db.Insert(ctx, job)
+	if err != nil {
+		return model.Job{}, err
+	}
+	job.ID = id
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	slog.InfoContext(ctx, "Enqueued job", "job", job.ID, "kind", job.Kind)
+	return job, nil
+}
+
+// Returns a job along with its progress.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries.
+//   - id int : The unique ID of the job.
+//
+// Returns:
+//   - model.Job : The job.
+//   - error : utils.ErrRecordNotExist if the job doesn't exist, nil on success.
+func (r *Runner) Get(ctx context.Context, id int) (model.Job, error) {
+	return r.job_db.Get(ctx, id)
+}
+
+// Marks the result of a job that succeeded as applied, so that it is applied
+// only once.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries.
+//   - id int : The unique ID of the job.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the job doesn't exist, utils.ErrJobApplied
+//     if it was already applied or hasn't succeeded, nil on success.
+func (r *Runner) MarkApplied(ctx context.Context, id int) error {
+	return r.job_db.MarkApplied(ctx, id, time.Now())
+}
+
+// Cancels a job. A queued job never runs, and a running job is stopped by
+// its worker: right away if this runner runs it, on its next heartbeat otherwise.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries.
+//   - id int : The unique ID of the job.
+//
+// Returns:
+//   - model.Job : The job after the request.
+//   - error : utils.ErrRecordNotExist if the job doesn't exist, utils.ErrJobFinished
+//     if it already finished, nil on success.
+func (r *Runner) Cancel(ctx context.Context, id int) (model.Job, error) {
+	job, err := r.job_db.Cancel(ctx, id, time.Now())
+	if err != nil {
+		return model.Job{}, err
+	}
+
+	r.mu.Lock()
+	if cancel, running := r.running[id]; running {
+		cancel(utils.ErrJobCancelled)
+	}
+	r.mu.Unlock()
+
+	slog.InfoContext(ctx, "Cancelled job", "job", job.ID, "status", job.Status)
+	return job, nil
+}
+
+// Runs the workers until the context is cancelled, and then waits for them
+// to stop. The jobs running at that time are queued again. The recurring
+// jobs are enqueued first, unless they are already pending.
+//
+// Parameters:
+//   - ctx context.Context : Cancelling the context stops the workers.
+func (r *Runner) Run(ctx context.Context) {
+	r.enqueueRecurring(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+	slog.Debug("Stopped job runner")
+}
+
+// A helper function that enqueues a job of every recurring kind that has no
+// pending job, to run right away.
+func (r *Runner) enqueueRecurring(ctx context.Context) {
+	kinds := make([]string, 0, len(r.recurring))
+	for kind := range r.recurring {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	for _, kind := range kinds {
+		job, err := model.NewJob(kind, struct{}{}, time.Now())
+		if err != nil {
+			slog.ErrorContext(ctx, "Error creating recurring job", "kind", kind, "error", err)
+			continue
+		}
+
+		if job, err = r.job_db.InsertUnique(ctx, job); err != nil {
+			slog.ErrorContext(ctx, "Error enqueuing recurring job", "kind", kind, "error", err)
+			continue
+		}
+		slog.InfoContext(ctx, "Enqueued recurring job", "job", job.ID, "kind", kind, "run at", job.RunAt)
+	}
+}
+
+// Leases and runs a single job, if one is due.
+//
+// Parameters:
+//   - ctx context.Context : Cancelling the context stops the job and queues it again.
+//
+// Returns:
+//   - bool : Whether a job was run.
+func (r *Runner) RunOnce(ctx context.Context) bool {
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	now := time.Now()
+	job, err := r.job_db.Lease(ctx, kinds, r.owner, now, now.Add(r.lease))
+	if err == utils.ErrNoJob {
+		return false
+	} else if err != nil {
+		slog.Error("Error leasing job", "error", err)
+		return false
+	}
+
+	r.run(ctx, job)
+	return true
+}
+
+// A helper function that runs jobs one after the other until the context is
+// cancelled, waiting for an enqueued job or the next poll when none is due.
+func (r *Runner) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		if r.RunOnce(ctx) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-r.wake:
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// A helper function that runs a leased job and releases it with the outcome
+// of the run.
+func (r *Runner) run(ctx context.Context, job model.Job) {
+	logger := slog.With("job", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+
+	if job.CancelRequested {
+		r.release(ctx, r.finish(job, model.JobStatusCancelled, job.LastError), logger)
+		return
+	}
+	if job.Attempts > job.MaxAttempts {
+		// The last attempt was cut short by a crash
+		if interval, recurring := r.recurring[job.Kind]; recurring {
+			r.release(ctx, r.requeue(job, interval, job.LastError), logger)
+		} else {
+			r.release(ctx, r.finish(job, model.JobStatusFailed, job.LastError), logger)
+		}
+		return
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	r.mu.Lock()
+	r.running[job.ID] = cancel
+	r.mu.Unlock()
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		r.heartbeat(jobCtx, job.ID, cancel)
+	}()
+
+	run := &Run{job_db: r.job_db, job: job}
+	logger.Info("Running job")
+	err := r.call(jobCtx, run)
+	cause := context.Cause(jobCtx)
+
+	cancel(nil)
+	<-heartbeatDone
+	r.mu.Lock()
+	delete(r.running, job.ID)
+	r.mu.Unlock()
+
+	job = run.job
+	if err != nil {
+		job.Result = nil
+	}
+	interval, recurring := r.recurring[job.Kind]
+	switch {
+	case recurring && err == nil:
+		logger.Info("Job succeeded", "progress", job.Progress, "total", job.Total, "next run", interval)
+		r.release(ctx, r.requeue(job, interval, ""), logger)
+	case err == nil:
+		logger.Info("Job succeeded", "progress", job.Progress, "total", job.Total)
+		r.release(ctx, r.finish(job, model.JobStatusSucceeded, ""), logger)
+	case errors.Is(cause, errLeaseLost):
+		logger.Warn("Job lease lost", "error", err)
+	case errors.Is(cause, utils.ErrJobCancelled):
+		logger.Info("Job cancelled", "progress", job.Progress)
+		r.release(ctx, r.finish(job, model.JobStatusCancelled, ""), logger)
+	case ctx.Err() != nil:
+		// Stopping the runner isn't the fault of the job, so the attempt isn't counted
+		logger.Info("Job interrupted", "progress", job.Progress)
+		job.Status = model.JobStatusQueued
+		job.Attempts--
+		job.RunAt = time.Now()
+		job.UpdatedAt = job.RunAt
+		r.release(ctx, job, logger)
+	case recurring && (job.Attempts >= job.MaxAttempts || errors.As(err, new(permanentError))):
+		logger.Error("Job failed", "error", err, "next run", interval)
+		r.release(ctx, r.requeue(job, interval, err.Error()), logger)
+	case job.Attempts >= job.MaxAttempts || errors.As(err, new(permanentError)):
+		logger.Error("Job failed", "error", err)
+		r.release(ctx, r.finish(job, model.JobStatusFailed, err.Error()), logger)
+	default:
+		delay := r.backoff(job.Attempts)
+		logger.Warn("Job failed, retrying", "error", err, "delay", delay)
+		job.Status = model.JobStatusQueued
+		job.LastError = err.Error()
+		job.UpdatedAt = time.Now()
+		job.RunAt = job.UpdatedAt.Add(delay)
+		r.release(ctx, job, logger)
+	}
+}
+
+// A helper function that calls the handler of a job, turning a panic into an error.
+func (r *Runner) call(ctx context.Context, run *Run) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+
+	handler, exists := r.handlers[run.job.Kind]
+	if !exists {
+		return fmt.Errorf("no handler for job kind %q", run.job.Kind)
+	}
+	return handler(ctx, run)
+}
+
+// A helper function that extends the lease of a running job until its context
+// is cancelled. The run is cancelled if the job is cancelled from another
+// process or if the lease was lost.
+func (r *Runner) heartbeat(ctx context.Context, id int, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(r.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cancelRequested, err := r.job_db.Heartbeat(ctx, id, r.owner, now.Add(r.lease))
+			if err == utils.ErrRecordNotExist {
+				cancel(errLeaseLost)
+				return
+			} else if err != nil {
+				slog.Error("Error extending job lease", "job", id, "error", err)
+				continue
+			}
+
+			if cancelRequested {
+				cancel(utils.ErrJobCancelled)
+				return
+			}
+		}
+	}
+}
+
+// A helper function that sets the final status of a job.
+func (r *Runner) finish(job model.Job, status string, lastError string) model.Job {
+	now := time.Now()
+	job.Status = status
+	job.LastError = lastError
+	job.UpdatedAt = now
+	job.FinishedAt = &now
+	return job
+}
+
+// A helper function that queues a recurring job again, to run after the
+// interval with all of its attempts.
+func (r *Runner) requeue(job model.Job, interval time.Duration, lastError string) model.Job {
+	now := time.Now()
+	job.Status = model.JobStatusQueued
+	job.Attempts = 0
+	job.Progress = 0
+	job.Checkpoint = nil
+	job.LastError = lastError
+	job.UpdatedAt = now
+	job.RunAt = now.Add(interval)
+	return job
+}
+
+// A helper function that saves the outcome of a run and gives up the lease.
+// The outcome is saved even if the runner is stopping.
+func (r *Runner) release(ctx context.Context, job model.Job, logger *slog.Logger) {
+	if err := r.job_db.Release(context.WithoutCancel(ctx), job, r.owner); err != nil {
+		logger.Error("Error releasing job", "error", err)
+	}
+}
+
+// A helper function that returns the delay before the next attempt of a job,
+// which doubles with every failed attempt.
+func (r *Runner) backoff(attempts int) time.Duration {
+	delay := r.retryDelay
+	for i := 1; i < attempts && delay < r.maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, r.maxRetryDelay)
+}
+
+// Run is a job being run, handed to its handler.
+type Run struct {
+	job_db database.JobDBWrapperInterface
+	job    model.Job
+}
+
+// Returns the job being run.
+func (run *Run) Job() model.Job {
+	return run.job
+}
+
+// Decodes the payload of the job.
+//
+// Parameters:
+//   - v any : A pointer to the value to decode into.
+//
+// Returns:
+//   - error : An error if the payload can't be decoded, nil otherwise.
+func (run *Run) Payload(v any) error {
+	return json.Unmarshal(run.job.Payload, v)
+}
+
+// Decodes the checkpoint saved by a previous attempt of the job.
+//
+// Parameters:
+//   - v any : A pointer to the value to decode into.
+//
+// Returns:
+//   - bool : Whether a checkpoint was saved.
+//   - error : An error if the checkpoint can't be decoded, nil otherwise.
+func (run *Run) Checkpoint(v any) (bool, error) {
+	if len(run.job.Checkpoint) == 0 {
+		return false, nil
+	}
+	return true, json.Unmarshal(run.job.Checkpoint, v)
+}
+
+// Saves the progress of the job along with a checkpoint, from which the job
+// resumes if it is interrupted.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries.
+//   - progress int : The units of work done.
+//   - total int : The units of work overall.
+//   - checkpoint any : The state of the job, encoded as JSON.
+//
+// Returns:
+//   - error : utils.ErrRecordNotExist if the lease of the job was lost, nil on success.
+func (run *Run) Progress(ctx context.Context, progress int, total int, checkpoint any) error {
+	encoded, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	job := run.job
+	job.Progress = progress
+	job.Total = total
+	job.Checkpoint = encoded
+	job.UpdatedAt = time.Now()
+	if err = run.job_db.SaveProgress(ctx, job); err != nil {
+		return err
+	}
+
+	run.job = job
+	return nil
+}
+
+// Sets the result of the job, saved once the job succeeds.
+//
+// Parameters:
+//   - result any : The output of the job, encoded as JSON.
+//
+// Returns:
+//   - error : An error if the result can't be encoded, nil otherwise.
+func (run *Run) SetResult(result any) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	run.job.Result = encoded
+	return nil
+}
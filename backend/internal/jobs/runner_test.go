@@ -0,0 +1,287 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"flash-learn/internal/database"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRunner returns a runner over an empty job database, retrying failed
+// jobs right away.
+func newTestRunner() (*Runner, *database.JobDBWrapperMock) {
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	return NewRunner(job_db, 2).WithRetryDelay(0, 0), job_db
+}
+
+func TestRunnerRunsJob(t *testing.T) {
+	runner, _ := newTestRunner()
+
+	var payload model.ReschedulePayload
+	runner.Register("test", func(ctx context.Context, run *Run) error {
+		if err := run.Payload(&payload); err != nil {
+			return err
+		}
+		if err := run.SetResult(map[string]int{"sum": 3}); err != nil {
+			return err
+		}
+		return run.Progress(context.Background(), 3, 3, map[string]int{"done": 3})
+	})
+
+	job, err := runner.Enqueue(context.Background(), "test", model.ReschedulePayload{DeckIDs: []int{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, model.JobStatusQueued, job.Status)
+
+	assert.True(t, runner.RunOnce(context.Background()))
+	assert.Equal(t, []int{1, 2}, payload.DeckIDs)
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusSucceeded, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, 3, job.Progress)
+	assert.JSONEq(t, `{"sum": 3}`, string(job.Result))
+	assert.NotNil(t, job.FinishedAt)
+	assert.Empty(t, job.LeaseOwner)
+
+	assert.False(t, runner.RunOnce(context.Background()))
+}
+
+func TestRunnerLeasesRegisteredKindsOnly(t *testing.T) {
+	runner, _ := newTestRunner()
+	runner.Register("test", func(ctx context.Context, run *Run) error { return nil })
+
+	job, _ := runner.Enqueue(context.Background(), "other", struct{}{})
+	assert.False(t, runner.RunOnce(context.Background()))
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusQueued, job.Status)
+}
+
+func TestRunnerRetriesFailedJob(t *testing.T) {
+	runner, _ := newTestRunner()
+
+	calls := 0
+	runner.Register("test", func(ctx context.Context, run *Run) error {
+		calls++
+		if calls == 2 {
+			panic("broken handler")
+		}
+		return errors.New("temporary failure")
+	})
+	job, _ := runner.Enqueue(context.Background(), "test", struct{}{})
+
+	assert.True(t, runner.RunOnce(context.Background()))
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusQueued, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "temporary failure", job.LastError)
+
+	assert.True(t, runner.RunOnce(context.Background()))
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusQueued, job.Status)
+	assert.Contains(t, job.LastError, "broken handler", "Expected a panic to fail the attempt")
+
+	for runner.RunOnce(context.Background()) {
+	}
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusFailed, job.Status)
+	assert.Equal(t, model.JobDefaultMaxAttempts, job.Attempts)
+	assert.Equal(t, model.JobDefaultMaxAttempts, calls)
+	assert.NotNil(t, job.FinishedAt)
+}
+
+func TestRunnerFailsPermanentErrors(t *testing.T) {
+	runner, _ := newTestRunner()
+
+	calls := 0
+	runner.Register("test", func(ctx context.Context, run *Run) error {
+		calls++
+		run.SetResult("partial")
+		return Permanent(utils.ErrNotEnoughReviews)
+	})
+	job, _ := runner.Enqueue(context.Background(), "test", struct{}{})
+
+	assert.True(t, runner.RunOnce(context.Background()))
+	assert.False(t, runner.RunOnce(context.Background()), "Expected a permanent error not to be retried")
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusFailed, job.Status)
+	assert.Equal(t, utils.ErrNotEnoughReviews.Error(), job.LastError)
+	assert.Empty(t, job.Result, "Expected a failed job not to keep a result")
+	assert.Equal(t, 1, calls)
+}
+
+func TestRunnerBacksOffRetries(t *testing.T) {
+	runner, _ := newTestRunner()
+	runner.WithRetryDelay(time.Hour, 3*time.Hour)
+	runner.Register("test", func(ctx context.Context, run *Run) error { return errors.New("failure") })
+	job, _ := runner.Enqueue(context.Background(), "test", struct{}{})
+
+	before := time.Now()
+	assert.True(t, runner.RunOnce(context.Background()))
+	assert.False(t, runner.RunOnce(context.Background()), "Expected the retry not to be due yet")
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.WithinDuration(t, before.Add(time.Hour), job.RunAt, time.Minute)
+
+	assert.Equal(t, time.Hour, runner.backoff(1))
+	assert.Equal(t, 2*time.Hour, runner.backoff(2))
+	assert.Equal(t, 3*time.Hour, runner.backoff(3))
+	assert.Equal(t, 3*time.Hour, runner.backoff(10))
+}
+
+func TestRunnerCancelsJob(t *testing.T) {
+	runner, _ := newTestRunner()
+
+	started := make(chan struct{})
+	runner.Register("test", func(ctx context.Context, run *Run) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	t.Run("Queued", func(t *testing.T) {
+		job, _ := runner.Enqueue(context.Background(), "test", struct{}{})
+		job, err := runner.Cancel(context.Background(), job.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, model.JobStatusCancelled, job.Status)
+		assert.False(t, runner.RunOnce(context.Background()), "Expected a cancelled job not to run")
+
+		_, err = runner.Cancel(context.Background(), job.ID)
+		assert.Equal(t, utils.ErrJobFinished, err)
+	})
+
+	t.Run("Running", func(t *testing.T) {
+		job, _ := runner.Enqueue(context.Background(), "test", struct{}{})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			runner.RunOnce(context.Background())
+		}()
+		<-started
+
+		cancelled, err := runner.Cancel(context.Background(), job.ID)
+		assert.Nil(t, err)
+		assert.True(t, cancelled.CancelRequested)
+		<-done
+
+		job, _ = runner.Get(context.Background(), job.ID)
+		assert.Equal(t, model.JobStatusCancelled, job.Status)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := runner.Cancel(context.Background(), 99)
+		assert.Equal(t, utils.ErrRecordNotExist, err)
+	})
+}
+
+func TestRunnerTakesOverExpiredLease(t *testing.T) {
+	runner, job_db := newTestRunner()
+	runner.Register("test", func(ctx context.Context, run *Run) error {
+		var checkpoint map[string]int
+		if resumed, err := run.Checkpoint(&checkpoint); err != nil || !resumed {
+			return errors.New("expected a checkpoint")
+		}
+		return run.Progress(context.Background(), checkpoint["done"]+1, 2, checkpoint)
+	})
+	job, _ := runner.Enqueue(context.Background(), "test", struct{}{})
+
+	// A worker of another process that crashed after saving a checkpoint
+	now := time.Now()
+	crashed, _ := job_db.Lease(context.Background(), []string{"test"}, "crashed", now, now.Add(-time.Minute))
+	crashed.Progress = 1
+	crashed.Checkpoint = []byte(`{"done":1}`)
+	assert.Nil(t, job_db.SaveProgress(context.Background(), crashed))
+
+	assert.True(t, runner.RunOnce(context.Background()))
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusSucceeded, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, 2, job.Progress)
+
+	_, err := job_db.Heartbeat(context.Background(), job.ID, "crashed", now.Add(time.Minute))
+	assert.Equal(t, utils.ErrRecordNotExist, err, "Expected the crashed worker to have lost its lease")
+}
+
+func TestRunnerRun(t *testing.T) {
+	runner, _ := newTestRunner()
+	runner.WithPollInterval(time.Millisecond)
+
+	var runs atomic.Int32
+	runner.Register("test", func(ctx context.Context, run *Run) error {
+		runs.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		runner.Run(ctx)
+	}()
+
+	ids := []int{}
+	for i := 0; i < 4; i++ {
+		job, _ := runner.Enqueue(context.Background(), "test", struct{}{})
+		ids = append(ids, job.ID)
+	}
+
+	assert.Eventually(t, func() bool { return runs.Load() == 4 }, time.Second, time.Millisecond)
+	cancel()
+	<-stopped
+
+	for _, id := range ids {
+		job, _ := runner.Get(context.Background(), id)
+		assert.Equal(t, model.JobStatusSucceeded, job.Status)
+	}
+}
+
+func TestRunnerRequeuesRecurringJob(t *testing.T) {
+	runner, job_db := newTestRunner()
+
+	calls := 0
+	runner.RegisterRecurring("recurring", func(ctx context.Context, run *Run) error {
+		calls++
+		if calls == 2 {
+			return Permanent(errors.New("broken"))
+		}
+		return nil
+	}, time.Hour)
+
+	runner.enqueueRecurring(context.Background())
+	runner.enqueueRecurring(context.Background())
+	counts, _ := job_db.CountUnfinished(context.Background())
+	assert.Equal(t, []model.JobCount{{Kind: "recurring", Status: model.JobStatusQueued, Count: 1}}, counts,
+		"Expected a single pending job of a recurring kind")
+
+	assert.True(t, runner.RunOnce(context.Background()))
+	assert.False(t, runner.RunOnce(context.Background()), "Expected the next run to wait for the interval")
+
+	job, _ := runner.Get(context.Background(), 0)
+	assert.Equal(t, model.JobStatusQueued, job.Status)
+	assert.Equal(t, 0, job.Attempts)
+	assert.Nil(t, job.FinishedAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), job.RunAt, time.Minute)
+
+	// The next run, once the interval elapsed
+	later := time.Now().Add(2 * time.Hour)
+	job, err := job_db.Lease(context.Background(), []string{"recurring"}, runner.owner, later, later.Add(time.Minute))
+	assert.Nil(t, err)
+	runner.run(context.Background(), job)
+
+	job, _ = runner.Get(context.Background(), 0)
+	assert.Equal(t, model.JobStatusQueued, job.Status, "Expected a failed recurring job to run again")
+	assert.Equal(t, "broken", job.LastError)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), job.RunAt, time.Minute)
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,88 @@
+package maintenance
+
+import (
+	"context"
+	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/model"
+	"flash-learn/internal/scheduler"
+	"flash-learn/internal/utils"
+	"log/slog"
+	"time"
+)
+
+// The handler of the background jobs that fit the parameters of a scheduler
+// to the review log of a deck and its sub-decks. Fitting goes through the
+// whole log, which takes too long for a request, so the fitted parameters are
+// saved as the result of the job and applied by a later request.
+type Optimizer struct {
+	deck_db database.DBWrapper
+	card_db database.CardDBWrapperInterface
+}
+
+// Creates and returns a new instance of Optimizer.
+//
+// Parameters:
+//   - deck_db database.DBWrapper : The deck database, used to get the settings and sub-decks of the deck.
+//   - card_db database.CardDBWrapperInterface : The card database, used to get the review log.
+//
+// Returns:
+//   - *Optimizer
+func NewOptimizer(deck_db database.DBWrapper, card_db database.CardDBWrapperInterface) *Optimizer {
+	return &Optimizer{
+		deck_db: deck_db,
+		card_db: card_db,
+	}
+}
+
+// Runs an optimize job and saves the fitted parameters as its result, a
+// model.OptimizeResult. This is the handler of the model.JobKindOptimize jobs.
+// A job whose deck was deleted or whose log has too few reviews fails
+// without being retried.
+//
+// Parameters:
+//   - ctx context.Context : Cancelling the context stops the job before the fit.
+//   - run *jobs.Run : The job, whose payload is a model.OptimizePayload.
+//
+// Returns:
+//   - error : An error if the job failed or was stopped, nil once the parameters are fitted.
+func (o *Optimizer) Handle(ctx context.Context, run *jobs.Run) error {
+	var payload model.OptimizePayload
+	if err := run.Payload(&payload); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	deck, err := o.deck_db.GetSingle(ctx, payload.DeckID)
+	if err == utils.ErrRecordNotExist {
+		return jobs.Permanent(utils.ErrDeckNotExist)
+	} else if err != nil {
+		return err
+	}
+
+	settings := deck.Settings()
+	if payload.Scheduler != "" {
+		settings.Scheduler = payload.Scheduler
+	}
+
+	deckIDs, err := o.deck_db.GetDescendantIDs(ctx, payload.DeckID)
+	if err != nil {
+		return err
+	}
+
+	reviews, err := o.card_db.GetReviews(ctx, deckIDs, time.Time{}, time.Now())
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	slog.Info("Optimizing scheduler", "job", run.Job().ID, "deck", payload.DeckID, "scheduler", settings.Scheduler, "reviews", len(reviews))
+	result, err := scheduler.Optimize(reviews, settings)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	result.Preset = deck.Preset
+
+	return run.SetResult(result)
+}
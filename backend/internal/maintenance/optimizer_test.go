@@ -0,0 +1,76 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newOptimizerRunner returns a job runner with an optimizer.
+func newOptimizerRunner(deck_db database.DBWrapper, card_db database.CardDBWrapperInterface) *jobs.Runner {
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	runner := jobs.NewRunner(job_db, 1)
+	runner.Register(model.JobKindOptimize, NewOptimizer(deck_db, card_db).Handle)
+	return runner
+}
+
+func TestOptimizerHandle(t *testing.T) {
+	deck_db, card_db := newReschedulerFixture(20)
+	runner := newOptimizerRunner(deck_db, card_db)
+
+	job, err := runner.Enqueue(context.Background(), model.JobKindOptimize, model.OptimizePayload{DeckID: 0})
+	assert.Nil(t, err)
+	assert.True(t, runner.RunOnce(context.Background()))
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusSucceeded, job.Status)
+
+	var result model.OptimizeResult
+	assert.Nil(t, json.Unmarshal(job.Result, &result))
+	assert.Equal(t, model.SchedulerFSRS, result.Scheduler, "Expected the scheduler of the deck by default")
+	assert.Equal(t, 20, result.Reviews, "Expected the first answer of every card not to be predicted")
+	assert.Equal(t, model.FSRSWeightCount, len(result.FSRSWeights))
+	assert.False(t, result.Applied)
+}
+
+func TestOptimizerFailsWithoutRetrying(t *testing.T) {
+	deck_db, card_db := newReschedulerFixture(1)
+	runner := newOptimizerRunner(deck_db, card_db)
+
+	testCases := []struct {
+		name          string
+		payload       model.OptimizePayload
+		expectedError error
+	}{
+		{
+			name:          "Not enough reviews",
+			payload:       model.OptimizePayload{DeckID: 0, Scheduler: model.SchedulerSM2},
+			expectedError: utils.ErrNotEnoughReviews,
+		},
+		{
+			name:          "Deck not found",
+			payload:       model.OptimizePayload{DeckID: 99},
+			expectedError: utils.ErrDeckNotExist,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			job, _ := runner.Enqueue(context.Background(), model.JobKindOptimize, tc.payload)
+			assert.True(t, runner.RunOnce(context.Background()))
+
+			job, _ = runner.Get(context.Background(), job.ID)
+			assert.Equal(t, model.JobStatusFailed, job.Status)
+			assert.Equal(t, 1, job.Attempts)
+			assert.Equal(t, tc.expectedError.Error(), job.LastError)
+			assert.Empty(t, job.Result)
+		})
+	}
+}
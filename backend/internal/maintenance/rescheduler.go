@@ -0,0 +1,191 @@
+package maintenance
+
+import (
+	"context"
+	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/model"
+	"flash-learn/internal/scheduler"
+	"flash-learn/internal/utils"
+	"log/slog"
+)
+
+// RescheduleBatchSize is the number of cards a reschedule job processes at once.
+const RescheduleBatchSize = 500
+
+// rescheduleRetries is the number of times the cards of a batch reviewed
+// while it was rescheduled are rescheduled again before being skipped.
+const rescheduleRetries = 3
+
+// The handler of the background jobs that reschedule the cards of decks whose
+// settings changed, by replaying their review log through the scheduler of the
+// new settings. The cards are processed by ascending ID and the job saves a
+// checkpoint after every batch, so a job interrupted by a crash or a shutdown
+// resumes from its last batch. Replaying a batch twice gives the same result,
+// so a batch processed right before an interruption is harmless.
+type Rescheduler struct {
+	deck_db   database.DBWrapper
+	card_db   database.CardDBWrapperInterface
+	batchSize int
+}
+
+// Creates and returns a new instance of Rescheduler.
+//
+// Parameters:
+//   - deck_db database.DBWrapper : The deck database, used to get the settings of the cards.
+//   - card_db database.CardDBWrapperInterface : The card database.
+//   - batchSize int : The number of cards processed at once.
+//
+// Returns:
+//   - *Rescheduler
+func NewRescheduler(deck_db database.DBWrapper, card_db database.CardDBWrapperInterface, batchSize int) *Rescheduler {
+	return &Rescheduler{
+		deck_db:   deck_db,
+		card_db:   card_db,
+		batchSize: batchSize,
+	}
+}
+
+// Runs a reschedule job from its last checkpoint to the end. This is the
+// handler of the model.JobKindReschedule jobs.
+//
+// Parameters:
+//   - ctx context.Context : Cancelling the context stops the job after its current batch.
+//   - run *jobs.Run : The job, whose payload is a model.ReschedulePayload.
+//
+// Returns:
+//   - error : An error if the job failed or was stopped, nil once every card is rescheduled.
+func (r *Rescheduler) Handle(ctx context.Context, run *jobs.Run) error {
+	var payload model.ReschedulePayload
+	if err := run.Payload(&payload); err != nil {
+		return err
+	}
+
+	var checkpoint model.RescheduleCheckpoint
+	resumed, err := run.Checkpoint(&checkpoint)
+	if err != nil {
+		return err
+	}
+
+	job := run.Job()
+	processed, total := job.Progress, job.Total
+	if !resumed {
+		total, err = r.card_db.CountRescheduleCards(ctx, payload.DeckIDs)
+		if err != nil {
+			return err
+		}
+		if err = run.Progress(ctx, 0, total, checkpoint); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Rescheduling cards", "job", job.ID, "processed", processed, "total", total)
+	settings := make(map[int]model.DeckPreset)
+
+	for ctx.Err() == nil {
+		cards, err := r.card_db.GetRescheduleBatch(ctx, payload.DeckIDs, checkpoint.LastCardID, r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(cards) == 0 {
+			slog.Info("Rescheduled cards", "job", job.ID, "processed", processed)
+			return nil
+		}
+
+		if err = r.rescheduleBatch(ctx, cards, settings); err != nil {
+			return err
+		}
+
+		checkpoint.LastCardID = cards[len(cards)-1].ID
+		processed += len(cards)
+		// Cards answered for the first time since the job started are processed too
+		total = max(total, processed)
+		if err = run.Progress(ctx, processed, total, checkpoint); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+// A helper function that reschedules a batch of cards with the settings of
+// their home decks, which are cached for the rest of the job. The cards
+// reviewed while the batch was rescheduled are read again and rescheduled
+// with their new answers, up to rescheduleRetries times.
+func (r *Rescheduler) rescheduleBatch(ctx context.Context, cards []model.Card, settings map[int]model.DeckPreset) error {
+	pending := cards
+	for attempt := 0; ; attempt++ {
+		changed, err := r.rescheduleCards(ctx, pending, settings)
+		if err != nil || len(changed) == 0 {
+			return err
+		}
+		if attempt == rescheduleRetries {
+			slog.Warn("Skipped cards reviewed while being rescheduled", "cards", changed)
+			return nil
+		}
+
+		pending = []model.Card{}
+		for _, cardID := range changed {
+			card, err := r.card_db.GetSingle(ctx, cardID)
+			if err == utils.ErrRecordNotExist {
+				continue
+			} else if err != nil {
+				return err
+			}
+			pending = append(pending, card)
+		}
+	}
+}
+
+// A helper function that reschedules cards by replaying their review logs.
+//
+// Returns:
+//   - []int : The unique IDs of the cards skipped because they were reviewed meanwhile.
+//   - error : An error if the cards couldn't be rescheduled, nil otherwise.
+func (r *Rescheduler) rescheduleCards(ctx context.Context, cards []model.Card, settings map[int]model.DeckPreset) ([]int, error) {
+	cardIDs := make([]int, len(cards))
+	for i, card := range cards {
+		cardIDs[i] = card.ID
+	}
+
+	reviews, err := r.card_db.GetCardReviews(ctx, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	histories := make(map[int][]model.Review)
+	for _, review := range reviews {
+		histories[review.CardID] = append(histories[review.CardID], review)
+	}
+
+	changes := []model.CardAnswer{}
+	for _, card := range cards {
+		homeDeckID := card.DeckID
+		if card.OriginalDeckID != nil {
+			homeDeckID = *card.OriginalDeckID
+		}
+
+		deckSettings, cached := settings[homeDeckID]
+		if !cached {
+			deck, err := r.deck_db.GetSingle(ctx, homeDeckID)
+			if err == utils.ErrRecordNotExist {
+				// The deck was deleted since the job started, and its cards with it
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			deckSettings = deck.Settings()
+			settings[homeDeckID] = deckSettings
+		}
+
+		if rescheduled, ok := scheduler.Reschedule(card, histories[card.ID], deckSettings); ok {
+			changes = append(changes, model.NewCardAnswer(card, rescheduled))
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return r.card_db.Reschedule(ctx, changes)
+}
@@ -0,0 +1,175 @@
+package maintenance
+
+import (
+	"context"
+	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A card database that records where every batch starts, and that calls a
+// function before returning a batch and before saving rescheduled cards.
+type batchRecordingCardDB struct {
+	*database.CardDBWrapperMock
+	afterIDs     []int
+	onBatch      func()
+	onReschedule func()
+}
+
+func (card_db *batchRecordingCardDB) Reschedule(ctx context.Context, changes []model.CardAnswer) ([]int, error) {
+	if card_db.onReschedule != nil {
+		card_db.onReschedule()
+	}
+	return card_db.CardDBWrapperMock.Reschedule(ctx, changes)
+}
+
+func (card_db *batchRecordingCardDB) GetRescheduleBatch(ctx context.Context, deckIDs []int, afterID int, limit int) ([]model.Card, error) {
+	card_db.afterIDs = append(card_db.afterIDs, afterID)
+	if card_db.onBatch != nil {
+		card_db.onBatch()
+	}
+	return card_db.CardDBWrapperMock.GetRescheduleBatch(ctx, deckIDs, afterID, limit)
+}
+
+// newReschedulerFixture returns a deck database and a card database with a
+// deck whose review cards were all learned and reviewed once with the default
+// settings, and whose preset now uses FSRS.
+func newReschedulerFixture(cards int) (*database.DeckDBWrapperMock, *batchRecordingCardDB) {
+	preset := model.DefaultDeckPreset()
+	preset.Scheduler = model.SchedulerFSRS
+
+	deck_db := database.NewDeckDBWrapperMock()
+	deck_db.CreateTable()
+	deck_db.Insert(context.Background(), model.Deck{Name: "Deck", Preset: &preset})
+
+	card_db := database.NewCardDBWrapperMock()
+	card_db.CreateTable()
+	card_db.InsertDeck(0)
+
+	// The IDs of the mock start at 0, which a job treats as already processed
+	card_db.Insert(context.Background(), model.NewCard(0, "Placeholder", ""))
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < cards; i++ {
+		card := model.NewCard(0, "Test content", "")
+		card.ID, _ = card_db.Insert(context.Background(), card)
+		card.State = model.CardStateReview
+		card.Interval = 100
+		card.NextReviewTime = start.AddDate(0, 0, 100)
+
+		card_db.RecordReview(context.Background(), model.NewCardAnswer(card, card), model.Review{CardID: card.ID, Grade: model.GradeEasy, Kind: model.ReviewKindLearn, State: model.CardStateNew, ReviewTime: start})
+		card_db.RecordReview(context.Background(), model.NewCardAnswer(card, card), model.Review{CardID: card.ID, Grade: model.GradeGood, Kind: model.ReviewKindReview, State: model.CardStateReview, ReviewTime: start.AddDate(0, 0, 4)})
+	}
+
+	return deck_db, &batchRecordingCardDB{CardDBWrapperMock: card_db}
+}
+
+// newReschedulerRunner returns a job runner with a rescheduler processing two cards per batch.
+func newReschedulerRunner(job_db database.JobDBWrapperInterface, deck_db database.DBWrapper, card_db database.CardDBWrapperInterface) *jobs.Runner {
+	runner := jobs.NewRunner(job_db, 1)
+	runner.Register(model.JobKindReschedule, NewRescheduler(deck_db, card_db, 2).Handle)
+	return runner
+}
+
+func TestReschedulerHandle(t *testing.T) {
+	deck_db, card_db := newReschedulerFixture(5)
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	runner := newReschedulerRunner(job_db, deck_db, card_db)
+
+	job, err := runner.Enqueue(context.Background(), model.JobKindReschedule, model.ReschedulePayload{DeckIDs: []int{0}})
+	assert.Nil(t, err)
+
+	assert.True(t, runner.RunOnce(context.Background()))
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusSucceeded, job.Status)
+	assert.Equal(t, 5, job.Total)
+	assert.Equal(t, 5, job.Progress)
+
+	for cardID := 1; cardID <= 5; cardID++ {
+		card, _ := card_db.GetSingle(context.Background(), cardID)
+		assert.Greater(t, card.Stability, 0.0, "Expected the card to be replayed with FSRS")
+		assert.NotEqual(t, 100, card.Interval)
+		assert.Equal(t, card.LastReviewTime.AddDate(0, 0, card.Interval), card.NextReviewTime)
+	}
+
+	placeholder, _ := card_db.GetSingle(context.Background(), 0)
+	assert.Equal(t, model.CardStateNew, placeholder.State, "Expected new cards to be left alone")
+
+	assert.False(t, runner.RunOnce(context.Background()), "Expected finished jobs not to run again")
+}
+
+func TestReschedulerResumesInterruptedJob(t *testing.T) {
+	deck_db, card_db := newReschedulerFixture(5)
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	runner := newReschedulerRunner(job_db, deck_db, card_db)
+	job, _ := runner.Enqueue(context.Background(), model.JobKindReschedule, model.ReschedulePayload{DeckIDs: []int{0}})
+
+	// The runner stops while the second batch is processed
+	ctx, cancel := context.WithCancel(context.Background())
+	card_db.onBatch = func() {
+		if len(card_db.afterIDs) == 2 {
+			cancel()
+		}
+	}
+	assert.True(t, runner.RunOnce(ctx))
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusQueued, job.Status, "Expected the job to be queued for the next start")
+	assert.Equal(t, 0, job.Attempts, "Expected a shutdown not to count as an attempt")
+	assert.Equal(t, 4, job.Progress)
+
+	untouched, _ := card_db.GetSingle(context.Background(), 5)
+	assert.Equal(t, 100, untouched.Interval)
+
+	card_db.onBatch = nil
+	card_db.afterIDs = nil
+	assert.True(t, newReschedulerRunner(job_db, deck_db, card_db).RunOnce(context.Background()))
+
+	job, _ = runner.Get(context.Background(), job.ID)
+	assert.Equal(t, model.JobStatusSucceeded, job.Status)
+	assert.Equal(t, 5, job.Progress)
+	assert.Equal(t, []int{4, 5}, card_db.afterIDs, "Expected the job to resume after its last batch")
+
+	resumed, _ := card_db.GetSingle(context.Background(), 5)
+	assert.NotEqual(t, 100, resumed.Interval)
+}
+
+func TestReschedulerRetriesCardsReviewedMeanwhile(t *testing.T) {
+	deck_db, card_db := newReschedulerFixture(2)
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	runner := newReschedulerRunner(job_db, deck_db, card_db)
+	runner.Enqueue(context.Background(), model.JobKindReschedule, model.ReschedulePayload{DeckIDs: []int{0}})
+
+	// Card 1 is answered and flagged after its batch was read
+	saves := 0
+	reviewTime := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	card_db.onReschedule = func() {
+		saves++
+		if saves > 1 {
+			return
+		}
+		card, _ := card_db.GetSingle(context.Background(), 1)
+		answered := card
+		answered.LastReviewTime = &reviewTime
+		card_db.RecordReview(context.Background(), model.NewCardAnswer(card, answered), model.Review{CardID: 1, Grade: model.GradeGood, Kind: model.ReviewKindReview, State: model.CardStateReview, ReviewTime: reviewTime})
+		card_db.SetFlag(context.Background(), 1, 4)
+	}
+	assert.True(t, runner.RunOnce(context.Background()))
+
+	assert.Equal(t, 2, saves, "Expected the card reviewed meanwhile to be rescheduled again")
+	card, _ := card_db.GetSingle(context.Background(), 1)
+	assert.Greater(t, card.Stability, 0.0)
+	assert.Equal(t, reviewTime, *card.LastReviewTime, "Expected the answer given meanwhile to be replayed")
+	assert.Equal(t, 4, card.Flag, "Expected the edits made meanwhile to be kept")
+
+	other, _ := card_db.GetSingle(context.Background(), 2)
+	assert.Greater(t, other.Stability, 0.0)
+}
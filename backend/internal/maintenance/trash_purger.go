@@ -0,0 +1,79 @@
+package maintenance
+
+import (
+	"context"
+	"flash-learn/internal/jobs"
+	"log/slog"
+	"time"
+)
+
+// An interface for a trash that can permanently delete its old entries.
+type Purger interface {
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+// The handler of the recurring background job that purges entries that have
+// been in the trash for longer than the retention period.
+type TrashPurger struct {
+	retention time.Duration
+	purgers   []Purger
+}
+
+// Creates and returns a new instance of TrashPurger.
+//
+// Parameters:
+//   - retention time.Duration : How long entries stay in the trash before being purged.
+//   - purgers ...Purger : The trashes to purge.
+//
+// Returns:
+//   - *TrashPurger
+func NewTrashPurger(retention time.Duration, purgers ...Purger) *TrashPurger {
+	return &TrashPurger{
+		retention: retention,
+		purgers:   purgers,
+	}
+}
+
+// Purges every trash once. A failing trash is logged and doesn't prevent
+// the others from being purged.
+//
+// Parameters:
+//   - ctx context.Context : The context of the queries.
+//   - now time.Time : The current time, entries deleted before now minus the retention are purged.
+//
+// Returns:
+//   - int : The total number of purged entries.
+func (purger *TrashPurger) PurgeOnce(ctx context.Context, now time.Time) int {
+	deletedBefore := now.Add(-purger.retention)
+	total := 0
+
+	for _, p := range purger.purgers {
+		purged, err := p.PurgeTrash(ctx, deletedBefore)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error purging trash", "error", err)
+			continue
+		}
+		total += purged
+	}
+
+	if total > 0 {
+		slog.InfoContext(ctx, "Purged trash", "entries", total)
+	}
+
+	return total
+}
+
+// Runs a purge job, purging every trash once. This is the handler of the
+// recurring model.JobKindPurgeTrash jobs, which run again after their interval
+// whatever the outcome, so a failing trash is only logged.
+//
+// Parameters:
+//   - ctx context.Context : Cancelling the context stops the job.
+//   - run *jobs.Run : The job, which has no payload.
+//
+// Returns:
+//   - error : The error of the context if the job was stopped, nil otherwise.
+func (purger *TrashPurger) Handle(ctx context.Context, run *jobs.Run) error {
+	purger.PurgeOnce(ctx, time.Now())
+	return ctx.Err()
+}
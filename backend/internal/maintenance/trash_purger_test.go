@@ -0,0 +1,69 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingPurger struct{}
+
+func (failingPurger) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return 0, errors.New("purge failed")
+}
+
+func TestTrashPurgerPurgeOnce(t *testing.T) {
+	db := database.NewDeckDBWrapperMock()
+	db.CreateTable()
+	db.Insert(context.Background(), model.NewDeck("First", "First deck"))
+	db.Insert(context.Background(), model.NewDeck("Second", "Second deck"))
+	db.Delete(context.Background(), 0, database.DeckDeleteOptions{})
+	db.Delete(context.Background(), 1, database.DeckDeleteOptions{})
+
+	purger := NewTrashPurger(database.DeckTrashRetention, failingPurger{}, db)
+
+	assert.Equal(t, 0, purger.PurgeOnce(context.Background(), time.Now()), "Expected recently trashed decks to be kept")
+
+	purged := purger.PurgeOnce(context.Background(), time.Now().Add(database.DeckTrashRetention+time.Minute))
+	assert.Equal(t, 2, purged, "Expected expired decks to be purged despite a failing trash")
+
+	trash, _ := db.GetTrash(context.Background())
+	assert.Equal(t, 0, len(trash))
+}
+
+func TestTrashPurgerRunsAsRecurringJob(t *testing.T) {
+	db := database.NewDeckDBWrapperMock()
+	db.CreateTable()
+	db.Insert(context.Background(), model.NewDeck("First", "First deck"))
+	db.Delete(context.Background(), 0, database.DeckDeleteOptions{})
+
+	job_db := database.NewJobDBWrapperMock()
+	job_db.CreateTable()
+	runner := jobs.NewRunner(job_db, 1).WithPollInterval(time.Millisecond)
+	runner.RegisterRecurring(model.JobKindPurgeTrash, NewTrashPurger(0, db).Handle, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		runner.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		job, err := runner.Get(context.Background(), 0)
+		return err == nil && job.Attempts == 0 && job.RunAt.After(time.Now().Add(time.Minute))
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-stopped
+
+	trash, _ := db.GetTrash(context.Background())
+	assert.Equal(t, 0, len(trash), "Expected the trash to be purged by the job")
+	job, _ := runner.Get(context.Background(), 0)
+	assert.Equal(t, model.JobStatusQueued, job.Status, "Expected the job to wait for its next run")
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import "database/sql"
+
+// RegisterDBStats registers the statistics of a database connection pool,
+// read from stats every time the registry is written.
+//
+// Parameters:
+//   - registry *Registry : The registry the metrics are added to.
+//   - stats func() sql.DBStats : Returns the statistics of the pool, usually (*sql.DB).Stats.
+func RegisterDBStats(registry *Registry, stats func() sql.DBStats) {
+	gauge := func(name string, help string, value func(sql.DBStats) float64) {
+		registry.GaugeFunc(name, help, nil, func() ([]Sample, error) {
+			return []Sample{{Value: value(stats())}}, nil
+		})
+	}
+	counter := func(name string, help string, value func(sql.DBStats) float64) {
+		registry.CounterFunc(name, help, nil, func() ([]Sample, error) {
+			return []Sample{{Value: value(stats())}}, nil
+		})
+	}
+
+	gauge("flashlearn_db_max_open_connections", "Maximum number of open connections to the database, 0 for no limit.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("flashlearn_db_open_connections", "Number of established connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("flashlearn_db_in_use_connections", "Number of connections to the database currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("flashlearn_db_idle_connections", "Number of idle connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("flashlearn_db_wait_count_total", "Number of connections waited for.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("flashlearn_db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("flashlearn_db_max_idle_closed_total", "Number of connections closed because of the maximum of idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("flashlearn_db_max_idle_time_closed_total", "Number of connections closed because they were idle for too long.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) })
+	counter("flashlearn_db_max_lifetime_closed_total", "Number of connections closed because they reached their maximum lifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
@@ -0,0 +1,448 @@
+// Package metrics keeps counters, gauges and histograms and writes them in
+// the Prometheus text exposition format, so that the server can be scraped
+// without depending on the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// labelSeparator separates the label values of a series in its key, and
+// can't appear in a valid UTF-8 label value.
+const labelSeparator = "\xff"
+
+var (
+	// DefaultDurationBuckets are the upper bounds, in seconds, of the buckets
+	// of a histogram of request durations.
+	DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// QueryDurationBuckets are the upper bounds, in seconds, of the buckets
+	// of a histogram of database query durations, which are shorter.
+	QueryDurationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelName   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// metric is a metric family of a registry.
+type metric interface {
+	// write writes the samples of the family, without its HELP and TYPE lines.
+	write(w *bufio.Writer, name string) error
+}
+
+// family is a registered metric with its metadata.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	metric metric
+}
+
+// Registry holds metrics and writes them in the Prometheus text format. It
+// is safe for concurrent use.
+//
+// The metrics are registered once, usually at startup, and registering a
+// name twice or an invalid name panics, as does using a metric with the
+// wrong number of label values, since both are programming errors.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]family
+}
+
+// Creates and returns a new empty registry.
+//
+// Returns:
+//   - *Registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+// Counter registers a counter, a value which only goes up, with a series for
+// every combination of values of its labels.
+//
+// Parameters:
+//   - name string : The name of the metric, which should end with "_total".
+//   - help string : What the metric counts.
+//   - labels ...string : The names of the labels of the metric.
+//
+// Returns:
+//   - *CounterVec
+func (registry *Registry) Counter(name string, help string, labels ...string) *CounterVec {
+	counter := &CounterVec{vec: newVec[float64](labels)}
+	registry.register(name, help, "counter", counter)
+	return counter
+}
+
+// Histogram registers a histogram, which counts observed values in buckets,
+// with a series for every combination of values of its labels.
+//
+// Parameters:
+//   - name string : The name of the metric.
+//   - help string : What the metric observes.
+//   - buckets []float64 : The upper bounds of the buckets, in increasing order.
+//   - labels ...string : The names of the labels of the metric.
+//
+// Returns:
+//   - *HistogramVec
+func (registry *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("metrics: buckets of %s aren't sorted", name))
+	}
+	for _, label := range labels {
+		if label == "le" {
+			panic(fmt.Sprintf("metrics: histogram %s can't have a label named le", name))
+		}
+	}
+	histogram := &HistogramVec{vec: newVec[histogramSeries](labels), buckets: slices.Clone(buckets)}
+	registry.register(name, help, "histogram", histogram)
+	return histogram
+}
+
+// GaugeFunc registers a gauge whose samples are collected when the registry
+// is written, for values read from elsewhere such as the size of a queue.
+// If collect fails, the error is logged and the gauge is left out.
+//
+// Parameters:
+//   - name string : The name of the metric.
+//   - help string : What the metric measures.
+//   - labels []string : The names of the labels of the metric.
+//   - collect func() ([]Sample, error) : Returns the current samples.
+func (registry *Registry) GaugeFunc(name string, help string, labels []string, collect func() ([]Sample, error)) {
+	registry.register(name, help, "gauge", &funcMetric{labels: validLabels(labels), collect: collect})
+}
+
+// CounterFunc registers a counter whose samples are collected when the
+// registry is written, for counts kept elsewhere. If collect fails, the
+// error is logged and the counter is left out.
+//
+// Parameters:
+//   - name string : The name of the metric, which should end with "_total".
+//   - help string : What the metric counts.
+//   - labels []string : The names of the labels of the metric.
+//   - collect func() ([]Sample, error) : Returns the current samples.
+func (registry *Registry) CounterFunc(name string, help string, labels []string, collect func() ([]Sample, error)) {
+	registry.register(name, help, "counter", &funcMetric{labels: validLabels(labels), collect: collect})
+}
+
+// WriteText writes every metric of the registry in the text exposition
+// format, sorted by name.
+//
+// Parameters:
+//   - w io.Writer : Where the metrics are written.
+//
+// Returns:
+//   - error : An error if writing fails, nil otherwise.
+func (registry *Registry) WriteText(w io.Writer) error {
+	registry.mu.RLock()
+	families := make([]family, 0, len(registry.families))
+	for _, f := range registry.families {
+		families = append(families, f)
+	}
+	registry.mu.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	buffered := bufio.NewWriter(w)
+	for _, f := range families {
+		// The samples are written first so that a family whose collection
+		// fails is left out entirely
+		var samples strings.Builder
+		samplesWriter := bufio.NewWriter(&samples)
+		if err := f.metric.write(samplesWriter, f.name); err != nil {
+			slog.Error("Error collecting metric", "metric", f.name, "error", err)
+			continue
+		}
+		samplesWriter.Flush()
+
+		fmt.Fprintf(buffered, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(buffered, "# TYPE %s %s\n", f.name, f.kind)
+		buffered.WriteString(samples.String())
+	}
+	return buffered.Flush()
+}
+
+// A helper function that adds a metric to the registry after checking its name.
+func (registry *Registry) register(name string, help string, kind string, m metric) {
+	if !namePattern.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, exists := registry.families[name]; exists {
+		panic(fmt.Sprintf("metrics: metric %s registered twice", name))
+	}
+	registry.families[name] = family{name: name, help: help, kind: kind, metric: m}
+}
+
+// Sample is a value of a metric collected by a function, with the values of
+// its labels in the order of the label names.
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// funcMetric is a metric whose samples are collected by a function.
+type funcMetric struct {
+	labels  []string
+	collect func() ([]Sample, error)
+}
+
+func (m *funcMetric) write(w *bufio.Writer, name string) error {
+	samples, err := m.collect()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return slices.Compare(samples[i].LabelValues, samples[j].LabelValues) < 0
+	})
+	for _, sample := range samples {
+		if len(sample.LabelValues) != len(m.labels) {
+			return fmt.Errorf("sample has %d label values, expected %d", len(sample.LabelValues), len(m.labels))
+		}
+		writeSample(w, name, m.labels, sample.LabelValues, "", "", sample.Value)
+	}
+	return nil
+}
+
+// vec holds the series of a metric with labels, keyed by their label values.
+type vec[T any] struct {
+	labels []string
+	mu     sync.Mutex
+	series map[string]*T
+}
+
+func newVec[T any](labels []string) vec[T] {
+	return vec[T]{labels: validLabels(labels), series: make(map[string]*T)}
+}
+
+// get returns the series of the given label values, created with create if
+// it doesn't exist yet. It must be called with the lock held.
+func (v *vec[T]) get(values []string, create func() *T) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values, expected %d", len(values), len(v.labels)))
+	}
+
+	key := strings.Join(values, labelSeparator)
+	series, ok := v.series[key]
+	if !ok {
+		series = create()
+		v.series[key] = series
+	}
+	return series
+}
+
+// sortedKeys returns the keys of the series in order. It must be called
+// with the lock held.
+func (v *vec[T]) sortedKeys() []string {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelValues returns the label values a key was made of.
+func (v *vec[T]) labelValues(key string) []string {
+	if len(v.labels) == 0 {
+		return nil
+	}
+	return strings.Split(key, labelSeparator)
+}
+
+// CounterVec is a counter with a series for every combination of values of
+// its labels.
+type CounterVec struct {
+	vec[float64]
+}
+
+// Inc adds one to the series of the given label values.
+//
+// Parameters:
+//   - labelValues ...string : The values of the labels, in the order of their names.
+func (counter *CounterVec) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add adds a value to the series of the given label values. Negative values
+// are ignored since a counter only goes up.
+//
+// Parameters:
+//   - value float64 : The value to add.
+//   - labelValues ...string : The values of the labels, in the order of their names.
+func (counter *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	*counter.get(labelValues, func() *float64 { return new(float64) }) += value
+}
+
+// Value returns the value of the series of the given label values, zero if
+// it was never added to.
+//
+// Parameters:
+//   - labelValues ...string : The values of the labels, in the order of their names.
+//
+// Returns:
+//   - float64
+func (counter *CounterVec) Value(labelValues ...string) float64 {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	if value, ok := counter.series[strings.Join(labelValues, labelSeparator)]; ok {
+		return *value
+	}
+	return 0
+}
+
+func (counter *CounterVec) write(w *bufio.Writer, name string) error {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	for _, key := range counter.sortedKeys() {
+		writeSample(w, name, counter.labels, counter.labelValues(key), "", "", *counter.series[key])
+	}
+	return nil
+}
+
+// histogramSeries holds the observations of a series of a histogram.
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec is a histogram with a series for every combination of values
+// of its labels.
+type HistogramVec struct {
+	vec[histogramSeries]
+	buckets []float64
+}
+
+// Observe adds a value to the series of the given label values.
+//
+// Parameters:
+//   - value float64 : The observed value, such as a duration in seconds.
+//   - labelValues ...string : The values of the labels, in the order of their names.
+func (histogram *HistogramVec) Observe(value float64, labelValues ...string) {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+	series := histogram.get(labelValues, func() *histogramSeries {
+		return &histogramSeries{counts: make([]uint64, len(histogram.buckets))}
+	})
+
+	// The counts are stored per bucket and summed up when written
+	if i := sort.SearchFloat64s(histogram.buckets, value); i < len(histogram.buckets) {
+		series.counts[i]++
+	}
+	series.count++
+	series.sum += value
+}
+
+// Count returns the number of values observed by the series of the given
+// label values.
+//
+// Parameters:
+//   - labelValues ...string : The values of the labels, in the order of their names.
+//
+// Returns:
+//   - uint64
+func (histogram *HistogramVec) Count(labelValues ...string) uint64 {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+	if series, ok := histogram.series[strings.Join(labelValues, labelSeparator)]; ok {
+		return series.count
+	}
+	return 0
+}
+
+func (histogram *HistogramVec) write(w *bufio.Writer, name string) error {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+	for _, key := range histogram.sortedKeys() {
+		series := histogram.series[key]
+		values := histogram.labelValues(key)
+
+		var cumulative uint64
+		for i, bound := range histogram.buckets {
+			cumulative += series.counts[i]
+			writeSample(w, name+"_bucket", histogram.labels, values, "le", formatFloat(bound), float64(cumulative))
+		}
+		writeSample(w, name+"_bucket", histogram.labels, values, "le", "+Inf", float64(series.count))
+		writeSample(w, name+"_sum", histogram.labels, values, "", "", series.sum)
+		writeSample(w, name+"_count", histogram.labels, values, "", "", float64(series.count))
+	}
+	return nil
+}
+
+// A helper function that writes a sample line, with an optional extra label
+// such as the bound of a histogram bucket.
+func writeSample(w *bufio.Writer, name string, labels []string, values []string, extraLabel string, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, label, escapeLabelValue(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// A helper function that formats a value the way the text format expects.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// A helper function that escapes a label value.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// A helper function that escapes the help of a metric.
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// A helper function that checks the names of the labels of a metric.
+func validLabels(labels []string) []string {
+	for _, label := range labels {
+		if !labelName.MatchString(label) || strings.HasPrefix(label, "__") {
+			panic(fmt.Sprintf("metrics: invalid label name %q", label))
+		}
+	}
+	return slices.Clone(labels)
+}
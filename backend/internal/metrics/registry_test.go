@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// text returns the metrics of a registry in the text format.
+func text(t *testing.T, registry *Registry) string {
+	var b strings.Builder
+	assert.Nil(t, registry.WriteText(&b))
+	return b.String()
+}
+
+func TestCounter(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.Counter("test_requests_total", "Requests handled.", "method", "status")
+
+	counter.Inc("GET", "200")
+	counter.Inc("GET", "200")
+	counter.Add(3, "POST", "400")
+	counter.Add(-1, "POST", "400")
+	counter.Inc("GET", "say \"hi\"\\\n")
+
+	assert.Equal(t, 2.0, counter.Value("GET", "200"))
+	assert.Equal(t, 3.0, counter.Value("POST", "400"), "Expected a negative value to be ignored")
+	assert.Equal(t, 0.0, counter.Value("DELETE", "200"))
+	assert.Equal(t, `# HELP test_requests_total Requests handled.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",status="200"} 2
+test_requests_total{method="GET",status="say \"hi\"\\\n"} 1
+test_requests_total{method="POST",status="400"} 3
+`, text(t, registry))
+
+	assert.Panics(t, func() { counter.Inc("GET") }, "Expected a missing label value to panic")
+}
+
+func TestHistogram(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.Histogram("test_duration_seconds", "Durations.", []float64{0.1, 1}, "route")
+
+	histogram.Observe(0.05, "/a")
+	histogram.Observe(0.1, "/a")
+	histogram.Observe(0.5, "/a")
+	histogram.Observe(2, "/a")
+
+	assert.Equal(t, uint64(4), histogram.Count("/a"))
+	assert.Equal(t, `# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/a",le="0.1"} 2
+test_duration_seconds_bucket{route="/a",le="1"} 3
+test_duration_seconds_bucket{route="/a",le="+Inf"} 4
+test_duration_seconds_sum{route="/a"} 2.65
+test_duration_seconds_count{route="/a"} 4
+`, text(t, registry))
+
+	assert.Panics(t, func() { registry.Histogram("unsorted", "", []float64{1, 0.1}) })
+	assert.Panics(t, func() { registry.Histogram("reserved", "", []float64{1}, "le") })
+}
+
+func TestFuncMetrics(t *testing.T) {
+	registry := NewRegistry()
+	registry.GaugeFunc("test_queue_depth", "Jobs waiting.", []string{"status"}, func() ([]Sample, error) {
+		return []Sample{
+			{LabelValues: []string{"running"}, Value: 1},
+			{LabelValues: []string{"queued"}, Value: 4},
+		}, nil
+	})
+	registry.CounterFunc("test_broken_total", "Always fails.", nil, func() ([]Sample, error) {
+		return nil, errors.New("unavailable")
+	})
+
+	assert.Equal(t, `# HELP test_queue_depth Jobs waiting.
+# TYPE test_queue_depth gauge
+test_queue_depth{status="queued"} 4
+test_queue_depth{status="running"} 1
+`, text(t, registry), "Expected the failing metric to be left out")
+}
+
+func TestRegistryErrors(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("test_total", "")
+
+	assert.Panics(t, func() { registry.Counter("test_total", "") }, "Expected a duplicate name to panic")
+	assert.Panics(t, func() { registry.Counter("test-total", "") }, "Expected an invalid name to panic")
+	assert.Panics(t, func() { registry.Counter("test_other_total", "", "__reserved") }, "Expected an invalid label to panic")
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.Counter("test_total", "", "worker")
+	histogram := registry.Histogram("test_seconds", "", DefaultDurationBuckets)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				counter.Inc("w")
+				histogram.Observe(0.01)
+				registry.WriteText(&strings.Builder{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 800.0, counter.Value("w"))
+	assert.Equal(t, uint64(800), histogram.Count())
+}
+
+func TestRegisterDBStats(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDBStats(registry, func() sql.DBStats {
+		return sql.DBStats{MaxOpenConnections: 25, OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 7, WaitDuration: 1500 * time.Millisecond}
+	})
+
+	output := text(t, registry)
+	assert.Contains(t, output, "flashlearn_db_max_open_connections 25\n")
+	assert.Contains(t, output, "flashlearn_db_open_connections 3\n")
+	assert.Contains(t, output, "flashlearn_db_in_use_connections 1\n")
+	assert.Contains(t, output, "flashlearn_db_idle_connections 2\n")
+	assert.Contains(t, output, "# TYPE flashlearn_db_wait_count_total counter\nflashlearn_db_wait_count_total 7\n")
+	assert.Contains(t, output, "flashlearn_db_wait_duration_seconds_total 1.5\n")
+}
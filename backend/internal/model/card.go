@@ -1,17 +1,122 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Range of the flag a card can be marked with, 0 meaning no flag.
+const (
+	CardMinFlag = 0
+	CardMaxFlag = 9
+)
+
+// Ease factors used by the SM-2 scheduler. A new card starts at the default
+// ease, which each lapse lowers down to the minimum.
+const (
+	CardDefaultEase = 2.5
+	CardMinEase     = 1.3
+)
+
+// Scheduling states a card moves through as it is studied.
+const (
+	CardStateNew = iota
+	CardStateLearning
+	CardStateReview
+	CardStateRelearning
+)
 
 type Card struct {
-	ID               int       `json:"id"`
-	DeckID           int       `json:"deck_id"`
-	Content          string    `json:"content"`
-	CreationTime     time.Time `json:"creation_time"`
-	ModificationTime time.Time `json:"modification_time"`
-	NextReviewTime   time.Time `json:"next_review_time"`
-	RetentionLevel   int       `json:"retention_level"`
-	Flag             int       `json:"flag"`
-	Source           string    `json:"source"`
+	ID               int        `json:"id"`
+	DeckID           int        `json:"deck_id"`
+	Content          string     `json:"content"`
+	CreationTime     time.Time  `json:"creation_time"`
+	ModificationTime time.Time  `json:"modification_time"`
+	NextReviewTime   time.Time  `json:"next_review_time"`
+	RetentionLevel   int        `json:"retention_level"`
+	Flag             int        `json:"flag"`
+	Source           string     `json:"source"`
+	State            int        `json:"state"`
+	Tags             []string   `json:"tags"`
+	Suspended        bool       `json:"suspended"`
+	BuriedUntil      *time.Time `json:"buried_until,omitempty"`
+	NoteID           *int       `json:"note_id"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	Step             int        `json:"step"`
+	Interval         int        `json:"interval"`
+	Ease             float64    `json:"ease"`
+	Stability        float64    `json:"stability"`
+	Difficulty       float64    `json:"difficulty"`
+	Lapses           int        `json:"lapses"`
+	LastReviewTime   *time.Time `json:"last_review_time,omitempty"`
+	OriginalDeckID   *int       `json:"original_deck_id,omitempty"`
+}
+
+// CardContent is the structure stored as JSON in the content of a card,
+// pairing every field name with its value.
+type CardContent struct {
+	Fields []string `json:"fields"`
+	Values []string `json:"values"`
+}
+
+// Valid reports whether every field has a non-empty value.
+func (c CardContent) Valid() bool {
+	if len(c.Fields) == 0 || len(c.Fields) != len(c.Values) {
+		return false
+	}
+
+	for _, value := range c.Values {
+		if value == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseCardContent decodes the JSON content of a card.
+//
+// Parameters:
+//   - content string : The content of a card.
+//
+// Returns:
+//   - CardContent : The decoded content.
+//   - error : An error if the content isn't valid JSON.
+func ParseCardContent(content string) (CardContent, error) {
+	var parsed CardContent
+	err := json.Unmarshal([]byte(content), &parsed)
+	return parsed, err
+}
+
+// NormalizeTags trims the tags and drops empty and duplicate ones,
+// keeping the order in which they first appear.
+//
+// Parameters:
+//   - tags []string : The tags to normalize.
+//
+// Returns:
+//   - []string : The normalized tags, never nil.
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+// InQueue reports whether the card can be studied at the given time,
+// that is it is neither suspended nor buried.
+func (c Card) InQueue(now time.Time) bool {
+	return !c.Suspended && (c.BuriedUntil == nil || !c.BuriedUntil.After(now))
 }
 
 func NewCard(deckID int, content string, source string) Card {
@@ -24,5 +129,8 @@ func NewCard(deckID int, content string, source string) Card {
 		NextReviewTime:   time.Now().Add(time.Minute * 10),
 		RetentionLevel:   0,
 		Flag:             0,
+		State:            CardStateNew,
+		Tags:             []string{},
+		Ease:             CardDefaultEase,
 	}
 }
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"flash-learn/internal/utils"
+	"time"
+)
+
+// Operations that can be applied to many cards at once.
+const (
+	BulkMove            = "move"
+	BulkAddTags         = "add_tags"
+	BulkRemoveTags      = "remove_tags"
+	BulkSetFlag         = "set_flag"
+	BulkDelete          = "delete"
+	BulkSuspend         = "suspend"
+	BulkResetScheduling = "reset_scheduling"
+	BulkSetDue          = "set_due"
+)
+
+// BulkOperation describes an operation applied to many cards at once.
+// Only the parameters of the chosen operation type are used.
+type BulkOperation struct {
+	Type   string    `json:"type"`
+	DeckID int       `json:"deck_id"`
+	Tags   []string  `json:"tags"`
+	Flag   int       `json:"flag"`
+	Due    time.Time `json:"due"`
+}
+
+// Validate checks that the operation type is known and its parameters are set.
+//
+// Returns:
+//   - error : utils.ErrInvalidBulkOperation if the operation can't be applied, nil otherwise.
+func (op BulkOperation) Validate() error {
+	switch op.Type {
+	case BulkMove, BulkDelete, BulkSuspend, BulkResetScheduling:
+		return nil
+	case BulkAddTags, BulkRemoveTags:
+		if len(NormalizeTags(op.Tags)) == 0 {
+			return utils.ErrInvalidBulkOperation
+		}
+	case BulkSetFlag:
+		if op.Flag < CardMinFlag || op.Flag > CardMaxFlag {
+			return utils.ErrInvalidBulkOperation
+		}
+	case BulkSetDue:
+		if op.Due.IsZero() {
+			return utils.ErrInvalidBulkOperation
+		}
+	default:
+		return utils.ErrInvalidBulkOperation
+	}
+
+	return nil
+}
+
+// BulkResult is the outcome of a bulk operation for a single card.
+type BulkResult struct {
+	ID      int    `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BulkReport summarizes the outcome of a bulk operation.
+type BulkReport struct {
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
+
+// NewBulkReport builds a report from the IDs a bulk operation was asked to
+// change and the IDs it actually changed. Cards that weren't changed are
+// reported as not found.
+//
+// Parameters:
+//   - requested []int : The IDs of the cards the operation was applied to.
+//   - changed []int : The IDs of the cards that were changed.
+//
+// Returns:
+//   - BulkReport : The per-card report, in the requested order.
+func NewBulkReport(requested []int, changed []int) BulkReport {
+	changedIDs := make(map[int]bool, len(changed))
+	for _, id := range changed {
+		changedIDs[id] = true
+	}
+
+	report := BulkReport{Results: make([]BulkResult, 0, len(requested))}
+	for _, id := range requested {
+		if changedIDs[id] {
+			report.Succeeded++
+			report.Results = append(report.Results, BulkResult{ID: id, Success: true})
+		} else {
+			report.Failed++
+			report.Results = append(report.Results, BulkResult{ID: id, Error: utils.ErrRecordNotExist.Error()})
+		}
+	}
+
+	return report
+}
+
+// CardFilter selects cards by their deck, tags, flag and state. Unset
+// criteria match every card, and all set criteria must match. AddedDays
+// matches the cards added in the last days and ForgottenDays the cards
+// answered again in a review in the last days.
+type CardFilter struct {
+	DeckIDs       []int    `json:"deck_ids"`
+	Tags          []string `json:"tags"`
+	Flag          *int     `json:"flag"`
+	State         *int     `json:"state"`
+	Suspended     *bool    `json:"suspended"`
+	Text          string   `json:"text"`
+	AddedDays     int      `json:"added_days"`
+	ForgottenDays int      `json:"forgotten_days"`
+	Limit         int      `json:"limit"`
+}
@@ -0,0 +1,177 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// CardRevision is a snapshot of the editable parts of a card taken right
+// before it was modified. Revisions are numbered from 1 for every card.
+type CardRevision struct {
+	ID           int       `json:"id"`
+	CardID       int       `json:"card_id"`
+	Revision     int       `json:"revision"`
+	Content      string    `json:"content"`
+	Source       string    `json:"source"`
+	Tags         []string  `json:"tags"`
+	CreationTime time.Time `json:"creation_time"`
+}
+
+// FieldChange describes how a single field of a card changed between two
+// versions. A nil Old means the field was added, a nil New that it was removed.
+type FieldChange struct {
+	Field string  `json:"field"`
+	Old   *string `json:"old"`
+	New   *string `json:"new"`
+}
+
+// CardRevisionDiff is a revision along with the changes made by the edit
+// that replaced it.
+type CardRevisionDiff struct {
+	CardRevision
+	Changes []FieldChange `json:"changes"`
+}
+
+// DiffCardRevisions computes the changes between a revision and the version
+// of the card that replaced it. Content fields are compared by name and
+// prefixed with "content.", tags are compared as a set, one change per tag.
+//
+// Parameters:
+//   - before CardRevision : The older version.
+//   - after CardRevision : The newer version.
+//
+// Returns:
+//   - []FieldChange : The changes, never nil.
+func DiffCardRevisions(before CardRevision, after CardRevision) []FieldChange {
+	changes := diffContent(before.Content, after.Content)
+
+	if before.Source != after.Source {
+		changes = append(changes, newFieldChange("source", &before.Source, &after.Source))
+	}
+
+	beforeTags := make(map[string]bool)
+	for _, tag := range before.Tags {
+		beforeTags[tag] = true
+	}
+	afterTags := make(map[string]bool)
+	for _, tag := range after.Tags {
+		afterTags[tag] = true
+	}
+	for _, tag := range before.Tags {
+		if !afterTags[tag] {
+			changes = append(changes, newFieldChange("tags", &tag, nil))
+		}
+	}
+	for _, tag := range after.Tags {
+		if !beforeTags[tag] {
+			changes = append(changes, newFieldChange("tags", nil, &tag))
+		}
+	}
+
+	return changes
+}
+
+// RevisionOf returns the editable parts of a card in the shape of a revision,
+// so the current version can be compared with the saved ones.
+func RevisionOf(card Card) CardRevision {
+	return CardRevision{
+		CardID:       card.ID,
+		Content:      card.Content,
+		Source:       card.Source,
+		Tags:         card.Tags,
+		CreationTime: card.ModificationTime,
+	}
+}
+
+// diffContent compares two card contents field by field. Contents that
+// can't be parsed are compared as a whole.
+func diffContent(before string, after string) []FieldChange {
+	changes := []FieldChange{}
+	if before == after {
+		return changes
+	}
+
+	beforeContent, beforeErr := ParseCardContent(before)
+	afterContent, afterErr := ParseCardContent(after)
+	if beforeErr != nil || afterErr != nil {
+		return append(changes, newFieldChange("content", &before, &after))
+	}
+
+	beforeValues := contentValues(beforeContent)
+	afterValues := contentValues(afterContent)
+
+	fields := append([]string{}, beforeContent.Fields...)
+	for _, field := range afterContent.Fields {
+		if _, exists := beforeValues[field]; !exists {
+			fields = append(fields, field)
+		}
+	}
+
+	for _, field := range fields {
+		oldValue, hadField := beforeValues[field]
+		newValue, hasField := afterValues[field]
+
+		switch {
+		case !hadField:
+			changes = append(changes, newFieldChange("content."+field, nil, &newValue))
+		case !hasField:
+			changes = append(changes, newFieldChange("content."+field, &oldValue, nil))
+		case oldValue != newValue:
+			changes = append(changes, newFieldChange("content."+field, &oldValue, &newValue))
+		}
+	}
+
+	return changes
+}
+
+func contentValues(content CardContent) map[string]string {
+	values := make(map[string]string, len(content.Fields))
+	for i, field := range content.Fields {
+		if i < len(content.Values) {
+			values[field] = content.Values[i]
+		}
+	}
+	return values
+}
+
+func newFieldChange(field string, before *string, after *string) FieldChange {
+	change := FieldChange{Field: field}
+	if before != nil {
+		value := *before
+		change.Old = &value
+	}
+	if after != nil {
+		value := *after
+		change.New = &value
+	}
+	return change
+}
+
+// BuildRevisionDiffs pairs every revision with the changes of the edit that
+// replaced it, newest revision first.
+//
+// Parameters:
+//   - revisions []CardRevision : The saved revisions of a card.
+//   - current Card : The current version of the card.
+//
+// Returns:
+//   - []CardRevisionDiff : The revisions with their changes.
+func BuildRevisionDiffs(revisions []CardRevision, current Card) []CardRevisionDiff {
+	sorted := make([]CardRevision, len(revisions))
+	copy(sorted, revisions)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Revision < sorted[j].Revision
+	})
+
+	diffs := make([]CardRevisionDiff, len(sorted))
+	next := RevisionOf(current)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		diffs[len(sorted)-1-i] = CardRevisionDiff{
+			CardRevision: sorted[i],
+			Changes:      DiffCardRevisions(sorted[i], next),
+		}
+		next = sorted[i]
+	}
+
+	return diffs
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestDiffCardRevisions(t *testing.T) {
+	before := CardRevision{
+		Content: `{"fields":["Front","Back","Hint"],"values":["Hund","Dog","Animal"]}`,
+		Source:  "GenAI",
+		Tags:    []string{"german", "generated"},
+	}
+	after := CardRevision{
+		Content: `{"fields":["Front","Back","Example"],"values":["Hund","The dog","Der Hund bellt"]}`,
+		Source:  "Manual",
+		Tags:    []string{"german", "reviewed"},
+	}
+
+	changes := DiffCardRevisions(before, after)
+
+	assert.Equal(t, 6, len(changes))
+	assert.Equal(t, "content.Back", changes[0].Field)
+	assert.Equal(t, "Dog", *changes[0].Old)
+	assert.Equal(t, "The dog", *changes[0].New)
+	assert.Equal(t, "content.Hint", changes[1].Field)
+	assert.Nil(t, changes[1].New, "Expected removed field to have no new value")
+	assert.Equal(t, "content.Example", changes[2].Field)
+	assert.Nil(t, changes[2].Old, "Expected added field to have no old value")
+	assert.Equal(t, "source", changes[3].Field)
+	assert.Equal(t, "tags", changes[4].Field)
+	assert.Equal(t, "generated", *changes[4].Old)
+	assert.Equal(t, "tags", changes[5].Field)
+	assert.Equal(t, "reviewed", *changes[5].New)
+
+	t.Run("Identical versions", func(t *testing.T) {
+		changes := DiffCardRevisions(before, before)
+		assert.NotNil(t, changes)
+		assert.Equal(t, 0, len(changes))
+	})
+
+	t.Run("Unparsable content", func(t *testing.T) {
+		changes := DiffCardRevisions(CardRevision{Content: "old"}, CardRevision{Content: "new"})
+		assert.Equal(t, 1, len(changes))
+		assert.Equal(t, "content", changes[0].Field)
+	})
+}
+
+func TestBuildRevisionDiffs(t *testing.T) {
+	revisions := []CardRevision{
+		{Revision: 2, Content: "second", CreationTime: time.Now()},
+		{Revision: 1, Content: "first", CreationTime: time.Now()},
+	}
+	current := Card{Content: "third"}
+
+	diffs := BuildRevisionDiffs(revisions, current)
+
+	assert.Equal(t, 2, len(diffs))
+	assert.Equal(t, 2, diffs[0].Revision, "Expected newest revision first")
+	assert.Equal(t, "third", *diffs[0].Changes[0].New, "Expected newest revision to be compared with the current card")
+	assert.Equal(t, 1, diffs[1].Revision)
+	assert.Equal(t, "second", *diffs[1].Changes[0].New)
+}
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, NormalizeTags([]string{" a", "b", "", "a "}))
+	assert.Equal(t, []string{}, NormalizeTags(nil))
+}
@@ -33,6 +33,25 @@ func TestNewCard(t *testing.T) {
 			assert.True(t, card.NextReviewTime.After(card.CreationTime))
 			assert.Equal(t, 0, card.RetentionLevel)
 			assert.Equal(t, 0, card.Flag)
+			assert.Equal(t, CardStateNew, card.State)
 		}
 	})
 }
+
+func TestCardInQueue(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	card := NewCard(1, "Content", "Source")
+	assert.True(t, card.InQueue(now))
+
+	card.BuriedUntil = &later
+	assert.False(t, card.InQueue(now), "Expected buried card to be out of the queue")
+
+	card.BuriedUntil = &earlier
+	assert.True(t, card.InQueue(now), "Expected card to come back once the burial is over")
+
+	card.Suspended = true
+	assert.False(t, card.InQueue(now), "Expected suspended card to be out of the queue")
+}
@@ -3,12 +3,16 @@ package model
 import "time"
 
 type Deck struct {
-	ID               int       `json:"id"`
-	Name             string    `json:"name"`
-	Description      string    `json:"description"`
-	CreationDate     time.Time `json:"creation_date"`
-	ModificationDate time.Time `json:"modification_date"`
-	LastStudyDate    time.Time `json:"last_study_date"`
+	ID               int         `json:"id"`
+	Name             string      `json:"name"`
+	Description      string      `json:"description"`
+	CreationDate     time.Time   `json:"creation_date"`
+	ModificationDate time.Time   `json:"modification_date"`
+	LastStudyDate    time.Time   `json:"last_study_date"`
+	ParentID         *int        `json:"parent_id"`
+	PresetID         *int        `json:"preset_id"`
+	Preset           *DeckPreset `json:"-"`
+	DeletedAt        *time.Time  `json:"deleted_at,omitempty"`
 }
 
 func NewDeck(name string, description string) Deck {
@@ -20,3 +24,82 @@ func NewDeck(name string, description string) Deck {
 		LastStudyDate:    time.Time{},
 	}
 }
+
+// Settings returns the effective study settings of the deck, which are the
+// settings of its preset or the default settings if it isn't linked to one.
+//
+// Returns:
+//   - DeckPreset : The settings the study queue and scheduler should use.
+func (d Deck) Settings() DeckPreset {
+	if d.Preset != nil {
+		return *d.Preset
+	}
+	return DefaultDeckPreset()
+}
+
+// DeckSummary is a deck along with the card counts shown on the deck list.
+// The due counts only include cards that are due before the end of the day.
+type DeckSummary struct {
+	Deck
+	TotalCards    int `json:"total_cards"`
+	NewCount      int `json:"new_count"`
+	LearningCount int `json:"learning_count"`
+	ReviewCount   int `json:"review_count"`
+}
+
+// DeckNode is a deck summary placed in the deck tree. The card counts of a
+// node include the card counts of all of its descendants.
+type DeckNode struct {
+	DeckSummary
+	Children []*DeckNode `json:"children"`
+}
+
+// BuildDeckTree arranges flat deck summaries into a tree and aggregates the
+// card counts of every node over its descendants. Decks whose parent isn't
+// part of the given summaries are treated as roots. The relative order of
+// the summaries is kept among siblings.
+//
+// Parameters:
+//   - summaries []DeckSummary : The decks to arrange.
+//
+// Returns:
+//   - []*DeckNode : The root nodes of the tree.
+func BuildDeckTree(summaries []DeckSummary) []*DeckNode {
+	nodes := make(map[int]*DeckNode, len(summaries))
+	for _, summary := range summaries {
+		nodes[summary.ID] = &DeckNode{DeckSummary: summary, Children: []*DeckNode{}}
+	}
+
+	roots := []*DeckNode{}
+	for _, summary := range summaries {
+		node := nodes[summary.ID]
+		if summary.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+
+		parent, ok := nodes[*summary.ParentID]
+		if !ok || parent == node {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	for _, root := range roots {
+		aggregateDeckCounts(root)
+	}
+
+	return roots
+}
+
+// aggregateDeckCounts adds the card counts of all descendants of node to it.
+func aggregateDeckCounts(node *DeckNode) {
+	for _, child := range node.Children {
+		aggregateDeckCounts(child)
+		node.TotalCards += child.TotalCards
+		node.NewCount += child.NewCount
+		node.LearningCount += child.LearningCount
+		node.ReviewCount += child.ReviewCount
+	}
+}
@@ -0,0 +1,245 @@
+package model
+
+import (
+	"flash-learn/internal/utils"
+	"math"
+	"slices"
+)
+
+// Names of the schedulers a deck preset can choose from.
+const (
+	SchedulerSM2  = "sm2"
+	SchedulerFSRS = "fsrs"
+)
+
+// Actions taken on a card that becomes a leech, on top of tagging it.
+const (
+	LeechActionTag     = "tag"
+	LeechActionSuspend = "suspend"
+)
+
+// LeechTag is the tag given to cards that have lapsed too many times.
+const LeechTag = "leech"
+
+// Range of the probability of recall the FSRS scheduler aims for.
+const (
+	MinDesiredRetention = 0.7
+	MaxDesiredRetention = 0.99
+)
+
+// Range of the modifier of the ease factor used by the SM-2 scheduler.
+const (
+	MinEaseModifier = 0.5
+	MaxEaseModifier = 2.5
+)
+
+// FSRSWeightCount is the number of weights of the FSRS scheduler.
+const FSRSWeightCount = 17
+
+// DeckPreset is a reusable set of study options that decks can be linked to.
+// Steps are in minutes and intervals are in days. FSRSWeights and EaseModifier
+// are fitted to the review log by the optimizer, and empty weights stand for
+// the default weights of FSRS.
+type DeckPreset struct {
+	ID                 int       `json:"id"`
+	Name               string    `json:"name"`
+	NewCardsPerDay     int       `json:"new_cards_per_day"`
+	MaxReviewsPerDay   int       `json:"max_reviews_per_day"`
+	LearningSteps      []int     `json:"learning_steps"`
+	RelearningSteps    []int     `json:"relearning_steps"`
+	GraduatingInterval int       `json:"graduating_interval"`
+	EasyBonus          float64   `json:"easy_bonus"`
+	MaxInterval        int       `json:"max_interval"`
+	Scheduler          string    `json:"scheduler"`
+	LeechThreshold     int       `json:"leech_threshold"`
+	LeechAction        string    `json:"leech_action"`
+	LeechFlag          int       `json:"leech_flag"`
+	DesiredRetention   float64   `json:"desired_retention"`
+	FSRSWeights        []float64 `json:"fsrs_weights"`
+	EaseModifier       float64   `json:"ease_modifier"`
+}
+
+// DefaultDeckPreset returns the settings used by decks that aren't linked to a preset.
+func DefaultDeckPreset() DeckPreset {
+	return DeckPreset{
+		Name:               "Default",
+		NewCardsPerDay:     20,
+		MaxReviewsPerDay:   200,
+		LearningSteps:      []int{1, 10},
+		RelearningSteps:    []int{10},
+		GraduatingInterval: 1,
+		EasyBonus:          1.3,
+		MaxInterval:        36500,
+		Scheduler:          SchedulerSM2,
+		LeechThreshold:     8,
+		LeechAction:        LeechActionTag,
+		LeechFlag:          CardMinFlag,
+		DesiredRetention:   0.9,
+		FSRSWeights:        []float64{},
+		EaseModifier:       1,
+	}
+}
+
+// Validate checks that every setting of the preset is within its allowed range.
+//
+// Returns:
+//   - error : utils.ErrInvalidPreset if a setting is out of range, nil otherwise.
+func (p DeckPreset) Validate() error {
+	if p.Name == "" || p.NewCardsPerDay < 0 || p.MaxReviewsPerDay < 0 {
+		return utils.ErrInvalidPreset
+	}
+
+	if len(p.LearningSteps) == 0 || !isIncreasing(p.LearningSteps) || !isIncreasing(p.RelearningSteps) {
+		return utils.ErrInvalidPreset
+	}
+
+	if p.GraduatingInterval < 1 || p.MaxInterval < p.GraduatingInterval || p.EasyBonus < 1 {
+		return utils.ErrInvalidPreset
+	}
+
+	if p.Scheduler != SchedulerSM2 && p.Scheduler != SchedulerFSRS {
+		return utils.ErrInvalidPreset
+	}
+
+	if p.LeechThreshold < 1 || (p.LeechAction != LeechActionTag && p.LeechAction != LeechActionSuspend) {
+		return utils.ErrInvalidPreset
+	}
+
+	if p.LeechFlag < CardMinFlag || p.LeechFlag > CardMaxFlag {
+		return utils.ErrInvalidPreset
+	}
+
+	if p.DesiredRetention < MinDesiredRetention || p.DesiredRetention > MaxDesiredRetention {
+		return utils.ErrInvalidPreset
+	}
+
+	if p.EaseModifier < MinEaseModifier || p.EaseModifier > MaxEaseModifier {
+		return utils.ErrInvalidPreset
+	}
+
+	if len(p.FSRSWeights) != 0 && len(p.FSRSWeights) != FSRSWeightCount {
+		return utils.ErrInvalidPreset
+	}
+	for _, weight := range p.FSRSWeights {
+		if math.IsNaN(weight) || math.IsInf(weight, 0) || weight < 0 {
+			return utils.ErrInvalidPreset
+		}
+	}
+
+	return nil
+}
+
+// SameScheduling reports whether two presets schedule cards the same way,
+// in which case linking a deck to the other preset doesn't change when its
+// cards are due.
+//
+// Parameters:
+//   - other DeckPreset : The preset to compare with.
+//
+// Returns:
+//   - bool : True if every setting used by the schedulers is the same.
+func (p DeckPreset) SameScheduling(other DeckPreset) bool {
+	return p.Scheduler == other.Scheduler &&
+		slices.Equal(p.LearningSteps, other.LearningSteps) &&
+		slices.Equal(p.RelearningSteps, other.RelearningSteps) &&
+		p.GraduatingInterval == other.GraduatingInterval &&
+		p.EasyBonus == other.EasyBonus &&
+		p.MaxInterval == other.MaxInterval &&
+		p.DesiredRetention == other.DesiredRetention &&
+		slices.Equal(p.FSRSWeights, other.FSRSWeights) &&
+		p.EaseModifier == other.EaseModifier
+}
+
+// StudyCounts is the number of cards studied since the start of the day, which
+// are taken from the daily limits of a preset.
+type StudyCounts struct {
+	// NewCards is the number of new cards answered for the first time.
+	NewCards int
+	// Reviews is the number of answers to cards in review.
+	Reviews int
+}
+
+// LimitStudyQueue trims a queue of due cards to what is left of the daily
+// limits of the preset once the cards already studied today are taken out.
+// New cards are capped by NewCardsPerDay and review cards by MaxReviewsPerDay,
+// while learning cards are never held back. The order of the cards is kept.
+//
+// Parameters:
+//   - cards []Card : The due cards, in study order.
+//   - studied StudyCounts : The cards studied since the start of the day.
+//
+// Returns:
+//   - []Card : The cards that fit within the daily limits.
+func (p DeckPreset) LimitStudyQueue(cards []Card, studied StudyCounts) []Card {
+	limited := make([]Card, 0, len(cards))
+	newCount, reviewCount := studied.NewCards, studied.Reviews
+
+	for _, card := range cards {
+		switch card.State {
+		case CardStateNew:
+			if newCount >= p.NewCardsPerDay {
+				continue
+			}
+			newCount++
+		case CardStateReview:
+			if reviewCount >= p.MaxReviewsPerDay {
+				continue
+			}
+			reviewCount++
+		}
+		limited = append(limited, card)
+	}
+
+	return limited
+}
+
+// IsLeech reports whether a card with the given number of lapses should be
+// handled as a leech. A card becomes a leech when it reaches the threshold,
+// and is handled again every half threshold after that if it keeps lapsing.
+//
+// Parameters:
+//   - lapses int : The number of lapses of the card, including the latest one.
+//
+// Returns:
+//   - bool : True if the leech action should be taken.
+func (p DeckPreset) IsLeech(lapses int) bool {
+	if lapses < p.LeechThreshold {
+		return false
+	}
+
+	repeat := max(p.LeechThreshold/2, 1)
+	return (lapses-p.LeechThreshold)%repeat == 0
+}
+
+// HandleLeech tags a card as a leech and takes the action of the preset,
+// suspending the card or marking it with the leech flag.
+//
+// Parameters:
+//   - card Card : The card that became a leech.
+//
+// Returns:
+//   - Card : The card with its tags, suspension and flag updated.
+func (p DeckPreset) HandleLeech(card Card) Card {
+	card.Tags = NormalizeTags(append(card.Tags, LeechTag))
+
+	if p.LeechAction == LeechActionSuspend {
+		card.Suspended = true
+	}
+	if p.LeechFlag != CardMinFlag {
+		card.Flag = p.LeechFlag
+	}
+
+	return card
+}
+
+// isIncreasing reports whether every step is positive and longer than the previous one.
+func isIncreasing(steps []int) bool {
+	previous := 0
+	for _, step := range steps {
+		if step <= previous {
+			return false
+		}
+		previous = step
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestDefaultDeckPreset(t *testing.T) {
+	t.Run("Default Preset Is Valid", func(t *testing.T) {
+		preset := DefaultDeckPreset()
+
+		assert.Nil(t, preset.Validate())
+		assert.Equal(t, SchedulerSM2, preset.Scheduler)
+	})
+}
+
+func TestDeckPresetValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(preset *DeckPreset)
+		valid  bool
+	}{
+		{name: "Empty Name", modify: func(p *DeckPreset) { p.Name = "" }},
+		{name: "Negative New Cards", modify: func(p *DeckPreset) { p.NewCardsPerDay = -1 }},
+		{name: "Negative Reviews", modify: func(p *DeckPreset) { p.MaxReviewsPerDay = -1 }},
+		{name: "No Learning Steps", modify: func(p *DeckPreset) { p.LearningSteps = []int{} }},
+		{name: "Decreasing Learning Steps", modify: func(p *DeckPreset) { p.LearningSteps = []int{10, 1} }},
+		{name: "Zero Relearning Step", modify: func(p *DeckPreset) { p.RelearningSteps = []int{0} }},
+		{name: "Zero Graduating Interval", modify: func(p *DeckPreset) { p.GraduatingInterval = 0 }},
+		{name: "Max Interval Below Graduating", modify: func(p *DeckPreset) { p.GraduatingInterval = 5; p.MaxInterval = 4 }},
+		{name: "Easy Bonus Below One", modify: func(p *DeckPreset) { p.EasyBonus = 0.9 }},
+		{name: "Unknown Scheduler", modify: func(p *DeckPreset) { p.Scheduler = "leitner" }},
+		{name: "Zero Leech Threshold", modify: func(p *DeckPreset) { p.LeechThreshold = 0 }},
+		{name: "Unknown Leech Action", modify: func(p *DeckPreset) { p.LeechAction = "delete" }},
+		{name: "Leech Flag Out Of Range", modify: func(p *DeckPreset) { p.LeechFlag = CardMaxFlag + 1 }},
+		{name: "Desired Retention Too Low", modify: func(p *DeckPreset) { p.DesiredRetention = 0.5 }},
+		{name: "Desired Retention Too High", modify: func(p *DeckPreset) { p.DesiredRetention = 1 }},
+		{name: "Ease Modifier Too Low", modify: func(p *DeckPreset) { p.EaseModifier = 0.4 }},
+		{name: "Ease Modifier Too High", modify: func(p *DeckPreset) { p.EaseModifier = 3 }},
+		{name: "Wrong Number Of FSRS Weights", modify: func(p *DeckPreset) { p.FSRSWeights = []float64{1, 2, 3} }},
+		{name: "Negative FSRS Weight", modify: func(p *DeckPreset) { p.FSRSWeights = make([]float64, FSRSWeightCount); p.FSRSWeights[3] = -1 }},
+		{name: "Fitted FSRS Weights", modify: func(p *DeckPreset) { p.FSRSWeights = make([]float64, FSRSWeightCount) }, valid: true},
+		{name: "Suspend Leeches", modify: func(p *DeckPreset) { p.LeechAction = LeechActionSuspend; p.LeechFlag = 2 }, valid: true},
+		{name: "No Relearning Steps", modify: func(p *DeckPreset) { p.RelearningSteps = []int{} }, valid: true},
+		{name: "FSRS Scheduler", modify: func(p *DeckPreset) { p.Scheduler = SchedulerFSRS }, valid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			preset := DefaultDeckPreset()
+			tc.modify(&preset)
+
+			if tc.valid {
+				assert.Nil(t, preset.Validate())
+			} else {
+				assert.NotNil(t, preset.Validate())
+			}
+		})
+	}
+}
+
+func TestDeckPresetSameScheduling(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(p *DeckPreset)
+		same   bool
+	}{
+		{name: "Other Name And Limits", modify: func(p *DeckPreset) { p.Name = "Other"; p.NewCardsPerDay = 5; p.LeechThreshold = 3 }, same: true},
+		{name: "No Weights", modify: func(p *DeckPreset) { p.FSRSWeights = nil }, same: true},
+		{name: "Other Scheduler", modify: func(p *DeckPreset) { p.Scheduler = SchedulerFSRS }},
+		{name: "Other Desired Retention", modify: func(p *DeckPreset) { p.DesiredRetention = 0.85 }},
+		{name: "Other Learning Steps", modify: func(p *DeckPreset) { p.LearningSteps = []int{5} }},
+		{name: "Fitted Weights", modify: func(p *DeckPreset) { p.FSRSWeights = make([]float64, FSRSWeightCount) }},
+		{name: "Other Ease Modifier", modify: func(p *DeckPreset) { p.EaseModifier = 1.2 }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			preset := DefaultDeckPreset()
+			tc.modify(&preset)
+			assert.Equal(t, tc.same, DefaultDeckPreset().SameScheduling(preset))
+		})
+	}
+}
+
+func TestDeckPresetLimitStudyQueue(t *testing.T) {
+	t.Run("Limits New And Review Cards", func(t *testing.T) {
+		preset := DefaultDeckPreset()
+		preset.NewCardsPerDay = 1
+		preset.MaxReviewsPerDay = 2
+
+		cards := []Card{
+			{ID: 1, State: CardStateNew},
+			{ID: 2, State: CardStateReview},
+			{ID: 3, State: CardStateLearning},
+			{ID: 4, State: CardStateNew},
+			{ID: 5, State: CardStateReview},
+			{ID: 6, State: CardStateRelearning},
+			{ID: 7, State: CardStateReview},
+		}
+
+		limited := preset.LimitStudyQueue(cards, StudyCounts{})
+
+		ids := []int{}
+		for _, card := range limited {
+			ids = append(ids, card.ID)
+		}
+		assert.Equal(t, []int{1, 2, 3, 5, 6}, ids)
+	})
+
+	t.Run("Takes Out Cards Studied Today", func(t *testing.T) {
+		preset := DefaultDeckPreset()
+		preset.NewCardsPerDay = 2
+		preset.MaxReviewsPerDay = 3
+
+		cards := []Card{
+			{ID: 1, State: CardStateNew},
+			{ID: 2, State: CardStateReview},
+			{ID: 3, State: CardStateLearning},
+			{ID: 4, State: CardStateNew},
+			{ID: 5, State: CardStateReview},
+		}
+
+		limited := preset.LimitStudyQueue(cards, StudyCounts{NewCards: 2, Reviews: 2})
+
+		ids := []int{}
+		for _, card := range limited {
+			ids = append(ids, card.ID)
+		}
+		assert.Equal(t, []int{2, 3}, ids)
+	})
+}
+
+func TestDeckPresetIsLeech(t *testing.T) {
+	preset := DefaultDeckPreset()
+	preset.LeechThreshold = 8
+
+	assert.False(t, preset.IsLeech(7))
+	assert.True(t, preset.IsLeech(8))
+	assert.False(t, preset.IsLeech(9))
+	assert.True(t, preset.IsLeech(12))
+	assert.True(t, preset.IsLeech(16))
+}
+
+func TestDeckPresetHandleLeech(t *testing.T) {
+	card := NewCard(0, "", "")
+	card.Tags = []string{"verbs"}
+
+	tagged := DefaultDeckPreset().HandleLeech(card)
+	assert.Equal(t, []string{"verbs", LeechTag}, tagged.Tags)
+	assert.False(t, tagged.Suspended)
+	assert.Equal(t, CardMinFlag, tagged.Flag)
+
+	preset := DefaultDeckPreset()
+	preset.LeechAction = LeechActionSuspend
+	preset.LeechFlag = 3
+	handled := preset.HandleLeech(tagged)
+	assert.Equal(t, []string{"verbs", LeechTag}, handled.Tags)
+	assert.True(t, handled.Suspended)
+	assert.Equal(t, 3, handled.Flag)
+}
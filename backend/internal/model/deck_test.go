@@ -48,3 +48,62 @@ func TestNewDeck(t *testing.T) {
 		}
 	})
 }
+
+func TestBuildDeckTree(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	t.Run("Empty Summaries", func(t *testing.T) {
+		roots := BuildDeckTree([]DeckSummary{})
+		assert.Equal(t, 0, len(roots))
+	})
+
+	t.Run("Nested Decks Aggregate Counts", func(t *testing.T) {
+		summaries := []DeckSummary{
+			{Deck: Deck{ID: 1, Name: "Languages"}, TotalCards: 1, NewCount: 1},
+			{Deck: Deck{ID: 2, Name: "Japanese", ParentID: intPtr(1)}, TotalCards: 10, NewCount: 2, ReviewCount: 3},
+			{Deck: Deck{ID: 3, Name: "Kanji", ParentID: intPtr(2)}, TotalCards: 5, LearningCount: 4, ReviewCount: 1},
+			{Deck: Deck{ID: 4, Name: "Spanish", ParentID: intPtr(1)}, TotalCards: 2},
+			{Deck: Deck{ID: 5, Name: "Orphan", ParentID: intPtr(99)}, TotalCards: 7},
+		}
+
+		roots := BuildDeckTree(summaries)
+
+		assert.Equal(t, 2, len(roots))
+		assert.Equal(t, 1, roots[0].ID)
+		assert.Equal(t, 5, roots[1].ID)
+
+		languages := roots[0]
+		assert.Equal(t, 18, languages.TotalCards)
+		assert.Equal(t, 3, languages.NewCount)
+		assert.Equal(t, 4, languages.LearningCount)
+		assert.Equal(t, 4, languages.ReviewCount)
+		assert.Equal(t, 2, len(languages.Children))
+
+		japanese := languages.Children[0]
+		assert.Equal(t, 2, japanese.ID)
+		assert.Equal(t, 15, japanese.TotalCards)
+		assert.Equal(t, 1, len(japanese.Children))
+		assert.Equal(t, 0, len(japanese.Children[0].Children))
+
+		assert.Equal(t, 7, roots[1].TotalCards)
+	})
+}
+
+func TestDeckSettings(t *testing.T) {
+	t.Run("Default Settings Without Preset", func(t *testing.T) {
+		deck := NewDeck("Name", "Description")
+		assert.Equal(t, DefaultDeckPreset(), deck.Settings())
+	})
+
+	t.Run("Preset Settings", func(t *testing.T) {
+		preset := DefaultDeckPreset()
+		preset.Name = "Exam"
+		preset.NewCardsPerDay = 50
+
+		deck := NewDeck("Name", "Description")
+		deck.Preset = &preset
+
+		assert.Equal(t, 50, deck.Settings().NewCardsPerDay)
+		assert.Equal(t, "Exam", deck.Settings().Name)
+	})
+}
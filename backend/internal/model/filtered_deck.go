@@ -0,0 +1,102 @@
+package model
+
+import (
+	"flash-learn/internal/utils"
+	"math/rand/v2"
+	"sort"
+)
+
+// Orders in which the cards matching the query of a filtered deck are picked.
+const (
+	FilteredOrderDue    = "due"
+	FilteredOrderRandom = "random"
+	FilteredOrderAdded  = "added"
+	FilteredOrderLapses = "lapses"
+)
+
+// FilteredDeckMaxLimit is the maximum number of cards a filtered deck can borrow.
+const FilteredDeckMaxLimit = 9999
+
+// FilteredDeck holds the search a filtered deck is built from. Building the
+// deck borrows up to Limit matching cards from their home decks, picked in
+// the given order. Cards already borrowed by another filtered deck, suspended
+// cards and buried cards are never picked. When Reschedule is false, answers
+// are logged as previews and leave the scheduling of the cards untouched.
+type FilteredDeck struct {
+	DeckID     int        `json:"deck_id"`
+	Query      CardFilter `json:"query"`
+	Order      string     `json:"order"`
+	Limit      int        `json:"limit"`
+	Reschedule bool       `json:"reschedule"`
+}
+
+// Validate checks that the order is known and the limit is in range.
+//
+// Returns:
+//   - error : utils.ErrInvalidFilteredDeck if the filtered deck can't be built, nil otherwise.
+func (f FilteredDeck) Validate() error {
+	switch f.Order {
+	case FilteredOrderDue, FilteredOrderRandom, FilteredOrderAdded, FilteredOrderLapses:
+	default:
+		return utils.ErrInvalidFilteredDeck
+	}
+
+	if f.Limit <= 0 || f.Limit > FilteredDeckMaxLimit || f.Query.AddedDays < 0 || f.Query.ForgottenDays < 0 {
+		return utils.ErrInvalidFilteredDeck
+	}
+
+	return nil
+}
+
+// Sort orders the cards of the filtered deck in its order, shuffling them
+// with rng for the random order. Ties are broken by card ID.
+//
+// Parameters:
+//   - cards []Card : The cards to sort in place.
+//   - rng *rand.Rand : The source of randomness of the random order.
+func (f FilteredDeck) Sort(cards []Card, rng *rand.Rand) {
+	if f.Order == FilteredOrderRandom {
+		rng.Shuffle(len(cards), func(i, j int) {
+			cards[i], cards[j] = cards[j], cards[i]
+		})
+		return
+	}
+
+	sort.SliceStable(cards, func(i, j int) bool {
+		a, b := cards[i], cards[j]
+		switch {
+		case f.Order == FilteredOrderDue && !a.NextReviewTime.Equal(b.NextReviewTime):
+			return a.NextReviewTime.Before(b.NextReviewTime)
+		case f.Order == FilteredOrderAdded && !a.CreationTime.Equal(b.CreationTime):
+			return a.CreationTime.After(b.CreationTime)
+		case f.Order == FilteredOrderLapses && a.Lapses != b.Lapses:
+			return a.Lapses > b.Lapses
+		}
+		return a.ID < b.ID
+	})
+}
+
+// Answered returns a card answered in the filtered deck to its home deck once
+// it is done with for the session. A rescheduled card goes home when it leaves
+// its learning or relearning steps, a previewed card when it isn't failed.
+//
+// Parameters:
+//   - card Card : The answered card, in the filtered deck.
+//   - grade int : The grade of the answer.
+//
+// Returns:
+//   - Card : The card, moved back to its home deck if it is done with.
+func (f FilteredDeck) Answered(card Card, grade int) Card {
+	if card.OriginalDeckID == nil {
+		return card
+	}
+
+	learning := card.State == CardStateLearning || card.State == CardStateRelearning
+	if (f.Reschedule && learning) || (!f.Reschedule && grade == GradeAgain) {
+		return card
+	}
+
+	card.DeckID = *card.OriginalDeckID
+	card.OriginalDeckID = nil
+	return card
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestFilteredDeckValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filtered FilteredDeck
+		valid    bool
+	}{
+		{name: "Unknown Order", filtered: FilteredDeck{Order: "oldest", Limit: 10}},
+		{name: "Zero Limit", filtered: FilteredDeck{Order: FilteredOrderDue}},
+		{name: "Limit Too High", filtered: FilteredDeck{Order: FilteredOrderDue, Limit: FilteredDeckMaxLimit + 1}},
+		{name: "Negative Added Days", filtered: FilteredDeck{Order: FilteredOrderAdded, Limit: 10, Query: CardFilter{AddedDays: -1}}},
+		{name: "Negative Forgotten Days", filtered: FilteredDeck{Order: FilteredOrderLapses, Limit: 10, Query: CardFilter{ForgottenDays: -1}}},
+		{name: "Valid", filtered: FilteredDeck{Order: FilteredOrderRandom, Limit: FilteredDeckMaxLimit, Query: CardFilter{Tags: []string{"verbs"}}}, valid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, tc.filtered.Validate() == nil)
+		})
+	}
+}
+
+func TestFilteredDeckSort(t *testing.T) {
+	now := time.Now()
+	cards := func() []Card {
+		return []Card{
+			{ID: 0, NextReviewTime: now.Add(time.Hour), CreationTime: now.Add(-time.Hour), Lapses: 1},
+			{ID: 1, NextReviewTime: now, CreationTime: now, Lapses: 3},
+			{ID: 2, NextReviewTime: now, CreationTime: now.Add(-2 * time.Hour), Lapses: 1},
+		}
+	}
+	ids := func(cards []Card) []int {
+		result := []int{}
+		for _, card := range cards {
+			result = append(result, card.ID)
+		}
+		return result
+	}
+
+	testCases := []struct {
+		order    string
+		expected []int
+	}{
+		{order: FilteredOrderDue, expected: []int{1, 2, 0}},
+		{order: FilteredOrderAdded, expected: []int{1, 0, 2}},
+		{order: FilteredOrderLapses, expected: []int{1, 0, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.order, func(t *testing.T) {
+			sorted := cards()
+			FilteredDeck{Order: tc.order}.Sort(sorted, nil)
+			assert.Equal(t, tc.expected, ids(sorted))
+		})
+	}
+
+	t.Run(FilteredOrderRandom, func(t *testing.T) {
+		first, second := cards(), cards()
+		FilteredDeck{Order: FilteredOrderRandom}.Sort(first, rand.New(rand.NewPCG(1, 0)))
+		FilteredDeck{Order: FilteredOrderRandom}.Sort(second, rand.New(rand.NewPCG(1, 0)))
+		assert.Equal(t, ids(first), ids(second), "Expected the same seed to give the same order")
+		assert.Equal(t, 3, len(first))
+	})
+}
+
+func TestFilteredDeckAnswered(t *testing.T) {
+	homeDeckID := 4
+	borrowed := func(state int) Card {
+		return Card{DeckID: 7, OriginalDeckID: &homeDeckID, State: state}
+	}
+
+	testCases := []struct {
+		name       string
+		reschedule bool
+		card       Card
+		grade      int
+		goesHome   bool
+	}{
+		{name: "Rescheduled Card Graduates", reschedule: true, card: borrowed(CardStateReview), grade: GradeGood, goesHome: true},
+		{name: "Rescheduled Card Still Learning", reschedule: true, card: borrowed(CardStateLearning), grade: GradeGood},
+		{name: "Rescheduled Card Relearning", reschedule: true, card: borrowed(CardStateRelearning), grade: GradeAgain},
+		{name: "Previewed Card Passed", card: borrowed(CardStateLearning), grade: GradeHard, goesHome: true},
+		{name: "Previewed Card Failed", card: borrowed(CardStateReview), grade: GradeAgain},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			card := FilteredDeck{DeckID: 7, Reschedule: tc.reschedule}.Answered(tc.card, tc.grade)
+
+			if tc.goesHome {
+				assert.Equal(t, homeDeckID, card.DeckID)
+				assert.Nil(t, card.OriginalDeckID)
+			} else {
+				assert.Equal(t, 7, card.DeckID)
+				assert.Equal(t, &homeDeckID, card.OriginalDeckID)
+			}
+		})
+	}
+
+	t.Run("Card In Home Deck", func(t *testing.T) {
+		card := Card{DeckID: homeDeckID}
+		assert.Equal(t, card, FilteredDeck{DeckID: 7}.Answered(card, GradeGood))
+	})
+}
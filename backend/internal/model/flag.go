@@ -0,0 +1,38 @@
+package model
+
+import (
+	"flash-learn/internal/utils"
+	"regexp"
+	"strings"
+)
+
+// FlagNameMaxLength is the maximum length of the name of a flag.
+const FlagNameMaxLength = 32
+
+var flagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// FlagDefinition gives a name and a color to one of the flag values a card
+// can be marked with, for example "red" and "#e53935" for flag 1.
+type FlagDefinition struct {
+	Flag  int    `json:"flag"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// Validate checks that the flag value can be defined, that the name isn't
+// empty and that the color is a hexadecimal RGB color such as "#e53935".
+//
+// Returns:
+//   - error : utils.ErrInvalidFlag if the definition is invalid, nil otherwise.
+func (f FlagDefinition) Validate() error {
+	if f.Flag <= CardMinFlag || f.Flag > CardMaxFlag {
+		return utils.ErrInvalidFlag
+	}
+
+	name := strings.TrimSpace(f.Name)
+	if name == "" || len(name) > FlagNameMaxLength || !flagColorPattern.MatchString(f.Color) {
+		return utils.ErrInvalidFlag
+	}
+
+	return nil
+}
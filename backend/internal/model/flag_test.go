@@ -0,0 +1,35 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestFlagDefinitionValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		definition FlagDefinition
+		valid      bool
+	}{
+		{name: "No Flag", definition: FlagDefinition{Flag: CardMinFlag, Name: "Red", Color: "#e53935"}},
+		{name: "Flag Too High", definition: FlagDefinition{Flag: CardMaxFlag + 1, Name: "Red", Color: "#e53935"}},
+		{name: "Blank Name", definition: FlagDefinition{Flag: 1, Name: "  ", Color: "#e53935"}},
+		{name: "Name Too Long", definition: FlagDefinition{Flag: 1, Name: strings.Repeat("a", FlagNameMaxLength+1), Color: "#e53935"}},
+		{name: "Color Name", definition: FlagDefinition{Flag: 1, Name: "Red", Color: "red"}},
+		{name: "Short Color", definition: FlagDefinition{Flag: 1, Name: "Red", Color: "#e53"}},
+		{name: "Valid", definition: FlagDefinition{Flag: 1, Name: "Red", Color: "#E53935"}, valid: true},
+		{name: "Highest Flag", definition: FlagDefinition{Flag: CardMaxFlag, Name: "Gray", Color: "#9e9e9e"}, valid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.valid {
+				assert.Nil(t, tc.definition.Validate())
+			} else {
+				assert.NotNil(t, tc.definition.Validate())
+			}
+		})
+	}
+}
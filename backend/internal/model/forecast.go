@@ -0,0 +1,79 @@
+package model
+
+// MatureInterval is the interval in days from which a card in review is mature.
+const MatureInterval = 21
+
+// DefaultPassRate is the pass rate assumed for a kind of review that has no history.
+const DefaultPassRate = 0.9
+
+// Series a forecast day is split into, in the order they are stacked.
+const (
+	ForecastSeriesLearning = "learning"
+	ForecastSeriesYoung    = "young"
+	ForecastSeriesMature   = "mature"
+)
+
+// ForecastSeries are the names of the series of a forecast, in stacking order.
+var ForecastSeries = []string{ForecastSeriesLearning, ForecastSeriesYoung, ForecastSeriesMature}
+
+// ForecastDay is the number of reviews expected on a study day, split by the
+// kind of card reviewed. Learning counts cards in learning or relearning,
+// young and mature count cards in review below and from MatureInterval days.
+type ForecastDay struct {
+	Date     string `json:"date"`
+	Learning int    `json:"learning"`
+	Young    int    `json:"young"`
+	Mature   int    `json:"mature"`
+	Total    int    `json:"total"`
+}
+
+// Forecast is the expected workload of a deck over the coming days.
+// The first day includes the cards that are overdue.
+type Forecast struct {
+	Timezone  string             `json:"timezone"`
+	Series    []string           `json:"series"`
+	PassRates map[string]float64 `json:"pass_rates"`
+	Days      []ForecastDay      `json:"days"`
+}
+
+// ComputePassRates computes the share of reviews that weren't forgotten,
+// for every kind of review. Kinds without history get DefaultPassRate.
+//
+// Parameters:
+//   - reviews []Review : The review history.
+//
+// Returns:
+//   - map[string]float64 : The pass rates, by review kind.
+func ComputePassRates(reviews []Review) map[string]float64 {
+	total := make(map[string]int)
+	passed := make(map[string]int)
+	for _, review := range reviews {
+		total[review.Kind]++
+		if review.Grade != GradeAgain {
+			passed[review.Kind]++
+		}
+	}
+
+	rates := make(map[string]float64)
+	for _, kind := range []string{ReviewKindLearn, ReviewKindReview, ReviewKindRelearn} {
+		rates[kind] = DefaultPassRate
+		if total[kind] > 0 {
+			rates[kind] = ratio(passed[kind], total[kind])
+		}
+	}
+
+	return rates
+}
+
+// Add counts a card due on the day in the series matching its state.
+func (d *ForecastDay) Add(card Card) {
+	switch {
+	case card.State != CardStateReview:
+		d.Learning++
+	case card.Interval < MatureInterval:
+		d.Young++
+	default:
+		d.Mature++
+	}
+	d.Total++
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestComputePassRates(t *testing.T) {
+	reviews := []Review{
+		{Kind: ReviewKindReview, Grade: GradeGood},
+		{Kind: ReviewKindReview, Grade: GradeHard},
+		{Kind: ReviewKindReview, Grade: GradeGood},
+		{Kind: ReviewKindReview, Grade: GradeAgain},
+		{Kind: ReviewKindLearn, Grade: GradeAgain},
+	}
+
+	rates := ComputePassRates(reviews)
+
+	assert.Equal(t, 0.75, rates[ReviewKindReview])
+	assert.Equal(t, 0.0, rates[ReviewKindLearn])
+	assert.Equal(t, DefaultPassRate, rates[ReviewKindRelearn])
+}
+
+func TestForecastDayAdd(t *testing.T) {
+	day := ForecastDay{}
+	card := NewCard(0, "", "")
+
+	card.State = CardStateRelearning
+	day.Add(card)
+	card.State = CardStateReview
+	card.Interval = MatureInterval - 1
+	day.Add(card)
+	card.Interval = MatureInterval
+	day.Add(card)
+
+	assert.Equal(t, ForecastDay{Learning: 1, Young: 1, Mature: 1, Total: 3}, day)
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a background job. A queued job waits for a worker, possibly
+// until a retry is due, and a running job is leased by a worker until its
+// lease expires. The other statuses are final.
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// Kinds of background jobs, each run by its own handler. The purge_trash job
+// is recurring and has no payload.
+const (
+	JobKindReschedule = "reschedule"
+	JobKindOptimize   = "optimize"
+	JobKindPurgeTrash = "purge_trash"
+)
+
+// JobDefaultMaxAttempts is how many times a job is run before it fails for good.
+const JobDefaultMaxAttempts = 5
+
+// Job is a task run in the background by a worker. Payload holds the input of
+// the job and Checkpoint the state it saved along with its progress, so that
+// a job interrupted by a crash or a shutdown resumes where it left off.
+// Result holds the output of a job that succeeded, if its kind has one.
+// Progress and Total count the units of work of the job, done and overall.
+// AppliedAt is set once the result of a job that succeeded is applied, for
+// the kinds whose result is applied by a later request.
+type Job struct {
+	ID              int             `json:"id"`
+	Kind            string          `json:"kind"`
+	Payload         json.RawMessage `json:"payload"`
+	Checkpoint      json.RawMessage `json:"-"`
+	Result          json.RawMessage `json:"result,omitempty"`
+	Status          string          `json:"status"`
+	Progress        int             `json:"progress"`
+	Total           int             `json:"total"`
+	Attempts        int             `json:"attempts"`
+	MaxAttempts     int             `json:"max_attempts"`
+	LastError       string          `json:"error,omitempty"`
+	CancelRequested bool            `json:"cancel_requested"`
+	RunAt           time.Time       `json:"run_at"`
+	LeaseOwner      string          `json:"-"`
+	LeaseUntil      *time.Time      `json:"-"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+	AppliedAt       *time.Time      `json:"applied_at,omitempty"`
+}
+
+// ReschedulePayload is the payload of a reschedule job, which replays the
+// review log of the cards of some decks through their current settings.
+type ReschedulePayload struct {
+	DeckIDs []int `json:"deck_ids"`
+}
+
+// RescheduleCheckpoint is the state a reschedule job saves after every batch
+// of cards, which are processed by ascending ID.
+type RescheduleCheckpoint struct {
+	LastCardID int `json:"last_card_id"`
+}
+
+// OptimizePayload is the payload of an optimize job, which fits the parameters
+// of a scheduler to the review log of a deck and its sub-decks. The result of
+// the job is a model.OptimizeResult.
+type OptimizePayload struct {
+	DeckID    int    `json:"deck_id"`
+	Scheduler string `json:"scheduler"`
+}
+
+// JobCount is the number of jobs of a kind in a status.
+type JobCount struct {
+	Kind   string
+	Status string
+	Count  int
+}
+
+// NewJob creates a queued job that can run right away.
+//
+// Parameters:
+//   - kind string : The kind of the job, selecting its handler.
+//   - payload any : The input of the job, encoded as JSON.
+//   - now time.Time : The time the job is created.
+//
+// Returns:
+//   - Job : The queued job.
+//   - error : An error if the payload can't be encoded, nil otherwise.
+func NewJob(kind string, payload any, now time.Time) (Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, err
+	}
+
+	return Job{
+		Kind:        kind,
+		Payload:     encoded,
+		Status:      JobStatusQueued,
+		MaxAttempts: JobDefaultMaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// Finished reports whether the job reached a final status.
+func (j Job) Finished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestNewJob(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	job, err := NewJob(JobKindReschedule, ReschedulePayload{DeckIDs: []int{1, 2}}, now)
+	assert.Nil(t, err)
+	assert.Equal(t, JobStatusQueued, job.Status)
+	assert.Equal(t, `{"deck_ids":[1,2]}`, string(job.Payload))
+	assert.Equal(t, JobDefaultMaxAttempts, job.MaxAttempts)
+	assert.Equal(t, now, job.RunAt)
+	assert.False(t, job.Finished())
+
+	_, err = NewJob(JobKindReschedule, make(chan int), now)
+	assert.NotNil(t, err)
+}
+
+func TestJobFinished(t *testing.T) {
+	for status, finished := range map[string]bool{
+		JobStatusQueued:    false,
+		JobStatusRunning:   false,
+		JobStatusSucceeded: true,
+		JobStatusFailed:    true,
+		JobStatusCancelled: true,
+	} {
+		assert.Equal(t, finished, Job{Status: status}.Finished(), status)
+	}
+}
@@ -0,0 +1,37 @@
+package model
+
+// OptimizeResult is the outcome of fitting the parameters of a scheduler to
+// the review log of a deck. The log-loss measures how well the parameters
+// predict whether the reviewed cards were recalled, lower being better.
+// Only the parameters of the fitted scheduler are set. Preset is the preset of
+// the deck when the fit started, nil if the deck used the default settings,
+// and the parameters are only applied to it while its scheduling settings are
+// unchanged. Once the parameters are applied, the cards of the decks of the
+// preset are rescheduled by the job RescheduleJobID, if any.
+type OptimizeResult struct {
+	Scheduler       string      `json:"scheduler"`
+	Reviews         int         `json:"reviews"`
+	LogLossBefore   float64     `json:"log_loss_before"`
+	LogLossAfter    float64     `json:"log_loss_after"`
+	FSRSWeights     []float64   `json:"fsrs_weights,omitempty"`
+	EaseModifier    float64     `json:"ease_modifier,omitempty"`
+	Preset          *DeckPreset `json:"preset,omitempty"`
+	Applied         bool        `json:"applied"`
+	RescheduleJobID *int        `json:"reschedule_job_id,omitempty"`
+}
+
+// Apply returns the preset with the fitted parameters of the result.
+//
+// Parameters:
+//   - preset DeckPreset : The preset to update.
+//
+// Returns:
+//   - DeckPreset : The preset using the fitted parameters.
+func (r OptimizeResult) Apply(preset DeckPreset) DeckPreset {
+	if r.Scheduler == SchedulerFSRS {
+		preset.FSRSWeights = append([]float64{}, r.FSRSWeights...)
+	} else {
+		preset.EaseModifier = r.EaseModifier
+	}
+	return preset
+}
@@ -0,0 +1,120 @@
+package model
+
+import "time"
+
+// Grades a card can be answered with, from forgotten to effortless recall.
+const (
+	GradeAgain = iota + 1
+	GradeHard
+	GradeGood
+	GradeEasy
+)
+
+// Kinds of entries in the review log, telling which phase of
+// scheduling the card was in when it was answered. Previews and cram answers
+// didn't change the scheduling of the card.
+const (
+	ReviewKindLearn   = "learn"
+	ReviewKindReview  = "review"
+	ReviewKindRelearn = "relearn"
+	ReviewKindPreview = "preview"
+	ReviewKindCram    = "cram"
+)
+
+// Review is an entry of the review log, recorded every time a card is answered.
+// State, LastInterval and the scheduling fields before the answer let the log
+// be replayed, while Interval, Ease, Stability and Difficulty are the values
+// the card was given by the answer.
+type Review struct {
+	ID           int       `json:"id"`
+	CardID       int       `json:"card_id"`
+	DeckID       int       `json:"deck_id"`
+	Grade        int       `json:"grade"`
+	Kind         string    `json:"kind"`
+	State        int       `json:"state"`
+	Interval     int       `json:"interval"`
+	LastInterval int       `json:"last_interval"`
+	Ease         float64   `json:"ease"`
+	Stability    float64   `json:"stability"`
+	Difficulty   float64   `json:"difficulty"`
+	Duration     int       `json:"duration_ms"`
+	ReviewTime   time.Time `json:"review_time"`
+}
+
+// ValidGrade reports whether the grade is one of the grades a card can be answered with.
+func ValidGrade(grade int) bool {
+	return grade >= GradeAgain && grade <= GradeEasy
+}
+
+// ReviewKindOf returns the kind of review log entry for a card answered in the given state.
+func ReviewKindOf(state int) string {
+	switch state {
+	case CardStateReview:
+		return ReviewKindReview
+	case CardStateRelearning:
+		return ReviewKindRelearn
+	default:
+		return ReviewKindLearn
+	}
+}
+
+// IsLapse reports whether the review is a forgotten card that had graduated.
+func (r Review) IsLapse() bool {
+	return r.Kind == ReviewKindReview && r.Grade == GradeAgain
+}
+
+// CardAnswer is what an answer changes on a card. Card holds the new
+// scheduling state, which replaces the one saved, while the leech changes and
+// the return to the home deck are applied to the card as it is saved, so
+// that the edits made to it since it was read are kept.
+type CardAnswer struct {
+	// Card is the answered card with its new scheduling state.
+	Card Card
+	// PreviousReviewTime is the last review time of the card when it was
+	// read. The answer is refused if the card was reviewed since.
+	PreviousReviewTime *time.Time
+	// AddTags are the tags the answer adds to the card, such as LeechTag.
+	AddTags []string
+	// Suspend suspends the card.
+	Suspend bool
+	// Flag is the flag the answer marks the card with, nil to keep its flag.
+	Flag *int
+	// ReturnHome moves a card borrowed by a filtered deck back to its home deck.
+	ReturnHome bool
+}
+
+// NewCardAnswer compares a card before and after an answer to find what the
+// answer changes on it.
+//
+// Parameters:
+//   - before Card : The card as it was read, before the answer.
+//   - after Card : The answered card.
+//
+// Returns:
+//   - CardAnswer : The changes of the answer.
+func NewCardAnswer(before Card, after Card) CardAnswer {
+	answer := CardAnswer{
+		Card:               after,
+		PreviousReviewTime: before.LastReviewTime,
+		AddTags:            []string{},
+		Suspend:            after.Suspended && !before.Suspended,
+		ReturnHome:         before.OriginalDeckID != nil && after.OriginalDeckID == nil,
+	}
+
+	tags := make(map[string]bool)
+	for _, tag := range before.Tags {
+		tags[tag] = true
+	}
+	for _, tag := range NormalizeTags(after.Tags) {
+		if !tags[tag] {
+			answer.AddTags = append(answer.AddTags, tag)
+		}
+	}
+
+	if after.Flag != before.Flag {
+		flag := after.Flag
+		answer.Flag = &flag
+	}
+
+	return answer
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestNewCardAnswer(t *testing.T) {
+	reviewed := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	home := 4
+	before := Card{ID: 1, DeckID: 9, Tags: []string{"verb"}, Flag: 2, LastReviewTime: &reviewed, OriginalDeckID: &home}
+
+	t.Run("Scheduling Only", func(t *testing.T) {
+		after := before
+		after.Interval = 3
+
+		answer := NewCardAnswer(before, after)
+
+		assert.Equal(t, after, answer.Card)
+		assert.Equal(t, &reviewed, answer.PreviousReviewTime)
+		assert.Empty(t, answer.AddTags)
+		assert.False(t, answer.Suspend)
+		assert.Nil(t, answer.Flag)
+		assert.False(t, answer.ReturnHome)
+	})
+
+	t.Run("Leech Back Home", func(t *testing.T) {
+		after := DeckPreset{LeechAction: LeechActionSuspend, LeechFlag: 5}.HandleLeech(before)
+		after.DeckID = home
+		after.OriginalDeckID = nil
+
+		answer := NewCardAnswer(before, after)
+
+		assert.Equal(t, []string{LeechTag}, answer.AddTags)
+		assert.True(t, answer.Suspend)
+		if assert.NotNil(t, answer.Flag) {
+			assert.Equal(t, 5, *answer.Flag)
+		}
+		assert.True(t, answer.ReturnHome)
+	})
+}
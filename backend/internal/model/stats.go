@@ -0,0 +1,303 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// DateLayout is the layout of the dates used by the statistics.
+const DateLayout = "2006-01-02"
+
+// Range of the hour at which a study day starts.
+const (
+	MinRolloverHour = 0
+	MaxRolloverHour = 23
+)
+
+// StudyCalendar splits time into the study days of a user, which start at
+// the rollover hour in the user's timezone. With a rollover hour of 4, a
+// review done at 1am still counts towards the previous day.
+type StudyCalendar struct {
+	Location     *time.Location
+	RolloverHour int
+}
+
+// Day returns the date of the study day the given time falls in, at midnight.
+func (c StudyCalendar) Day(t time.Time) time.Time {
+	local := t.In(c.Location)
+	year, month, day := local.Date()
+	if local.Hour() < c.RolloverHour {
+		day--
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, c.Location)
+}
+
+// Start returns the time the study day of the given date starts at.
+func (c StudyCalendar) Start(date time.Time) time.Time {
+	year, month, day := date.Date()
+	return time.Date(year, month, day, c.RolloverHour, 0, 0, 0, c.Location)
+}
+
+// Date returns the study day the given time falls in, formatted with DateLayout.
+func (c StudyCalendar) Date(t time.Time) string {
+	return c.Day(t).Format(DateLayout)
+}
+
+// DailyStats holds the reviews done on a single study day.
+type DailyStats struct {
+	Date      string `json:"date"`
+	Reviews   int    `json:"reviews"`
+	StudyTime int    `json:"study_time_ms"`
+}
+
+// HeatmapDay holds the reviews done on a single study day, with the study
+// time in minutes as shown on the heatmap.
+type HeatmapDay struct {
+	Date    string  `json:"date"`
+	Reviews int     `json:"reviews"`
+	Minutes float64 `json:"minutes"`
+}
+
+// Heatmap is the study activity of every day of a year, with the streaks of
+// that year. For the current year, the current streak ends today.
+type Heatmap struct {
+	Year         int          `json:"year"`
+	Timezone     string       `json:"timezone"`
+	RolloverHour int          `json:"rollover_hour"`
+	Reviews      int          `json:"reviews"`
+	Days         []HeatmapDay `json:"days"`
+	Streak       Streak       `json:"streak"`
+}
+
+// RetentionBucket holds the true retention of the cards in review whose
+// interval was within [MinInterval, MaxInterval] days, MaxInterval 0
+// meaning no upper bound.
+type RetentionBucket struct {
+	MinInterval int     `json:"min_interval"`
+	MaxInterval int     `json:"max_interval"`
+	Reviews     int     `json:"reviews"`
+	Passed      int     `json:"passed"`
+	Retention   float64 `json:"retention"`
+}
+
+// HourStats holds the reviews done in one hour of the day.
+type HourStats struct {
+	Hour      int     `json:"hour"`
+	Reviews   int     `json:"reviews"`
+	Passed    int     `json:"passed"`
+	Retention float64 `json:"retention"`
+}
+
+// Streak holds the number of consecutive study days with at least one review.
+// The current streak ends on the last day of the range, or the day before if
+// nothing has been studied on the last day yet.
+type Streak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+// RetentionLevelCount is the number of cards at a retention level.
+type RetentionLevelCount struct {
+	RetentionLevel int `json:"retention_level"`
+	Cards          int `json:"cards"`
+}
+
+// StudyStats are the statistics of the reviews done between two dates,
+// From and To included.
+type StudyStats struct {
+	From            string                `json:"from"`
+	To              string                `json:"to"`
+	Timezone        string                `json:"timezone"`
+	RolloverHour    int                   `json:"rollover_hour"`
+	Reviews         int                   `json:"reviews"`
+	StudyTime       int                   `json:"study_time_ms"`
+	Days            []DailyStats          `json:"days"`
+	Retention       []RetentionBucket     `json:"retention"`
+	Hours           []HourStats           `json:"hours"`
+	Streak          Streak                `json:"streak"`
+	RetentionLevels []RetentionLevelCount `json:"retention_levels"`
+}
+
+// RetentionBucketBounds are the lower interval bounds, in days, of the
+// buckets the true retention is split into.
+var RetentionBucketBounds = []int{1, 7, 21, 90, 365}
+
+// ComputeStudyStats computes the statistics of the reviews done between two
+// study days. True retention only counts the reviews of cards that had
+// graduated, since learning steps are too short to say anything about memory.
+//
+// Parameters:
+//   - reviews []Review : The reviews done in the range.
+//   - levels []RetentionLevelCount : The number of cards at every retention level.
+//   - calendar StudyCalendar : The calendar the study days are taken from.
+//   - from time.Time : The date of the first study day of the range, at midnight.
+//   - to time.Time : The date of the last study day of the range, at midnight.
+//
+// Returns:
+//   - StudyStats : The statistics, with an entry for every day of the range.
+func ComputeStudyStats(reviews []Review, levels []RetentionLevelCount, calendar StudyCalendar, from time.Time, to time.Time) StudyStats {
+	stats := StudyStats{
+		From:            from.Format(DateLayout),
+		To:              to.Format(DateLayout),
+		Timezone:        calendar.Location.String(),
+		RolloverHour:    calendar.RolloverHour,
+		Retention:       make([]RetentionBucket, len(RetentionBucketBounds)),
+		Hours:           make([]HourStats, 24),
+		RetentionLevels: levels,
+	}
+
+	days, dayIndex := emptyDays(from, to)
+	stats.Days = days
+
+	for i, bound := range RetentionBucketBounds {
+		stats.Retention[i].MinInterval = bound
+		if i+1 < len(RetentionBucketBounds) {
+			stats.Retention[i].MaxInterval = RetentionBucketBounds[i+1] - 1
+		}
+	}
+	for hour := range stats.Hours {
+		stats.Hours[hour].Hour = hour
+	}
+
+	for _, review := range reviews {
+		index, ok := dayIndex[calendar.Date(review.ReviewTime)]
+		if !ok {
+			continue
+		}
+		passed := review.Grade != GradeAgain
+
+		stats.Reviews++
+		stats.StudyTime += review.Duration
+		stats.Days[index].Reviews++
+		stats.Days[index].StudyTime += review.Duration
+
+		hour := &stats.Hours[review.ReviewTime.In(calendar.Location).Hour()]
+		hour.Reviews++
+		if passed {
+			hour.Passed++
+		}
+
+		if review.Kind == ReviewKindReview {
+			bucket := &stats.Retention[retentionBucketIndex(review.LastInterval)]
+			bucket.Reviews++
+			if passed {
+				bucket.Passed++
+			}
+		}
+	}
+
+	for i := range stats.Retention {
+		stats.Retention[i].Retention = ratio(stats.Retention[i].Passed, stats.Retention[i].Reviews)
+	}
+	for i := range stats.Hours {
+		stats.Hours[i].Retention = ratio(stats.Hours[i].Passed, stats.Hours[i].Reviews)
+	}
+	stats.Streak = ComputeStreak(stats.Days)
+
+	return stats
+}
+
+// ComputeHeatmap computes the study activity of every day of a year.
+//
+// Parameters:
+//   - reviews []Review : The reviews done in the year.
+//   - calendar StudyCalendar : The calendar the study days are taken from.
+//   - year int : The year.
+//   - now time.Time : The current time, after which days don't break the current streak.
+//
+// Returns:
+//   - Heatmap : The activity of every day of the year.
+func ComputeHeatmap(reviews []Review, calendar StudyCalendar, year int, now time.Time) Heatmap {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, calendar.Location)
+	to := time.Date(year, time.December, 31, 0, 0, 0, 0, calendar.Location)
+	days, dayIndex := emptyDays(from, to)
+
+	heatmap := Heatmap{
+		Year:         year,
+		Timezone:     calendar.Location.String(),
+		RolloverHour: calendar.RolloverHour,
+		Days:         make([]HeatmapDay, len(days)),
+	}
+
+	for _, review := range reviews {
+		if index, ok := dayIndex[calendar.Date(review.ReviewTime)]; ok {
+			heatmap.Reviews++
+			days[index].Reviews++
+			days[index].StudyTime += review.Duration
+		}
+	}
+
+	for i, day := range days {
+		minutes := float64(day.StudyTime) / float64(time.Minute/time.Millisecond)
+		heatmap.Days[i] = HeatmapDay{Date: day.Date, Reviews: day.Reviews, Minutes: math.Round(minutes*10) / 10}
+	}
+
+	past := days
+	if index, ok := dayIndex[calendar.Date(now)]; ok {
+		past = days[:index+1]
+	}
+	heatmap.Streak = ComputeStreak(past)
+
+	return heatmap
+}
+
+// ComputeStreak computes the current and longest streaks of consecutive days.
+//
+// Parameters:
+//   - days []DailyStats : Every day of a range, in order.
+//
+// Returns:
+//   - Streak : The streaks of days with at least one review.
+func ComputeStreak(days []DailyStats) Streak {
+	streak := Streak{}
+	run := 0
+	for _, day := range days {
+		if day.Reviews == 0 {
+			run = 0
+			continue
+		}
+		run++
+		streak.Longest = max(streak.Longest, run)
+	}
+
+	end := len(days) - 1
+	if end >= 0 && days[end].Reviews == 0 {
+		end--
+	}
+	for i := end; i >= 0 && days[i].Reviews > 0; i-- {
+		streak.Current++
+	}
+
+	return streak
+}
+
+// emptyDays returns a day without reviews for every day between two dates,
+// both included, along with the index of every date.
+func emptyDays(from time.Time, to time.Time) ([]DailyStats, map[string]int) {
+	days := []DailyStats{}
+	dayIndex := make(map[string]int)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		dayIndex[day.Format(DateLayout)] = len(days)
+		days = append(days, DailyStats{Date: day.Format(DateLayout)})
+	}
+	return days, dayIndex
+}
+
+// retentionBucketIndex returns the index of the retention bucket of an interval.
+func retentionBucketIndex(interval int) int {
+	index := 0
+	for i, bound := range RetentionBucketBounds {
+		if interval >= bound {
+			index = i
+		}
+	}
+	return index
+}
+
+// ratio returns part divided by total, 0 if total is 0.
+func ratio(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
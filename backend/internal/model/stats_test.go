@@ -0,0 +1,119 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestComputeStudyStats(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+	calendar := StudyCalendar{Location: paris}
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, paris)
+	to := time.Date(2024, 3, 3, 0, 0, 0, 0, paris)
+
+	reviews := []Review{
+		// 23:30 UTC on February 29 is already March 1 in Paris.
+		{Grade: GradeGood, Kind: ReviewKindLearn, Duration: 1000, ReviewTime: time.Date(2024, 2, 29, 23, 30, 0, 0, time.UTC)},
+		{Grade: GradeAgain, Kind: ReviewKindReview, LastInterval: 3, Duration: 2000, ReviewTime: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)},
+		{Grade: GradeGood, Kind: ReviewKindReview, LastInterval: 30, Duration: 3000, ReviewTime: time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC)},
+		{Grade: GradeHard, Kind: ReviewKindReview, LastInterval: 45, Duration: 4000, ReviewTime: time.Date(2024, 3, 3, 8, 30, 0, 0, time.UTC)},
+		// Outside of the range.
+		{Grade: GradeGood, Kind: ReviewKindReview, LastInterval: 1, Duration: 5000, ReviewTime: time.Date(2024, 3, 3, 23, 30, 0, 0, time.UTC)},
+	}
+	levels := []RetentionLevelCount{{RetentionLevel: 0, Cards: 2}}
+
+	stats := ComputeStudyStats(reviews, levels, calendar, from, to)
+
+	assert.Equal(t, "2024-03-01", stats.From)
+	assert.Equal(t, "2024-03-03", stats.To)
+	assert.Equal(t, "Europe/Paris", stats.Timezone)
+	assert.Equal(t, 4, stats.Reviews)
+	assert.Equal(t, 10000, stats.StudyTime)
+	assert.Equal(t, []DailyStats{
+		{Date: "2024-03-01", Reviews: 2, StudyTime: 3000},
+		{Date: "2024-03-02", Reviews: 0, StudyTime: 0},
+		{Date: "2024-03-03", Reviews: 2, StudyTime: 7000},
+	}, stats.Days)
+
+	assert.Equal(t, 1, stats.Retention[0].Reviews)
+	assert.Equal(t, 0.0, stats.Retention[0].Retention)
+	assert.Equal(t, 2, stats.Retention[2].Reviews)
+	assert.Equal(t, 1.0, stats.Retention[2].Retention)
+	assert.Equal(t, 89, stats.Retention[2].MaxInterval)
+	assert.Equal(t, 0, stats.Retention[len(stats.Retention)-1].MaxInterval)
+
+	assert.Equal(t, 24, len(stats.Hours))
+	assert.Equal(t, 1, stats.Hours[0].Reviews)
+	assert.Equal(t, 3, stats.Hours[9].Reviews)
+	assert.Equal(t, 2, stats.Hours[9].Passed)
+
+	assert.Equal(t, Streak{Current: 1, Longest: 1}, stats.Streak)
+	assert.Equal(t, levels, stats.RetentionLevels)
+}
+
+func TestComputeStreak(t *testing.T) {
+	days := func(reviews ...int) []DailyStats {
+		stats := []DailyStats{}
+		for _, count := range reviews {
+			stats = append(stats, DailyStats{Reviews: count})
+		}
+		return stats
+	}
+
+	testCases := []struct {
+		name     string
+		days     []DailyStats
+		expected Streak
+	}{
+		{name: "No Days", days: days(), expected: Streak{}},
+		{name: "No Reviews", days: days(0, 0), expected: Streak{}},
+		{name: "Studied Today", days: days(1, 0, 2, 3, 1), expected: Streak{Current: 3, Longest: 3}},
+		{name: "Not Studied Yet Today", days: days(1, 1, 0, 4, 0), expected: Streak{Current: 1, Longest: 2}},
+		{name: "Broken Streak", days: days(1, 1, 1, 0, 0), expected: Streak{Current: 0, Longest: 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ComputeStreak(tc.days))
+		})
+	}
+}
+
+func TestStudyCalendarDay(t *testing.T) {
+	calendar := StudyCalendar{Location: time.UTC, RolloverHour: 4}
+
+	assert.Equal(t, "2024-02-29", calendar.Date(time.Date(2024, 3, 1, 3, 59, 0, 0, time.UTC)))
+	assert.Equal(t, "2024-03-01", calendar.Date(time.Date(2024, 3, 1, 4, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2023-12-31", calendar.Date(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 3, 1, 4, 0, 0, 0, time.UTC), calendar.Start(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestComputeHeatmap(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	calendar := StudyCalendar{Location: tokyo, RolloverHour: 4}
+
+	reviews := []Review{
+		// 02:00 on January 3 in Tokyo still counts as January 2.
+		{Duration: 30000, ReviewTime: time.Date(2024, 1, 2, 17, 0, 0, 0, time.UTC)},
+		{Duration: 45000, ReviewTime: time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC)},
+		{Duration: 60000, ReviewTime: time.Date(2024, 1, 3, 1, 0, 0, 0, time.UTC)},
+		// Before the first study day of the year.
+		{Duration: 60000, ReviewTime: time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC)},
+	}
+	now := time.Date(2024, 1, 4, 1, 0, 0, 0, time.UTC)
+
+	heatmap := ComputeHeatmap(reviews, calendar, 2024, now)
+
+	assert.Equal(t, 2024, heatmap.Year)
+	assert.Equal(t, "Asia/Tokyo", heatmap.Timezone)
+	assert.Equal(t, 4, heatmap.RolloverHour)
+	assert.Equal(t, 3, heatmap.Reviews)
+	assert.Equal(t, 366, len(heatmap.Days))
+	assert.Equal(t, HeatmapDay{Date: "2024-01-01", Reviews: 0, Minutes: 0}, heatmap.Days[0])
+	assert.Equal(t, HeatmapDay{Date: "2024-01-02", Reviews: 2, Minutes: 1.3}, heatmap.Days[1])
+	assert.Equal(t, HeatmapDay{Date: "2024-01-03", Reviews: 1, Minutes: 1}, heatmap.Days[2])
+	assert.Equal(t, "2024-12-31", heatmap.Days[365].Date)
+	assert.Equal(t, Streak{Current: 2, Longest: 2}, heatmap.Streak)
+}
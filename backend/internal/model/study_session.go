@@ -0,0 +1,254 @@
+package model
+
+import (
+	"flash-learn/internal/utils"
+	"math/rand/v2"
+	"time"
+)
+
+// Modes a study session can be run in. A normal session studies the due cards
+// and reschedules them, while a cram session runs through every card without
+// changing when they are due.
+const (
+	StudyModeNormal = "normal"
+	StudyModeCram   = "cram"
+)
+
+// StudySession is a run through the cards of a deck or of a search result.
+// Queue holds the IDs of the cards left to study, the current card first.
+// When Shuffle is true the queue is shuffled when the session starts, and when
+// RepeatFailed is true a card answered Again goes back to the end of the queue
+// to be studied again before the session ends. History keeps every answer so
+// that they can be undone, the last one first. Version counts the times the
+// session was saved, so that a session saved since it was read isn't overwritten.
+type StudySession struct {
+	ID           int                  `json:"id"`
+	DeckID       *int                 `json:"deck_id,omitempty"`
+	Mode         string               `json:"mode"`
+	Shuffle      bool                 `json:"shuffle"`
+	RepeatFailed bool                 `json:"repeat_failed"`
+	Queue        []int                `json:"queue"`
+	Answered     int                  `json:"answered"`
+	Failed       int                  `json:"failed"`
+	History      []StudySessionAnswer `json:"-"`
+	StartTime    time.Time            `json:"start_time"`
+	EndTime      *time.Time           `json:"end_time,omitempty"`
+	Version      int                  `json:"-"`
+}
+
+// StudySessionAnswer is an answer given in a study session. Card is the card
+// as it was before the answer, so that undoing the answer can restore it.
+type StudySessionAnswer struct {
+	ReviewID int  `json:"review_id"`
+	Grade    int  `json:"grade"`
+	Duration int  `json:"duration_ms"`
+	Card     Card `json:"card"`
+}
+
+// StudySessionSummary sums up the answers given in a study session.
+// Grades counts the answers per grade, from Again to Easy.
+type StudySessionSummary struct {
+	SessionID int       `json:"session_id"`
+	Mode      string    `json:"mode"`
+	Answered  int       `json:"answered"`
+	Failed    int       `json:"failed"`
+	Remaining int       `json:"remaining"`
+	Grades    [4]int    `json:"grades"`
+	Duration  int       `json:"duration_ms"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// GradeInterval is how long a card would wait until its next review if it
+// were answered with a grade.
+type GradeInterval struct {
+	Grade   int   `json:"grade"`
+	Seconds int64 `json:"seconds"`
+}
+
+// Validate checks that the mode of the session is known.
+//
+// Returns:
+//   - error : utils.ErrInvalidStudySession if the session can't be run, nil otherwise.
+func (s StudySession) Validate() error {
+	if s.Mode != StudyModeNormal && s.Mode != StudyModeCram {
+		return utils.ErrInvalidStudySession
+	}
+
+	return nil
+}
+
+// Start fills the queue of the session with the cards to study, in their
+// order or shuffled with rng if the session shuffles its cards.
+//
+// Parameters:
+//   - cards []Card : The cards to study.
+//   - rng *rand.Rand : The source of randomness of the shuffle.
+//
+// Returns:
+//   - StudySession : The session with its queue filled.
+func (s StudySession) Start(cards []Card, rng *rand.Rand) StudySession {
+	s.Queue = make([]int, len(cards))
+	for i, card := range cards {
+		s.Queue[i] = card.ID
+	}
+
+	if s.Shuffle {
+		rng.Shuffle(len(s.Queue), func(i, j int) {
+			s.Queue[i], s.Queue[j] = s.Queue[j], s.Queue[i]
+		})
+	}
+
+	return s
+}
+
+// Current returns the ID of the card to study next.
+//
+// Returns:
+//   - int : The ID of the current card.
+//   - bool : False if no card is left to study.
+func (s StudySession) Current() (int, bool) {
+	if len(s.Queue) == 0 {
+		return 0, false
+	}
+
+	return s.Queue[0], true
+}
+
+// Skip removes the current card from the queue without answering it,
+// for a card that can't be studied anymore.
+//
+// Returns:
+//   - StudySession : The session without its current card.
+func (s StudySession) Skip() StudySession {
+	if len(s.Queue) > 0 {
+		s.Queue = append([]int{}, s.Queue[1:]...)
+	}
+
+	return s
+}
+
+// Ended reports whether the session has ended.
+func (s StudySession) Ended() bool {
+	return s.EndTime != nil
+}
+
+// Answer moves past the current card once it is answered. A failed card is
+// put back at the end of the queue if the session repeats failed cards.
+//
+// Parameters:
+//   - answer StudySessionAnswer : The answer to the current card.
+//
+// Returns:
+//   - StudySession : The session with its next card current.
+func (s StudySession) Answer(answer StudySessionAnswer) StudySession {
+	cardID, ok := s.Current()
+	if !ok {
+		return s
+	}
+
+	s = s.Skip()
+	s.Answered++
+	if answer.Grade == GradeAgain {
+		s.Failed++
+		if s.RepeatFailed {
+			s.Queue = append(s.Queue, cardID)
+		}
+	}
+	s.History = append(append([]StudySessionAnswer{}, s.History...), answer)
+
+	return s
+}
+
+// Undo reverts the last answer of the session, making its card current again.
+//
+// Returns:
+//   - StudySession : The session as it was before the answer.
+//   - StudySessionAnswer : The undone answer.
+//   - bool : False if there is no answer to undo.
+func (s StudySession) Undo() (StudySession, StudySessionAnswer, bool) {
+	if len(s.History) == 0 {
+		return s, StudySessionAnswer{}, false
+	}
+
+	answer := s.History[len(s.History)-1]
+	s.History = s.History[:len(s.History)-1]
+
+	queue := append([]int{}, s.Queue...)
+	if answer.Grade == GradeAgain && s.RepeatFailed {
+		for i := len(queue) - 1; i >= 0; i-- {
+			if queue[i] == answer.Card.ID {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+	}
+	s.Queue = append([]int{answer.Card.ID}, queue...)
+
+	s.Answered--
+	if answer.Grade == GradeAgain {
+		s.Failed--
+	}
+
+	return s, answer, true
+}
+
+// StudiedDeckIDs returns the decks studied in the session, that is the deck
+// of the session and the home decks of the answered cards.
+//
+// Returns:
+//   - []int : The unique IDs of the studied decks, without duplicates.
+func (s StudySession) StudiedDeckIDs() []int {
+	deckIDs := []int{}
+	seen := make(map[int]bool)
+	add := func(deckID int) {
+		if !seen[deckID] {
+			seen[deckID] = true
+			deckIDs = append(deckIDs, deckID)
+		}
+	}
+
+	if s.DeckID != nil {
+		add(*s.DeckID)
+	}
+	for _, answer := range s.History {
+		if answer.Card.OriginalDeckID != nil {
+			add(*answer.Card.OriginalDeckID)
+		} else {
+			add(answer.Card.DeckID)
+		}
+	}
+
+	return deckIDs
+}
+
+// Summarize sums up the answers given in the session.
+//
+// Parameters:
+//   - end time.Time : The time the session ended, used if it hasn't ended yet.
+//
+// Returns:
+//   - StudySessionSummary : The summary of the session.
+func (s StudySession) Summarize(end time.Time) StudySessionSummary {
+	if s.EndTime != nil {
+		end = *s.EndTime
+	}
+
+	summary := StudySessionSummary{
+		SessionID: s.ID,
+		Mode:      s.Mode,
+		Answered:  s.Answered,
+		Failed:    s.Failed,
+		Remaining: len(s.Queue),
+		StartTime: s.StartTime,
+		EndTime:   end,
+	}
+	for _, answer := range s.History {
+		if ValidGrade(answer.Grade) {
+			summary.Grades[answer.Grade-GradeAgain]++
+		}
+		summary.Duration += answer.Duration
+	}
+
+	return summary
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/attic-labs/testify/assert"
+)
+
+func TestStudySessionValidate(t *testing.T) {
+	assert.NotNil(t, StudySession{}.Validate())
+	assert.NotNil(t, StudySession{Mode: "exam"}.Validate())
+	assert.Nil(t, StudySession{Mode: StudyModeNormal}.Validate())
+	assert.Nil(t, StudySession{Mode: StudyModeCram}.Validate())
+}
+
+func TestStudySessionStart(t *testing.T) {
+	cards := []Card{{ID: 4}, {ID: 2}, {ID: 7}, {ID: 1}, {ID: 9}}
+
+	session := StudySession{Mode: StudyModeCram}.Start(cards, rand.New(rand.NewPCG(1, 0)))
+	assert.Equal(t, []int{4, 2, 7, 1, 9}, session.Queue)
+
+	shuffled := StudySession{Mode: StudyModeCram, Shuffle: true}.Start(cards, rand.New(rand.NewPCG(1, 0)))
+	assert.Equal(t, 5, len(shuffled.Queue))
+	assert.NotEqual(t, session.Queue, shuffled.Queue)
+	assert.Equal(t, shuffled.Queue, StudySession{Mode: StudyModeCram, Shuffle: true}.Start(cards, rand.New(rand.NewPCG(1, 0))).Queue,
+		"Expected the same seed to give the same shuffle")
+}
+
+func TestStudySessionAnswer(t *testing.T) {
+	testCases := []struct {
+		name         string
+		repeatFailed bool
+		grades       []int
+		queue        []int
+		answered     int
+		failed       int
+	}{
+		{name: "Passed", grades: []int{GradeGood}, queue: []int{2, 3}, answered: 1},
+		{name: "Failed Not Repeated", grades: []int{GradeAgain}, queue: []int{2, 3}, answered: 1, failed: 1},
+		{name: "Failed Repeated", repeatFailed: true, grades: []int{GradeAgain}, queue: []int{2, 3, 1}, answered: 1, failed: 1},
+		{name: "Failed Until Passed", repeatFailed: true, grades: []int{GradeAgain, GradeGood, GradeHard, GradeAgain, GradeEasy}, queue: []int{}, answered: 5, failed: 2},
+		{name: "Finished", grades: []int{GradeGood, GradeGood, GradeGood, GradeGood}, queue: []int{}, answered: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			session := StudySession{Mode: StudyModeCram, RepeatFailed: tc.repeatFailed, Queue: []int{1, 2, 3}}
+			for _, grade := range tc.grades {
+				cardID, _ := session.Current()
+				session = session.Answer(StudySessionAnswer{Grade: grade, Card: Card{ID: cardID}})
+			}
+
+			assert.Equal(t, tc.queue, session.Queue)
+			assert.Equal(t, tc.failed, session.Failed)
+			assert.Equal(t, tc.answered, session.Answered)
+			assert.Equal(t, tc.answered, len(session.History))
+
+			_, ok := session.Current()
+			assert.Equal(t, len(tc.queue) > 0, ok)
+		})
+	}
+}
+
+func TestStudySessionUndo(t *testing.T) {
+	answer := func(session StudySession, grade int) StudySession {
+		cardID, _ := session.Current()
+		return session.Answer(StudySessionAnswer{ReviewID: session.Answered, Grade: grade, Card: Card{ID: cardID}})
+	}
+
+	t.Run("Nothing To Undo", func(t *testing.T) {
+		_, _, ok := StudySession{Queue: []int{1}}.Undo()
+		assert.False(t, ok)
+	})
+
+	for _, repeatFailed := range []bool{false, true} {
+		start := StudySession{Mode: StudyModeCram, RepeatFailed: repeatFailed, Queue: []int{1, 2, 3}}
+
+		session := answer(answer(start, GradeGood), GradeAgain)
+		session, undone, ok := session.Undo()
+		assert.True(t, ok)
+		assert.Equal(t, 1, undone.ReviewID)
+		assert.Equal(t, 2, undone.Card.ID)
+		assert.Equal(t, []int{2, 3}, session.Queue)
+		assert.Equal(t, 1, session.Answered)
+		assert.Equal(t, 0, session.Failed)
+
+		session, undone, ok = session.Undo()
+		assert.True(t, ok)
+		assert.Equal(t, 0, undone.ReviewID)
+		assert.Equal(t, start.Queue, session.Queue)
+		assert.Equal(t, 0, session.Answered)
+		assert.Equal(t, 0, len(session.History))
+	}
+}
+
+func TestStudySessionSummarize(t *testing.T) {
+	home := 4
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Minute)
+	session := StudySession{ID: 2, DeckID: &home, Mode: StudyModeNormal, RepeatFailed: true, Queue: []int{1, 2, 3}, StartTime: start}
+
+	for i, grade := range []int{GradeAgain, GradeGood, GradeEasy, GradeGood} {
+		card := Card{ID: session.Queue[0], DeckID: 5 + i%2}
+		if i == 0 {
+			card.OriginalDeckID = &home
+		}
+		session = session.Answer(StudySessionAnswer{Grade: grade, Duration: 1000, Card: card})
+	}
+
+	summary := session.Summarize(end)
+	assert.Equal(t, 2, summary.SessionID)
+	assert.Equal(t, 4, summary.Answered)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, 0, summary.Remaining)
+	assert.Equal(t, [4]int{1, 0, 2, 1}, summary.Grades)
+	assert.Equal(t, 4000, summary.Duration)
+	assert.Equal(t, end, summary.EndTime)
+	assert.Equal(t, []int{4, 6, 5}, session.StudiedDeckIDs())
+
+	ended := end.Add(time.Hour)
+	session.EndTime = &ended
+	assert.True(t, session.Ended())
+	assert.Equal(t, ended, session.Summarize(end).EndTime)
+}
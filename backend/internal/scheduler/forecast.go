@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"math/rand/v2"
+	"time"
+)
+
+// The most answers simulated for a single card, which bounds the work done
+// for cards that keep failing their learning steps.
+const forecastMaxAnswers = 1000
+
+// Forecasts the number of reviews on each of the coming study days. Every card
+// is counted on the day it is due, then answered with a grade drawn from the
+// pass rate of its kind of review and rescheduled, until it falls beyond the
+// last day. New and suspended cards aren't counted.
+//
+// Parameters:
+//   - cards []model.Card : The cards of the deck.
+//   - settings model.DeckPreset : The effective settings of the deck.
+//   - passRates map[string]float64 : The historical pass rates, by review kind.
+//   - calendar model.StudyCalendar : The calendar the study days are taken from.
+//   - now time.Time : The current time; overdue cards are counted on its day.
+//   - days int : The number of days to forecast.
+//   - rng *rand.Rand : The source of the simulated grades.
+//
+// Returns:
+//   - []model.ForecastDay : The expected reviews of every day, starting with today.
+func Forecast(cards []model.Card, settings model.DeckPreset, passRates map[string]float64, calendar model.StudyCalendar, now time.Time, days int, rng *rand.Rand) []model.ForecastDay {
+	today := calendar.Day(now)
+	end := calendar.Start(today.AddDate(0, 0, days))
+
+	forecast := make([]model.ForecastDay, days)
+	dayIndex := make(map[string]int, days)
+	for i := range forecast {
+		forecast[i].Date = today.AddDate(0, 0, i).Format(model.DateLayout)
+		dayIndex[forecast[i].Date] = i
+	}
+
+	scheduler := New(settings)
+	for _, card := range cards {
+		if card.State == model.CardStateNew || card.Suspended {
+			continue
+		}
+
+		for answers := 0; answers < forecastMaxAnswers; answers++ {
+			due := card.NextReviewTime
+			if card.BuriedUntil != nil && card.BuriedUntil.After(due) {
+				due = *card.BuriedUntil
+			}
+			if due.Before(now) {
+				due = now
+			}
+			if !due.Before(end) {
+				break
+			}
+
+			forecast[dayIndex[calendar.Date(due)]].Add(card)
+
+			grade := model.GradeGood
+			if rng.Float64() >= passRates[model.ReviewKindOf(card.State)] {
+				grade = model.GradeAgain
+			}
+			card = scheduler.Schedule(card, grade, due)
+		}
+	}
+
+	return forecast
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForecast(t *testing.T) {
+	settings := model.DefaultDeckPreset()
+	calendar := model.StudyCalendar{Location: time.UTC}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	alwaysPass := map[string]float64{model.ReviewKindLearn: 1, model.ReviewKindReview: 1, model.ReviewKindRelearn: 1}
+
+	review := func(due time.Time, interval int) model.Card {
+		card := model.NewCard(0, "", "")
+		card.State = model.CardStateReview
+		card.Interval = interval
+		card.NextReviewTime = due
+		return card
+	}
+
+	t.Run("Cards Are Counted On Their Due Day", func(t *testing.T) {
+		overdue := review(now.AddDate(0, 0, -3), 30)
+		young := review(now.AddDate(0, 0, 2), 4)
+		suspended := review(now, 4)
+		suspended.Suspended = true
+		cards := []model.Card{overdue, young, suspended, model.NewCard(0, "", "")}
+
+		forecast := Forecast(cards, settings, alwaysPass, calendar, now, 5, rand.New(rand.NewPCG(1, 1)))
+
+		assert.Equal(t, 5, len(forecast))
+		assert.Equal(t, "2024-03-01", forecast[0].Date)
+		assert.Equal(t, model.ForecastDay{Date: "2024-03-01", Mature: 1, Total: 1}, forecast[0])
+		assert.Equal(t, model.ForecastDay{Date: "2024-03-03", Young: 1, Total: 1}, forecast[2])
+	})
+
+	t.Run("Future Reviews Are Simulated", func(t *testing.T) {
+		cards := []model.Card{review(now, 1)}
+
+		forecast := Forecast(cards, settings, alwaysPass, calendar, now, 10, rand.New(rand.NewPCG(1, 1)))
+
+		total := 0
+		for _, day := range forecast {
+			total += day.Total
+		}
+		assert.Equal(t, 1, forecast[0].Total)
+		assert.Equal(t, 1, forecast[3].Total, "Expected a Good answer to multiply the interval by the ease")
+		assert.Equal(t, 2, total)
+	})
+
+	t.Run("Failed Reviews Are Relearned", func(t *testing.T) {
+		alwaysFail := map[string]float64{model.ReviewKindLearn: 1, model.ReviewKindReview: 0, model.ReviewKindRelearn: 1}
+		cards := []model.Card{review(now, 10)}
+
+		forecast := Forecast(cards, settings, alwaysFail, calendar, now, 2, rand.New(rand.NewPCG(1, 1)))
+
+		assert.Equal(t, model.ForecastDay{Date: "2024-03-01", Learning: 1, Young: 1, Total: 2}, forecast[0])
+		assert.Equal(t, model.ForecastDay{Date: "2024-03-02", Learning: 1, Young: 1, Total: 2}, forecast[1])
+	})
+
+	t.Run("Same Seed Gives Same Forecast", func(t *testing.T) {
+		cards := []model.Card{}
+		for i := 0; i < 50; i++ {
+			cards = append(cards, review(now.AddDate(0, 0, i%7), 1+i%30))
+		}
+		rates := map[string]float64{model.ReviewKindLearn: 0.8, model.ReviewKindReview: 0.7, model.ReviewKindRelearn: 0.8}
+
+		first := Forecast(cards, settings, rates, calendar, now, 30, rand.New(rand.NewPCG(7, 7)))
+		second := Forecast(cards, settings, rates, calendar, now, 30, rand.New(rand.NewPCG(7, 7)))
+
+		assert.Equal(t, first, second)
+	})
+}
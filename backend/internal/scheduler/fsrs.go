@@ -0,0 +1,185 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"math"
+	"time"
+)
+
+// Constants of the forgetting curve of FSRS, chosen so that the probability
+// of recall after a number of days equal to the stability is 90%.
+const (
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0
+)
+
+// Range of the difficulty of a card.
+const (
+	fsrsMinDifficulty = 1.0
+	fsrsMaxDifficulty = 10.0
+)
+
+// DefaultFSRSWeights are the FSRS-4.5 weights fitted on a large collection
+// of review logs, used until weights are fitted to the user's own history.
+var DefaultFSRSWeights = []float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031, 1.6474,
+	0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+// A scheduler based on the Free Spaced Repetition Scheduler. Every card has a
+// stability, the number of days after which it is recalled with a probability
+// of 90%, and a difficulty. Intervals are chosen so that the card is recalled
+// with the desired retention of the settings.
+type FSRS struct {
+	settings model.DeckPreset
+	weights  []float64
+}
+
+// Creates and returns a new instance of FSRS. The weights fitted to the review
+// log are used if the settings have them, and the default weights otherwise.
+//
+// Parameters:
+//   - settings model.DeckPreset : The effective settings of the deck.
+//
+// Returns:
+//   - *FSRS
+func NewFSRS(settings model.DeckPreset) *FSRS {
+	weights := DefaultFSRSWeights
+	if len(settings.FSRSWeights) == len(DefaultFSRSWeights) {
+		weights = settings.FSRSWeights
+	}
+	return &FSRS{settings: settings, weights: weights}
+}
+
+// Returns the card with the scheduling state the grade gives it.
+//
+// Parameters:
+//   - card model.Card : The card being answered.
+//   - grade int : The grade of the answer.
+//   - now time.Time : The time of the answer.
+//
+// Returns:
+//   - model.Card : The rescheduled card.
+func (s *FSRS) Schedule(card model.Card, grade int, now time.Time) model.Card {
+	elapsed := 0.0
+	if card.LastReviewTime != nil {
+		elapsed = math.Max(0, now.Sub(*card.LastReviewTime).Hours()/24)
+	}
+	reviewed := now
+	card.LastReviewTime = &reviewed
+
+	if card.State == model.CardStateNew || card.Stability <= 0 {
+		card.Stability = s.initialStability(grade)
+		card.Difficulty = s.initialDifficulty(grade)
+	}
+
+	switch card.State {
+	case model.CardStateReview:
+		return s.scheduleReview(card, grade, elapsed, now)
+	case model.CardStateRelearning:
+		step, delay, graduated := answerStep(s.settings.RelearningSteps, card.Step, grade)
+		if graduated {
+			return setReview(card, card.Interval, now)
+		}
+		return setStep(card, model.CardStateRelearning, step, delay, now)
+	default:
+		step, delay, graduated := answerStep(s.settings.LearningSteps, card.Step, grade)
+		if !graduated {
+			return setStep(card, model.CardStateLearning, step, delay, now)
+		}
+		card.RetentionLevel = 1
+		return setReview(card, s.interval(card.Stability), now)
+	}
+}
+
+// A helper function that schedules a card answered in review.
+func (s *FSRS) scheduleReview(card model.Card, grade int, elapsed float64, now time.Time) model.Card {
+	retrievability := Retrievability(elapsed, card.Stability)
+	difficulty := card.Difficulty
+	card.Difficulty = s.nextDifficulty(difficulty, grade)
+
+	if grade == model.GradeAgain {
+		card.Stability = s.forgetStability(difficulty, card.Stability, retrievability)
+		card.Lapses++
+		card.RetentionLevel = 0
+		card.Interval = s.interval(card.Stability)
+
+		if len(s.settings.RelearningSteps) > 0 {
+			return setStep(card, model.CardStateRelearning, 0, minutes(s.settings.RelearningSteps[0]), now)
+		}
+		return setReview(card, card.Interval, now)
+	}
+
+	card.Stability = s.recallStability(difficulty, card.Stability, retrievability, grade)
+	card.RetentionLevel++
+	return setReview(card, s.interval(card.Stability), now)
+}
+
+// Returns the probability of recalling a card some days after its last review.
+//
+// Parameters:
+//   - elapsed float64 : The number of days since the last review.
+//   - stability float64 : The stability of the card.
+//
+// Returns:
+//   - float64 : The probability of recall, between 0 and 1.
+func Retrievability(elapsed float64, stability float64) float64 {
+	if stability <= 0 {
+		return 0
+	}
+	return math.Pow(1+fsrsFactor*elapsed/stability, fsrsDecay)
+}
+
+// A helper function that returns the interval in days after which a card
+// is recalled with the desired retention.
+func (s *FSRS) interval(stability float64) int {
+	interval := stability / fsrsFactor * (math.Pow(s.settings.DesiredRetention, 1/fsrsDecay) - 1)
+	return clampInterval(int(math.Round(interval)), s.settings)
+}
+
+// A helper function that returns the stability of a card after its first answer.
+func (s *FSRS) initialStability(grade int) float64 {
+	return math.Max(s.weights[grade-1], 0.1)
+}
+
+// A helper function that returns the difficulty of a card after its first answer.
+func (s *FSRS) initialDifficulty(grade int) float64 {
+	return clampDifficulty(s.weights[4] - float64(grade-3)*s.weights[5])
+}
+
+// A helper function that returns the difficulty of a card after an answer,
+// reverting it towards the initial difficulty of a Good answer.
+func (s *FSRS) nextDifficulty(difficulty float64, grade int) float64 {
+	next := difficulty - s.weights[6]*float64(grade-3)
+	return clampDifficulty(s.weights[7]*s.initialDifficulty(model.GradeGood) + (1-s.weights[7])*next)
+}
+
+// A helper function that returns the stability of a card after it is recalled.
+func (s *FSRS) recallStability(difficulty float64, stability float64, retrievability float64, grade int) float64 {
+	hardPenalty, easyBonus := 1.0, 1.0
+	if grade == model.GradeHard {
+		hardPenalty = s.weights[15]
+	} else if grade == model.GradeEasy {
+		easyBonus = s.weights[16]
+	}
+
+	return stability * (1 + math.Exp(s.weights[8])*
+		(11-difficulty)*
+		math.Pow(stability, -s.weights[9])*
+		(math.Exp((1-retrievability)*s.weights[10])-1)*
+		hardPenalty*easyBonus)
+}
+
+// A helper function that returns the stability of a card after it is forgotten.
+func (s *FSRS) forgetStability(difficulty float64, stability float64, retrievability float64) float64 {
+	next := s.weights[11] *
+		math.Pow(difficulty, -s.weights[12]) *
+		(math.Pow(stability+1, s.weights[13]) - 1) *
+		math.Exp((1-retrievability)*s.weights[14])
+	return math.Min(next, stability)
+}
+
+// A helper function that limits a difficulty to its allowed range.
+func clampDifficulty(difficulty float64) float64 {
+	return math.Max(fsrsMinDifficulty, math.Min(difficulty, fsrsMaxDifficulty))
+}
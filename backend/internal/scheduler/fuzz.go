@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// The ranges of intervals, in days, and how much of each range an interval
+// may be fuzzed by. Every interval may also move by a day, and intervals
+// shorter than the first range aren't fuzzed.
+var fuzzRanges = []struct {
+	start  float64
+	end    float64
+	factor float64
+}{
+	{start: 2.5, end: 7, factor: 0.15},
+	{start: 7, end: 20, factor: 0.1},
+	{start: 20, end: math.Inf(1), factor: 0.05},
+}
+
+// DueCounter returns how many cards are due on each of a number of days,
+// the first day starting at from.
+type DueCounter func(from time.Time, days int) ([]int, error)
+
+// Fuzzer spreads the due dates of the cards put in review, so that cards
+// added and studied together don't keep coming due on the same days. The
+// interval of a card is moved to one of the days around it that has the
+// fewest cards due, picked at random when several do.
+type Fuzzer struct {
+	rng       *rand.Rand
+	dueCounts DueCounter
+}
+
+// Creates and returns a new instance of Fuzzer.
+//
+// Parameters:
+//   - rng *rand.Rand : The source of randomness, which makes the fuzz deterministic when seeded.
+//   - dueCounts DueCounter : The due counts of the deck, nil to only fuzz the intervals.
+//
+// Returns:
+//   - *Fuzzer
+func NewFuzzer(rng *rand.Rand, dueCounts DueCounter) *Fuzzer {
+	return &Fuzzer{rng: rng, dueCounts: dueCounts}
+}
+
+// Fuzzes the interval of a card in review. Other cards are returned unchanged.
+//
+// Parameters:
+//   - card model.Card : The rescheduled card.
+//   - settings model.DeckPreset : The effective settings of the deck of the card.
+//   - now time.Time : The time of the answer.
+//
+// Returns:
+//   - model.Card : The card with its fuzzed interval.
+//   - error : An error if the due counts can't be retrieved, nil otherwise.
+func (f *Fuzzer) Fuzz(card model.Card, settings model.DeckPreset, now time.Time) (model.Card, error) {
+	if card.State != model.CardStateReview {
+		return card, nil
+	}
+
+	low, high := fuzzRange(card.Interval, settings.MaxInterval)
+	if low == high {
+		return card, nil
+	}
+
+	counts := make([]int, high-low+1)
+	if f.dueCounts != nil {
+		due, err := f.dueCounts(utils.StartOfDay(now.AddDate(0, 0, low)), len(counts))
+		if err != nil {
+			return card, err
+		}
+		copy(counts, due)
+	}
+
+	least := []int{}
+	for i, count := range counts {
+		if len(least) > 0 && count < counts[least[0]] {
+			least = least[:0]
+		}
+		if len(least) == 0 || count == counts[least[0]] {
+			least = append(least, i)
+		}
+	}
+
+	return setReview(card, low+least[f.rng.IntN(len(least))], now), nil
+}
+
+// A helper function that returns the range of days an interval may be fuzzed to,
+// limited to the maximum interval.
+func fuzzRange(interval int, maxInterval int) (int, int) {
+	days := float64(interval)
+	if days < fuzzRanges[0].start {
+		return interval, interval
+	}
+
+	delta := 1.0
+	for _, r := range fuzzRanges {
+		delta += r.factor * math.Max(0, math.Min(days, r.end)-r.start)
+	}
+
+	high := min(int(math.Round(days+delta)), maxInterval)
+	low := min(max(2, int(math.Round(days-delta))), high)
+	return low, high
+}
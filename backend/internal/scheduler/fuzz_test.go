@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"errors"
+	"flash-learn/internal/model"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzRange(t *testing.T) {
+	testCases := []struct {
+		name        string
+		interval    int
+		maxInterval int
+		low         int
+		high        int
+	}{
+		{name: "Short Interval", interval: 2, maxInterval: 36500, low: 2, high: 2},
+		{name: "First Range", interval: 5, maxInterval: 36500, low: 4, high: 6},
+		{name: "Second Range", interval: 10, maxInterval: 36500, low: 8, high: 12},
+		{name: "Last Range", interval: 100, maxInterval: 36500, low: 93, high: 107},
+		{name: "Max Interval", interval: 100, maxInterval: 100, low: 93, high: 100},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			low, high := fuzzRange(tc.interval, tc.maxInterval)
+			assert.Equal(t, tc.low, low)
+			assert.Equal(t, tc.high, high)
+		})
+	}
+}
+
+func TestFuzz(t *testing.T) {
+	settings := model.DefaultDeckPreset()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	card := setReview(model.NewCard(0, "", ""), 10, now)
+
+	t.Run("Within Range", func(t *testing.T) {
+		fuzzer := NewFuzzer(rand.New(rand.NewPCG(1, 0)), nil)
+		seen := make(map[int]bool)
+		for i := 0; i < 100; i++ {
+			fuzzed, err := fuzzer.Fuzz(card, settings, now)
+			assert.Nil(t, err)
+			assert.GreaterOrEqual(t, fuzzed.Interval, 8)
+			assert.LessOrEqual(t, fuzzed.Interval, 12)
+			assert.Equal(t, now.AddDate(0, 0, fuzzed.Interval), fuzzed.NextReviewTime)
+			seen[fuzzed.Interval] = true
+		}
+		assert.Equal(t, 5, len(seen), "Expected every day of the range to be picked")
+	})
+
+	t.Run("Deterministic Under Seed", func(t *testing.T) {
+		first := NewFuzzer(rand.New(rand.NewPCG(7, 3)), nil)
+		second := NewFuzzer(rand.New(rand.NewPCG(7, 3)), nil)
+		for i := 0; i < 10; i++ {
+			a, _ := first.Fuzz(card, settings, now)
+			b, _ := second.Fuzz(card, settings, now)
+			assert.Equal(t, a.Interval, b.Interval)
+		}
+	})
+
+	t.Run("Least Loaded Day", func(t *testing.T) {
+		var from time.Time
+		dueCounts := func(start time.Time, days int) ([]int, error) {
+			from = start
+			return []int{9, 4, 7, 2, 5}[:days], nil
+		}
+
+		for seed := uint64(0); seed < 10; seed++ {
+			fuzzed, err := NewFuzzer(rand.New(rand.NewPCG(seed, 0)), dueCounts).Fuzz(card, settings, now)
+			assert.Nil(t, err)
+			assert.Equal(t, 11, fuzzed.Interval)
+		}
+		assert.Equal(t, time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), from)
+	})
+
+	t.Run("Ties Broken At Random", func(t *testing.T) {
+		dueCounts := func(start time.Time, days int) ([]int, error) {
+			return []int{3, 1, 2, 1, 3}, nil
+		}
+
+		seen := make(map[int]bool)
+		for seed := uint64(0); seed < 20; seed++ {
+			fuzzed, _ := NewFuzzer(rand.New(rand.NewPCG(seed, 0)), dueCounts).Fuzz(card, settings, now)
+			seen[fuzzed.Interval] = true
+		}
+		assert.Equal(t, map[int]bool{9: true, 11: true}, seen)
+	})
+
+	t.Run("Due Count Error", func(t *testing.T) {
+		failure := errors.New("count failed")
+		dueCounts := func(start time.Time, days int) ([]int, error) {
+			return nil, failure
+		}
+
+		_, err := NewFuzzer(rand.New(rand.NewPCG(1, 0)), dueCounts).Fuzz(card, settings, now)
+		assert.Equal(t, failure, err)
+	})
+
+	t.Run("Cards Not In Review Unchanged", func(t *testing.T) {
+		fuzzer := NewFuzzer(rand.New(rand.NewPCG(1, 0)), nil)
+
+		learning := setStep(model.NewCard(0, "", ""), model.CardStateLearning, 1, 10*time.Minute, now)
+		fuzzed, _ := fuzzer.Fuzz(learning, settings, now)
+		assert.Equal(t, learning, fuzzed)
+
+		short := setReview(model.NewCard(0, "", ""), 2, now)
+		fuzzed, _ = fuzzer.Fuzz(short, settings, now)
+		assert.Equal(t, short, fuzzed)
+	})
+
+	t.Run("Answer Fuzzes Review Log", func(t *testing.T) {
+		reviewed := card
+		reviewed.Interval = 4
+		result, err := Answer(reviewed, model.GradeGood, 0, settings, now, NewFuzzer(rand.New(rand.NewPCG(1, 0)), nil))
+		assert.Nil(t, err)
+		assert.Equal(t, result.Card.Interval, result.Review.Interval)
+		assert.Equal(t, 4, result.Review.LastInterval)
+	})
+}
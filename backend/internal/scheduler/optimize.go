@@ -0,0 +1,280 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"math"
+	"sort"
+)
+
+// Settings of the gradient descent that fits the FSRS weights. Each step
+// moves a weight by a fraction of its magnitude, with a floor for the weights
+// close to zero.
+const (
+	optimizeIterations   = 100
+	optimizeLearningRate = 0.05
+	optimizeMinStep      = 0.1
+	optimizeGradientStep = 1e-4
+)
+
+// Number of golden section steps taken when fitting the SM-2 ease modifier.
+const optimizeSearchSteps = 40
+
+// OptimizeMinReviews is the number of reviews needed to fit a scheduler.
+const OptimizeMinReviews = 16
+
+// Smallest probability used when computing the log-loss, so that a confident
+// wrong prediction costs a lot instead of an infinite amount.
+const optimizeMinProbability = 1e-6
+
+// Probability of recall SM-2 intervals are assumed to aim for.
+const sm2TargetRetention = 0.9
+
+// Bounds of each FSRS weight while fitting, keeping the memory model sensible.
+var fsrsWeightBounds = [][2]float64{
+	{0.1, 100}, {0.1, 100}, {0.1, 100}, {0.1, 100},
+	{1, 10}, {0.001, 4}, {0.001, 4}, {0.001, 0.75},
+	{0, 4.5}, {0, 0.8}, {0.001, 3.5}, {0.001, 5},
+	{0.001, 0.25}, {0.001, 0.9}, {0, 4}, {0, 1}, {1, 6},
+}
+
+// Fits the parameters of the scheduler chosen by the settings to a review log.
+// The log is replayed card by card, and the parameters are chosen to best
+// predict whether each card in review was recalled. FSRS weights are fitted by
+// gradient descent on the log-loss, and the SM-2 ease modifier by maximum
+// likelihood. The fitted parameters are only kept if they lower the log-loss.
+//
+// Parameters:
+//   - reviews []model.Review : The review log, in any order.
+//   - settings model.DeckPreset : The settings the parameters are fitted for.
+//
+// Returns:
+//   - model.OptimizeResult : The fitted parameters and the log-loss before and after.
+//   - error : utils.ErrNotEnoughReviews if the log has too few reviews, nil otherwise.
+func Optimize(reviews []model.Review, settings model.DeckPreset) (model.OptimizeResult, error) {
+	histories := reviewHistories(reviews)
+	if settings.Scheduler == model.SchedulerFSRS {
+		return optimizeFSRS(histories, settings)
+	}
+	return optimizeSM2(histories, settings)
+}
+
+// A helper function that fits the FSRS weights by gradient descent, using
+// the Adam update rule and numerical gradients.
+func optimizeFSRS(histories [][]model.Review, settings model.DeckPreset) (model.OptimizeResult, error) {
+	initial := DefaultFSRSWeights
+	if len(settings.FSRSWeights) == len(DefaultFSRSWeights) {
+		initial = settings.FSRSWeights
+	}
+
+	before, samples := fsrsLogLoss(histories, settings, initial)
+	if samples < OptimizeMinReviews {
+		return model.OptimizeResult{}, utils.ErrNotEnoughReviews
+	}
+
+	weights := clampWeights(append([]float64{}, initial...))
+	loss, _ := fsrsLogLoss(histories, settings, weights)
+	best, bestLoss := append([]float64{}, weights...), loss
+
+	moment := make([]float64, len(weights))
+	velocity := make([]float64, len(weights))
+	for iteration := 1; iteration <= optimizeIterations; iteration++ {
+		gradient := fsrsGradient(histories, settings, weights, loss)
+		for i := range weights {
+			moment[i] = 0.9*moment[i] + 0.1*gradient[i]
+			velocity[i] = 0.999*velocity[i] + 0.001*gradient[i]*gradient[i]
+			momentHat := moment[i] / (1 - math.Pow(0.9, float64(iteration)))
+			velocityHat := velocity[i] / (1 - math.Pow(0.999, float64(iteration)))
+
+			step := optimizeLearningRate * math.Max(math.Abs(weights[i]), optimizeMinStep)
+			weights[i] -= step * momentHat / (math.Sqrt(velocityHat) + 1e-8)
+		}
+		clampWeights(weights)
+
+		loss, _ = fsrsLogLoss(histories, settings, weights)
+		if loss < bestLoss {
+			best, bestLoss = append([]float64{}, weights...), loss
+		}
+	}
+
+	for i := range best {
+		best[i] = math.Round(best[i]*1e4) / 1e4
+	}
+	after, _ := fsrsLogLoss(histories, settings, best)
+	if after >= before {
+		best, after = append([]float64{}, initial...), before
+	}
+
+	return model.OptimizeResult{
+		Scheduler:     model.SchedulerFSRS,
+		Reviews:       samples,
+		LogLossBefore: before,
+		LogLossAfter:  after,
+		FSRSWeights:   best,
+	}, nil
+}
+
+// A helper function that returns the gradient of the FSRS log-loss at the
+// given weights, estimated by moving each weight a little.
+func fsrsGradient(histories [][]model.Review, settings model.DeckPreset, weights []float64, loss float64) []float64 {
+	gradient := make([]float64, len(weights))
+	moved := append([]float64{}, weights...)
+
+	for i, weight := range weights {
+		step := optimizeGradientStep * math.Max(math.Abs(weight), 1)
+		moved[i] = weight + step
+		movedLoss, _ := fsrsLogLoss(histories, settings, moved)
+		gradient[i] = (movedLoss - loss) / step
+		moved[i] = weight
+	}
+
+	return gradient
+}
+
+// A helper function that replays the review histories with the given FSRS
+// weights and returns the mean log-loss of the predicted recall of the cards
+// answered in review, along with the number of such reviews.
+func fsrsLogLoss(histories [][]model.Review, settings model.DeckPreset, weights []float64) (float64, int) {
+	fsrs := &FSRS{settings: settings, weights: weights}
+	total, samples := 0.0, 0
+
+	for _, history := range histories {
+		card := model.Card{}
+		for _, review := range history {
+			if review.State == model.CardStateReview && card.LastReviewTime != nil {
+				elapsed := math.Max(0, review.ReviewTime.Sub(*card.LastReviewTime).Hours()/24)
+				total += logLoss(Retrievability(elapsed, card.Stability), review.Grade)
+				samples++
+			}
+
+			// The log tells which state the card was in, whatever the steps of the settings
+			card.State = review.State
+			card = fsrs.Schedule(card, review.Grade, review.ReviewTime)
+		}
+	}
+
+	if samples == 0 {
+		return 0, 0
+	}
+	return total / float64(samples), samples
+}
+
+// A helper function that fits the SM-2 ease modifier. The recall of a card
+// answered in review is modeled as decaying so that it reaches the target
+// retention after its interval times a scale, which is fitted by golden
+// section search. The scale multiplies the current ease modifier.
+func optimizeSM2(histories [][]model.Review, settings model.DeckPreset) (model.OptimizeResult, error) {
+	current := settings.EaseModifier
+	before, samples := sm2LogLoss(histories, 1)
+	if samples < OptimizeMinReviews {
+		return model.OptimizeResult{}, utils.ErrNotEnoughReviews
+	}
+
+	low, high := math.Log(model.MinEaseModifier/current), math.Log(model.MaxEaseModifier/current)
+	ratio := (math.Sqrt(5) - 1) / 2
+	for range optimizeSearchSteps {
+		left := high - ratio*(high-low)
+		right := low + ratio*(high-low)
+
+		leftLoss, _ := sm2LogLoss(histories, math.Exp(left))
+		rightLoss, _ := sm2LogLoss(histories, math.Exp(right))
+		if leftLoss < rightLoss {
+			high = right
+		} else {
+			low = left
+		}
+	}
+
+	modifier := math.Round(current*math.Exp((low+high)/2)*100) / 100
+	modifier = math.Max(model.MinEaseModifier, math.Min(modifier, model.MaxEaseModifier))
+	after, _ := sm2LogLoss(histories, modifier/current)
+	if after >= before {
+		modifier, after = current, before
+	}
+
+	return model.OptimizeResult{
+		Scheduler:     model.SchedulerSM2,
+		Reviews:       samples,
+		LogLossBefore: before,
+		LogLossAfter:  after,
+		EaseModifier:  modifier,
+	}, nil
+}
+
+// A helper function that returns the mean log-loss of the recall predicted for
+// the cards answered in review when their intervals are multiplied by the
+// scale, along with the number of such reviews.
+func sm2LogLoss(histories [][]model.Review, scale float64) (float64, int) {
+	total, samples := 0.0, 0
+
+	for _, history := range histories {
+		for i, review := range history {
+			if i == 0 || review.State != model.CardStateReview || review.LastInterval < 1 {
+				continue
+			}
+
+			elapsed := math.Max(0, review.ReviewTime.Sub(history[i-1].ReviewTime).Hours()/24)
+			recall := math.Pow(sm2TargetRetention, elapsed/(float64(review.LastInterval)*scale))
+			total += logLoss(recall, review.Grade)
+			samples++
+		}
+	}
+
+	if samples == 0 {
+		return 0, 0
+	}
+	return total / float64(samples), samples
+}
+
+// A helper function that groups the review log entries that changed the
+// scheduling of their card by card, oldest first. Cards are ordered by ID.
+func reviewHistories(reviews []model.Review) [][]model.Review {
+	byCard := map[int][]model.Review{}
+	cardIDs := []int{}
+
+	for _, review := range reviews {
+		if !changedScheduling(review) {
+			continue
+		}
+		if _, exists := byCard[review.CardID]; !exists {
+			cardIDs = append(cardIDs, review.CardID)
+		}
+		byCard[review.CardID] = append(byCard[review.CardID], review)
+	}
+
+	sort.Ints(cardIDs)
+	histories := make([][]model.Review, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		history := byCard[cardID]
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].ReviewTime.Before(history[j].ReviewTime)
+		})
+		histories = append(histories, history)
+	}
+
+	return histories
+}
+
+// A helper function that reports whether a review log entry changed the
+// scheduling of its card, unlike previews and cram answers.
+func changedScheduling(review model.Review) bool {
+	return review.Kind != model.ReviewKindPreview && review.Kind != model.ReviewKindCram && model.ValidGrade(review.Grade)
+}
+
+// A helper function that returns the log-loss of a predicted probability of
+// recall, given the grade the card was actually answered with.
+func logLoss(recall float64, grade int) float64 {
+	recall = math.Max(optimizeMinProbability, math.Min(recall, 1-optimizeMinProbability))
+	if grade == model.GradeAgain {
+		return -math.Log(1 - recall)
+	}
+	return -math.Log(recall)
+}
+
+// A helper function that limits every FSRS weight to its bounds.
+func clampWeights(weights []float64) []float64 {
+	for i, bounds := range fsrsWeightBounds {
+		weights[i] = math.Max(bounds[0], math.Min(weights[i], bounds[1]))
+	}
+	return weights
+}
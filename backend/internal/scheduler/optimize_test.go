@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// simulatedLog returns the review log of cards that are learned and then
+// reviewed every few days, answering Again when forgotten returns true.
+func simulatedLog(cards int, reviews int, interval int, forgotten func(card int, review int) bool) []model.Review {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	log := []model.Review{}
+
+	for card := 0; card < cards; card++ {
+		reviewTime := start.Add(time.Duration(card) * time.Minute)
+		log = append(log, model.Review{
+			CardID: card, Grade: model.GradeGood, Kind: model.ReviewKindLearn,
+			State: model.CardStateNew, Interval: interval, ReviewTime: reviewTime,
+		})
+
+		for review := 0; review < reviews; review++ {
+			reviewTime = reviewTime.AddDate(0, 0, interval)
+			grade := model.GradeGood
+			if forgotten(card, review) {
+				grade = model.GradeAgain
+			}
+			log = append(log, model.Review{
+				CardID: card, Grade: grade, Kind: model.ReviewKindReview,
+				State: model.CardStateReview, Interval: interval, LastInterval: interval, ReviewTime: reviewTime,
+			})
+		}
+	}
+
+	return log
+}
+
+func TestOptimizeSM2(t *testing.T) {
+	settings := model.DefaultDeckPreset()
+
+	t.Run("Not Enough Reviews", func(t *testing.T) {
+		_, err := Optimize(simulatedLog(2, 2, 10, func(int, int) bool { return false }), settings)
+		assert.Equal(t, utils.ErrNotEnoughReviews, err)
+	})
+
+	t.Run("Previews Are Ignored", func(t *testing.T) {
+		log := simulatedLog(2, 2, 10, func(int, int) bool { return false })
+		for i := 0; i < OptimizeMinReviews; i++ {
+			log = append(log, model.Review{CardID: 0, Grade: model.GradeGood, Kind: model.ReviewKindPreview, State: model.CardStateReview, LastInterval: 10})
+		}
+		_, err := Optimize(log, settings)
+		assert.Equal(t, utils.ErrNotEnoughReviews, err)
+	})
+
+	t.Run("Always Recalled", func(t *testing.T) {
+		result, err := Optimize(simulatedLog(10, 3, 10, func(int, int) bool { return false }), settings)
+		assert.Nil(t, err)
+		assert.Equal(t, model.SchedulerSM2, result.Scheduler)
+		assert.Equal(t, 30, result.Reviews)
+		assert.Greater(t, result.EaseModifier, 1.0)
+		assert.LessOrEqual(t, result.EaseModifier, model.MaxEaseModifier)
+		assert.Less(t, result.LogLossAfter, result.LogLossBefore)
+		assert.Nil(t, result.FSRSWeights)
+	})
+
+	t.Run("Often Forgotten", func(t *testing.T) {
+		result, err := Optimize(simulatedLog(10, 4, 10, func(card int, review int) bool { return (card+review)%2 == 0 }), settings)
+		assert.Nil(t, err)
+		assert.Less(t, result.EaseModifier, 1.0)
+		assert.GreaterOrEqual(t, result.EaseModifier, model.MinEaseModifier)
+		assert.Less(t, result.LogLossAfter, result.LogLossBefore)
+	})
+}
+
+func TestOptimizeFSRS(t *testing.T) {
+	settings := model.DefaultDeckPreset()
+	settings.Scheduler = model.SchedulerFSRS
+
+	t.Run("Not Enough Reviews", func(t *testing.T) {
+		_, err := Optimize(simulatedLog(1, 3, 30, func(int, int) bool { return false }), settings)
+		assert.Equal(t, utils.ErrNotEnoughReviews, err)
+	})
+
+	t.Run("Fits The Log", func(t *testing.T) {
+		log := simulatedLog(10, 3, 30, func(card int, review int) bool { return card%5 == 0 && review == 0 })
+		result, err := Optimize(log, settings)
+		assert.Nil(t, err)
+		assert.Equal(t, model.SchedulerFSRS, result.Scheduler)
+		assert.Equal(t, 30, result.Reviews)
+		assert.Less(t, result.LogLossAfter, result.LogLossBefore)
+
+		assert.Equal(t, model.FSRSWeightCount, len(result.FSRSWeights))
+		for i, weight := range result.FSRSWeights {
+			assert.GreaterOrEqual(t, weight, fsrsWeightBounds[i][0])
+			assert.LessOrEqual(t, weight, fsrsWeightBounds[i][1])
+		}
+
+		settings.FSRSWeights = result.FSRSWeights
+		assert.Nil(t, settings.Validate())
+	})
+
+	t.Run("Fitted Weights Are Used", func(t *testing.T) {
+		fitted := model.DefaultDeckPreset()
+		fitted.FSRSWeights = append([]float64{}, DefaultFSRSWeights...)
+		fitted.FSRSWeights[2] = 30
+
+		now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		card := model.NewCard(0, "", "")
+		card.State = model.CardStateLearning
+		card.Step = 1
+
+		assert.Greater(t, NewFSRS(fitted).Schedule(card, model.GradeGood, now).Interval,
+			NewFSRS(model.DefaultDeckPreset()).Schedule(card, model.GradeGood, now).Interval)
+	})
+}
+
+func TestSM2EaseModifier(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	card := setReview(model.NewCard(0, "", ""), 10, now)
+	card.Ease = model.CardDefaultEase
+
+	settings := model.DefaultDeckPreset()
+	settings.EaseModifier = 2
+	assert.Equal(t, 50, NewSM2(settings).Schedule(card, model.GradeGood, now).Interval)
+
+	settings.EaseModifier = 1
+	assert.Equal(t, 25, NewSM2(settings).Schedule(card, model.GradeGood, now).Interval)
+}
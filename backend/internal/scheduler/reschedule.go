@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"sort"
+)
+
+// Reschedules a card by replaying its review log through the scheduler chosen
+// by the settings, as if the card had always been studied with them. Only the
+// answers since the card was last new are replayed, so that a card whose
+// scheduling was reset starts over from its first answer after the reset.
+// Every answer is replayed in the state the log recorded it in. The content,
+// tags, flag and suspension of the card are kept.
+//
+// Parameters:
+//   - card model.Card : The card to reschedule.
+//   - reviews []model.Review : The review log entries of the card, in any order.
+//   - settings model.DeckPreset : The effective settings of the home deck of the card.
+//
+// Returns:
+//   - model.Card : The rescheduled card.
+//   - bool : False if the card is new or has no answer to replay, in which case it is returned unchanged.
+func Reschedule(card model.Card, reviews []model.Review, settings model.DeckPreset) (model.Card, bool) {
+	if card.State == model.CardStateNew {
+		return card, false
+	}
+
+	history := []model.Review{}
+	for _, review := range reviews {
+		if review.CardID == card.ID && changedScheduling(review) {
+			history = append(history, review)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].ReviewTime.Before(history[j].ReviewTime)
+	})
+
+	start := 0
+	for i, review := range history {
+		if review.State == model.CardStateNew {
+			start = i
+		}
+	}
+	history = history[start:]
+	if len(history) == 0 {
+		return card, false
+	}
+
+	rescheduled := card
+	rescheduled.State = model.CardStateNew
+	rescheduled.Step = 0
+	rescheduled.Interval = 0
+	rescheduled.Ease = model.CardDefaultEase
+	rescheduled.Stability = 0
+	rescheduled.Difficulty = 0
+	rescheduled.Lapses = 0
+	rescheduled.RetentionLevel = 0
+	rescheduled.LastReviewTime = nil
+
+	s := New(settings)
+	for _, review := range history {
+		rescheduled.State = review.State
+		rescheduled = s.Schedule(rescheduled, review.Grade, review.ReviewTime)
+	}
+
+	return rescheduled, true
+}
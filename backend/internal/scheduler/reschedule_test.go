@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReschedule(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	learned := []model.Review{
+		{CardID: 4, Grade: model.GradeGood, Kind: model.ReviewKindLearn, State: model.CardStateNew, ReviewTime: start},
+		{CardID: 4, Grade: model.GradeGood, Kind: model.ReviewKindLearn, State: model.CardStateLearning, ReviewTime: start.Add(10 * time.Minute)},
+		{CardID: 4, Grade: model.GradeGood, Kind: model.ReviewKindReview, State: model.CardStateReview, ReviewTime: start.AddDate(0, 0, 1)},
+	}
+
+	card := model.NewCard(0, "Test content", "")
+	card.ID = 4
+	card.Tags = []string{"kept"}
+	card.State = model.CardStateReview
+	card.Interval = 100
+	card.Lapses = 5
+
+	t.Run("New Card", func(t *testing.T) {
+		_, ok := Reschedule(model.NewCard(0, "", ""), learned, model.DefaultDeckPreset())
+		assert.False(t, ok)
+	})
+
+	t.Run("No History", func(t *testing.T) {
+		preview := model.Review{CardID: 4, Grade: model.GradeGood, Kind: model.ReviewKindPreview, ReviewTime: start}
+		_, ok := Reschedule(card, []model.Review{preview}, model.DefaultDeckPreset())
+		assert.False(t, ok)
+	})
+
+	t.Run("Replays With SM-2", func(t *testing.T) {
+		rescheduled, ok := Reschedule(card, learned, model.DefaultDeckPreset())
+		assert.True(t, ok)
+		assert.Equal(t, model.CardStateReview, rescheduled.State)
+		assert.Equal(t, 3, rescheduled.Interval, "Expected the graduating interval times the ease")
+		assert.Equal(t, start.AddDate(0, 0, 4), rescheduled.NextReviewTime)
+		assert.Equal(t, 0, rescheduled.Lapses)
+		assert.Equal(t, []string{"kept"}, rescheduled.Tags)
+		assert.Equal(t, "Test content", rescheduled.Content)
+	})
+
+	t.Run("Replays With FSRS", func(t *testing.T) {
+		settings := model.DefaultDeckPreset()
+		settings.Scheduler = model.SchedulerFSRS
+
+		rescheduled, ok := Reschedule(card, learned, settings)
+		assert.True(t, ok)
+		assert.Greater(t, rescheduled.Stability, 0.0)
+		assert.Equal(t, start.AddDate(0, 0, 1+rescheduled.Interval), rescheduled.NextReviewTime)
+
+		settings.DesiredRetention = model.MaxDesiredRetention
+		stricter, _ := Reschedule(card, learned, settings)
+		assert.Less(t, stricter.Interval, rescheduled.Interval, "Expected a higher retention to shorten the interval")
+	})
+
+	t.Run("Starts Over After A Reset", func(t *testing.T) {
+		history := []model.Review{
+			{CardID: 4, Grade: model.GradeAgain, Kind: model.ReviewKindReview, State: model.CardStateReview, ReviewTime: start.AddDate(0, 0, -20)},
+		}
+		history = append(history, learned...)
+
+		rescheduled, ok := Reschedule(card, history, model.DefaultDeckPreset())
+		assert.True(t, ok)
+		assert.Equal(t, 0, rescheduled.Lapses)
+		assert.Equal(t, 3, rescheduled.Interval)
+	})
+}
@@ -0,0 +1,215 @@
+// Package scheduler decides when a card should be studied again after it is
+// answered, following the scheduler chosen by the settings of its deck.
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"time"
+)
+
+// An interface for the algorithms that compute the next scheduling state of a card.
+type Scheduler interface {
+	// Schedule returns the card with the scheduling state the grade gives it.
+	// The grade must be valid.
+	Schedule(card model.Card, grade int, now time.Time) model.Card
+}
+
+// Result is the outcome of answering a card.
+type Result struct {
+	// Card is the answered card with its new scheduling state.
+	Card model.Card
+	// Review is the review log entry recording the answer.
+	Review model.Review
+	// Leech is true if the answer made the card a leech.
+	Leech bool
+}
+
+// Returns the scheduler selected by the settings of a deck.
+//
+// Parameters:
+//   - settings model.DeckPreset : The effective settings of the deck.
+//
+// Returns:
+//   - Scheduler
+func New(settings model.DeckPreset) Scheduler {
+	if settings.Scheduler == model.SchedulerFSRS {
+		return NewFSRS(settings)
+	}
+	return NewSM2(settings)
+}
+
+// Answers a card with a grade. The card is rescheduled by the scheduler of the
+// settings, its interval is fuzzed if it is put in review, and a review log
+// entry is built. When the answer is a lapse that makes the card a leech, the
+// leech action of the settings is applied to it.
+//
+// Parameters:
+//   - card model.Card : The card being answered.
+//   - grade int : The grade of the answer.
+//   - duration int : How long the answer took, in milliseconds.
+//   - settings model.DeckPreset : The effective settings of the deck of the card.
+//   - now time.Time : The time of the answer.
+//   - fuzzer *Fuzzer : The fuzzer spreading the due dates, nil to keep the computed interval.
+//
+// Returns:
+//   - Result : The rescheduled card and its review log entry.
+//   - error : utils.ErrInvalidGrade if the grade or the duration is invalid,
+//     or an error if the fuzzer can't retrieve the due counts, nil otherwise.
+func Answer(card model.Card, grade int, duration int, settings model.DeckPreset, now time.Time, fuzzer *Fuzzer) (Result, error) {
+	if !model.ValidGrade(grade) || duration < 0 {
+		return Result{}, utils.ErrInvalidGrade
+	}
+
+	scheduled := New(settings).Schedule(card, grade, now)
+	if fuzzer != nil {
+		var err error
+		if scheduled, err = fuzzer.Fuzz(scheduled, settings, now); err != nil {
+			return Result{}, err
+		}
+	}
+
+	review := model.Review{
+		CardID:       card.ID,
+		DeckID:       card.DeckID,
+		Grade:        grade,
+		Kind:         model.ReviewKindOf(card.State),
+		State:        card.State,
+		Interval:     scheduled.Interval,
+		LastInterval: card.Interval,
+		Ease:         scheduled.Ease,
+		Stability:    scheduled.Stability,
+		Difficulty:   scheduled.Difficulty,
+		Duration:     duration,
+		ReviewTime:   now,
+	}
+
+	leech := review.IsLapse() && settings.IsLeech(scheduled.Lapses)
+	if leech {
+		scheduled = settings.HandleLeech(scheduled)
+	}
+
+	return Result{Card: scheduled, Review: review, Leech: leech}, nil
+}
+
+// Answers a card with a grade without rescheduling it. The review log entry
+// is a preview that keeps the scheduling state the card already had.
+//
+// Parameters:
+//   - card model.Card : The card being answered.
+//   - grade int : The grade of the answer.
+//   - duration int : How long the answer took, in milliseconds.
+//   - now time.Time : The time of the answer.
+//
+// Returns:
+//   - Result : The unchanged card and its review log entry.
+//   - error : utils.ErrInvalidGrade if the grade or the duration is invalid, nil otherwise.
+func Preview(card model.Card, grade int, duration int, now time.Time) (Result, error) {
+	return unscheduled(card, grade, duration, model.ReviewKindPreview, now)
+}
+
+// Answers a card with a grade while cramming. Like a preview, the card keeps
+// its scheduling state, but the review log entry is a cram answer.
+//
+// Parameters:
+//   - card model.Card : The card being answered.
+//   - grade int : The grade of the answer.
+//   - duration int : How long the answer took, in milliseconds.
+//   - now time.Time : The time of the answer.
+//
+// Returns:
+//   - Result : The unchanged card and its review log entry.
+//   - error : utils.ErrInvalidGrade if the grade or the duration is invalid, nil otherwise.
+func Cram(card model.Card, grade int, duration int, now time.Time) (Result, error) {
+	return unscheduled(card, grade, duration, model.ReviewKindCram, now)
+}
+
+// A helper function that answers a card without rescheduling it, logging
+// the answer with the given kind.
+func unscheduled(card model.Card, grade int, duration int, kind string, now time.Time) (Result, error) {
+	if !model.ValidGrade(grade) || duration < 0 {
+		return Result{}, utils.ErrInvalidGrade
+	}
+
+	review := model.Review{
+		CardID:       card.ID,
+		DeckID:       card.DeckID,
+		Grade:        grade,
+		Kind:         kind,
+		State:        card.State,
+		Interval:     card.Interval,
+		LastInterval: card.Interval,
+		Ease:         card.Ease,
+		Stability:    card.Stability,
+		Difficulty:   card.Difficulty,
+		Duration:     duration,
+		ReviewTime:   now,
+	}
+
+	return Result{Card: card, Review: review}, nil
+}
+
+// A helper function that moves a card through its learning or relearning steps.
+// Again goes back to the first step, Hard repeats the current step, Good moves
+// to the next step and Easy skips the remaining steps. The card graduates once
+// it passes its last step.
+//
+// Parameters:
+//   - steps []int : The steps of the card, in minutes.
+//   - step int : The index of the current step of the card.
+//   - grade int : The grade of the answer.
+//
+// Returns:
+//   - int : The index of the next step.
+//   - time.Duration : How long until the card is shown again, if it doesn't graduate.
+//   - bool : True if the card graduates.
+func answerStep(steps []int, step int, grade int) (int, time.Duration, bool) {
+	if len(steps) == 0 || grade == model.GradeEasy {
+		return 0, 0, true
+	}
+	step = min(step, len(steps)-1)
+
+	switch grade {
+	case model.GradeAgain:
+		return 0, minutes(steps[0]), false
+	case model.GradeHard:
+		if step == 0 && len(steps) > 1 {
+			return step, minutes(steps[0]+steps[1]) / 2, false
+		} else if step == 0 {
+			return step, minutes(steps[0]) * 3 / 2, false
+		}
+		return step, minutes(steps[step]), false
+	default:
+		if step+1 >= len(steps) {
+			return 0, 0, true
+		}
+		return step + 1, minutes(steps[step+1]), false
+	}
+}
+
+// A helper function that puts a card that hasn't graduated on its next step.
+func setStep(card model.Card, state int, step int, delay time.Duration, now time.Time) model.Card {
+	card.State = state
+	card.Step = step
+	card.NextReviewTime = now.Add(delay)
+	return card
+}
+
+// A helper function that schedules a card for review in a number of days.
+func setReview(card model.Card, interval int, now time.Time) model.Card {
+	card.State = model.CardStateReview
+	card.Step = 0
+	card.Interval = interval
+	card.NextReviewTime = now.AddDate(0, 0, interval)
+	return card
+}
+
+// A helper function that converts a step in minutes to a duration.
+func minutes(step int) time.Duration {
+	return time.Duration(step) * time.Minute
+}
+
+// A helper function that limits an interval in days to the range allowed by the settings.
+func clampInterval(interval int, settings model.DeckPreset) int {
+	return max(1, min(interval, settings.MaxInterval))
+}
@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"flash-learn/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnswerStep(t *testing.T) {
+	steps := []int{1, 10}
+
+	testCases := []struct {
+		name      string
+		steps     []int
+		step      int
+		grade     int
+		nextStep  int
+		delay     time.Duration
+		graduated bool
+	}{
+		{name: "Again", steps: steps, step: 1, grade: model.GradeAgain, nextStep: 0, delay: time.Minute},
+		{name: "Hard On First Step", steps: steps, step: 0, grade: model.GradeHard, nextStep: 0, delay: 5*time.Minute + 30*time.Second},
+		{name: "Hard On Single Step", steps: []int{10}, step: 0, grade: model.GradeHard, nextStep: 0, delay: 15 * time.Minute},
+		{name: "Hard On Later Step", steps: steps, step: 1, grade: model.GradeHard, nextStep: 1, delay: 10 * time.Minute},
+		{name: "Good", steps: steps, step: 0, grade: model.GradeGood, nextStep: 1, delay: 10 * time.Minute},
+		{name: "Good On Last Step", steps: steps, step: 1, grade: model.GradeGood, graduated: true},
+		{name: "Easy", steps: steps, step: 0, grade: model.GradeEasy, graduated: true},
+		{name: "No Steps", steps: []int{}, step: 0, grade: model.GradeAgain, graduated: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step, delay, graduated := answerStep(tc.steps, tc.step, tc.grade)
+
+			assert.Equal(t, tc.graduated, graduated)
+			if !tc.graduated {
+				assert.Equal(t, tc.nextStep, step)
+				assert.Equal(t, tc.delay, delay)
+			}
+		})
+	}
+}
+
+func TestSM2Schedule(t *testing.T) {
+	settings := model.DefaultDeckPreset()
+	scheduler := NewSM2(settings)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("New Card Goes Through Learning Steps", func(t *testing.T) {
+		card := scheduler.Schedule(model.NewCard(0, "", ""), model.GradeGood, now)
+		assert.Equal(t, model.CardStateLearning, card.State)
+		assert.Equal(t, now.Add(10*time.Minute), card.NextReviewTime)
+
+		card = scheduler.Schedule(card, model.GradeGood, now)
+		assert.Equal(t, model.CardStateReview, card.State)
+		assert.Equal(t, settings.GraduatingInterval, card.Interval)
+		assert.Equal(t, 1, card.RetentionLevel)
+	})
+
+	t.Run("Review Intervals Grow With Grade", func(t *testing.T) {
+		card := model.NewCard(0, "", "")
+		card.State = model.CardStateReview
+		card.Interval = 10
+
+		hard := scheduler.Schedule(card, model.GradeHard, now)
+		good := scheduler.Schedule(card, model.GradeGood, now)
+		easy := scheduler.Schedule(card, model.GradeEasy, now)
+
+		assert.Equal(t, 12, hard.Interval)
+		assert.Equal(t, 25, good.Interval)
+		assert.Equal(t, 33, easy.Interval)
+		assert.Equal(t, now.AddDate(0, 0, 25), good.NextReviewTime)
+		assert.Less(t, hard.Ease, model.CardDefaultEase)
+		assert.Greater(t, easy.Ease, model.CardDefaultEase)
+	})
+
+	t.Run("Lapse Moves Card To Relearning", func(t *testing.T) {
+		card := model.NewCard(0, "", "")
+		card.State = model.CardStateReview
+		card.Interval = 10
+		card.RetentionLevel = 3
+
+		card = scheduler.Schedule(card, model.GradeAgain, now)
+		assert.Equal(t, model.CardStateRelearning, card.State)
+		assert.Equal(t, 1, card.Lapses)
+		assert.Equal(t, 0, card.RetentionLevel)
+		assert.Equal(t, model.CardDefaultEase-sm2LapseEasePenalty, card.Ease)
+
+		card = scheduler.Schedule(card, model.GradeGood, now)
+		assert.Equal(t, model.CardStateReview, card.State)
+		assert.Equal(t, now.AddDate(0, 0, 1), card.NextReviewTime)
+	})
+
+	t.Run("Interval Is Capped", func(t *testing.T) {
+		card := model.NewCard(0, "", "")
+		card.State = model.CardStateReview
+		card.Interval = settings.MaxInterval
+
+		card = scheduler.Schedule(card, model.GradeEasy, now)
+		assert.Equal(t, settings.MaxInterval, card.Interval)
+	})
+}
+
+func TestFSRSSchedule(t *testing.T) {
+	settings := model.DefaultDeckPreset()
+	settings.Scheduler = model.SchedulerFSRS
+	scheduler := NewFSRS(settings)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Retrievability At Stability", func(t *testing.T) {
+		assert.InDelta(t, 0.9, Retrievability(12, 12), 1e-9)
+		assert.Equal(t, 1.0, Retrievability(0, 12))
+	})
+
+	t.Run("New Card Is Initialized", func(t *testing.T) {
+		card := scheduler.Schedule(model.NewCard(0, "", ""), model.GradeEasy, now)
+		assert.Equal(t, model.CardStateReview, card.State)
+		assert.Equal(t, DefaultFSRSWeights[3], card.Stability)
+		assert.Equal(t, 14, card.Interval)
+	})
+
+	t.Run("Recall Increases Stability", func(t *testing.T) {
+		last := now.AddDate(0, 0, -10)
+		card := model.NewCard(0, "", "")
+		card.State = model.CardStateReview
+		card.Stability = 10
+		card.Difficulty = 5
+		card.Interval = 10
+		card.LastReviewTime = &last
+
+		good := scheduler.Schedule(card, model.GradeGood, now)
+		again := scheduler.Schedule(card, model.GradeAgain, now)
+
+		assert.Greater(t, good.Stability, card.Stability)
+		assert.Greater(t, good.Interval, card.Interval)
+		assert.Less(t, again.Stability, card.Stability)
+		assert.Greater(t, again.Difficulty, card.Difficulty)
+		assert.Equal(t, 1, again.Lapses)
+	})
+
+	t.Run("Lower Retention Gives Longer Intervals", func(t *testing.T) {
+		relaxed := settings
+		relaxed.DesiredRetention = 0.8
+		assert.Greater(t, NewFSRS(relaxed).interval(10), scheduler.interval(10))
+	})
+}
+
+func TestAnswer(t *testing.T) {
+	settings := model.DefaultDeckPreset()
+	settings.LeechThreshold = 2
+	settings.LeechAction = model.LeechActionSuspend
+	settings.LeechFlag = 1
+	now := time.Now()
+
+	card := model.NewCard(3, "", "")
+	card.ID = 7
+	card.State = model.CardStateReview
+	card.Interval = 5
+
+	t.Run("Invalid Grade", func(t *testing.T) {
+		_, err := Answer(card, 0, 0, settings, now, nil)
+		assert.Equal(t, utils.ErrInvalidGrade, err)
+	})
+
+	t.Run("Review Log Entry", func(t *testing.T) {
+		result, err := Answer(card, model.GradeGood, 4200, settings, now, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 7, result.Review.CardID)
+		assert.Equal(t, 3, result.Review.DeckID)
+		assert.Equal(t, model.ReviewKindReview, result.Review.Kind)
+		assert.Equal(t, 5, result.Review.LastInterval)
+		assert.Equal(t, result.Card.Interval, result.Review.Interval)
+		assert.Equal(t, 4200, result.Review.Duration)
+		assert.False(t, result.Leech)
+	})
+
+	t.Run("Leech At Threshold", func(t *testing.T) {
+		first, _ := Answer(card, model.GradeAgain, 0, settings, now, nil)
+		assert.False(t, first.Leech)
+
+		relearned := first.Card
+		relearned.State = model.CardStateReview
+		second, _ := Answer(relearned, model.GradeAgain, 0, settings, now, nil)
+		assert.True(t, second.Leech)
+		assert.Equal(t, 2, second.Card.Lapses)
+		assert.Contains(t, second.Card.Tags, model.LeechTag)
+		assert.True(t, second.Card.Suspended)
+		assert.Equal(t, 1, second.Card.Flag)
+	})
+}
+
+func TestCram(t *testing.T) {
+	now := time.Now()
+
+	card := model.NewCard(3, "", "")
+	card.ID = 7
+	card.State = model.CardStateReview
+	card.Interval = 5
+	card.RetentionLevel = 2
+
+	t.Run("Invalid Grade", func(t *testing.T) {
+		_, err := Cram(card, 5, 0, now)
+		assert.Equal(t, utils.ErrInvalidGrade, err)
+	})
+
+	t.Run("Scheduling Unchanged", func(t *testing.T) {
+		for _, grade := range []int{model.GradeAgain, model.GradeEasy} {
+			result, err := Cram(card, grade, 1500, now)
+			assert.Nil(t, err)
+			assert.Equal(t, card, result.Card)
+			assert.Equal(t, model.ReviewKindCram, result.Review.Kind)
+			assert.Equal(t, 5, result.Review.Interval)
+			assert.False(t, result.Review.IsLapse())
+			assert.False(t, result.Leech)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"flash-learn/internal/model"
+	"math"
+	"time"
+)
+
+// Changes of the ease factor of a card in review.
+const (
+	sm2LapseEasePenalty = 0.2
+	sm2HardEasePenalty  = 0.15
+	sm2EasyEaseBonus    = 0.15
+	sm2HardMultiplier   = 1.2
+)
+
+// A scheduler based on SuperMemo 2, as popularized by Anki. Every card has an
+// ease factor by which its interval is multiplied when it is remembered,
+// and which decreases when the card is hard to remember. The ease modifier of
+// the settings scales the ease of Good and Easy answers.
+type SM2 struct {
+	settings model.DeckPreset
+}
+
+// Creates and returns a new instance of SM2.
+//
+// Parameters:
+//   - settings model.DeckPreset : The effective settings of the deck.
+//
+// Returns:
+//   - *SM2
+func NewSM2(settings model.DeckPreset) *SM2 {
+	return &SM2{settings: settings}
+}
+
+// Returns the card with the scheduling state the grade gives it.
+//
+// Parameters:
+//   - card model.Card : The card being answered.
+//   - grade int : The grade of the answer.
+//   - now time.Time : The time of the answer.
+//
+// Returns:
+//   - model.Card : The rescheduled card.
+func (s *SM2) Schedule(card model.Card, grade int, now time.Time) model.Card {
+	if card.Ease < model.CardMinEase {
+		card.Ease = model.CardDefaultEase
+	}
+	reviewed := now
+	card.LastReviewTime = &reviewed
+
+	switch card.State {
+	case model.CardStateReview:
+		return s.scheduleReview(card, grade, now)
+	case model.CardStateRelearning:
+		step, delay, graduated := answerStep(s.settings.RelearningSteps, card.Step, grade)
+		if graduated {
+			return setReview(card, card.Interval, now)
+		}
+		return setStep(card, model.CardStateRelearning, step, delay, now)
+	default:
+		step, delay, graduated := answerStep(s.settings.LearningSteps, card.Step, grade)
+		if !graduated {
+			return setStep(card, model.CardStateLearning, step, delay, now)
+		}
+
+		interval := s.settings.GraduatingInterval
+		if grade == model.GradeEasy {
+			interval = max(interval+1, int(math.Ceil(float64(interval)*s.settings.EasyBonus)))
+		}
+		card.RetentionLevel = 1
+		return setReview(card, clampInterval(interval, s.settings), now)
+	}
+}
+
+// A helper function that schedules a card answered in review.
+func (s *SM2) scheduleReview(card model.Card, grade int, now time.Time) model.Card {
+	last := max(card.Interval, 1)
+
+	var interval int
+	switch grade {
+	case model.GradeAgain:
+		card.Ease = math.Max(model.CardMinEase, card.Ease-sm2LapseEasePenalty)
+		card.Lapses++
+		card.RetentionLevel = 0
+		card.Interval = 1
+
+		if len(s.settings.RelearningSteps) > 0 {
+			return setStep(card, model.CardStateRelearning, 0, minutes(s.settings.RelearningSteps[0]), now)
+		}
+		return setReview(card, card.Interval, now)
+	case model.GradeHard:
+		interval = int(math.Round(float64(last) * sm2HardMultiplier))
+		card.Ease = math.Max(model.CardMinEase, card.Ease-sm2HardEasePenalty)
+	case model.GradeGood:
+		interval = int(math.Round(float64(last) * card.Ease * s.settings.EaseModifier))
+	default:
+		interval = int(math.Round(float64(last) * card.Ease * s.settings.EaseModifier * s.settings.EasyBonus))
+		card.Ease += sm2EasyEaseBonus
+	}
+
+	card.RetentionLevel++
+	return setReview(card, clampInterval(max(interval, last+1), s.settings), now)
+}
@@ -9,4 +9,20 @@ var (
 	ErrMaxLengthExceeded     = errors.New("max length exceeded")
 	ErrDuplicateKeyViolation = errors.New("duplicate key violation")
 	ErrDeckNotExist          = errors.New("deck doesn't exist")
+	ErrInvalidParent         = errors.New("invalid parent deck")
+	ErrInvalidPreset         = errors.New("invalid deck preset")
+	ErrPresetNotExist        = errors.New("deck preset doesn't exist")
+	ErrPresetChanged         = errors.New("deck preset changed since it was read")
+	ErrInvalidBulkOperation  = errors.New("invalid bulk operation")
+	ErrInvalidFlag           = errors.New("invalid flag")
+	ErrInvalidGrade          = errors.New("invalid grade")
+	ErrInvalidFilteredDeck   = errors.New("invalid filtered deck")
+	ErrInvalidStudySession   = errors.New("invalid study session")
+	ErrNotEnoughReviews      = errors.New("not enough reviews")
+	ErrJobFinished           = errors.New("job already finished")
+	ErrJobCancelled          = errors.New("job cancelled")
+	ErrJobApplied            = errors.New("job already applied")
+	ErrNoJob                 = errors.New("no job to run")
+	ErrCardChanged           = errors.New("card changed since it was read")
+	ErrStudySessionChanged   = errors.New("study session changed since it was read")
 )
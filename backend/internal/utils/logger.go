@@ -1,15 +1,80 @@
 package utils
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"os"
 )
 
-func GetLogger() *slog.Logger {
+// logAttrsKey is the context key of the attributes added to every line logged with the context.
+type logAttrsKey struct{}
+
+func GetLogger(level slog.Level) *slog.Logger {
+	return NewLogger(os.Stdout, level)
+}
+
+// NewLogger creates a text logger writing to w, which adds the attributes
+// stored in the context with WithLogAttrs to every line logged with it.
+//
+// Parameters:
+//   - w io.Writer : Where the log lines are written.
+//   - level slog.Level : The minimum level of the lines written.
+//
+// Returns:
+//   - *slog.Logger
+func NewLogger(w io.Writer, level slog.Level) *slog.Logger {
 	handlerOptions := &slog.HandlerOptions{
-		Level:     slog.LevelInfo,
+		Level:     level,
 		AddSource: true,
 	}
-	logger := slog.New(slog.NewTextHandler(os.Stdout, handlerOptions))
-	return logger
+	return slog.New(contextHandler{slog.NewTextHandler(w, handlerOptions)})
+}
+
+// WithLogAttrs returns a copy of the context carrying attributes which are
+// added to every line logged with it, such as the ID of a request.
+//
+// Parameters:
+//   - ctx context.Context : The parent context.
+//   - attrs ...slog.Attr : The attributes to add, after those of the parent.
+//
+// Returns:
+//   - context.Context
+func WithLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	parent := LogAttrs(ctx)
+	merged := make([]slog.Attr, 0, len(parent)+len(attrs))
+	merged = append(merged, parent...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, logAttrsKey{}, merged)
+}
+
+// LogAttrs returns the attributes stored in the context with WithLogAttrs.
+func LogAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler is a slog.Handler adding the attributes of the context of
+// each record before passing it to the wrapped handler.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := LogAttrs(ctx); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
@@ -0,0 +1,27 @@
+package utils
+
+import "time"
+
+// StartOfNextDay returns midnight of the day following t, in t's location.
+//
+// Parameters:
+//   - t time.Time : The reference time.
+//
+// Returns:
+//   - time.Time : The start of the next day.
+func StartOfNextDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+}
+
+// StartOfDay returns midnight of the day of t, in t's location.
+//
+// Parameters:
+//   - t time.Time : The reference time.
+//
+// Returns:
+//   - time.Time : The start of the day.
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
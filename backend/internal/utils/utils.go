@@ -2,66 +2,35 @@ package utils
 
 import (
 	"database/sql"
-	"fmt"
+	"flash-learn/internal/config"
 	"log/slog"
-	"os"
-
-	"github.com/joho/godotenv"
 )
 
-type Config struct {
-	DriverName string
-	UserName   string
-	Password   string
-	Host       string
-	Port       string
-	DbName     string
-	SSLMode    string
-}
-
-func getPostgresConfig() (Config, error) {
-	slog.Debug("Getting postgres config")
-	const envFileName string = "postgres.env"
-	err := godotenv.Load(envFileName)
-
-	if err != nil {
-		slog.Error("Error loading secret.env file", "error", err)
-		return Config{}, err
-	}
-
-	config := Config{
-		DriverName: os.Getenv("POSTGRES_DRIVER"),
-		UserName:   os.Getenv("POSTGRES_USER"),
-		Password:   os.Getenv("POSTGRES_PASSWORD"),
-		Host:       os.Getenv("POSTGRES_HOST"),
-		Port:       os.Getenv("POSTGRES_PORT"),
-		DbName:     os.Getenv("POSTGRES_DB"),
-		SSLMode:    os.Getenv("POSTGRES_SSL_MODE"),
-	}
-
-	return config, nil
-}
-
-func ConnectToPostgres() (*sql.DB, error) {
-	config, err := getPostgresConfig()
-
-	if err != nil {
-		slog.Error("Error getting postgres config", "error", err)
-		return nil, err
-	}
-
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		config.UserName, config.Password, config.Host, config.Port, config.DbName, config.SSLMode)
-
-	db, err := sql.Open(config.DriverName, connStr)
+// ConnectToPostgres opens the Postgres connection pool and checks that the
+// database can be reached.
+//
+// Parameters:
+//   - cfg config.DatabaseConfig : The connection string and the limits of the pool.
+//
+// Returns:
+//   - *sql.DB : The connection pool.
+//   - error : An error if the database can't be reached, nil otherwise.
+func ConnectToPostgres(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DSN)
 
 	if err != nil {
 		slog.Error("Error connecting to postgres", "error", err)
 		return nil, err
 	}
 
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
 	if err = db.Ping(); err != nil {
 		slog.Error("Error pinging postgres", "error", err)
+		db.Close()
 		return nil, err
 	}
 
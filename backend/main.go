@@ -1,40 +1,194 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"flash-learn/internal/api"
+	"flash-learn/internal/config"
 	"flash-learn/internal/database"
+	"flash-learn/internal/jobs"
+	"flash-learn/internal/maintenance"
+	"flash-learn/internal/metrics"
+	"flash-learn/internal/model"
 	"flash-learn/internal/utils"
+	"fmt"
 	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	_ "time/tzdata"
 
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	logger := utils.GetLogger()
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// The level is validated with the rest of the config
+	level, _ := cfg.Log.SlogLevel()
+	logger := utils.GetLogger(level)
 	slog.SetDefault(logger)
 
+	if err := run(cfg); err != nil {
+		slog.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run starts the server and its background workers, and stops them when the
+// process receives SIGINT or SIGTERM: the server stops accepting connections
+// and drains the requests in flight, then the workers finish their current
+// step, and the database is closed last since both use it.
+//
+// Parameters:
+//   - cfg config.Config : The validated configuration.
+//
+// Returns:
+//   - error : An error if the server fails to start or stops unexpectedly, nil after a shutdown.
+func run(cfg config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	slog.Info("Starting server")
-	db, err := utils.ConnectToPostgres()
+	db, err := utils.ConnectToPostgres(cfg.Database)
 	if err != nil {
-		slog.Error("Error connecting to postgres", "error", err)
-		return
+		return fmt.Errorf("connecting to postgres: %w", err)
 	}
+	defer func() {
+		slog.Info("Closing database")
+		if err := db.Close(); err != nil {
+			slog.Error("Error closing database", "error", err)
+		}
+	}()
+
+	preset_db_wrapper := database.NewPresetDBWrapper(db)
 	db_wrapper := database.NewDeckDBWrapper(db)
 	card_db_wrapper := database.NewCardDBWrapper(db)
+	flag_db_wrapper := database.NewFlagDBWrapper(db)
+	filtered_db_wrapper := database.NewFilteredDeckDBWrapper(db)
+	session_db_wrapper := database.NewStudySessionDBWrapper(db)
+	job_db_wrapper := database.NewJobDBWrapper(db)
 
 	slog.Info("Creating table if not exists")
-	db_wrapper.CreateTable()
-	card_db_wrapper.CreateTable()
+	tables := []interface{ CreateTable() error }{
+		preset_db_wrapper,
+		db_wrapper,
+		card_db_wrapper,
+		flag_db_wrapper,
+		filtered_db_wrapper,
+		session_db_wrapper,
+		job_db_wrapper,
+	}
+	for _, table := range tables {
+		if err := table.CreateTable(); err != nil {
+			return fmt.Errorf("creating tables: %w", err)
+		}
+	}
 
-	slog.Info("Starting API server")
-	server := api.NewAPIServer("localhost:8080", db_wrapper, card_db_wrapper)
-	err = server.Start()
+	// The workers get their own context, so that they keep running while
+	// the server drains its requests, which may enqueue jobs
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
 
-	if err != nil {
-		slog.Error("Error starting server", "error", err)
-		return
+	slog.Info("Starting job runner")
+	rescheduler := maintenance.NewRescheduler(db_wrapper, card_db_wrapper, maintenance.RescheduleBatchSize)
+	job_runner := jobs.NewRunner(job_db_wrapper, jobs.DefaultWorkers)
+	job_runner.Register(model.JobKindReschedule, rescheduler.Handle)
+	job_runner.Register(model.JobKindOptimize, maintenance.NewOptimizer(db_wrapper, card_db_wrapper).Handle)
+	purger := maintenance.NewTrashPurger(database.DeckTrashRetention, db_wrapper, card_db_wrapper)
+	job_runner.RegisterRecurring(model.JobKindPurgeTrash, purger.Handle, time.Hour)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		job_runner.Run(workerCtx)
+	}()
+
+	defer func() {
+		slog.Info("Stopping background workers")
+		stopWorkers()
+		workers.Wait()
+	}()
+
+	registry := metrics.NewRegistry()
+	registerMetrics(registry, db, job_db_wrapper)
+	defer database.SetQueryObserver(nil)
+
+	server := api.NewAPIServer(cfg.Server.Address, db_wrapper, card_db_wrapper).
+		WithServerConfig(cfg.Server).
+		WithPresetDB(preset_db_wrapper).
+		WithFlagDB(flag_db_wrapper).
+		WithFilteredDeckDB(filtered_db_wrapper).
+		WithStudySessionDB(session_db_wrapper).
+		WithJobRunner(job_runner).
+		WithMetrics(registry)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start()
+	}()
+	slog.Info("Starting API server", "address", cfg.Server.Address)
+
+	select {
+	case err = <-serverErr:
+		return fmt.Errorf("starting API server: %w", err)
+	case <-ctx.Done():
+	}
+
+	// A second signal kills the process right away
+	stop()
+	slog.Info("Shutting down API server", "timeout", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error shutting down API server", "error", err)
 	}
+	if err = <-serverErr; err != nil {
+		return fmt.Errorf("running API server: %w", err)
+	}
+
+	return nil
+}
+
+// registerMetrics adds the metrics of the database and of the job queue to
+// the registry served by the API server, along with its own metrics.
+//
+// Parameters:
+//   - registry *metrics.Registry : The registry the metrics are added to.
+//   - db *sql.DB : The database, whose connection pool is measured.
+//   - job_db database.JobDBWrapperInterface : The job database, whose unfinished jobs are counted.
+func registerMetrics(registry *metrics.Registry, db *sql.DB, job_db database.JobDBWrapperInterface) {
+	metrics.RegisterDBStats(registry, db.Stats)
+
+	queryDuration := registry.Histogram("flashlearn_db_query_duration_seconds",
+		"Time taken by the methods of the database wrappers, by wrapper and method.",
+		metrics.QueryDurationBuckets, "wrapper", "method")
+	database.SetQueryObserver(func(wrapper string, method string, duration time.Duration) {
+		queryDuration.Observe(duration.Seconds(), wrapper, method)
+	})
+
+	registry.GaugeFunc("flashlearn_jobs", "Number of background jobs queued or running, by kind and status.",
+		[]string{"kind", "status"}, func() ([]metrics.Sample, error) {
+			counts, err := job_db.CountUnfinished(context.Background())
+			if err != nil {
+				return nil, err
+			}
 
-	slog.Info("Server started on localhost:8080")
-	defer db.Close()
+			samples := make([]metrics.Sample, len(counts))
+			for i, count := range counts {
+				samples[i] = metrics.Sample{LabelValues: []string{count.Kind, count.Status}, Value: float64(count.Count)}
+			}
+			return samples, nil
+		})
 }